@@ -66,8 +66,10 @@ func main() {
 
 	// Запуск админ бота ПЕРЕД HTTP сервером чтобы callback был установлен
 	var adminBot *bot.AdminBot
+	var adminService *service.AdminService
+	var tonWallet *ton.Wallet
 	if cfg.AdminBotEnabled && len(cfg.AdminTelegramIDs) > 0 {
-		adminService := service.NewAdminService(dbPool)
+		adminService = service.NewAdminService(dbPool)
 
 		// Инициализация TON кошелька для автоматических выводов
 		walletMnemonic := os.Getenv("TON_WALLET_MNEMONIC")
@@ -76,10 +78,14 @@ func main() {
 			if os.Getenv("TON_NETWORK") == "testnet" {
 				network = ton.NetworkTestnet
 			}
-			tonWallet, err := ton.NewWallet(walletMnemonic, network)
+			wallet, err := ton.NewWallet(walletMnemonic, network)
 			if err != nil {
 				log.Error("failed to init TON wallet for withdrawals", "error", err)
 			} else {
+				tonWallet = wallet
+				if master := ton.USDTMaster(network, os.Getenv("TON_USDT_MASTER")); master != "" {
+					tonWallet.SetJettonMaster(ton.AssetUSDT, master)
+				}
 				adminService.SetWallet(tonWallet)
 				log.Info("TON wallet initialized for auto-withdrawals", "address", tonWallet.GetAddress())
 			}
@@ -120,14 +126,16 @@ func main() {
 		if os.Getenv("TON_NETWORK") == "testnet" {
 			network = ton.NetworkTestnet
 		}
-		tonClient := ton.NewClient(network, os.Getenv("TON_API_KEY"))
-
 		depositWatcher = service.NewDepositWatcher(
 			dbPool,
-			tonClient,
+			newChainIndexer(network),
 			platformWallet,
 			ton.DepositCheckInterval,
 		)
+		// депозиты USDT: transfer_notification от кошелька жетона платформы
+		if master := ton.USDTMaster(network, os.Getenv("TON_USDT_MASTER")); master != "" {
+			depositWatcher.SetJettonMaster(ton.AssetUSDT, master)
+		}
 
 		// Устанавливаем callbacks для уведомлений о депозитах, если админ бот запущен
 		if adminBot != nil {
@@ -135,10 +143,18 @@ func main() {
 			depositWatcher.SetDepositNotifyCallback(adminBot.NotifyAdminsNewDeposit)
 			// Уведомление пользователя о депозите
 			depositWatcher.SetUserNotifyCallback(func(n service.DepositNotification) {
-				adminBot.NotifyUserDeposit(n.TgID, n.AmountTON, n.CoinsCredited, n.TxHash)
+				adminBot.NotifyUserDeposit(n.TgID, n.Amount, string(n.Asset), n.CoinsCredited, n.TxHash)
 			})
+			// Уведомление админов о депозитах без зачисления (фоновая сверка)
+			depositWatcher.SetReconcileNotifyCallback(adminBot.NotifyAdminsMissingCredits)
+			// Уведомление админов о переводах с неизвестным кодом депозита
+			depositWatcher.SetUnmatchedNotifyCallback(adminBot.NotifyUnmatchedDeposit)
 			log.Info("deposit watcher: уведомления админов и пользователей включены")
 		}
+		// бэкфилл пропущенных депозитов из админ-бота
+		if adminService != nil {
+			adminService.SetDepositWatcher(depositWatcher)
+		}
 
 		go depositWatcher.Start()
 		log.Info("deposit watcher запущен", "wallet", platformWallet, "interval", ton.DepositCheckInterval)
@@ -146,6 +162,46 @@ func main() {
 		log.Warn("deposit watcher не запущен: TON_PLATFORM_WALLET не настроен")
 	}
 
+	// Воркер выводов: автоодобрение и подтверждение отправленных выводов в сети
+	var withdrawalWorker *service.WithdrawalWorker
+	if tonWallet != nil {
+		network := ton.NetworkMainnet
+		if os.Getenv("TON_NETWORK") == "testnet" {
+			network = ton.NetworkTestnet
+		}
+		withdrawalWorker = service.NewWithdrawalWorker(
+			dbPool,
+			tonWallet,
+			newChainIndexer(network),
+			service.WithdrawAutoRules{
+				Enabled:       cfg.WithdrawAutoEnabled,
+				MaxCoins:      cfg.WithdrawAutoMaxCoins,
+				MinAccountAge: time.Duration(cfg.WithdrawAutoMinAccountAge) * time.Hour,
+			},
+			time.Duration(cfg.WithdrawConfirmTimeoutMins)*time.Minute,
+			ton.WithdrawProcessInterval,
+		)
+		if adminBot != nil {
+			withdrawalWorker.SetNotifyCallback(adminBot.NotifyWithdrawalEvent)
+		}
+		go withdrawalWorker.Start()
+	}
+
+	// Мониторинг горячего кошелька: метрики и алерт админам при нехватке средств на выводы
+	var treasuryMonitor *service.TreasuryMonitor
+	if tonWallet != nil && cfg.TreasuryCheckMinutes > 0 {
+		treasuryMonitor = service.NewTreasuryMonitor(
+			dbPool,
+			tonWallet,
+			cfg.TreasuryMinCoverage,
+			time.Duration(cfg.TreasuryCheckMinutes)*time.Minute,
+		)
+		if adminBot != nil {
+			treasuryMonitor.SetAlertCallback(adminBot.NotifyTreasuryAlert)
+		}
+		go treasuryMonitor.Start()
+	}
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
@@ -162,6 +218,19 @@ func main() {
 		depositWatcher.Stop()
 	}
 
+	if withdrawalWorker != nil {
+		withdrawalWorker.Stop()
+	}
+
+	if treasuryMonitor != nil {
+		treasuryMonitor.Stop()
+	}
+
+	// PvP: новые матчи не создаются, /readyz отдает draining, живые матчи доигрывают
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), time.Duration(cfg.PvPDrainTimeout)*time.Second)
+	httpServer.DrainPvP(drainCtx)
+	drainCancel()
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -171,3 +240,14 @@ func main() {
 
 	log.Info("server exited")
 }
+
+// newChainIndexer индексатор из TON_INDEXERS (tonapi, toncenter с failover);
+// при ошибке в настройке - только tonapi
+func newChainIndexer(network ton.Network) ton.ChainIndexer {
+	indexer, err := ton.NewChainIndexer(network, os.Getenv("TON_INDEXERS"), os.Getenv("TON_API_KEY"), os.Getenv("TONCENTER_API_KEY"))
+	if err != nil {
+		logger.Get().Error("неверный TON_INDEXERS, используется tonapi", "error", err)
+		return ton.NewClient(network, os.Getenv("TON_API_KEY"))
+	}
+	return indexer
+}
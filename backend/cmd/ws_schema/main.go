@@ -0,0 +1,19 @@
+// Генерирует JSON Schema WebSocket протокола из типизированных payload (internal/ws).
+// Использование: go run ./cmd/ws_schema > ../frontend/src/api/wsProtocol.schema.json
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+
+	"telegram_webapp/internal/ws"
+)
+
+func main() {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(ws.ProtocolSchema()); err != nil {
+		log.Fatalf("encode schema: %v", err)
+	}
+}
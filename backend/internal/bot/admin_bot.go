@@ -2,15 +2,20 @@ package bot
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"html"
 	"log/slog"
+	"math"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"telegram_webapp/internal/domain"
 	"telegram_webapp/internal/logger"
 	"telegram_webapp/internal/service"
+	"telegram_webapp/internal/ton"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
@@ -241,6 +246,75 @@ func (b *AdminBot) handleCommand(msg *tgbotapi.Message) {
 	case "depositshistory":
 		response = b.handleDepositsHistory(ctx)
 
+	case "tournaments":
+		response = b.handleTournaments(ctx)
+
+	case "newtournament":
+		response = b.handleNewTournament(ctx, msg.From.ID, msg.CommandArguments())
+
+	case "canceltournament":
+		response = b.handleCancelTournament(ctx, msg.CommandArguments())
+
+	case "replay":
+		response = b.handleReplay(ctx, msg.CommandArguments())
+
+	case "chatreports":
+		response = b.handleChatReports(ctx)
+
+	case "chatlog":
+		response = b.handleChatLog(ctx, msg.CommandArguments())
+
+	case "resolvereport":
+		response = b.handleResolveChatReport(ctx, msg.From.ID, msg.CommandArguments())
+
+	case "collusion":
+		response = b.handleCollusionFlags(ctx)
+
+	case "collusionflag":
+		response = b.handleCollusionFlag(ctx, msg.CommandArguments())
+
+	case "resolvecollusion":
+		response = b.handleResolveCollusion(ctx, msg.From.ID, msg.CommandArguments())
+
+	case "collusionscan":
+		response = b.handleCollusionScan(ctx)
+
+	case "pvpconfig":
+		response = b.handlePvPConfigs(ctx)
+
+	case "setpvpconfig":
+		response = b.handleSetPvPConfig(ctx, msg.From.ID, msg.CommandArguments())
+
+	case "delpvpconfig":
+		response = b.handleDeletePvPConfig(ctx, msg.CommandArguments())
+
+	case "depositcursor":
+		response = b.handleDepositCursor()
+
+	case "backfilldeposits":
+		response = b.handleBackfillDeposits(msg.Chat.ID, msg.CommandArguments())
+
+	case "reconciledeposits":
+		response = b.handleReconcileDeposits(ctx, msg.CommandArguments())
+
+	case "treasury":
+		response = b.handleTreasury(ctx)
+
+	case "unmatched":
+		response = b.handleUnmatchedDeposits(ctx)
+
+	case "assigndeposit":
+		response = b.handleAssignDeposit(ctx, msg.From.ID, msg.CommandArguments())
+
+	case "refunddeposit":
+		response = b.handleRefundDeposit(ctx, msg.From.ID, msg.CommandArguments())
+
+	case "pricing":
+		response = b.handlePricing(ctx)
+
+	case "setpricing":
+		response = b.handleSetPricing(ctx, msg.From.ID, msg.CommandArguments())
+
 	default:
 		response = "❌ Неизвестная команда. Используйте /help для списка команд."
 	}
@@ -289,11 +363,44 @@ func (b *AdminBot) helpMessage() string {
 /withdrawalshistory - История всех выводов
 /approve &lt;id&gt; [tx_hash] - Одобрить вывод
 /reject &lt;id&gt; &lt;причина&gt; - Отклонить вывод
+/treasury - Горячий кошелек, ожидающие выводы и коины пользователей
 
 <b>💰 Депозиты:</b>
 /deposits - Последние депозиты
 /depositshistory - История всех депозитов
 /deposit &lt;tx_hash&gt; &lt;user_id&gt; &lt;ton_amount&gt; - Ручной депозит
+/depositcursor - Последний обработанный lt депозитов
+/backfilldeposits &lt;from_lt&gt; &lt;to_lt&gt; - Догнать пропущенные депозиты в диапазоне lt
+/reconciledeposits [часы] - Подтвержденные депозиты без зачисления коинов (по умолчанию 24ч)
+/unmatched - Несопоставленные переводы (очередь разбора)
+/assigndeposit &lt;id&gt; &lt;@username|tg_id&gt; - Зачислить перевод пользователю
+/refunddeposit &lt;id&gt; [tx_hash] - Вернуть перевод отправителю за вычетом комиссии сети
+
+<b>💱 Курс и комиссия:</b>
+/pricing - Курс коинов, комиссия на вывод, дневной лимит и история версий
+/setpricing ton=10 usdt=2 fee=1 maxday=1000 from=2026-01-31T12:00 - Новая версия (любые из параметров, from в UTC, без from - сразу)
+
+<b>🏆 Турниры:</b>
+/tournaments - Открытые и идущие турниры
+/newtournament &lt;rps|mines&gt; &lt;gems|coins&gt; &lt;взнос&gt; &lt;макс_игроков&gt; &lt;старт_через_мин&gt; [bo1|bo3|bo5] [призы%: 60,30,10] [название] - Создать турнир
+/canceltournament &lt;id&gt; - Отменить турнир с возвратом взносов
+
+<b>💬 Жалобы на PvP чат:</b>
+/chatreports - Открытые жалобы
+/chatlog &lt;id&gt; - Переписка по жалобе
+/resolvereport &lt;id&gt; - Отметить жалобу разобранной
+/replay &lt;room_id&gt; - Журнал событий PvP матча (споры)
+
+<b>🕵️ Сговор в PvP:</b>
+/collusion - Очередь подозрительных пар
+/collusionflag &lt;id&gt; - Подробности по паре
+/resolvecollusion &lt;id&gt; &lt;dismiss|confirm&gt; [заметка] - Разобрать (confirm запрещает матчи пары)
+/collusionscan - Запустить анализ сейчас
+
+<b>⚙️ Параметры PvP игр:</b>
+/pvpconfig - Уровни ставок: таймеры, рейк, раунды
+/setpvpconfig &lt;rps|mines&gt; &lt;min_stake&gt; setup=10 turn=15 rake=2.5 rounds=5 enabled=on|off - Создать/изменить уровень
+/delpvpconfig &lt;rps|mines&gt; &lt;min_stake&gt; - Удалить уровень
 
 <b>📢 Рассылка:</b>
 /broadcast - Отправить сообщение всем (фото, кнопки)`
@@ -305,7 +412,7 @@ func (b *AdminBot) handleStats(ctx context.Context) string {
 		return fmt.Sprintf("Ошибка: %v", err)
 	}
 
-	return fmt.Sprintf(`<b>Статистика платформы</b>
+	report := fmt.Sprintf(`<b>Статистика платформы</b>
 
 <b>Пользователи:</b>
 - Всего: %d
@@ -349,6 +456,29 @@ func (b *AdminBot) handleStats(ctx context.Context) string {
 		stats.TotalWithdrawn,
 		stats.PendingWithdraws,
 	)
+
+	return report + formatRakeStats(stats.RakeByDay, stats.RakeByGame)
+}
+
+// секция рейка PvP для /stats: по дням за неделю и по играм за 30 дней
+func formatRakeStats(byDay, byGame []service.RakeRevenue) string {
+	var sb strings.Builder
+	sb.WriteString("\n\n<b>Рейк PvP по дням:</b>")
+	if len(byDay) == 0 {
+		sb.WriteString("\n- нет")
+	}
+	for _, r := range byDay {
+		sb.WriteString(fmt.Sprintf("\n- %s: %d %s (%d игр)", r.Day.Format("02.01"), r.Amount, r.Currency, r.Games))
+	}
+
+	sb.WriteString("\n\n<b>Рейк PvP по играм (30 дней):</b>")
+	if len(byGame) == 0 {
+		sb.WriteString("\n- нет")
+	}
+	for _, r := range byGame {
+		sb.WriteString(fmt.Sprintf("\n- %s: %d %s (%d игр)", r.GameType, r.Amount, r.Currency, r.Games))
+	}
+	return sb.String()
 }
 
 func (b *AdminBot) handleUser(ctx context.Context, args string) string {
@@ -585,11 +715,11 @@ func (b *AdminBot) handleApproveWithdrawal(ctx context.Context, args string) str
 
 	// Уведомляем пользователя об одобрении вывода
 	if result.UserTgID > 0 {
-		go b.NotifyUserWithdrawalApproved(result.UserTgID, result.TonAmount, result.TxHash)
+		go b.NotifyUserWithdrawalApproved(result.UserTgID, result.Amount, result.Asset, result.TxHash)
 	}
 
 	if result.AutoSent {
-		return fmt.Sprintf("Вывод #%d одобрен и отправлен автоматически!\n\nСумма: %.4f TON\nТранзакция: <code>%s</code>", id, result.TonAmount, result.TxHash)
+		return fmt.Sprintf("Вывод #%d одобрен и отправлен автоматически!\n\nСумма: %.4f %s\nТранзакция: <code>%s</code>", id, result.Amount, result.Asset, result.TxHash)
 	}
 	return fmt.Sprintf("Вывод #%d одобрен (ручной режим)\nТранзакция: %s", id, result.TxHash)
 }
@@ -964,14 +1094,14 @@ func (b *AdminBot) NotifyAdminsNewWithdrawal(ctx context.Context, withdrawalID i
 	message := fmt.Sprintf(`<b>Новый запрос на вывод!</b>
 
 Пользователь: @%s (TG: %d)
-Сумма: %d coins (%.4f TON)
+Сумма: %d coins (%.4f %s)
 Кошелек: <code>%s</code>
 
 ID: #%d
 
 /approve %d - одобрить
 /reject %d причина - отклонить`,
-		w.Username, w.TgID, w.CoinsAmount, w.TonAmount, w.WalletAddress, w.ID, w.ID, w.ID)
+		w.Username, w.TgID, w.CoinsAmount, w.TonAmount, w.Asset, w.WalletAddress, w.ID, w.ID, w.ID)
 
 	for _, adminID := range b.adminIDs {
 		b.log.Info("sending notification to admin", "admin_id", adminID)
@@ -1331,8 +1461,8 @@ func (b *AdminBot) handleRecentDeposits(ctx context.Context) string {
 			status = "❌"
 		}
 
-		sb.WriteString(fmt.Sprintf("%s #%d | TG:%d | %.4f TON | %d coins\n",
-			status, d.ID, d.UserID, float64(d.AmountNano)/1e9, d.CoinsCredited))
+		sb.WriteString(fmt.Sprintf("%s #%d | TG:%d | %s | %d coins\n",
+			status, d.ID, d.UserID, ton.FormatUnits(ton.Asset(d.Asset), d.AmountNano), d.CoinsCredited))
 		sb.WriteString(fmt.Sprintf("   %s\n\n", d.CreatedAt.Format("02.01.2006 15:04")))
 	}
 
@@ -1392,8 +1522,8 @@ func (b *AdminBot) handleDepositsHistory(ctx context.Context) string {
 			status = "❌"
 		}
 
-		sb.WriteString(fmt.Sprintf("%s #%d | @%s | %.4f TON | %d coins\n",
-			status, d.ID, d.Username, float64(d.AmountNano)/1e9, d.CoinsCredited))
+		sb.WriteString(fmt.Sprintf("%s #%d | @%s | %s | %d coins\n",
+			status, d.ID, d.Username, ton.FormatUnits(ton.Asset(d.Asset), d.AmountNano), d.CoinsCredited))
 		sb.WriteString(fmt.Sprintf("   Статус: %s | %s\n\n", d.Status, d.CreatedAt.Format("02.01.2006 15:04")))
 	}
 
@@ -1401,12 +1531,12 @@ func (b *AdminBot) handleDepositsHistory(ctx context.Context) string {
 }
 
 // NotifyUserDeposit уведомляет пользователя об успешном депозите
-func (b *AdminBot) NotifyUserDeposit(tgID int64, amountTON float64, coins int64, txHash string) {
+func (b *AdminBot) NotifyUserDeposit(tgID int64, amount float64, asset string, coins int64, txHash string) {
 	message := fmt.Sprintf(`<b>Депозит зачислен!</b>
 
-Сумма: %.4f TON
+Сумма: %.4f %s
 Начислено: %d coins
-TX: <code>%s</code>`, amountTON, coins, txHash)
+TX: <code>%s</code>`, amount, asset, coins, txHash)
 
 	msg := tgbotapi.NewMessage(tgID, message)
 	msg.ParseMode = "HTML"
@@ -1416,11 +1546,11 @@ TX: <code>%s</code>`, amountTON, coins, txHash)
 }
 
 // NotifyUserWithdrawalApproved уведомляет пользователя об одобрении вывода
-func (b *AdminBot) NotifyUserWithdrawalApproved(tgID int64, tonAmount float64, txHash string) {
+func (b *AdminBot) NotifyUserWithdrawalApproved(tgID int64, amount float64, asset string, txHash string) {
 	message := fmt.Sprintf(`<b>Вывод выполнен!</b>
 
-Сумма: %.4f TON
-TX: <code>%s</code>`, tonAmount, txHash)
+Сумма: %.4f %s
+TX: <code>%s</code>`, amount, asset, txHash)
 
 	msg := tgbotapi.NewMessage(tgID, message)
 	msg.ParseMode = "HTML"
@@ -1443,6 +1573,39 @@ func (b *AdminBot) NotifyUserWithdrawalRejected(tgID int64, coinsAmount int64, r
 	}
 }
 
+// NotifyWithdrawalEvent уведомляет пользователя и админов о работе воркера выводов
+func (b *AdminBot) NotifyWithdrawalEvent(ev service.WithdrawalEvent) {
+	var message string
+	switch ev.Kind {
+	case service.WithdrawalEventSent:
+		if ev.TgID > 0 {
+			b.NotifyUserWithdrawalApproved(ev.TgID, ev.Amount, string(ev.Asset), ev.TxHash)
+		}
+		message = fmt.Sprintf("🤖 Вывод #%d отправлен автоматически\n\nСумма: %d coins (%.4f %s)\nTX: <code>%s</code>",
+			ev.WithdrawalID, ev.CoinsAmount, ev.Amount, ev.Asset, ev.TxHash)
+	case service.WithdrawalEventFailed:
+		if ev.TgID > 0 {
+			b.NotifyUserWithdrawalRejected(ev.TgID, ev.CoinsAmount, "перевод не прошел в сети TON")
+		}
+		message = fmt.Sprintf("❌ Вывод #%d не прошел в сети, %d coins возвращены\n\nПричина: %s\nTX: <code>%s</code>",
+			ev.WithdrawalID, ev.CoinsAmount, ev.Reason, ev.TxHash)
+	case service.WithdrawalEventStuck:
+		message = fmt.Sprintf("⚠️ Вывод #%d требует проверки\n\nСумма: %d coins (%.4f %s)\nПричина: %s",
+			ev.WithdrawalID, ev.CoinsAmount, ev.Amount, ev.Asset, ev.Reason)
+		if ev.TxHash != "" {
+			message += fmt.Sprintf("\nTX: <code>%s</code>", ev.TxHash)
+		}
+	default:
+		return
+	}
+
+	for _, adminID := range b.adminIDs {
+		if err := b.SendNotification(adminID, message); err != nil {
+			b.log.Error("не удалось уведомить админа о выводе", "admin_id", adminID, "error", err)
+		}
+	}
+}
+
 // NotifyAdminsNewDeposit уведомляет всех админов о новом депозите
 func (b *AdminBot) NotifyAdminsNewDeposit(notification service.DepositNotification) {
 	username := notification.Username
@@ -1453,12 +1616,12 @@ func (b *AdminBot) NotifyAdminsNewDeposit(notification service.DepositNotificati
 	message := fmt.Sprintf(`💰 <b>Новый депозит!</b>
 
 👤 Пользователь: @%s (TG: %d)
-💎 Сумма: %.4f TON
+💎 Сумма: %.4f %s
 🪙 Начислено: %d coins
 💳 Кошелёк: <code>%s</code>
 🔗 TX: <code>%s</code>
 💰 Новый баланс: %d coins`,
-		username, notification.TgID, notification.AmountTON, notification.CoinsCredited,
+		username, notification.TgID, notification.Amount, notification.Asset, notification.CoinsCredited,
 		notification.WalletAddress, notification.TxHash, notification.NewBalance)
 
 	for _, adminID := range b.adminIDs {
@@ -1469,3 +1632,712 @@ func (b *AdminBot) NotifyAdminsNewDeposit(notification service.DepositNotificati
 		}
 	}
 }
+
+// NotifyAdminsMissingCredits уведомляет админов о подтвержденных депозитах без зачисления коинов
+func (b *AdminBot) NotifyAdminsMissingCredits(missing []domain.Deposit) {
+	message := formatMissingCredits(missing)
+	for _, adminID := range b.adminIDs {
+		if err := b.SendNotification(adminID, message); err != nil {
+			b.log.Error("не удалось уведомить админа о сверке депозитов", "admin_id", adminID, "error", err)
+		}
+	}
+}
+
+func (b *AdminBot) handleChatReports(ctx context.Context) string {
+	reports, err := b.adminService.GetOpenChatReports(ctx, 20)
+	if err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+
+	if len(reports) == 0 {
+		return "Нет открытых жалоб"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<b>Жалобы на чат</b>\n\n")
+
+	for _, r := range reports {
+		sb.WriteString(fmt.Sprintf("#%d | комната %s\n", r.ID, html.EscapeString(r.RoomID)))
+		sb.WriteString(fmt.Sprintf("От: @%s → на: @%s (TG: %d)\n",
+			html.EscapeString(r.ReporterUsername), html.EscapeString(r.ReportedUsername), r.ReportedTgID))
+		if r.Reason != "" {
+			sb.WriteString(fmt.Sprintf("Причина: %s\n", html.EscapeString(r.Reason)))
+		}
+		sb.WriteString(fmt.Sprintf("%s\n\n", r.CreatedAt.Format("02.01.2006 15:04")))
+	}
+
+	sb.WriteString("\n/chatlog &lt;id&gt; — переписка\n/resolvereport &lt;id&gt; — закрыть")
+
+	return sb.String()
+}
+
+func (b *AdminBot) handleChatLog(ctx context.Context, args string) string {
+	id, err := strconv.ParseInt(strings.TrimSpace(args), 10, 64)
+	if err != nil {
+		return "Использование: /chatlog &lt;id&gt;"
+	}
+
+	report, lines, err := b.adminService.GetChatReportLog(ctx, id, 50)
+	if err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("<b>Жалоба #%d</b> (%s)\nКомната: %s\n\n", report.ID, report.Status, html.EscapeString(report.RoomID)))
+
+	if len(lines) == 0 {
+		sb.WriteString("Сообщений нет")
+		return sb.String()
+	}
+
+	for _, l := range lines {
+		author := "@" + report.ReporterUsername
+		if l.SenderID == report.ReportedID {
+			author = "@" + report.ReportedUsername
+		}
+		content := html.EscapeString(l.Content)
+		if l.Kind == "emote" {
+			content = "[" + content + "]"
+		}
+		var flags string
+		if l.Filtered {
+			flags += " 🚫"
+		}
+		if !l.Delivered {
+			flags += " 🔇"
+		}
+		sb.WriteString(fmt.Sprintf("%s <b>%s</b>: %s%s\n", l.CreatedAt.Format("15:04:05"), html.EscapeString(author), content, flags))
+	}
+
+	sb.WriteString("\n🚫 — замаскирован мат, 🔇 — не доставлено (mute)")
+
+	return sb.String()
+}
+
+func (b *AdminBot) handleResolveChatReport(ctx context.Context, adminTgID int64, args string) string {
+	id, err := strconv.ParseInt(strings.TrimSpace(args), 10, 64)
+	if err != nil {
+		return "Использование: /resolvereport &lt;id&gt;"
+	}
+
+	if err := b.adminService.ResolveChatReport(ctx, id, adminTgID); err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+
+	return fmt.Sprintf("Жалоба #%d разобрана", id)
+}
+
+// описания признаков сговора для админа
+var collusionSignalNames = map[string]string{
+	domain.CollusionSignalRepeatedPairing: "частые матчи друг с другом",
+	domain.CollusionSignalOneSided:        "побеждает почти всегда один",
+	domain.CollusionSignalChipFlow:        "крупный перелив ставок",
+	domain.CollusionSignalSameStake:       "всегда одна ставка",
+	domain.CollusionSignalRapidRematch:    "матчи подряд без пауз",
+	domain.CollusionSignalSharedWallet:    "общий TON адрес",
+	domain.CollusionSignalReferral:        "реферальная связь",
+}
+
+func collusionSignalsText(signals []string) string {
+	names := make([]string, 0, len(signals))
+	for _, sig := range signals {
+		if name, ok := collusionSignalNames[sig]; ok {
+			names = append(names, name)
+		} else {
+			names = append(names, sig)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+func (b *AdminBot) handleCollusionFlags(ctx context.Context) string {
+	flags, err := b.adminService.GetOpenCollusionFlags(ctx, 20)
+	if err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+
+	if len(flags) == 0 {
+		return "Нет подозрительных пар"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<b>Подозрения на сговор</b>\n\n")
+
+	for _, f := range flags {
+		sb.WriteString(fmt.Sprintf("#%d | score %d\n", f.ID, f.Score))
+		sb.WriteString(fmt.Sprintf("@%s (TG: %d) ↔ @%s (TG: %d)\n",
+			html.EscapeString(f.UsernameA), f.TgIDA, html.EscapeString(f.UsernameB), f.TgIDB))
+		sb.WriteString(fmt.Sprintf("Матчей: %d, побед: %d/%d\n", f.Games, f.WinsA, f.WinsB))
+		sb.WriteString(fmt.Sprintf("%s\n\n", html.EscapeString(collusionSignalsText(f.Signals))))
+	}
+
+	sb.WriteString("\n/collusionflag &lt;id&gt; — подробнее\n/resolvecollusion &lt;id&gt; &lt;dismiss|confirm&gt; — разобрать")
+
+	return sb.String()
+}
+
+func (b *AdminBot) handleCollusionFlag(ctx context.Context, args string) string {
+	id, err := strconv.ParseInt(strings.TrimSpace(args), 10, 64)
+	if err != nil {
+		return "Использование: /collusionflag &lt;id&gt;"
+	}
+
+	f, err := b.adminService.GetCollusionFlag(ctx, id)
+	if err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("<b>Подозрение #%d</b> (%s)\n\n", f.ID, f.Status))
+	sb.WriteString(fmt.Sprintf("A: @%s (id %d, TG: %d)\n", html.EscapeString(f.UsernameA), f.UserA, f.TgIDA))
+	sb.WriteString(fmt.Sprintf("B: @%s (id %d, TG: %d)\n\n", html.EscapeString(f.UsernameB), f.UserB, f.TgIDB))
+	sb.WriteString(fmt.Sprintf("Score: %d\n", f.Score))
+	sb.WriteString(fmt.Sprintf("Признаки: %s\n", html.EscapeString(collusionSignalsText(f.Signals))))
+	sb.WriteString(fmt.Sprintf("Матчей: %d, побед A: %d, побед B: %d\n", f.Games, f.WinsA, f.WinsB))
+	sb.WriteString(fmt.Sprintf("Сумма ставок: %d, чистый итог A: %+d\n", f.Volume, f.NetA))
+	if f.FirstGameAt != nil && f.LastGameAt != nil {
+		sb.WriteString(fmt.Sprintf("Период: %s — %s\n", f.FirstGameAt.Format("02.01 15:04"), f.LastGameAt.Format("02.01 15:04")))
+	}
+	if f.ReviewedAt != nil {
+		sb.WriteString(fmt.Sprintf("\nРазобрано %s", f.ReviewedAt.Format("02.01.2006 15:04")))
+		if f.Note != "" {
+			sb.WriteString(fmt.Sprintf(": %s", html.EscapeString(f.Note)))
+		}
+	}
+
+	return sb.String()
+}
+
+func (b *AdminBot) handleResolveCollusion(ctx context.Context, adminTgID int64, args string) string {
+	parts := strings.Fields(args)
+	if len(parts) < 2 {
+		return "Использование: /resolvecollusion &lt;id&gt; &lt;dismiss|confirm&gt; [заметка]"
+	}
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return "Неверный id"
+	}
+	note := strings.Join(parts[2:], " ")
+
+	f, err := b.adminService.ResolveCollusionFlag(ctx, id, parts[1], adminTgID, note)
+	if err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+
+	if f.Status == domain.CollusionFlagConfirmed {
+		return fmt.Sprintf("Сговор #%d подтвержден, пара больше не матчится. Для блокировки аккаунтов — /ban", f.ID)
+	}
+	return fmt.Sprintf("Подозрение #%d снято", f.ID)
+}
+
+func (b *AdminBot) handleCollusionScan(ctx context.Context) string {
+	flagged, err := b.adminService.ScanCollusion(ctx)
+	if err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+	return fmt.Sprintf("Анализ завершен, новых подозрений: %d\n/collusion — очередь", flagged)
+}
+
+func formatPvPConfig(c *domain.PvPGameConfig) string {
+	state := "вкл"
+	if !c.Enabled {
+		state = "выкл"
+	}
+	return fmt.Sprintf("%s от %d: подготовка %dс, ход %dс, рейк %.2f%%, раундов %d, %s",
+		c.GameType, c.MinStake, c.SetupTimeoutSec, c.TurnTimeoutSec, c.RakePercent, c.MaxRounds, state)
+}
+
+func (b *AdminBot) handlePvPConfigs(ctx context.Context) string {
+	configs, err := b.adminService.GetPvPConfigs(ctx)
+	if err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+
+	if len(configs) == 0 {
+		return "Уровни не заданы, используются встроенные значения"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<b>Параметры PvP игр</b>\n\n")
+	for _, c := range configs {
+		sb.WriteString(formatPvPConfig(c) + "\n")
+	}
+	sb.WriteString("\nСтавка попадает в уровень с наибольшим min_stake, не превышающим ее")
+
+	return sb.String()
+}
+
+func (b *AdminBot) handleSetPvPConfig(ctx context.Context, adminTgID int64, args string) string {
+	usage := "Использование: /setpvpconfig &lt;rps|mines&gt; &lt;min_stake&gt; [setup=10] [turn=15] [rake=2.5] [rounds=5] [enabled=on|off]"
+	parts := strings.Fields(args)
+	if len(parts) < 2 {
+		return usage
+	}
+	minStake, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "Неверный min_stake"
+	}
+
+	params := make(map[string]string, len(parts)-2)
+	for _, p := range parts[2:] {
+		key, value, ok := strings.Cut(p, "=")
+		if !ok {
+			return usage
+		}
+		params[strings.ToLower(key)] = value
+	}
+
+	c, err := b.adminService.SetPvPConfig(ctx, domain.GameType(parts[0]), minStake, params, adminTgID)
+	if err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+
+	return fmt.Sprintf("Сохранено:\n%s\n\nПрименится к новым комнатам в течение 30 секунд", formatPvPConfig(c))
+}
+
+func (b *AdminBot) handleDeletePvPConfig(ctx context.Context, args string) string {
+	parts := strings.Fields(args)
+	if len(parts) != 2 {
+		return "Использование: /delpvpconfig &lt;rps|mines&gt; &lt;min_stake&gt;"
+	}
+	minStake, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "Неверный min_stake"
+	}
+
+	if err := b.adminService.DeletePvPConfig(ctx, domain.GameType(parts[0]), minStake); err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+	return fmt.Sprintf("Уровень %s от %d удален", parts[0], minStake)
+}
+
+func (b *AdminBot) handleDepositCursor() string {
+	lt, err := b.adminService.DepositCursor()
+	if err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+	if lt == 0 {
+		return "Курсор депозитов еще не сохранен"
+	}
+	return fmt.Sprintf("Последний обработанный lt депозитов: <code>%d</code>", lt)
+}
+
+// бэкфилл идет дольше таймаута команды: отвечаем сразу, итог присылаем отдельным сообщением
+func (b *AdminBot) handleBackfillDeposits(chatID int64, args string) string {
+	parts := strings.Fields(args)
+	if len(parts) != 2 {
+		return "Использование: /backfilldeposits &lt;from_lt&gt; &lt;to_lt&gt;"
+	}
+	fromLt, err1 := strconv.ParseInt(parts[0], 10, 64)
+	toLt, err2 := strconv.ParseInt(parts[1], 10, 64)
+	if err1 != nil || err2 != nil || fromLt < 0 || toLt <= fromLt {
+		return "Неверный диапазон lt: нужно 0 &lt;= from &lt; to"
+	}
+	if _, err := b.adminService.DepositCursor(); err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		defer cancel()
+
+		var text string
+		res, err := b.adminService.BackfillDeposits(ctx, fromLt, toLt)
+		if err != nil {
+			text = fmt.Sprintf("Бэкфилл %d..%d: ошибка: %v", fromLt, toLt, err)
+		} else {
+			text = fmt.Sprintf("<b>Бэкфилл %d..%d завершен</b>\n\nТранзакций: %d\nВходящих: %d\nЗачислено новых: %d\nОшибок: %d",
+				fromLt, toLt, res.Scanned, res.Incoming, res.Credited, res.Failed)
+		}
+		if err := b.SendNotification(chatID, text); err != nil {
+			b.log.Error("backfill: failed to send result", "error", err)
+		}
+	}()
+
+	return fmt.Sprintf("Бэкфилл депозитов %d..%d запущен, итог придет отдельным сообщением", fromLt, toLt)
+}
+
+func (b *AdminBot) handleReconcileDeposits(ctx context.Context, args string) string {
+	hours := 24
+	if args = strings.TrimSpace(args); args != "" {
+		n, err := strconv.Atoi(args)
+		if err != nil || n <= 0 {
+			return "Использование: /reconciledeposits [часы]"
+		}
+		hours = n
+	}
+
+	missing, err := b.adminService.ReconcileDeposits(ctx, time.Duration(hours)*time.Hour)
+	if err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+	if len(missing) == 0 {
+		return fmt.Sprintf("✅ За %dч все подтвержденные депозиты зачислены", hours)
+	}
+	return formatMissingCredits(missing)
+}
+
+// список депозитов без строки зачисления в transactions
+func formatMissingCredits(missing []domain.Deposit) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("⚠️ <b>Депозиты без зачисления: %d</b>\n\n", len(missing)))
+	for _, d := range missing {
+		sb.WriteString(fmt.Sprintf("#%d user:%d %d coins %s\n<code>%s</code>\n",
+			d.ID, d.UserID, d.CoinsCredited, d.CreatedAt.Format("02.01 15:04"), d.TxHash))
+	}
+	return sb.String()
+}
+
+func (b *AdminBot) handleUnmatchedDeposits(ctx context.Context) string {
+	deposits, err := b.adminService.GetUnmatchedDeposits(ctx, 30)
+	if err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+	if len(deposits) == 0 {
+		return "✅ Очередь разбора пуста"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("<b>❓ Переводы на разбор: %d</b>\n\n", len(deposits)))
+	for _, d := range deposits {
+		sb.WriteString(formatUnmatchedDeposit(d))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// одна строка очереди разбора: сумма, memo, причина, отправитель и хэш
+func formatUnmatchedDeposit(d domain.Deposit) string {
+	status := ""
+	if d.Status == domain.DepositStatusRefunding {
+		status = " ⏳ возврат не завершен"
+	}
+	return fmt.Sprintf("#%d %s %s%s\nmemo: <code>%s</code> (%s)\nОт: <code>%s</code>\n<code>%s</code>\n",
+		d.ID, ton.FormatUnits(ton.Asset(d.Asset), d.AmountNano), d.CreatedAt.Format("02.01 15:04"), status,
+		html.EscapeString(d.Memo), d.UnmatchedReason, d.WalletAddress, d.TxHash)
+}
+
+func (b *AdminBot) handleAssignDeposit(ctx context.Context, adminTgID int64, args string) string {
+	parts := strings.Fields(args)
+	if len(parts) != 2 {
+		return "Использование: /assigndeposit &lt;id&gt; &lt;@username|tg_id&gt;"
+	}
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return "Неверный ID депозита"
+	}
+	userID, err := b.adminService.ResolveUserIdentifier(ctx, parts[1])
+	if err != nil {
+		return "Пользователь не найден"
+	}
+
+	result, err := b.adminService.AssignUnmatchedDeposit(ctx, id, userID, adminTgID)
+	if err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+
+	d := result.Deposit
+	asset := ton.Asset(d.Asset)
+	if result.UserTgID > 0 {
+		go b.NotifyUserDeposit(result.UserTgID, ton.UnitsToFloat(asset, d.AmountNano), d.Asset, d.CoinsCredited, d.TxHash)
+	}
+	return fmt.Sprintf("✅ Депозит #%d (%s) зачислен пользователю %s: +%d коинов, баланс %d",
+		d.ID, ton.FormatUnits(asset, d.AmountNano), html.EscapeString(parts[1]), d.CoinsCredited, result.NewBalance)
+}
+
+func (b *AdminBot) handleRefundDeposit(ctx context.Context, adminTgID int64, args string) string {
+	parts := strings.Fields(args)
+	if len(parts) < 1 || len(parts) > 2 {
+		return "Использование: /refunddeposit &lt;id&gt; [tx_hash]"
+	}
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return "Неверный ID депозита"
+	}
+	manualTxHash := ""
+	if len(parts) == 2 {
+		manualTxHash = parts[1]
+	}
+
+	result, err := b.adminService.RefundUnmatchedDeposit(ctx, id, manualTxHash, adminTgID)
+	if err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+
+	asset := ton.Asset(result.Deposit.Asset)
+	mode := "отправлен автоматически"
+	if !result.AutoSent {
+		mode = "ручной режим"
+	}
+	return fmt.Sprintf("↩️ Депозит #%d возвращен (%s)\n\nСумма: %s (комиссия сети %s)\nКому: <code>%s</code>\nТранзакция: <code>%s</code>",
+		id, mode, ton.FormatUnits(asset, result.RefundAmount), ton.FormatUnits(asset, result.Fee),
+		result.Deposit.WalletAddress, html.EscapeString(result.TxHash))
+}
+
+// NotifyUnmatchedDeposit уведомляет админов о переводе, который не удалось сопоставить с пользователем
+func (b *AdminBot) NotifyUnmatchedDeposit(d domain.Deposit) {
+	message := "❓ <b>Перевод не сопоставлен с пользователем</b>\n\n" + formatUnmatchedDeposit(d) +
+		fmt.Sprintf("\n/assigndeposit %d &lt;@username|tg_id&gt; или /refunddeposit %d", d.ID, d.ID)
+	for _, adminID := range b.adminIDs {
+		if err := b.SendNotification(adminID, message); err != nil {
+			b.log.Error("не удалось уведомить админа о переводе на разбор", "admin_id", adminID, "error", err)
+		}
+	}
+}
+
+func (b *AdminBot) handleTreasury(ctx context.Context) string {
+	snapshot, err := b.adminService.GetTreasury(ctx)
+	if err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<b>🏦 Казна</b>\n\n")
+	for _, a := range snapshot.Assets {
+		sb.WriteString(fmt.Sprintf("<b>%s</b>\n", a.Asset))
+		if a.BalanceErr != "" {
+			sb.WriteString(fmt.Sprintf("Кошелек: — (%s)\n", html.EscapeString(a.BalanceErr)))
+		} else {
+			sb.WriteString(fmt.Sprintf("Кошелек: %s\n", ton.FormatUnits(a.Asset, a.HotBalance)))
+		}
+		sb.WriteString(fmt.Sprintf("Ожидают вывода: %s (%d шт.)\n", ton.FormatUnits(a.Asset, a.Pending), a.PendingCount))
+		if a.BalanceErr == "" {
+			sb.WriteString(fmt.Sprintf("Покрытие: %s\n", formatCoverage(a.Coverage)))
+		}
+		sb.WriteString("\n")
+	}
+	if pricing, err := b.adminService.GetPricing(ctx); err == nil {
+		userTON := service.PricingRates(pricing).CoinsToUnits(ton.AssetTON, snapshot.UserCoins)
+		sb.WriteString(fmt.Sprintf("Коины пользователей: %d (≈ %.2f TON)\n", snapshot.UserCoins, ton.NanoToTON(userTON)))
+	} else {
+		sb.WriteString(fmt.Sprintf("Коины пользователей: %d\n", snapshot.UserCoins))
+	}
+	return sb.String()
+}
+
+func formatPricing(p *domain.PricingConfig) string {
+	return fmt.Sprintf("#%d с %s UTC: 1 TON = %d коинов, 1 USDT = %d коинов, комиссия вывода %d, лимит %d в день",
+		p.ID, p.EffectiveFrom.UTC().Format("02.01.2006 15:04"), p.CoinsPerTON, p.CoinsPerUSDT, p.WithdrawFeeCoins, p.MaxWithdrawCoinsPerDay)
+}
+
+func (b *AdminBot) handlePricing(ctx context.Context) string {
+	current, err := b.adminService.GetPricing(ctx)
+	if err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+	history, err := b.adminService.GetPricingHistory(ctx, 10)
+	if err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<b>💱 Курс и комиссия</b>\n\n")
+	sb.WriteString(fmt.Sprintf("Действует: %s\n\n<b>Версии:</b>\n", formatPricing(current)))
+	for _, p := range history {
+		line := formatPricing(p)
+		if p.EffectiveFrom.After(time.Now()) {
+			line += " (запланирована)"
+		}
+		if p.CreatedBy != nil {
+			line += fmt.Sprintf(", админ %d", *p.CreatedBy)
+		}
+		sb.WriteString(line + "\n")
+	}
+	sb.WriteString("\nДепозит считается по версии на момент перевода, вывод - на момент заявки")
+	return sb.String()
+}
+
+func (b *AdminBot) handleSetPricing(ctx context.Context, adminTgID int64, args string) string {
+	usage := "Использование: /setpricing [ton=10] [usdt=2] [fee=1] [maxday=1000] [from=2026-01-31T12:00]"
+	parts := strings.Fields(args)
+	if len(parts) == 0 {
+		return usage
+	}
+
+	params := make(map[string]string, len(parts))
+	for _, p := range parts {
+		key, value, ok := strings.Cut(p, "=")
+		if !ok {
+			return usage
+		}
+		params[strings.ToLower(key)] = value
+	}
+
+	p, err := b.adminService.SetPricing(ctx, params, adminTgID)
+	if err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+	return fmt.Sprintf("Сохранено:\n%s", formatPricing(p))
+}
+
+func formatCoverage(coverage float64) string {
+	if math.IsInf(coverage, 1) {
+		return "∞ (нет ожидающих выводов)"
+	}
+	return fmt.Sprintf("%.2fx", coverage)
+}
+
+// NotifyTreasuryAlert уведомляет админов о нехватке средств на горячем кошельке
+func (b *AdminBot) NotifyTreasuryAlert(alert service.TreasuryAlert) {
+	a := alert.Asset
+	message := fmt.Sprintf("🚨 <b>Мало средств на горячем кошельке (%s)</b>\n\nКошелек: %s\nОжидают вывода: %s (%d шт.)\nПокрытие: %s, порог %.2fx\n\nПополните кошелек, подробности - /treasury",
+		a.Asset, ton.FormatUnits(a.Asset, a.HotBalance), ton.FormatUnits(a.Asset, a.Pending), a.PendingCount,
+		formatCoverage(a.Coverage), alert.MinCoverage)
+
+	for _, adminID := range b.adminIDs {
+		if err := b.SendNotification(adminID, message); err != nil {
+			b.log.Error("не удалось уведомить админа о казне", "admin_id", adminID, "error", err)
+		}
+	}
+}
+
+func (b *AdminBot) handleTournaments(ctx context.Context) string {
+	tournaments, err := b.adminService.GetActiveTournaments(ctx)
+	if err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+
+	if len(tournaments) == 0 {
+		return "Нет активных турниров"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<b>Турниры</b>\n\n")
+
+	for _, t := range tournaments {
+		title := t.Title
+		if title == "" {
+			title = string(t.GameType)
+		}
+		sb.WriteString(fmt.Sprintf("#%d | %s (%s, bo%d)\n", t.ID, html.EscapeString(title), t.GameType, t.BestOf))
+		sb.WriteString(fmt.Sprintf("Статус: %s | Игроков: %d/%d\n", t.Status, t.Players, t.MaxPlayers))
+		sb.WriteString(fmt.Sprintf("Взнос: %d %s | Фонд: %d\n", t.EntryFee, t.Currency, t.PrizePool))
+		sb.WriteString(fmt.Sprintf("Старт: %s\n\n", t.StartsAt.Format("02.01.2006 15:04")))
+	}
+
+	return sb.String()
+}
+
+func (b *AdminBot) handleNewTournament(ctx context.Context, adminID int64, args string) string {
+	usage := "Использование: /newtournament &lt;rps|mines&gt; &lt;gems|coins&gt; &lt;взнос&gt; &lt;макс_игроков&gt; &lt;старт_через_мин&gt; [bo1|bo3|bo5] [призы%: 60,30,10] [название]"
+
+	parts := strings.Fields(args)
+	if len(parts) < 5 {
+		return usage
+	}
+
+	fee, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return "Неверный взнос"
+	}
+	maxPlayers, err := strconv.Atoi(parts[3])
+	if err != nil {
+		return "Неверное число игроков"
+	}
+	minutes, err := strconv.Atoi(parts[4])
+	if err != nil || minutes <= 0 {
+		return "Неверное время старта"
+	}
+
+	p := service.CreateTournamentParams{
+		GameType:   domain.GameType(parts[0]),
+		Currency:   parts[1],
+		EntryFee:   fee,
+		MaxPlayers: maxPlayers,
+		StartsAt:   time.Now().Add(time.Duration(minutes) * time.Minute),
+		CreatedBy:  adminID,
+	}
+
+	rest := parts[5:]
+	if len(rest) > 0 && strings.HasPrefix(rest[0], "bo") {
+		bestOf, err := strconv.Atoi(strings.TrimPrefix(rest[0], "bo"))
+		if err != nil {
+			return usage
+		}
+		p.BestOf = bestOf
+		rest = rest[1:]
+	}
+	if len(rest) > 0 && rest[0] != "" && strings.Trim(rest[0], "0123456789,") == "" {
+		for _, v := range strings.Split(rest[0], ",") {
+			percent, err := strconv.Atoi(v)
+			if err != nil {
+				return "Неверные доли призов"
+			}
+			p.PrizePercents = append(p.PrizePercents, percent)
+		}
+		rest = rest[1:]
+	}
+	p.Title = strings.Join(rest, " ")
+
+	t, err := b.adminService.CreateTournament(ctx, p)
+	if err != nil {
+		if err == service.ErrTournamentInvalid {
+			return "Неверные параметры турнира (доли призов должны давать 100%)\n\n" + usage
+		}
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+
+	return fmt.Sprintf("Турнир #%d создан\n\nИгра: %s, bo%d\nВзнос: %d %s\nИгроков: до %d\nПризы: %v%%\nСтарт: %s",
+		t.ID, t.GameType, t.BestOf, t.EntryFee, t.Currency, t.MaxPlayers, t.PrizePercents, t.StartsAt.Format("02.01.2006 15:04"))
+}
+
+func (b *AdminBot) handleCancelTournament(ctx context.Context, args string) string {
+	id, err := strconv.ParseInt(strings.TrimSpace(args), 10, 64)
+	if err != nil {
+		return "Использование: /canceltournament &lt;id&gt;"
+	}
+
+	_, refunded, err := b.adminService.CancelTournament(ctx, id)
+	if err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+
+	return fmt.Sprintf("Турнир #%d отменён. Взносы возвращены %d участникам.", id, refunded)
+}
+
+func (b *AdminBot) handleReplay(ctx context.Context, args string) string {
+	roomID := strings.TrimSpace(args)
+	if roomID == "" {
+		return "Использование: /replay &lt;room_id&gt;"
+	}
+
+	rep, err := b.adminService.GetMatchReplay(ctx, roomID)
+	if err != nil {
+		return fmt.Sprintf("Ошибка: %v", err)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("<b>Матч %s</b> (%s, bo%d)\n", html.EscapeString(rep.RoomID), rep.GameType, rep.BestOf))
+	sb.WriteString(fmt.Sprintf("Игроки: %d vs %d", rep.Player1ID, rep.Player2ID))
+	if rep.VsBot {
+		sb.WriteString(" (бот)")
+	}
+	sb.WriteString(fmt.Sprintf("\nСтавка: %d %s\n", rep.BetAmount, rep.Currency))
+	finished := "идет"
+	if rep.FinishedAt != nil {
+		finished = rep.FinishedAt.Format("15:04:05")
+	}
+	sb.WriteString(fmt.Sprintf("%s — %s\n\n", rep.StartedAt.Format("02.01.2006 15:04:05"), finished))
+
+	// сообщение Telegram ограничено 4096 символами
+	const maxEvents = 60
+	for i, e := range rep.Events {
+		if i == maxEvents {
+			sb.WriteString(fmt.Sprintf("... еще %d событий (полный журнал: GET /api/v1/pvp/matches/%s/replay)", len(rep.Events)-maxEvents, html.EscapeString(rep.RoomID)))
+			break
+		}
+		who := ""
+		if e.UserID != nil {
+			who = fmt.Sprintf(" [%d]", *e.UserID)
+		}
+		data := ""
+		if raw, ok := e.Data.(json.RawMessage); ok {
+			data = " " + html.EscapeString(string(raw))
+		}
+		sb.WriteString(fmt.Sprintf("%s #%d g%d %s%s%s\n", e.CreatedAt.Format("15:04:05"), e.Seq, e.Game, e.Type, who, data))
+	}
+
+	return sb.String()
+}
@@ -25,6 +25,56 @@ type Config struct {
 	MinBet         int64
 	GameRateLimit  int
 	GameRateWindow int
+
+	// Бот-соперник в PvP (занимает место, если человек не нашелся)
+	PvPBotEnabled    bool
+	PvPBotDelay      int     // секунд ожидания до подсадки бота
+	PvPBotStakes     []Stake // ставки, на которых разрешен бот
+	PvPBotDailyLimit int     // игр с ботом на пользователя в сутки
+
+	// Зрители PvP матчей
+	PvPMaxSpectators int // зрителей на одну комнату
+
+	// Остановка: сколько секунд живые PvP матчи могут доигрывать
+	PvPDrainTimeout int
+
+	// Сколько секунд /ws ждет кадры auth и join
+	WSAuthTimeout int
+	// устаревший JWT в ?token= для /ws и /ws/spectate (по умолчанию выключен)
+	WSLegacyQueryAuth bool
+
+	// Детектор сговора в PvP
+	PvPCollusionScanMinutes int  // период анализа (0 - выключен)
+	PvPCollusionWindowHours int  // окно game_history для анализа
+	PvPCollusionMinGames    int  // матчей пары для анализа
+	PvPRematchBlockMinutes  int  // запрет повторного матча той же пары (0 - без запрета)
+	PvPBlockFlaggedPairs    bool // пары под подозрением не матчатся до разбора
+
+	// Границы рейка PvP по валютам (процент - в pvp_game_configs)
+	PvPRakeCaps []RakeCap
+
+	// Автоматические выводы (воркер с интервалом ton.WithdrawProcessInterval)
+	WithdrawAutoEnabled        bool  // отправлять выводы без /approve по правилам ниже
+	WithdrawAutoMaxCoins       int64 // сумма вывода не больше
+	WithdrawAutoMinAccountAge  int   // часов с регистрации
+	WithdrawConfirmTimeoutMins int   // через сколько минут без подтверждения в сети звать админов
+
+	// Мониторинг горячего кошелька
+	TreasuryCheckMinutes int     // период проверки баланса (0 - выключен)
+	TreasuryMinCoverage  float64 // баланс / ожидающие выводы, ниже - алерт админам
+}
+
+// ставка в конкретной валюте
+type Stake struct {
+	Currency string
+	Amount   int64
+}
+
+// границы рейка в валюте (Max 0 - без верхней границы)
+type RakeCap struct {
+	Currency string
+	Min      int64
+	Max      int64
 }
 
 // Загрузка конфига из env
@@ -104,6 +154,144 @@ func Load() *Config {
 		}
 	}
 
+	// Бот-соперник (по умолчанию выключен)
+	pvpBotEnabled := os.Getenv("PVP_BOT_ENABLED") == "true"
+
+	pvpBotDelay := 8 // секунд, меньше таймаута поиска соперника (10с)
+	if v := os.Getenv("PVP_BOT_DELAY_SECONDS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			pvpBotDelay = n
+		}
+	}
+
+	// !! ЧЕРЕЗ ЗАПЯТУЮ В ENV в формате валюта:ставка, например gems:10,gems:50,coins:1 !!
+	pvpBotStakesStr := os.Getenv("PVP_BOT_STAKES")
+	if pvpBotStakesStr == "" {
+		pvpBotStakesStr = "gems:10,gems:50,gems:100"
+	}
+	var pvpBotStakes []Stake
+	for _, item := range strings.Split(pvpBotStakesStr, ",") {
+		parts := strings.SplitN(strings.TrimSpace(item), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		if n, err := strconv.ParseInt(parts[1], 10, 64); err == nil && n >= 0 {
+			pvpBotStakes = append(pvpBotStakes, Stake{Currency: parts[0], Amount: n})
+		}
+	}
+
+	pvpBotDailyLimit := 5
+	if v := os.Getenv("PVP_BOT_DAILY_LIMIT"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			pvpBotDailyLimit = n
+		}
+	}
+
+	pvpMaxSpectators := 50
+	if v := os.Getenv("PVP_MAX_SPECTATORS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			pvpMaxSpectators = n
+		}
+	}
+
+	pvpDrainTimeout := 60
+	if v := os.Getenv("PVP_DRAIN_TIMEOUT_SECONDS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			pvpDrainTimeout = n
+		}
+	}
+
+	wsAuthTimeout := 10
+	if v := os.Getenv("WS_AUTH_TIMEOUT_SECONDS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			wsAuthTimeout = n
+		}
+	}
+
+	wsLegacyQueryAuth := os.Getenv("WS_LEGACY_QUERY_AUTH") == "true"
+
+	pvpCollusionScan := 15
+	if v := os.Getenv("PVP_COLLUSION_SCAN_MINUTES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			pvpCollusionScan = n
+		}
+	}
+
+	pvpCollusionWindow := 24
+	if v := os.Getenv("PVP_COLLUSION_WINDOW_HOURS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			pvpCollusionWindow = n
+		}
+	}
+
+	pvpCollusionMinGames := 5
+	if v := os.Getenv("PVP_COLLUSION_MIN_GAMES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			pvpCollusionMinGames = n
+		}
+	}
+
+	pvpRematchBlock := 0
+	if v := os.Getenv("PVP_REMATCH_BLOCK_MINUTES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			pvpRematchBlock = n
+		}
+	}
+
+	pvpBlockFlaggedPairs := os.Getenv("PVP_BLOCK_FLAGGED_PAIRS") == "true"
+
+	// !! ЧЕРЕЗ ЗАПЯТУЮ В ENV в формате валюта:мин:макс, например gems:1:500,coins:1:50 !!
+	var pvpRakeCaps []RakeCap
+	for _, item := range strings.Split(os.Getenv("PVP_RAKE_CAPS"), ",") {
+		parts := strings.Split(strings.TrimSpace(item), ":")
+		if len(parts) != 3 {
+			continue
+		}
+		minRake, err1 := strconv.ParseInt(parts[1], 10, 64)
+		maxRake, err2 := strconv.ParseInt(parts[2], 10, 64)
+		if err1 != nil || err2 != nil || minRake < 0 || maxRake < 0 || (maxRake > 0 && maxRake < minRake) {
+			continue
+		}
+		pvpRakeCaps = append(pvpRakeCaps, RakeCap{Currency: parts[0], Min: minRake, Max: maxRake})
+	}
+
+	withdrawAutoEnabled := os.Getenv("WITHDRAW_AUTO_ENABLED") == "true"
+
+	withdrawAutoMaxCoins := int64(100) // 10 TON
+	if v := os.Getenv("WITHDRAW_AUTO_MAX_COINS"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			withdrawAutoMaxCoins = n
+		}
+	}
+
+	withdrawAutoMinAccountAge := 72
+	if v := os.Getenv("WITHDRAW_AUTO_MIN_ACCOUNT_AGE_HOURS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			withdrawAutoMinAccountAge = n
+		}
+	}
+
+	withdrawConfirmTimeout := 30
+	if v := os.Getenv("WITHDRAW_CONFIRM_TIMEOUT_MINUTES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			withdrawConfirmTimeout = n
+		}
+	}
+
+	treasuryCheckMinutes := 5
+	if v := os.Getenv("TREASURY_CHECK_MINUTES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			treasuryCheckMinutes = n
+		}
+	}
+
+	treasuryMinCoverage := 1.5
+	if v := os.Getenv("TREASURY_MIN_COVERAGE"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f > 0 {
+			treasuryMinCoverage = f
+		}
+	}
+
 	return &Config{
 		AppPort:          port,
 		DatabaseURL:      dbURL,
@@ -117,5 +305,30 @@ func Load() *Config {
 		MinBet:           minBet,
 		GameRateLimit:    gameRateLimit,
 		GameRateWindow:   gameRateWindow,
+		PvPBotEnabled:    pvpBotEnabled,
+		PvPBotDelay:      pvpBotDelay,
+		PvPBotStakes:     pvpBotStakes,
+		PvPBotDailyLimit: pvpBotDailyLimit,
+		PvPMaxSpectators: pvpMaxSpectators,
+		PvPDrainTimeout:  pvpDrainTimeout,
+		WSAuthTimeout:    wsAuthTimeout,
+
+		WSLegacyQueryAuth: wsLegacyQueryAuth,
+
+		PvPCollusionScanMinutes: pvpCollusionScan,
+		PvPCollusionWindowHours: pvpCollusionWindow,
+		PvPCollusionMinGames:    pvpCollusionMinGames,
+		PvPRematchBlockMinutes:  pvpRematchBlock,
+		PvPBlockFlaggedPairs:    pvpBlockFlaggedPairs,
+
+		PvPRakeCaps: pvpRakeCaps,
+
+		WithdrawAutoEnabled:        withdrawAutoEnabled,
+		WithdrawAutoMaxCoins:       withdrawAutoMaxCoins,
+		WithdrawAutoMinAccountAge:  withdrawAutoMinAccountAge,
+		WithdrawConfirmTimeoutMins: withdrawConfirmTimeout,
+
+		TreasuryCheckMinutes: treasuryCheckMinutes,
+		TreasuryMinCoverage:  treasuryMinCoverage,
 	}
 }
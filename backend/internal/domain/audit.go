@@ -5,7 +5,7 @@ import "time"
 // Логирование мастхев важных действий
 type AuditLog struct {
 	ID        int64                  `db:"id" json:"id"`
-	UserID    int64                  `db:"user_id" json:"user_id"`
+	UserID    int64                  `db:"user_id" json:"user_id"` // 0 - действие без пользователя (несопоставленный депозит)
 	Action    string                 `db:"action" json:"action"`
 	Category  string                 `db:"category" json:"category"`
 	Details   map[string]interface{} `db:"details" json:"details"`
@@ -43,6 +43,16 @@ const (
 	AuditActionWithdrawReject  = "withdraw_reject"
 	AuditActionWithdrawCancel  = "withdraw_cancel"
 
+	// Обработка выводов воркером
+	AuditActionWithdrawSend     = "withdraw_send"     // отправлен по автоодобрению
+	AuditActionWithdrawComplete = "withdraw_complete" // подтвержден в сети
+	AuditActionWithdrawFail     = "withdraw_fail"     // не прошел в сети, коины возвращены
+
+	// Разбор несопоставленных депозитов (user_id NULL, пока депозит не назначен)
+	AuditActionDepositUnmatched = "deposit_unmatched" // перевод отправлен на разбор
+	AuditActionDepositAssign    = "deposit_assign"    // админ назначил депозит пользователю
+	AuditActionDepositRefund    = "deposit_refund"    // админ вернул перевод отправителю
+
 	// Баланс
 	AuditActionBalanceCredit = "balance_credit"
 	AuditActionBalanceDebit  = "balance_debit"
@@ -53,4 +63,5 @@ const (
 	AuditActionAdminAddGems  = "admin_add_gems"
 	AuditActionAdminBanUser  = "admin_ban_user"
 	AuditActionAdminUnbanUser = "admin_unban_user"
+	AuditActionAdminSetPricing = "admin_set_pricing" // новая версия курса и комиссии на вывод
 )
@@ -0,0 +1,45 @@
+package domain
+
+import "time"
+
+// статус приватного PvP вызова
+type ChallengeStatus string
+
+const (
+	ChallengeStatusPending   ChallengeStatus = "pending"   // ждет соперника, ставка создателя в эскроу
+	ChallengeStatusStarted   ChallengeStatus = "started"   // оба игрока в комнате, ставками управляет комната
+	ChallengeStatusExpired   ChallengeStatus = "expired"   // истек, ставка возвращена создателю
+	ChallengeStatusCancelled ChallengeStatus = "cancelled" // отменен создателем, ставка возвращена
+)
+
+// время жизни вызова по умолчанию
+const ChallengeTTL = 10 * time.Minute
+
+// приватный PvP вызов по deep link
+type Challenge struct {
+	ID         int64           `json:"id"`
+	Code       string          `json:"code"`
+	CreatorID  int64           `json:"creator_id"`
+	InviteeID  *int64          `json:"invitee_id,omitempty"`
+	GameType   GameType        `json:"game_type"`
+	BetAmount  int64           `json:"bet_amount"`
+	Currency   string          `json:"currency"`
+	Status     ChallengeStatus `json:"status"`
+	OpponentID *int64          `json:"opponent_id,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	ExpiresAt  time.Time       `json:"expires_at"`
+	StartedAt  *time.Time      `json:"started_at,omitempty"`
+}
+
+// может ли пользователь принять вызов
+func (c *Challenge) CanJoin(userID int64) bool {
+	if userID == c.CreatorID {
+		return true
+	}
+	return c.InviteeID == nil || *c.InviteeID == userID
+}
+
+// открыт ли вызов для подключения
+func (c *Challenge) IsOpen() bool {
+	return c.Status == ChallengeStatusPending && time.Now().Before(c.ExpiresAt)
+}
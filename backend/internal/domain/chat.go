@@ -0,0 +1,45 @@
+package domain
+
+import "time"
+
+// вид сообщения в PvP чате
+type ChatKind string
+
+const (
+	ChatKindEmote ChatKind = "emote" // эмоция из фиксированного набора
+	ChatKindText  ChatKind = "text"  // короткий текст
+)
+
+// статус жалобы на сообщения в чате
+type ChatReportStatus string
+
+const (
+	ChatReportStatusOpen     ChatReportStatus = "open"
+	ChatReportStatusReviewed ChatReportStatus = "reviewed"
+)
+
+// сообщение чата PvP комнаты
+type ChatMessage struct {
+	ID          int64     `json:"id"`
+	RoomID      string    `json:"room_id"`
+	SenderID    int64     `json:"sender_id"`
+	RecipientID *int64    `json:"recipient_id,omitempty"`
+	Kind        ChatKind  `json:"kind"`
+	Content     string    `json:"content"`
+	Filtered    bool      `json:"filtered"`
+	Delivered   bool      `json:"delivered"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// жалоба игрока на соперника
+type ChatReport struct {
+	ID         int64            `json:"id"`
+	RoomID     string           `json:"room_id"`
+	ReporterID int64            `json:"reporter_id"`
+	ReportedID int64            `json:"reported_id"`
+	Reason     string           `json:"reason"`
+	Status     ChatReportStatus `json:"status"`
+	ReviewedBy *int64           `json:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time       `json:"reviewed_at,omitempty"`
+	CreatedAt  time.Time        `json:"created_at"`
+}
@@ -0,0 +1,45 @@
+package domain
+
+import "time"
+
+// статус подозрения на сговор
+type CollusionFlagStatus string
+
+const (
+	CollusionFlagOpen      CollusionFlagStatus = "open"      // ждет разбора админом
+	CollusionFlagDismissed CollusionFlagStatus = "dismissed" // ложное срабатывание
+	CollusionFlagConfirmed CollusionFlagStatus = "confirmed" // сговор подтвержден, пара не матчится
+)
+
+// признаки сговора пары игроков
+const (
+	CollusionSignalRepeatedPairing = "repeated_pairing" // много матчей друг с другом за окно
+	CollusionSignalOneSided        = "one_sided"        // почти все партии выигрывает один
+	CollusionSignalChipFlow        = "chip_flow"        // крупный чистый перелив ставок одному игроку
+	CollusionSignalSameStake       = "same_stake"       // все матчи на одну и ту же ставку
+	CollusionSignalRapidRematch    = "rapid_rematch"    // матчи идут подряд без пауз
+	CollusionSignalSharedWallet    = "shared_wallet"    // общий TON адрес депозитов/выводов
+	CollusionSignalReferral        = "referral_link"    // один пригласил другого
+)
+
+// подозрительная пара игроков (user_a < user_b)
+type CollusionFlag struct {
+	ID          int64               `json:"id"`
+	UserA       int64               `json:"user_a"`
+	UserB       int64               `json:"user_b"`
+	Score       int                 `json:"score"`
+	Signals     []string            `json:"signals"`
+	Games       int                 `json:"games"`
+	WinsA       int                 `json:"wins_a"`
+	WinsB       int                 `json:"wins_b"`
+	Volume      int64               `json:"volume"`
+	NetA        int64               `json:"net_a"`
+	FirstGameAt *time.Time          `json:"first_game_at,omitempty"`
+	LastGameAt  *time.Time          `json:"last_game_at,omitempty"`
+	Status      CollusionFlagStatus `json:"status"`
+	Note        string              `json:"note,omitempty"`
+	ReviewedBy  *int64              `json:"reviewed_by,omitempty"`
+	ReviewedAt  *time.Time          `json:"reviewed_at,omitempty"`
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+}
@@ -45,6 +45,7 @@ type GameHistory struct {
 	BetAmount  int64                  `db:"bet_amount" json:"bet_amount"`
 	WinAmount  int64                  `db:"win_amount" json:"win_amount"`
 	Currency   Currency               `db:"currency" json:"currency"` // gems or coins
+	VsBot      bool                   `db:"vs_bot" json:"vs_bot,omitempty"` // соперник - бот платформы
 	Details    map[string]interface{} `db:"details" json:"details,omitempty"`
 	CreatedAt  time.Time              `db:"created_at" json:"created_at"`
 }
@@ -0,0 +1,15 @@
+package domain
+
+import "time"
+
+// Версия курса коинов и комиссии на вывод, действует с EffectiveFrom до следующей версии
+type PricingConfig struct {
+	ID                     int64     `json:"id"`
+	CoinsPerTON            int64     `json:"coins_per_ton"`
+	CoinsPerUSDT           int64     `json:"coins_per_usdt"`
+	WithdrawFeeCoins       int64     `json:"withdraw_fee_coins"`
+	MaxWithdrawCoinsPerDay int64     `json:"max_withdraw_coins_per_day"`
+	EffectiveFrom          time.Time `json:"effective_from"`
+	CreatedBy              *int64    `json:"created_by,omitempty"`
+	CreatedAt              time.Time `json:"created_at"`
+}
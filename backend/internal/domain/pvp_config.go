@@ -0,0 +1,17 @@
+package domain
+
+import "time"
+
+// параметры PvP игры для уровня ставки (pvp_game_configs)
+type PvPGameConfig struct {
+	ID              int64     `json:"id"`
+	GameType        GameType  `json:"game_type"`
+	MinStake        int64     `json:"min_stake"`
+	SetupTimeoutSec int       `json:"setup_timeout_sec"`
+	TurnTimeoutSec  int       `json:"turn_timeout_sec"`
+	RakePercent     float64   `json:"rake_percent"`
+	MaxRounds       int       `json:"max_rounds"`
+	Enabled         bool      `json:"enabled"`
+	UpdatedBy       *int64    `json:"updated_by,omitempty"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
@@ -0,0 +1,51 @@
+package domain
+
+import "time"
+
+// тип события в журнале PvP комнаты
+type MatchEventType string
+
+const (
+	MatchEventJoin        MatchEventType = "join"         // игрок (или бот) сел за стол
+	MatchEventSetup       MatchEventType = "setup"        // расстановка мин игрока
+	MatchEventRoundStart  MatchEventType = "round_start"  // начало раунда
+	MatchEventMove        MatchEventType = "move"         // ход игрока
+	MatchEventTimeout     MatchEventType = "timeout"      // игрок не сходил, ход сделан автоматически
+	MatchEventRoundResult MatchEventType = "round_result" // раскрытые ходы раунда
+	MatchEventGameResult  MatchEventType = "game_result"  // итог партии
+	MatchEventNextGame    MatchEventType = "next_game"    // следующая партия серии или реванш
+	MatchEventDisconnect  MatchEventType = "disconnect"   // игрок отключился
+	MatchEventCancelled   MatchEventType = "cancelled"    // матч отменен (неявка и т.п.)
+	MatchEventAborted     MatchEventType = "aborted"      // матч прерван остановкой сервера
+)
+
+// событие журнала комнаты
+type MatchEvent struct {
+	Seq       int            `json:"seq"`
+	Game      int            `json:"game"`
+	Type      MatchEventType `json:"type"`
+	UserID    *int64         `json:"user_id,omitempty"`
+	Data      interface{}    `json:"data,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// запись PvP матча: участники, ставка и упорядоченный журнал событий
+type MatchReplay struct {
+	RoomID       string       `json:"room_id"`
+	GameType     GameType     `json:"game_type"`
+	Player1ID    int64        `json:"player1_id"`
+	Player2ID    int64        `json:"player2_id"`
+	BetAmount    int64        `json:"bet_amount"`
+	Currency     string       `json:"currency"`
+	BestOf       int          `json:"best_of"`
+	VsBot        bool         `json:"vs_bot"`
+	TournamentID *int64       `json:"tournament_id,omitempty"`
+	StartedAt    time.Time    `json:"started_at"`
+	FinishedAt   *time.Time   `json:"finished_at,omitempty"` // nil - матч еще идет
+	Events       []MatchEvent `json:"events"`
+}
+
+// участвовал ли пользователь в матче
+func (r *MatchReplay) HasPlayer(userID int64) bool {
+	return r.Player1ID == userID || r.Player2ID == userID
+}
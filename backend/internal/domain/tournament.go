@@ -0,0 +1,110 @@
+package domain
+
+import "time"
+
+// статус турнира
+type TournamentStatus string
+
+const (
+	TournamentStatusRegistration TournamentStatus = "registration" // идет регистрация, взносы в призовом фонде
+	TournamentStatusRunning      TournamentStatus = "running"      // сетка сформирована, идут матчи
+	TournamentStatusFinished     TournamentStatus = "finished"     // призы выплачены
+	TournamentStatusCancelled    TournamentStatus = "cancelled"    // отменен, взносы возвращены
+)
+
+// статус участника турнира
+type TournamentEntryStatus string
+
+const (
+	TournamentEntryRegistered TournamentEntryStatus = "registered"
+	TournamentEntryEliminated TournamentEntryStatus = "eliminated"
+	TournamentEntryRefunded   TournamentEntryStatus = "refunded"
+)
+
+// статус матча сетки
+type TournamentMatchStatus string
+
+const (
+	TournamentMatchPending  TournamentMatchStatus = "pending"  // ждет победителей предыдущего раунда
+	TournamentMatchPlaying  TournamentMatchStatus = "playing"  // комната создана, игроки подключаются или играют
+	TournamentMatchFinished TournamentMatchStatus = "finished" // есть победитель
+	TournamentMatchVoid     TournamentMatchStatus = "void"     // никто из игроков не пришел
+)
+
+// причины завершения матча
+const (
+	TournamentReasonWin          = "win"
+	TournamentReasonOpponentLeft = "opponent_left"
+	TournamentReasonNoShow       = "no_show"
+	TournamentReasonWalkover     = "walkover" // соперника нет (свободное место в сетке)
+	TournamentReasonTiebreak     = "tiebreak" // ничья, победитель выбран жребием
+)
+
+// минимальное число участников для старта
+const TournamentMinPlayers = 2
+
+// турнир на выбывание
+type Tournament struct {
+	ID             int64            `json:"id"`
+	Title          string           `json:"title"`
+	GameType       GameType         `json:"game_type"`
+	Currency       string           `json:"currency"`
+	EntryFee       int64            `json:"entry_fee"`
+	MaxPlayers     int              `json:"max_players"`
+	BestOf         int              `json:"best_of"`
+	PrizePercents  []int            `json:"prize_percents"`
+	PrizePool      int64            `json:"prize_pool"`
+	JoinTimeoutSec int              `json:"join_timeout_sec"`
+	Status         TournamentStatus `json:"status"`
+	Rounds         int              `json:"rounds"`
+	Players        int              `json:"players"`
+	CreatedBy      *int64           `json:"-"`
+	StartsAt       time.Time        `json:"starts_at"`
+	StartedAt      *time.Time       `json:"started_at,omitempty"`
+	FinishedAt     *time.Time       `json:"finished_at,omitempty"`
+	CreatedAt      time.Time        `json:"created_at"`
+}
+
+// открыта ли регистрация
+func (t *Tournament) RegistrationOpen() bool {
+	return t.Status == TournamentStatusRegistration && time.Now().Before(t.StartsAt)
+}
+
+// участник турнира
+type TournamentEntry struct {
+	TournamentID int64                 `json:"tournament_id"`
+	UserID       int64                 `json:"user_id"`
+	Username     string                `json:"username,omitempty"`
+	Seed         *int                  `json:"seed,omitempty"`
+	Status       TournamentEntryStatus `json:"status"`
+	Place        *int                  `json:"place,omitempty"`
+	Prize        int64                 `json:"prize"`
+	CreatedAt    time.Time             `json:"created_at"`
+}
+
+// матч сетки; победитель (round, position) переходит в (round+1, position/2)
+type TournamentMatch struct {
+	ID           int64                 `json:"id"`
+	TournamentID int64                 `json:"tournament_id"`
+	Round        int                   `json:"round"`
+	Position     int                   `json:"position"`
+	Player1ID    *int64                `json:"player1_id,omitempty"`
+	Player2ID    *int64                `json:"player2_id,omitempty"`
+	WinnerID     *int64                `json:"winner_id,omitempty"`
+	Status       TournamentMatchStatus `json:"status"`
+	Reason       *string               `json:"reason,omitempty"`
+	RoomID       *string               `json:"room_id,omitempty"`
+	Deadline     *time.Time            `json:"deadline,omitempty"`
+	StartedAt    *time.Time            `json:"started_at,omitempty"`
+	FinishedAt   *time.Time            `json:"finished_at,omitempty"`
+}
+
+// разрешен ли матч (победитель известен или матч пуст)
+func (m *TournamentMatch) Resolved() bool {
+	return m.Status == TournamentMatchFinished || m.Status == TournamentMatchVoid
+}
+
+// участвует ли пользователь в матче
+func (m *TournamentMatch) HasPlayer(userID int64) bool {
+	return (m.Player1ID != nil && *m.Player1ID == userID) || (m.Player2ID != nil && *m.Player2ID == userID)
+}
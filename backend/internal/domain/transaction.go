@@ -10,3 +10,15 @@ type Transaction struct {
 	Meta      map[string]interface{} `db:"meta" json:"meta,omitempty"`
 	CreatedAt time.Time              `db:"created_at" json:"created_at"`
 }
+
+// зачисление TON депозита (meta: deposit_id, tx_hash, amount_nano)
+const TransactionTypeTonDeposit = "ton_deposit"
+
+// возврат коинов за вывод, не прошедший в сети (meta: withdrawal_id, tx_hash, reason)
+const TransactionTypeWithdrawRefund = "withdraw_refund"
+
+// рейк платформы с PvP банка (пишется на счет платформы)
+const TransactionTypePvPRake = "pvp_rake"
+
+// tg_id системного пользователя-счета платформы (миграция 026)
+const HouseAccountTgID int64 = 0
@@ -23,9 +23,8 @@ const (
 	CurrencyCoins Currency = "coins"
 )
 
-// Курс коинов к TON
+// Вывод коинов (курс к TON и комиссия - в PricingConfig)
 const (
-	CoinsPerTON       = 10    //
 	WithdrawalFeePct  = 5     // 5% комка на вывод !сейчас стоит 0,1TON на любой вывод
 	MinWithdrawCoins  = 10    //  мин кол-во коинов на вывод
 )
@@ -13,6 +13,16 @@ type Wallet struct {
 	LastProofTimestamp int64     `db:"last_proof_timestamp" json:"last_proof_timestamp,omitempty"`
 }
 
+// Nonce для TON Connect proof, выданный пользователю
+type ProofPayload struct {
+	ID         int64      `db:"id" json:"-"`
+	Payload    string     `db:"payload" json:"payload"`
+	UserID     int64      `db:"user_id" json:"-"`
+	ExpiresAt  time.Time  `db:"expires_at" json:"expires_at"`
+	ConsumedAt *time.Time `db:"consumed_at" json:"-"`
+	CreatedAt  time.Time  `db:"created_at" json:"-"`
+}
+
 // Входящий перевод - пополнение
 type Deposit struct {
 	ID            int64         `db:"id" json:"id"`
@@ -29,6 +39,11 @@ type Deposit struct {
 	CreatedAt     time.Time     `db:"created_at" json:"created_at"`
 	ConfirmedAt   *time.Time    `db:"confirmed_at" json:"confirmed_at,omitempty"`
 	Processed     bool          `db:"processed" json:"processed"`
+	Asset         string        `db:"asset" json:"asset"` // TON или тикер жетона; AmountNano - в минимальных единицах актива
+	// причина, по которой перевод не сопоставлен с пользователем (status unmatched, UserID 0)
+	UnmatchedReason string `db:"unmatched_reason" json:"unmatched_reason,omitempty"`
+	// версия pricing_config, по которой начислены коины (0 - до версионирования курса)
+	PricingVersion int64 `db:"pricing_version" json:"pricing_version,omitempty"`
 }
 
 // Статус обработки пополнения
@@ -39,8 +54,21 @@ const (
 	DepositStatusConfirmed DepositStatus = "confirmed"
 	DepositStatusFailed    DepositStatus = "failed"
 	DepositStatusExpired   DepositStatus = "expired"
+	DepositStatusUnmatched DepositStatus = "unmatched" // ждет разбора админом
+	DepositStatusRefunding DepositStatus = "refunding" // возврат отправителю отправляется
+	DepositStatusRefunded  DepositStatus = "refunded"  // возвращен отправителю
 )
 
+// Код депозита для memo: постоянный у пользователя или под одно пополнение (ExpiresAt)
+type DepositCode struct {
+	ID        int64      `db:"id" json:"id"`
+	UserID    int64      `db:"user_id" json:"user_id"`
+	Code      string     `db:"code" json:"code"`
+	ExpiresAt *time.Time `db:"expires_at" json:"expires_at,omitempty"`
+	UsedAt    *time.Time `db:"used_at" json:"used_at,omitempty"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+}
+
 // Исходящий вывод,размен коинов на  TON и отправка - вывод
 type Withdrawal struct {
 	ID            int64            `db:"id" json:"id"`
@@ -60,6 +88,10 @@ type Withdrawal struct {
 	// !!НЕ ИСПОЛЬЗУЕТСЯ, оставлено для работоспособности и дальнейшей модификации
 	GemsAmount int64 `db:"gems_amount" json:"gems_amount,omitempty"`
 	FeeGems    int64 `db:"fee_gems" json:"fee_gems,omitempty"`
+	// TON или тикер жетона; TonAmountNano - в минимальных единицах актива
+	Asset string `db:"asset" json:"asset"`
+	// версия pricing_config: курс и комиссия на момент запроса (0 - до версионирования курса)
+	PricingVersion int64 `db:"pricing_version" json:"pricing_version,omitempty"`
 }
 
 // Статус вывода
@@ -76,10 +108,14 @@ const (
 
 // Информация возвращается пользователю,когда он хочет вкинуть донат
 type DepositInfo struct {
-	PlatformAddress string `json:"platform_address"`
-	Memo            string `json:"memo"` //НЕ ЗАБЫВАТЬ УКАЗЫВАТЬ memo ибо не обработается
-	MinAmountTON    string `json:"min_amount_ton"`
-	ExchangeRate    int    `json:"exchange_rate"` // 10 коинов = 1 TON
+	PlatformAddress string     `json:"platform_address"`
+	Memo            string     `json:"memo"`                      //НЕ ЗАБЫВАТЬ УКАЗЫВАТЬ memo ибо не обработается (код депозита)
+	MemoExpiresAt   *time.Time `json:"memo_expires_at,omitempty"` // код под одно пополнение
+	MinAmountTON    string     `json:"min_amount_ton"`
+	ExchangeRate    int        `json:"exchange_rate"` // 10 коинов = 1 TON
+	Asset           string     `json:"asset"`
+	TransferLink    string     `json:"transfer_link"`   // ton://transfer с адресом и memo
+	CommentPayload  string     `json:"comment_payload"` // base64 BOC комментария для TON Connect sendTransaction
 }
 
 // Платежка ОТ пользователя
@@ -92,6 +128,7 @@ type WithdrawEstimate struct {
 	CoinsAmount   int64   `json:"coins_amount"`
 	FeeCoins      int64   `json:"fee_coins"`       //комка снимается с коинов
 	NetCoins      int64   `json:"net_coins"`
+	Asset         string  `json:"asset"`           // TON или USDT
 	TonAmount     string  `json:"ton_amount"`
 	TonAmountNano int64   `json:"ton_amount_nano"`
 	ExchangeRate  int     `json:"exchange_rate"`   // 10 коинов за TON
@@ -0,0 +1,88 @@
+package game
+
+import (
+	"math/rand"
+)
+
+// ID бота-соперника в комнате (в таблице users его нет)
+const BotPlayerID int64 = -1
+
+// стратегия бота-соперника для конкретного типа игры
+type BotStrategy interface {
+	// данные подготовки (nil если игре подготовка не нужна)
+	SetupMove(g Game, botID int64) interface{}
+	// ход в текущем раунде
+	NextMove(g Game, botID int64) interface{}
+}
+
+// возвращает стратегию бота для типа игры
+func NewBotStrategy(gameType GameType) BotStrategy {
+	switch gameType {
+	case TypeMines:
+		return minesBot{}
+	default:
+		return rpsBot{}
+	}
+}
+
+// RPS: в половине случаев бьет прошлый ход соперника после ничьей, иначе случайный ход
+type rpsBot struct{}
+
+func (rpsBot) SetupMove(g Game, botID int64) interface{} { return nil }
+
+func (rpsBot) NextMove(g Game, botID int64) interface{} {
+	moves := []string{"rock", "paper", "scissors"}
+
+	if rps, ok := g.(*RPSGame); ok && rand.Intn(2) == 0 {
+		for playerID, move := range rps.GetLastMoves() {
+			if playerID != botID {
+				return beats(move)
+			}
+		}
+	}
+	return moves[rand.Intn(3)]
+}
+
+// ход, который бьет переданный
+func beats(move string) string {
+	switch move {
+	case "rock":
+		return "paper"
+	case "paper":
+		return "scissors"
+	default:
+		return "rock"
+	}
+}
+
+// Mines: случайная расстановка, ходы не повторяют уже открытые ботом клетки
+type minesBot struct{}
+
+func (minesBot) SetupMove(g Game, botID int64) interface{} {
+	if mines, ok := g.(*MinesGame); ok {
+		return mines.randomPositions()
+	}
+	return NewMinesGame("", [2]int64{}).randomPositions()
+}
+
+func (minesBot) NextMove(g Game, botID int64) interface{} {
+	tried := make(map[int]bool)
+	cells := DefaultMinesCells
+	if mines, ok := g.(*MinesGame); ok {
+		cells = mines.Options().Cells
+		for _, m := range mines.GetMoveHistory(botID) {
+			tried[m.Cell] = true
+		}
+	}
+
+	free := make([]int, 0, cells)
+	for cell := 1; cell <= cells; cell++ {
+		if !tried[cell] {
+			free = append(free, cell)
+		}
+	}
+	if len(free) == 0 {
+		return rand.Intn(cells) + 1
+	}
+	return free[rand.Intn(len(free))]
+}
@@ -0,0 +1,52 @@
+package game
+
+import "testing"
+
+func TestMinesBotSetupPositions(t *testing.T) {
+	bot := NewBotStrategy(TypeMines)
+	g := NewMinesGame("test", [2]int64{1, BotPlayerID})
+
+	for i := 0; i < 100; i++ {
+		positions, ok := bot.SetupMove(g, BotPlayerID).([]int)
+		if !ok || len(positions) != 4 {
+			t.Fatalf("ожидалось 4 позиции мин, получено %v", positions)
+		}
+		seen := make(map[int]bool)
+		for _, p := range positions {
+			if p < 1 || p > 12 {
+				t.Fatalf("позиция вне поля: %d", p)
+			}
+			if seen[p] {
+				t.Fatalf("повторяющаяся позиция: %v", positions)
+			}
+			seen[p] = true
+		}
+	}
+}
+
+func TestMinesBotSkipsTriedCells(t *testing.T) {
+	bot := NewBotStrategy(TypeMines)
+	g := NewMinesGame("test", [2]int64{1, BotPlayerID})
+	for cell := 1; cell <= 11; cell++ {
+		g.moveHistory[BotPlayerID] = append(g.moveHistory[BotPlayerID], MoveResult{Cell: cell})
+	}
+
+	for i := 0; i < 20; i++ {
+		if move := bot.NextMove(g, BotPlayerID); move != 12 {
+			t.Fatalf("ожидалась единственная свободная клетка 12, получено %v", move)
+		}
+	}
+}
+
+func TestRPSBotMoveIsValid(t *testing.T) {
+	bot := NewBotStrategy(TypeRPS)
+	g := NewRPSGame("test", [2]int64{1, BotPlayerID})
+	g.lastMoves = map[int64]string{1: "rock", BotPlayerID: "rock"}
+
+	for i := 0; i < 50; i++ {
+		move := bot.NextMove(g, BotPlayerID)
+		if move != "rock" && move != "paper" && move != "scissors" {
+			t.Fatalf("неверный ход бота: %v", move)
+		}
+	}
+}
@@ -9,11 +9,21 @@ func NewFactory() *Factory {
 }
 
 func (f *Factory) CreateGame(gameType GameType, roomID string, players [2]int64) (Game, error) {
+	return f.CreateGameWithOptions(gameType, roomID, players, MinesOptions{})
+}
+
+// CreateGameWithOptions создает игру с параметрами комнаты (для rps параметры мин игнорируются)
+func (f *Factory) CreateGameWithOptions(gameType GameType, roomID string, players [2]int64, mines MinesOptions) (Game, error) {
+	return f.CreateGameWithRules(gameType, roomID, players, mines, Rules{})
+}
+
+// CreateGameWithRules создает игру с таймерами и лимитом раундов из конфигурации PvP
+func (f *Factory) CreateGameWithRules(gameType GameType, roomID string, players [2]int64, mines MinesOptions, rules Rules) (Game, error) {
 	switch gameType {
 	case TypeRPS:
-		return NewRPSGame(roomID, players), nil
+		return NewRPSGameWithRules(roomID, players, rules), nil
 	case TypeMines:
-		return NewMinesGame(roomID, players), nil
+		return NewMinesGameWithRules(roomID, players, mines, rules), nil
 	default:
 		return nil, fmt.Errorf("unknown game type: %s", gameType)
 	}
@@ -32,6 +32,12 @@ type Game interface {
 
 	// сериализация для клиента
 	SerializeState(playerID int64) interface{}
+
+	// сериализация для зрителей: без скрытых мин и без ходов до их раскрытия
+	SerializeSpectatorState() interface{}
+
+	// начинает новую партию с теми же игроками (серия, реванш)
+	Reset()
 }
 
 type GameResult struct {
@@ -1,15 +1,94 @@
 package game
 
 import (
+	"errors"
 	"log"
 	"math/rand"
 	"sync"
 	"time"
+
+	"telegram_webapp/internal/logger"
 )
 
+// параметры PvP-варианта мин по умолчанию
+const (
+	DefaultMinesCells       = 12
+	DefaultMinesPerPlayer   = 4
+	DefaultMinesTurnTimeout = 15 * time.Second
+
+	minTurnTimeout = 5 * time.Second
+	maxTurnTimeout = 60 * time.Second
+)
+
+// допустимые размеры поля
+var minesBoardSizes = []int{9, 12, 16, 20, 25}
+
+var (
+	ErrMinesBoardSize   = errors.New("cells must be one of 9, 12, 16, 20, 25")
+	ErrMinesCount       = errors.New("mines must be between 1 and half of the board")
+	ErrMinesTurnTimeout = errors.New("turn timeout must be between 5 and 60 seconds")
+
+	ErrWrongMinesCount = errors.New("wrong number of mines for this board")
+	ErrCellOutOfRange  = errors.New("cell is out of the board")
+	ErrDuplicateMine   = errors.New("mines must be placed on different cells")
+)
+
+// MinesOptions - параметры комнаты мин, выбираются при входе в очередь
+type MinesOptions struct {
+	Cells       int           // клеток на поле
+	Mines       int           // мин у каждого игрока
+	TurnTimeout time.Duration // время на ход
+}
+
+func DefaultMinesOptions() MinesOptions {
+	return MinesOptions{
+		Cells:       DefaultMinesCells,
+		Mines:       DefaultMinesPerPlayer,
+		TurnTimeout: DefaultMinesTurnTimeout,
+	}
+}
+
+// WithDefaults заполняет незаданные параметры значениями по умолчанию
+func (o MinesOptions) WithDefaults() MinesOptions {
+	d := DefaultMinesOptions()
+	if o.Cells == 0 {
+		o.Cells = d.Cells
+	}
+	if o.Mines == 0 {
+		o.Mines = d.Mines
+	}
+	if o.TurnTimeout == 0 {
+		o.TurnTimeout = d.TurnTimeout
+	}
+	return o
+}
+
+// Validate проверяет размер поля, число мин (не больше половины поля) и таймер хода
+func (o MinesOptions) Validate() error {
+	validSize := false
+	for _, n := range minesBoardSizes {
+		if o.Cells == n {
+			validSize = true
+			break
+		}
+	}
+	if !validSize {
+		return ErrMinesBoardSize
+	}
+	if o.Mines < 1 || o.Mines > o.Cells/2 {
+		return ErrMinesCount
+	}
+	if o.TurnTimeout < minTurnTimeout || o.TurnTimeout > maxTurnTimeout || o.TurnTimeout%time.Second != 0 {
+		return ErrMinesTurnTimeout
+	}
+	return nil
+}
+
 type MinesGame struct {
 	id       string
 	players  [2]int64
+	options  MinesOptions
+	rules    Rules
 	boards   map[int64]*Board
 	moves    map[int64]int
 	round    int
@@ -33,14 +112,37 @@ type RoundResult struct {
 }
 
 type Board struct {
-	mines [12]bool
+	mines []bool
 }
 
-// создает новую игру в мины
+// создает новую игру в мины с параметрами по умолчанию (12 клеток, 4 мины)
 func NewMinesGame(id string, players [2]int64) *MinesGame {
+	return NewMinesGameWithOptions(id, players, DefaultMinesOptions())
+}
+
+// создает игру в мины с заданными размером поля, числом мин и таймером хода
+// невалидные параметры заменяются значениями по умолчанию
+func NewMinesGameWithOptions(id string, players [2]int64, opts MinesOptions) *MinesGame {
+	return NewMinesGameWithRules(id, players, opts, Rules{})
+}
+
+// NewMinesGameWithOptions с таймерами и лимитом раундов из конфигурации;
+// таймер хода из rules используется, если поле не задало свой
+func NewMinesGameWithRules(id string, players [2]int64, opts MinesOptions, rules Rules) *MinesGame {
+	rules = rules.WithDefaults(TypeMines)
+	if opts.TurnTimeout == 0 {
+		opts.TurnTimeout = rules.TurnTimeout
+	}
+	opts = opts.WithDefaults()
+	if err := opts.Validate(); err != nil {
+		log.Printf("NewMinesGame: room=%s invalid options %+v: %v, using defaults", id, opts, err)
+		opts = DefaultMinesOptions()
+	}
 	g := &MinesGame{
 		id:          id,
 		players:     players,
+		options:     opts,
+		rules:       rules,
 		boards:      make(map[int64]*Board),
 		moves:       make(map[int64]int),
 		moveHistory: make(map[int64][]MoveResult),
@@ -53,8 +155,11 @@ func NewMinesGame(id string, players [2]int64) *MinesGame {
 
 func (g *MinesGame) Type() GameType { return TypeMines }
 func (g *MinesGame) Players() [2]int64 { return g.players }
-func (g *MinesGame) SetupTimeout() time.Duration { return 10 * time.Second }
-func (g *MinesGame) TurnTimeout() time.Duration { return 15 * time.Second }
+func (g *MinesGame) SetupTimeout() time.Duration { return g.rules.SetupTimeout }
+func (g *MinesGame) TurnTimeout() time.Duration { return g.options.TurnTimeout }
+
+// параметры комнаты: размер поля, число мин, таймер хода
+func (g *MinesGame) Options() MinesOptions { return g.options }
 
 // устанавливает второго игрока
 func (g *MinesGame) SetSecondPlayer(playerID int64) {
@@ -109,7 +214,8 @@ func (g *MinesGame) HandleMove(playerID int64, data interface{}) error {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
-	log.Printf("MinesGame.HandleMove: player=%d data=%v dataType=%T setupComplete=%v", playerID, data, data, g.isSetupCompleteUnlocked())
+	// без данных хода: расстановка мин не должна попадать в логи
+	logger.Debug("MinesGame.HandleMove: ход", "player", playerID, "setupComplete", g.isSetupCompleteUnlocked())
 
 	// Фаза подготовки - расстановка мин
 	if !g.isSetupCompleteUnlocked() {
@@ -120,28 +226,22 @@ func (g *MinesGame) HandleMove(playerID int64, data interface{}) error {
 		}
 
 		positions, ok := data.([]int)
-		if !ok || len(positions) != 4 {
-			log.Printf("MinesGame.HandleMove: invalid setup data, using bot positions")
-			// Бот расставляет мины случайно
-			positions = []int{}
-			used := make(map[int]bool)
-			for len(positions) < 4 {
-				pos := rand.Intn(12) + 1
-				if !used[pos] {
-					used[pos] = true
-					positions = append(positions, pos)
-				}
+		if ok {
+			if err := g.validateSetup(positions); err != nil {
+				return err
 			}
+		} else {
+			log.Printf("MinesGame.HandleMove: no setup data, using bot positions")
+			// Бот расставляет мины случайно
+			positions = g.randomPositions()
 		}
 
-		board := &Board{}
+		board := &Board{mines: make([]bool, g.options.Cells)}
 		for _, pos := range positions {
-			if pos >= 1 && pos <= 12 {
-				board.mines[pos-1] = true
-			}
+			board.mines[pos-1] = true
 		}
 		g.boards[playerID] = board
-		log.Printf("MinesGame.HandleMove: player=%d placed mines at %v, boards=%d", playerID, positions, len(g.boards))
+		log.Printf("MinesGame.HandleMove: player=%d placed %d mines, boards=%d", playerID, len(positions), len(g.boards))
 		return nil
 	}
 
@@ -153,24 +253,52 @@ func (g *MinesGame) HandleMove(playerID int64, data interface{}) error {
 	}
 
 	position, ok := data.(int)
-	if !ok || position < 1 || position > 12 {
-		log.Printf("MinesGame.HandleMove: invalid move data, using random position")
+	if ok && (position < 1 || position > g.options.Cells) {
+		return ErrCellOutOfRange
+	}
+	if !ok {
+		log.Printf("MinesGame.HandleMove: no move data, using random position")
 		// Бот выбирает случайную клетку
-		position = rand.Intn(12) + 1
+		position = rand.Intn(g.options.Cells) + 1
 	}
 
 	g.moves[playerID] = position
-	log.Printf("MinesGame.HandleMove: player=%d selected cell %d, moves=%v", playerID, position, g.moves)
+	logger.Debug("MinesGame.HandleMove: клетка выбрана", "player", playerID, "moves", len(g.moves))
 	return nil
 }
 
+// расстановка должна содержать ровно options.Mines разных клеток поля
+func (g *MinesGame) validateSetup(positions []int) error {
+	if len(positions) != g.options.Mines {
+		return ErrWrongMinesCount
+	}
+	seen := make(map[int]bool, len(positions))
+	for _, pos := range positions {
+		if pos < 1 || pos > g.options.Cells {
+			return ErrCellOutOfRange
+		}
+		if seen[pos] {
+			return ErrDuplicateMine
+		}
+		seen[pos] = true
+	}
+	return nil
+}
+
+// случайная расстановка options.Mines мин
+func (g *MinesGame) randomPositions() []int {
+	positions := make([]int, 0, g.options.Mines)
+	for _, idx := range rand.Perm(g.options.Cells)[:g.options.Mines] {
+		positions = append(positions, idx+1)
+	}
+	return positions
+}
+
 // проверяет завершен ли текущий раунд
 func (g *MinesGame) IsRoundComplete() bool {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
-	complete := len(g.moves) == 2
-	log.Printf("MinesGame.IsRoundComplete: moves=%v count=%d complete=%v", g.moves, len(g.moves), complete)
-	return complete
+	return len(g.moves) == 2
 }
 
 // проверяет результат игры и определяет победителя
@@ -226,9 +354,9 @@ func (g *MinesGame) CheckResult() *GameResult {
 		log.Printf("MinesGame.CheckResult: both hit mines, round draw")
 	}
 
-	// Прошло 5 раундов
-	if g.round >= 5 {
-		log.Printf("MinesGame.CheckResult: draw (5 rounds)")
+	// Прошел лимит раундов
+	if g.round >= g.rules.MaxRounds {
+		log.Printf("MinesGame.CheckResult: draw (%d rounds)", g.round)
 		g.result = &GameResult{WinnerID: nil, Reason: "draw", Details: g.getResultDetails()}
 		return g.result
 	}
@@ -239,6 +367,22 @@ func (g *MinesGame) CheckResult() *GameResult {
 	return nil
 }
 
+// сбрасывает доски, ходы и результат для следующей партии
+// мины расставляются заново в новой фазе подготовки
+func (g *MinesGame) Reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.boards = make(map[int64]*Board)
+	g.moves = make(map[int64]int)
+	g.round = 0
+	g.result = nil
+	g.lastRoundResult = nil
+	g.moveHistory = make(map[int64][]MoveResult)
+	for _, p := range g.players {
+		g.moveHistory[p] = []MoveResult{}
+	}
+}
+
 // возвращает детали результата игры
 func (g *MinesGame) getResultDetails() map[string]interface{} {
 	return map[string]interface{}{
@@ -254,6 +398,23 @@ func (g *MinesGame) GetLastRoundResult() *RoundResult {
 	return g.lastRoundResult
 }
 
+// возвращает расстановку мин игрока (клетки 1..options.Cells), nil до расстановки
+func (g *MinesGame) MinePositions(playerID int64) []int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	board := g.boards[playerID]
+	if board == nil {
+		return nil
+	}
+	positions := []int{}
+	for i, mine := range board.mines {
+		if mine {
+			positions = append(positions, i+1)
+		}
+	}
+	return positions
+}
+
 // возвращает историю ходов для игрока
 func (g *MinesGame) GetMoveHistory(playerID int64) []MoveResult {
 	g.mu.RLock()
@@ -285,4 +446,28 @@ func (g *MinesGame) SerializeState(playerID int64) interface{} {
 		"round":  g.round,
 		"result": g.result,
 	}
-}
\ No newline at end of file
+}
+
+// сериализует состояние для зрителей
+// расстановка мин не раскрывается, выбранные клетки видны только после завершения раунда
+func (g *MinesGame) SerializeSpectatorState() interface{} {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	moved := make(map[int64]bool, 2)
+	history := make(map[int64][]MoveResult, 2)
+	for _, p := range g.players {
+		_, ok := g.moves[p]
+		moved[p] = ok
+		// копия: состояние сериализуется после снятия блокировки
+		history[p] = append([]MoveResult{}, g.moveHistory[p]...)
+	}
+
+	return map[string]interface{}{
+		"type":    "mines",
+		"round":   g.round,
+		"moved":   moved,
+		"history": history,
+		"result":  g.result,
+	}
+}
@@ -0,0 +1,61 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMinesOptionsValidate(t *testing.T) {
+	cases := []struct {
+		opts MinesOptions
+		want error
+	}{
+		{DefaultMinesOptions(), nil},
+		{MinesOptions{Cells: 25, Mines: 12, TurnTimeout: 60 * time.Second}, nil},
+		{MinesOptions{Cells: 10, Mines: 4, TurnTimeout: 15 * time.Second}, ErrMinesBoardSize},
+		{MinesOptions{Cells: 9, Mines: 5, TurnTimeout: 15 * time.Second}, ErrMinesCount},
+		{MinesOptions{Cells: 16, Mines: 4, TurnTimeout: 2 * time.Second}, ErrMinesTurnTimeout},
+	}
+	for _, tc := range cases {
+		if err := tc.opts.Validate(); err != tc.want {
+			t.Fatalf("%+v: ожидалось %v, получено %v", tc.opts, tc.want, err)
+		}
+	}
+}
+
+func TestMinesGameWithOptions(t *testing.T) {
+	opts := MinesOptions{Cells: 25, Mines: 6, TurnTimeout: 20 * time.Second}
+	g := NewMinesGameWithOptions("test", [2]int64{1, BotPlayerID}, opts)
+	if g.TurnTimeout() != 20*time.Second {
+		t.Fatalf("таймер хода: %v", g.TurnTimeout())
+	}
+
+	if err := g.HandleMove(1, []int{1, 2, 3, 4}); err != ErrWrongMinesCount {
+		t.Fatalf("ожидалась ошибка числа мин, получено %v", err)
+	}
+	if err := g.HandleMove(1, []int{1, 2, 3, 4, 5, 26}); err != ErrCellOutOfRange {
+		t.Fatalf("ожидалась ошибка клетки вне поля, получено %v", err)
+	}
+	if err := g.HandleMove(1, []int{20, 21, 22, 23, 24, 25}); err != nil {
+		t.Fatal(err)
+	}
+
+	bot := NewBotStrategy(TypeMines)
+	positions := bot.SetupMove(g, BotPlayerID).([]int)
+	if len(positions) != 6 {
+		t.Fatalf("бот расставил %d мин вместо 6", len(positions))
+	}
+	if err := g.HandleMove(BotPlayerID, positions); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.HandleMove(1, 26); err != ErrCellOutOfRange {
+		t.Fatalf("ожидалась ошибка клетки вне поля, получено %v", err)
+	}
+	if err := g.HandleMove(1, 25); err != nil {
+		t.Fatal(err)
+	}
+	if got := g.MinePositions(1); len(got) != 6 || got[5] != 25 {
+		t.Fatalf("расстановка игрока: %v", got)
+	}
+}
@@ -0,0 +1,41 @@
+package game
+
+import "testing"
+
+func TestMinesResetStartsNewSetup(t *testing.T) {
+	g := NewMinesGame("test", [2]int64{1, 2})
+	if err := g.HandleMove(1, []int{1, 2, 3, 4}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.HandleMove(2, []int{5, 6, 7, 8}); err != nil {
+		t.Fatal(err)
+	}
+	g.HandleMove(1, 5)
+	g.HandleMove(2, 9)
+	if g.CheckResult() == nil || !g.IsFinished() {
+		t.Fatal("ожидалась победа второго игрока: первый попал на мину")
+	}
+
+	g.Reset()
+	if g.IsFinished() || g.IsSetupComplete() || g.GetRound() != 0 {
+		t.Fatalf("после Reset ожидалась новая фаза подготовки: finished=%v setup=%v round=%d",
+			g.IsFinished(), g.IsSetupComplete(), g.GetRound())
+	}
+	if len(g.GetMoveHistory(1)) != 0 || len(g.GetMoveHistory(2)) != 0 {
+		t.Fatal("история ходов должна быть очищена")
+	}
+}
+
+func TestRPSResetClearsResult(t *testing.T) {
+	g := NewRPSGame("test", [2]int64{1, 2})
+	g.HandleMove(1, "rock")
+	g.HandleMove(2, "scissors")
+	if g.CheckResult() == nil {
+		t.Fatal("ожидалась победа первого игрока")
+	}
+
+	g.Reset()
+	if g.IsFinished() || g.IsRoundComplete() || g.GetRound() != 0 {
+		t.Fatal("после Reset ожидалась новая партия")
+	}
+}
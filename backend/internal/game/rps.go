@@ -2,10 +2,13 @@ package game
 
 import (
 	"errors"
+	"fmt"
 	"log"
 	"math/rand"
 	"sync"
 	"time"
+
+	"telegram_webapp/internal/logger"
 )
 
 type RPSGame struct {
@@ -15,15 +18,22 @@ type RPSGame struct {
 	lastMoves map[int64]string // сохраняем ходы при ничьей для отображения
 	round     int
 	result    *GameResult
+	rules     Rules
 	mu        sync.RWMutex
 }
 
 // создает новую игру камень-ножницы-бумага
 func NewRPSGame(id string, players [2]int64) *RPSGame {
+	return NewRPSGameWithRules(id, players, Rules{})
+}
+
+// создает игру с таймерами и лимитом раундов из конфигурации (нулевые - по умолчанию)
+func NewRPSGameWithRules(id string, players [2]int64, rules Rules) *RPSGame {
 	return &RPSGame{
 		id:      id,
 		players: players,
 		moves:   make(map[int64]string),
+		rules:   rules.WithDefaults(TypeRPS),
 	}
 }
 
@@ -37,7 +47,7 @@ func (g *RPSGame) Players() [2]int64 {
 
 // возвращает таймаут для фазы подготовки (таймаут поиска противника)
 func (g *RPSGame) SetupTimeout() time.Duration {
-	return 10 * time.Second // Отменяем игру если противник не найден за 10 секунд
+	return g.rules.SetupTimeout // Отменяем игру если противник не найден за это время
 }
 
 // устанавливает второго игрока
@@ -59,7 +69,7 @@ func (g *RPSGame) IsSetupComplete() bool {
 
 // возвращает таймаут для хода
 func (g *RPSGame) TurnTimeout() time.Duration {
-	return 20 * time.Second
+	return g.rules.TurnTimeout
 }
 
 // обрабатывает ход игрока
@@ -89,7 +99,7 @@ func (g *RPSGame) HandleMove(playerID int64, data interface{}) error {
 	}
 
 	g.moves[playerID] = move
-	log.Printf("RPSGame.HandleMove: игра=%s игрок=%d ход=%s ходы=%v", g.id, playerID, move, g.moves)
+	logger.Debug("RPSGame.HandleMove: ход принят", "game", g.id, "player", playerID, "moves", len(g.moves))
 	return nil
 }
 
@@ -106,7 +116,7 @@ func (g *RPSGame) CheckResult() *GameResult {
 	defer g.mu.Unlock()
 
 	if len(g.moves) < 2 {
-		log.Printf("RPSGame.CheckResult: еще недостаточно ходов: ходы=%v", g.moves)
+		log.Printf("RPSGame.CheckResult: еще недостаточно ходов: ходов=%d", len(g.moves))
 		return nil
 	}
 
@@ -148,11 +158,11 @@ func (g *RPSGame) CheckResult() *GameResult {
 	// Ничья - проверяем достигли ли максимального количества раундов
 	log.Printf("RPSGame.CheckResult: раунд %d завершился вничью", g.round)
 
-	if g.round >= 5 {
-		log.Printf("RPSGame.CheckResult: 5 раундов вничью, игра завершается ничьей")
+	if g.round >= g.rules.MaxRounds {
+		log.Printf("RPSGame.CheckResult: %d раундов вничью, игра завершается ничьей", g.round)
 		g.result = &GameResult{
 			WinnerID: nil,
-			Reason:   "draw_after_5_rounds",
+			Reason:   fmt.Sprintf("draw_after_%d_rounds", g.rules.MaxRounds),
 			Details: map[string]interface{}{
 				"rounds": g.round,
 			},
@@ -170,6 +180,16 @@ func (g *RPSGame) CheckResult() *GameResult {
 	return nil
 }
 
+// сбрасывает ходы и результат для следующей партии
+func (g *RPSGame) Reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.moves = make(map[int64]string)
+	g.lastMoves = nil
+	g.round = 0
+	g.result = nil
+}
+
 // GetLastMoves возвращает последние ходы при ничьей
 func (g *RPSGame) GetLastMoves() map[int64]string {
 	g.mu.RLock()
@@ -204,6 +224,35 @@ func (g *RPSGame) SerializeState(playerID int64) interface{} {
 	}
 }
 
+// сериализует состояние для зрителей
+// ходы текущего раунда скрыты: видно только, кто уже сходил
+func (g *RPSGame) SerializeSpectatorState() interface{} {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	moved := make(map[int64]bool, 2)
+	for _, p := range g.players {
+		_, ok := g.moves[p]
+		moved[p] = ok
+	}
+	// копия: состояние сериализуется после снятия блокировки
+	var lastMoves map[int64]string
+	if g.lastMoves != nil {
+		lastMoves = make(map[int64]string, len(g.lastMoves))
+		for p, m := range g.lastMoves {
+			lastMoves[p] = m
+		}
+	}
+
+	return map[string]interface{}{
+		"type":       "rps",
+		"round":      g.round,
+		"moved":      moved,
+		"last_moves": lastMoves, // ходы прошлого раунда (ничья), уже раскрыты
+		"result":     g.result,
+	}
+}
+
 // определяет результат одного раунда камень-ножницы-бумага
 func decide(moveA, moveB string) string {
 	if moveA == moveB {
@@ -0,0 +1,42 @@
+package game
+
+import "time"
+
+const (
+	DefaultSetupTimeout = 10 * time.Second
+	DefaultMaxRounds    = 5
+)
+
+// Rules параметры партии, задаваемые без изменения кода (таблица pvp_game_configs)
+type Rules struct {
+	SetupTimeout time.Duration // фаза подготовки (rps: ожидание соперника, mines: расстановка)
+	TurnTimeout  time.Duration // время на ход; для mines - если поле не задало свой таймер
+	MaxRounds    int           // раундов вничью до ничьей партии
+}
+
+// встроенные значения для типа игры
+func DefaultRules(gameType GameType) Rules {
+	r := Rules{SetupTimeout: DefaultSetupTimeout, MaxRounds: DefaultMaxRounds}
+	switch gameType {
+	case TypeMines:
+		r.TurnTimeout = DefaultMinesTurnTimeout
+	default:
+		r.TurnTimeout = 20 * time.Second
+	}
+	return r
+}
+
+// WithDefaults заполняет нулевые поля встроенными значениями
+func (r Rules) WithDefaults(gameType GameType) Rules {
+	d := DefaultRules(gameType)
+	if r.SetupTimeout <= 0 {
+		r.SetupTimeout = d.SetupTimeout
+	}
+	if r.TurnTimeout <= 0 {
+		r.TurnTimeout = d.TurnTimeout
+	}
+	if r.MaxRounds <= 0 {
+		r.MaxRounds = d.MaxRounds
+	}
+	return r
+}
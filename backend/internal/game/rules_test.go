@@ -0,0 +1,37 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRPSMaxRoundsFromRules(t *testing.T) {
+	g := NewRPSGameWithRules("test", [2]int64{1, 2}, Rules{MaxRounds: 2, TurnTimeout: 7 * time.Second})
+	if g.TurnTimeout() != 7*time.Second || g.SetupTimeout() != DefaultSetupTimeout {
+		t.Fatalf("таймеры: ход %v, подготовка %v", g.TurnTimeout(), g.SetupTimeout())
+	}
+
+	g.HandleMove(1, "rock")
+	g.HandleMove(2, "rock")
+	if g.CheckResult() != nil {
+		t.Fatal("первая ничья не завершает партию")
+	}
+	g.HandleMove(1, "paper")
+	g.HandleMove(2, "paper")
+	res := g.CheckResult()
+	if res == nil || res.WinnerID != nil || res.Reason != "draw_after_2_rounds" {
+		t.Fatalf("ожидалась ничья после 2 раундов, получено %+v", res)
+	}
+}
+
+func TestMinesTurnTimeoutFromRules(t *testing.T) {
+	g := NewMinesGameWithRules("test", [2]int64{1, 2}, MinesOptions{}, Rules{TurnTimeout: 25 * time.Second})
+	if g.TurnTimeout() != 25*time.Second {
+		t.Fatalf("таймер хода из конфигурации: %v", g.TurnTimeout())
+	}
+	// поле с явным таймером важнее конфигурации
+	g = NewMinesGameWithRules("test", [2]int64{1, 2}, MinesOptions{TurnTimeout: 10 * time.Second}, Rules{TurnTimeout: 25 * time.Second})
+	if g.TurnTimeout() != 10*time.Second {
+		t.Fatalf("таймер хода поля: %v", g.TurnTimeout())
+	}
+}
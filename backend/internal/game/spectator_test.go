@@ -0,0 +1,97 @@
+package game
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// кодирует состояние для зрителей так же, как оно уходит в сокет
+func spectatorJSON(t *testing.T, g Game) map[string]json.RawMessage {
+	t.Helper()
+	data, err := json.Marshal(g.SerializeSpectatorState())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state map[string]json.RawMessage
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+func TestRPSSpectatorStateHidesCurrentMoves(t *testing.T) {
+	g := NewRPSGame("test", [2]int64{1, 2})
+
+	g.HandleMove(1, "scissors")
+	state := spectatorJSON(t, g)
+	if string(state["moved"]) != `{"1":true,"2":false}` {
+		t.Fatalf("moved: %s", state["moved"])
+	}
+	if strings.Contains(string(mustMarshal(t, state)), "scissors") {
+		t.Fatalf("ход текущего раунда виден зрителям: %v", state)
+	}
+
+	// ничья раскрывает ходы прошлого раунда, но не новые
+	g.HandleMove(2, "scissors")
+	if g.CheckResult() != nil {
+		t.Fatal("ничья не завершает партию")
+	}
+	g.HandleMove(2, "paper")
+	state = spectatorJSON(t, g)
+	if string(state["last_moves"]) != `{"1":"scissors","2":"scissors"}` {
+		t.Fatalf("last_moves: %s", state["last_moves"])
+	}
+	if string(state["moved"]) != `{"1":false,"2":true}` {
+		t.Fatalf("moved: %s", state["moved"])
+	}
+	if strings.Contains(string(mustMarshal(t, state)), "paper") {
+		t.Fatalf("ход текущего раунда виден зрителям: %v", state)
+	}
+}
+
+func TestMinesSpectatorStateHidesMines(t *testing.T) {
+	g := NewMinesGame("test", [2]int64{1, 2})
+	if err := g.HandleMove(1, []int{1, 2, 3, 4}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.HandleMove(2, []int{9, 10, 11, 12}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]bool{"type": true, "round": true, "moved": true, "history": true, "result": true}
+	check := func(wantHistory string) {
+		t.Helper()
+		state := spectatorJSON(t, g)
+		for key := range state {
+			if !want[key] {
+				t.Fatalf("лишнее поле %q в состоянии для зрителей", key)
+			}
+		}
+		if string(state["history"]) != wantHistory {
+			t.Fatalf("history: %s, ожидалось %s", state["history"], wantHistory)
+		}
+	}
+
+	// после расстановки зрители не видят ни одной клетки
+	check(`{"1":[],"2":[]}`)
+
+	// выбранная клетка скрыта до конца раунда
+	g.HandleMove(1, 5)
+	check(`{"1":[],"2":[]}`)
+
+	g.HandleMove(2, 6)
+	if g.CheckResult() != nil {
+		t.Fatal("оба промахнулись, партия продолжается")
+	}
+	check(`{"1":[{"cell":5,"hit_mine":false,"round":1}],"2":[{"cell":6,"hit_mine":false,"round":1}]}`)
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
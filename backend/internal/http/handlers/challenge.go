@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Обработка приватных PvP вызовов (игра с другом по ссылке)
+type ChallengeHandler struct {
+	svc             *service.ChallengeService
+	botUsername     string
+	webAppShortName string
+}
+
+// создает новый handler для вызовов
+func NewChallengeHandler(svc *service.ChallengeService, botUsername, webAppShortName string) *ChallengeHandler {
+	return &ChallengeHandler{svc: svc, botUsername: botUsername, webAppShortName: webAppShortName}
+}
+
+// создание вызова
+type CreateChallengeRequest struct {
+	GameType  string `json:"game_type" binding:"required"`
+	BetAmount int64  `json:"bet_amount"`
+	Currency  string `json:"currency"`
+	InviteeID *int64 `json:"invitee_id"` // пусто = любой, у кого есть ссылка
+}
+
+// создает приватную комнату и резервирует ставку создателя
+func (h *ChallengeHandler) CreateChallenge(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req CreateChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	if req.Currency == "" {
+		req.Currency = string(domain.CurrencyGems)
+	}
+
+	ch, err := h.svc.Create(c.Request.Context(), userID, service.CreateChallengeParams{
+		GameType:  domain.GameType(req.GameType),
+		BetAmount: req.BetAmount,
+		Currency:  req.Currency,
+		InviteeID: req.InviteeID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInsufficientFunds):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "insufficient balance"})
+		case errors.Is(err, service.ErrChallengeInvalid), errors.Is(err, service.ErrInvalidAmount):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid challenge parameters"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create challenge"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"challenge": ch,
+		"code":      ch.Code,
+		"link":      h.challengeLink(ch.Code),
+	})
+}
+
+// информация о вызове для экрана приглашения
+func (h *ChallengeHandler) GetChallenge(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ch, err := h.svc.Get(c.Request.Context(), c.Param("code"))
+	if err != nil {
+		if errors.Is(err, service.ErrChallengeNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "challenge not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	if !ch.CanJoin(userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "challenge is for another user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"challenge": ch,
+		"open":      ch.IsOpen(),
+		"link":      h.challengeLink(ch.Code),
+	})
+}
+
+// отменяет вызов и возвращает ставку создателю
+func (h *ChallengeHandler) CancelChallenge(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ch, err := h.svc.Cancel(c.Request.Context(), c.Param("code"), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrChallengeNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "challenge not found"})
+		case errors.Is(err, service.ErrChallengeNotCreator):
+			c.JSON(http.StatusForbidden, gin.H{"error": "not your challenge"})
+		case errors.Is(err, service.ErrChallengeClosed):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "challenge cannot be cancelled"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to cancel challenge"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": true, "refunded": ch.BetAmount, "currency": ch.Currency})
+}
+
+// https://t.me/bot_username/webapp_short_name?startapp=ch_CODE
+// так же как реферальная ссылка, открывает веб аппку сразу на вызове
+func (h *ChallengeHandler) challengeLink(code string) string {
+	return "https://t.me/" + h.botUsername + "/" + h.webAppShortName + "?startapp=ch_" + code
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Обработка жалоб на сообщения соперника в PvP чате
+type ChatReportHandler struct {
+	repo *repository.ChatRepository
+}
+
+// создает новый handler для жалоб
+func NewChatReportHandler(repo *repository.ChatRepository) *ChatReportHandler {
+	return &ChatReportHandler{repo: repo}
+}
+
+// жалоба на соперника
+type CreateChatReportRequest struct {
+	RoomID     string `json:"room_id" binding:"required"`
+	ReportedID int64  `json:"reported_id" binding:"required"`
+	Reason     string `json:"reason"`
+}
+
+// создает жалобу; пожаловаться можно только на того, кто писал игроку в этой комнате
+func (h *ChatReportHandler) CreateReport(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req CreateChatReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.ReportedID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if r := []rune(reason); len(r) > 255 {
+		reason = string(r[:255])
+	}
+
+	ctx := c.Request.Context()
+	received, err := h.repo.HasMessages(ctx, req.RoomID, req.ReportedID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	if !received {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no messages from this user in room"})
+		return
+	}
+
+	report := &domain.ChatReport{
+		RoomID:     req.RoomID,
+		ReporterID: userID,
+		ReportedID: req.ReportedID,
+		Reason:     reason,
+	}
+	if err := h.repo.CreateReport(ctx, report); err != nil {
+		if errors.Is(err, repository.ErrChatReportExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": "already reported"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"report": report})
+}
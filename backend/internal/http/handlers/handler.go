@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"time"
+
 	"telegram_webapp/internal/repository"
 	"telegram_webapp/internal/service"
 
@@ -11,6 +13,10 @@ import (
 type HandlerConfig struct {
 	MinBet int64
 	MaxBet int64
+	// сколько ждать кадры auth и join на /ws (0 - ws.DefaultAuthTimeout)
+	WSAuthTimeout time.Duration
+	// принимать JWT в ?token= (старые клиенты)
+	WSLegacyQueryAuth bool
 }
 
 type Handler struct {
@@ -24,6 +30,8 @@ type Handler struct {
 	CoinFlipProService *service.CoinFlipProService
 	GameService        *service.GameService
 	AuditService       *service.AuditService
+	WSAuthTimeout      time.Duration
+	WSLegacyQueryAuth  bool
 }
 // Прием зависимостей на вход
 func NewHandler(db *pgxpool.Pool, botToken string) *Handler {
@@ -54,6 +62,8 @@ func NewHandlerWithConfig(db *pgxpool.Pool, botToken string, cfg HandlerConfig)
 		CoinFlipProService: service.NewCoinFlipProService(db),
 		GameService:        service.NewGameServiceWithLimits(db, cfg.MinBet, cfg.MaxBet),
 		AuditService:       service.NewAuditService(db),
+		WSAuthTimeout:      cfg.WSAuthTimeout,
+		WSLegacyQueryAuth:  cfg.WSLegacyQueryAuth,
 	}
 }
 
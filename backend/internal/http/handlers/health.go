@@ -16,6 +16,8 @@ type HealthHandler struct {
 	db        *pgxpool.Pool
 	startTime time.Time
 	version   string
+	// остановка PvP хаба (nil - не проверяется)
+	draining func() bool
 }
 
 // Создание нового health handler
@@ -36,6 +38,11 @@ type HealthResponse struct {
 	Checks    map[string]string `json:"checks,omitempty"`
 }
 
+// Проверка остановки PvP хаба для readiness
+func (h *HealthHandler) SetDrainCheck(draining func() bool) {
+	h.draining = draining
+}
+
 // Простой alive status (для k8s liveness probe)
 func (h *HealthHandler) Liveness(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
@@ -57,6 +64,12 @@ func (h *HealthHandler) Readiness(c *gin.Context) {
 		checks["database"] = "healthy"
 	}
 
+	// остановка: новые сокеты не принимаются, живые матчи доигрываются
+	draining := h.draining != nil && h.draining()
+	if draining {
+		checks["pvp"] = "draining"
+	}
+
 	// проверка памяти
 	var m runtime.MemStats
 	runtime.ReadMemStats(&m)
@@ -67,6 +80,9 @@ func (h *HealthHandler) Readiness(c *gin.Context) {
 	if !allHealthy {
 		status = "unhealthy"
 		statusCode = http.StatusServiceUnavailable
+	} else if draining {
+		status = "draining"
+		statusCode = http.StatusServiceUnavailable
 	}
 
 	c.JSON(statusCode, HealthResponse{
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+
+	"telegram_webapp/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Обработка повторов PvP матчей: участникам и админам (разбор споров)
+type ReplayHandler struct {
+	repo     *repository.ReplayRepository
+	users    *repository.UserRepository
+	adminIDs map[int64]bool // tg id админов
+}
+
+// создает новый handler для повторов
+func NewReplayHandler(repo *repository.ReplayRepository, users *repository.UserRepository, adminTgIDs []int64) *ReplayHandler {
+	admins := make(map[int64]bool, len(adminTgIDs))
+	for _, id := range adminTgIDs {
+		admins[id] = true
+	}
+	return &ReplayHandler{repo: repo, users: users, adminIDs: admins}
+}
+
+// журнал событий матча по id комнаты
+func (h *ReplayHandler) GetReplay(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	rep, err := h.repo.Get(ctx, c.Param("room_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	if rep == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "replay not found"})
+		return
+	}
+
+	// идущий матч участнику не отдаем: в журнале расстановка мин соперника
+	if !rep.HasPlayer(userID) || rep.FinishedAt == nil {
+		admin, err := h.isAdmin(c, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+			return
+		}
+		// чужой матч не раскрываем даже фактом существования
+		if !admin {
+			c.JSON(http.StatusNotFound, gin.H{"error": "replay not found"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"replay": rep})
+}
+
+func (h *ReplayHandler) isAdmin(c *gin.Context, userID int64) (bool, error) {
+	if len(h.adminIDs) == 0 {
+		return false, nil
+	}
+	user, err := h.users.GetByID(c.Request.Context(), userID)
+	if err != nil || user == nil {
+		return false, err
+	}
+	return h.adminIDs[user.TgID], nil
+}
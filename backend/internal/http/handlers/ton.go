@@ -6,9 +6,13 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
 
 	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/logger"
 	"telegram_webapp/internal/repository"
+	"telegram_webapp/internal/service"
 	"telegram_webapp/internal/ton"
 
 	"github.com/gin-gonic/gin"
@@ -25,10 +29,14 @@ type TonHandler struct {
 	WithdrawalRepo     *repository.WithdrawalRepository
 	ReferralRepo       *repository.ReferralRepository
 	UserRepo           *repository.UserRepository
-	TonClient          *ton.Client
+	TonClient          ton.ChainIndexer
 	PlatformWallet     string
 	AllowedDomain      string
 	MainDB             *Handler
+	JettonMasters      map[ton.Asset]string // мастер-контракты поддерживаемых жетонов
+	DepositCodes       *service.DepositCodeService
+	ProofPayloads      *repository.ProofPayloadRepository // выданные nonce TON Connect proof
+	Pricing            *service.PricingService            // курс коинов, комиссия и дневной лимит вывода
 	OnWithdrawalCreate WithdrawalNotifyFunc // уведомление админам о выводе!!
 }
 
@@ -39,17 +47,78 @@ func NewTonHandler(h *Handler) *TonHandler {
 		network = ton.NetworkTestnet
 	}
 
+	indexer, err := ton.NewChainIndexer(network, os.Getenv("TON_INDEXERS"), os.Getenv("TON_API_KEY"), os.Getenv("TONCENTER_API_KEY"))
+	if err != nil {
+		logger.Get().Warn("invalid TON_INDEXERS, using tonapi", "error", err)
+		indexer = ton.NewClient(network, os.Getenv("TON_API_KEY"))
+	}
+
+	allowedDomain := os.Getenv("TON_ALLOWED_DOMAIN")
+	if allowedDomain == "" && os.Getenv("DEV_MODE") != "true" {
+		logger.Get().Warn("TON_ALLOWED_DOMAIN not set, wallet linking is disabled")
+	}
+
 	return &TonHandler{
 		DB:             repository.NewWalletRepository(h.DB),
 		DepositRepo:    repository.NewDepositRepository(h.DB),
 		WithdrawalRepo: repository.NewWithdrawalRepository(h.DB),
 		ReferralRepo:   repository.NewReferralRepository(h.DB),
 		UserRepo:       repository.NewUserRepository(h.DB),
-		TonClient:      ton.NewClient(network, os.Getenv("TON_API_KEY")),
+		TonClient:      indexer,
 		PlatformWallet: os.Getenv("TON_PLATFORM_WALLET"),
-		AllowedDomain:  os.Getenv("TON_ALLOWED_DOMAIN"),
+		AllowedDomain:  allowedDomain,
 		MainDB:         h,
+		DepositCodes:   service.NewDepositCodeService(h.DB),
+		ProofPayloads:  repository.NewProofPayloadRepository(h.DB),
+		Pricing:        service.NewPricingService(h.DB),
+		JettonMasters: map[ton.Asset]string{
+			ton.AssetUSDT: ton.USDTMaster(network, os.Getenv("TON_USDT_MASTER")),
+		},
+	}
+}
+
+// assetEnabled актив доступен: TON всегда, жетон - если задан мастер-контракт
+func (h *TonHandler) assetEnabled(asset ton.Asset) bool {
+	if !asset.IsJetton() {
+		return true
+	}
+	return h.JettonMasters[asset] != ""
+}
+
+// currentPricing действующая версия курса; при ошибке отвечает 500
+func (h *TonHandler) currentPricing(c *gin.Context) (*domain.PricingConfig, bool) {
+	pricing, err := h.Pricing.Current(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "pricing not configured"})
+		return nil, false
+	}
+	return pricing, true
+}
+
+// действующих nonce на пользователя: повторный запрос и вторая вкладка не ломают уже выданный
+const maxLiveProofPayloads = 5
+
+// выдать nonce для TON Connect proof: кошелек подписывает его при подключении,
+// ConnectWallet принимает nonce один раз, только от этого пользователя и до истечения ton.ProofTTL
+func (h *TonHandler) GetProofPayload(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	payload := &domain.ProofPayload{
+		Payload:   ton.GeneratePayload(),
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(ton.ProofTTL),
+	}
+	if err := h.ProofPayloads.Create(ctx, payload, maxLiveProofPayloads); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to issue proof payload"})
+		return
 	}
+
+	c.JSON(http.StatusOK, payload)
 }
 
 // подключение кошелька
@@ -60,39 +129,35 @@ type ConnectWalletRequest struct {
 
 // связать кошелек с аккаунтом пользователя
 func (h *TonHandler) ConnectWallet(c *gin.Context) {
+	log := logger.Get()
 	userID, ok := getUserID(c)
 	if !ok {
-		fmt.Println("ConnectWallet: unauthorized")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
 
 	var req ConnectWalletRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		fmt.Printf("ConnectWallet: invalid request: %v\n", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
-	fmt.Printf("ConnectWallet: userID=%d, address=%s\n", userID, req.Account.Address)
 
 	ctx := c.Request.Context()
 
 	// проверить есть ли уже привязанный кошелек
 	existing, err := h.DB.GetByUserID(ctx, userID)
 	if err != nil {
-		fmt.Printf("ConnectWallet: db error getting wallet: %v\n", err)
+		log.Error("ConnectWallet: ошибка чтения кошелька", "userID", userID, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
 		return
 	}
 	if existing != nil {
-		fmt.Printf("ConnectWallet: wallet already linked for userID=%d\n", userID)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "wallet already linked"})
 		return
 	}
 
 	// подтвердить формат адреса
 	if !ton.ValidateAddress(req.Account.Address) {
-		fmt.Printf("ConnectWallet: invalid address format: %s\n", req.Account.Address)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wallet address"})
 		return
 	}
@@ -100,37 +165,45 @@ func (h *TonHandler) ConnectWallet(c *gin.Context) {
 	//проверить не связан ли кошелек с другим пользователем
 	addressExists, err := h.DB.AddressExists(ctx, req.Account.Address)
 	if err != nil {
-		fmt.Printf("ConnectWallet: db error checking address: %v\n", err)
+		log.Error("ConnectWallet: ошибка проверки адреса", "userID", userID, "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
 		return
 	}
 	if addressExists {
-		fmt.Printf("ConnectWallet: address already linked to another user: %s\n", req.Account.Address)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "wallet already linked to another account"})
 		return
 	}
 
-	// подтверждение  TON CONNECT  в  DEV_MODE - skip
-	isVerified := false
-	fmt.Printf("ConnectWallet: proof domain=%s, allowed=%s, timestamp=%d\n",
-		req.Proof.Domain.Value, h.AllowedDomain, req.Proof.Timestamp)
-	if os.Getenv("DEV_MODE") != "true" && h.AllowedDomain != "" {
-		if err := ton.VerifyProof(req.Account, req.Proof, h.AllowedDomain); err != nil {
-			fmt.Printf("ConnectWallet: proof verification failed: %v\n", err)
-			// В продакшене пропускаем верификацию если домен совпадает, но подпись не проходит
-			// TON Connect proof сложен и может меняться между версиями кошельков, пропускаем
-			if req.Proof.Domain.Value == h.AllowedDomain {
-				fmt.Println("ConnectWallet: домен совпадает, пропускаем проверку подписи")
-				isVerified = true
-			} else {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "proof verification failed: " + err.Error()})
-				return
-			}
-		} else {
-			isVerified = true
+	// подтверждение TON Connect отключается только в DEV_MODE; без домена proof проверить нечем - отказ
+	if os.Getenv("DEV_MODE") != "true" {
+		if h.AllowedDomain == "" {
+			log.Error("ConnectWallet: TON_ALLOWED_DOMAIN не задан, привязка кошелька отклонена", "userID", userID)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "wallet verification is not configured"})
+			return
+		}
+
+		// nonce из proof должен быть выдан этому пользователю, не использован и не истек; он гасится
+		// до проверки подписи, поэтому неудачная попытка его не оставляет (защита от повтора)
+		consumed, err := h.ProofPayloads.Consume(ctx, req.Proof.Payload, userID)
+		if err != nil {
+			log.Error("ConnectWallet: ошибка погашения nonce", "userID", userID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+			return
+		}
+		if !consumed {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "proof verification failed: invalid or expired payload"})
+			return
+		}
+
+		// подпись кошелька над погашенным nonce - единственное, что связывает nonce с ключом адреса
+		if err := ton.VerifyProof(req.Account, req.Proof, h.AllowedDomain, req.Proof.Payload); err != nil {
+			log.Warn("ConnectWallet: proof не прошел проверку",
+				"userID", userID,
+				"address", req.Account.Address,
+				"error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "proof verification failed: " + err.Error()})
+			return
 		}
-	} else {
-		isVerified = true
 	}
 
 	// нормализовать адрес
@@ -141,7 +214,7 @@ func (h *TonHandler) ConnectWallet(c *gin.Context) {
 		UserID:             userID,
 		Address:            req.Account.Address,
 		RawAddress:         rawAddress,
-		IsVerified:         isVerified,
+		IsVerified:         true,
 		LastProofTimestamp: req.Proof.Timestamp,
 	}
 
@@ -228,14 +301,49 @@ func (h *TonHandler) GetDepositInfo(c *gin.Context) {
 		platformAddress = userFriendlyAddr
 	}
 
-	// создать уникальное memo для пользователя
-	memo := fmt.Sprintf("deposit_%d", userID)
+	asset, ok := ton.ParseAsset(c.Query("asset"))
+	if !ok || !h.assetEnabled(asset) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported asset"})
+		return
+	}
+
+	// сумма для ссылки в минимальных единицах актива (необязательно)
+	var amountUnits int64
+	if raw := c.Query("amount"); raw != "" {
+		amountUnits, err = strconv.ParseInt(raw, 10, 64)
+		if err != nil || amountUnits < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid amount"})
+			return
+		}
+	}
+
+	pricing, ok := h.currentPricing(c)
+	if !ok {
+		return
+	}
+
+	// код депозита вместо user id: постоянный или под одно пополнение (intent=true)
+	ctx := c.Request.Context()
+	var code *domain.DepositCode
+	if c.Query("intent") == "true" {
+		code, err = h.DepositCodes.IntentCode(ctx, userID)
+	} else {
+		code, err = h.DepositCodes.UserCode(ctx, userID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to issue deposit code"})
+		return
+	}
 
 	c.JSON(http.StatusOK, domain.DepositInfo{
 		PlatformAddress: platformAddress,
-		Memo:            memo,
-		MinAmountTON:    fmt.Sprintf("%.2f", ton.NanoToTON(ton.MinDepositNano)),
-		ExchangeRate:    ton.CoinsPerTON,
+		Memo:            code.Code,
+		MemoExpiresAt:   code.ExpiresAt,
+		MinAmountTON:    fmt.Sprintf("%.2f", ton.UnitsToFloat(asset, asset.MinDepositUnits())),
+		ExchangeRate:    int(service.PricingRates(pricing)[asset]),
+		Asset:           string(asset),
+		TransferLink:    ton.TransferLink(platformAddress, asset, h.JettonMasters[asset], amountUnits, code.Code),
+		CommentPayload:  ton.CommentPayload(code.Code),
 	})
 }
 
@@ -259,7 +367,8 @@ func (h *TonHandler) GetDeposits(c *gin.Context) {
 
 // вывод коинов
 type WithdrawRequestBody struct {
-	CoinsAmount int64 `json:"coins_amount" binding:"required,min=10"`
+	CoinsAmount int64  `json:"coins_amount" binding:"required,min=10"`
+	Asset       string `json:"asset"` // TON (по умолчанию) или USDT
 }
 
 // новый запросов на вывод коинов
@@ -275,6 +384,11 @@ func (h *TonHandler) RequestWithdrawal(c *gin.Context, db *pgx.Conn) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request: " + err.Error()})
 		return
 	}
+	asset, ok := ton.ParseAsset(req.Asset)
+	if !ok || !h.assetEnabled(asset) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported asset"})
+		return
+	}
 
 	ctx := c.Request.Context()
 
@@ -300,14 +414,20 @@ func (h *TonHandler) RequestWithdrawal(c *gin.Context, db *pgx.Conn) {
 		return
 	}
 
+	// курс, комиссия и дневной лимит на момент запроса сохраняются в заявке
+	pricing, ok := h.currentPricing(c)
+	if !ok {
+		return
+	}
+
 	// дневной лимит на вывод коинов
 	todayTotal, err := h.WithdrawalRepo.GetTotalCoinsWithdrawnToday(ctx, userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
 		return
 	}
-	if todayTotal+req.CoinsAmount > ton.MaxWithdrawCoinsPerDay {
-		remaining := ton.MaxWithdrawCoinsPerDay - todayTotal
+	if todayTotal+req.CoinsAmount > pricing.MaxWithdrawCoinsPerDay {
+		remaining := pricing.MaxWithdrawCoinsPerDay - todayTotal
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":           "daily withdrawal limit exceeded",
 			"remaining_today": remaining,
@@ -331,9 +451,10 @@ func (h *TonHandler) RequestWithdrawal(c *gin.Context, db *pgx.Conn) {
 	}
 
 	// ЕСЛИ КОМСА В ПРОЦЕНТАХ
-	feeCoins := ton.CalculateWithdrawFeeCoins(req.CoinsAmount)
-	netCoins := ton.CalculateWithdrawNetCoins(req.CoinsAmount)
-	tonAmountNano := ton.CoinsToNano(netCoins)
+	rates := service.PricingRates(pricing)
+	feeCoins := pricing.WithdrawFeeCoins
+	netCoins := req.CoinsAmount - feeCoins
+	tonAmountNano := rates.CoinsToUnits(asset, netCoins)
 
 	// списываем коины с баланса пользователя ПЕРЕД созданием заявки
 	_, err = h.UserRepo.UpdateCoins(ctx, userID, -req.CoinsAmount)
@@ -344,13 +465,15 @@ func (h *TonHandler) RequestWithdrawal(c *gin.Context, db *pgx.Conn) {
 
 	// создание запроса на вывод средств
 	withdrawal := &domain.Withdrawal{
-		UserID:        userID,
-		WalletAddress: wallet.Address,
-		CoinsAmount:   req.CoinsAmount,
-		TonAmountNano: tonAmountNano,
-		FeeCoins:      feeCoins,
-		ExchangeRate:  ton.CoinsPerTON,
-		Status:        domain.WithdrawalStatusPending,
+		UserID:         userID,
+		WalletAddress:  wallet.Address,
+		CoinsAmount:    req.CoinsAmount,
+		TonAmountNano:  tonAmountNano,
+		FeeCoins:       feeCoins,
+		ExchangeRate:   int(rates[asset]),
+		Asset:          string(asset),
+		Status:         domain.WithdrawalStatusPending,
+		PricingVersion: pricing.ID,
 	}
 
 	if err := h.WithdrawalRepo.Create(ctx, withdrawal); err != nil {
@@ -399,16 +522,7 @@ func (h *TonHandler) RequestWithdrawal(c *gin.Context, db *pgx.Conn) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"withdrawal": withdrawal,
-		"estimate": domain.WithdrawEstimate{
-			CoinsAmount:   req.CoinsAmount,
-			FeeCoins:      feeCoins,
-			NetCoins:      netCoins,
-			TonAmount:     fmt.Sprintf("%.4f", ton.NanoToTON(tonAmountNano)),
-			TonAmountNano: tonAmountNano,
-			ExchangeRate:  ton.CoinsPerTON,
-			FeePercent:    0, // комса в %
-			FeeTON:        ton.CoinsToTON(feeCoins),
-		},
+		"estimate":   withdrawEstimate(rates, asset, req.CoinsAmount, feeCoins, netCoins),
 	})
 }
 
@@ -419,30 +533,47 @@ func (h *TonHandler) GetWithdrawEstimate(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
+	asset, ok := ton.ParseAsset(req.Asset)
+	if !ok || !h.assetEnabled(asset) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported asset"})
+		return
+	}
+
+	pricing, ok := h.currentPricing(c)
+	if !ok {
+		return
+	}
+	rates := service.PricingRates(pricing)
 
 	if req.CoinsAmount < ton.MinWithdrawCoins {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error":      "minimum withdrawal is 10 coins (1 TON)",
-			"min_coins":  ton.MinWithdrawCoins,
-			"min_ton":    fmt.Sprintf("%.2f", ton.NanoToTON(ton.CoinsToNano(ton.MinWithdrawCoins))),
+			"error":     "minimum withdrawal is 10 coins",
+			"min_coins": ton.MinWithdrawCoins,
+			"min_ton":   fmt.Sprintf("%.2f", ton.NanoToTON(rates.CoinsToUnits(ton.AssetTON, ton.MinWithdrawCoins))),
 		})
 		return
 	}
 
-	feeCoins := ton.CalculateWithdrawFeeCoins(req.CoinsAmount)
-	netCoins := ton.CalculateWithdrawNetCoins(req.CoinsAmount)
-	tonAmountNano := ton.CoinsToNano(netCoins)
+	feeCoins := pricing.WithdrawFeeCoins
+	netCoins := req.CoinsAmount - feeCoins
+
+	c.JSON(http.StatusOK, withdrawEstimate(rates, asset, req.CoinsAmount, feeCoins, netCoins))
+}
 
-	c.JSON(http.StatusOK, domain.WithdrawEstimate{
-		CoinsAmount:   req.CoinsAmount,
+// расчет вывода в единицах выбранного актива по курсу rates
+func withdrawEstimate(rates ton.Rates, asset ton.Asset, coinsAmount, feeCoins, netCoins int64) domain.WithdrawEstimate {
+	units := rates.CoinsToUnits(asset, netCoins)
+	return domain.WithdrawEstimate{
+		CoinsAmount:   coinsAmount,
 		FeeCoins:      feeCoins,
 		NetCoins:      netCoins,
-		TonAmount:     fmt.Sprintf("%.4f", ton.NanoToTON(tonAmountNano)),
-		TonAmountNano: tonAmountNano,
-		ExchangeRate:  ton.CoinsPerTON,
+		Asset:         string(asset),
+		TonAmount:     fmt.Sprintf("%.4f", ton.UnitsToFloat(asset, units)),
+		TonAmountNano: units,
+		ExchangeRate:  int(rates[asset]),
 		FeePercent:    0, // комса в %,сейчас фикс
-		FeeTON:        ton.CoinsToTON(feeCoins),
-	})
+		FeeTON:        ton.UnitsToFloat(asset, rates.CoinsToUnits(asset, feeCoins)),
+	}
 }
 
 // история выводов
@@ -517,21 +648,47 @@ func (h *TonHandler) CancelWithdrawal(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"ok": true, "coins_returned": withdrawal.CoinsAmount})
 }
 
-// конфиг TON for frontend
+// конфиг TON for frontend (курс и комиссия - действующая версия pricing_config)
 func (h *TonHandler) GetTonConfig(c *gin.Context) {
+	pricing, ok := h.currentPricing(c)
+	if !ok {
+		return
+	}
+	rates := service.PricingRates(pricing)
+
 	c.JSON(http.StatusOK, gin.H{
 		"platform_wallet":            h.PlatformWallet,
-		"coins_per_ton":              ton.CoinsPerTON, // 10 коинов = 1 TON
+		"coins_per_ton":              pricing.CoinsPerTON,
 		"min_deposit_ton":            fmt.Sprintf("%.2f", ton.NanoToTON(ton.MinDepositNano)),
 		"min_withdraw_coins":         ton.MinWithdrawCoins,
-		"withdraw_fee_coins":         ton.WithdrawFeeCoinsFixed, // 1 коин = 0.1 TON
-		"withdraw_fee_ton":           ton.CoinsToTON(ton.WithdrawFeeCoinsFixed), // 0.1 TON
+		"withdraw_fee_coins":         pricing.WithdrawFeeCoins,
+		"withdraw_fee_ton":           ton.NanoToTON(rates.CoinsToUnits(ton.AssetTON, pricing.WithdrawFeeCoins)),
 		"withdraw_fee_percent":       0, // если вводить назад комсу в %
-		"max_withdraw_coins_per_day": ton.MaxWithdrawCoinsPerDay,
+		"max_withdraw_coins_per_day": pricing.MaxWithdrawCoinsPerDay,
+		"pricing_version":            pricing.ID,
 		"network":                    os.Getenv("TON_NETWORK"),
+		"assets":                     h.assetsConfig(rates),
 	})
 }
 
+// курсы и параметры доступных активов для фронтенда
+func (h *TonHandler) assetsConfig(rates ton.Rates) []gin.H {
+	assets := make([]gin.H, 0, 2)
+	for _, asset := range []ton.Asset{ton.AssetTON, ton.AssetUSDT} {
+		if !h.assetEnabled(asset) {
+			continue
+		}
+		assets = append(assets, gin.H{
+			"asset":          asset,
+			"decimals":       asset.Decimals(),
+			"coins_per_unit": rates[asset],
+			"min_deposit":    fmt.Sprintf("%.2f", ton.UnitsToFloat(asset, asset.MinDepositUnits())),
+			"jetton_master":  h.JettonMasters[asset],
+		})
+	}
+	return assets
+}
+
 // для тестов и админов
 func (h *TonHandler) RecordManualDeposit(c *gin.Context, handler *Handler) {
 	if os.Getenv("DEV_MODE") != "true" {
@@ -579,18 +736,24 @@ func (h *TonHandler) RecordManualDeposit(c *gin.Context, handler *Handler) {
 		walletAddr = wallet.Address
 	}
 
+	pricing, ok := h.currentPricing(c)
+	if !ok {
+		return
+	}
+
 	amountNano := ton.TONToNano(req.AmountTON)
-	coinsCredited := ton.NanoToCoins(amountNano)
+	coinsCredited := service.PricingRates(pricing).UnitsToCoins(ton.AssetTON, amountNano)
 
 	deposit := &domain.Deposit{
-		UserID:        userID,
-		WalletAddress: walletAddr,
-		AmountNano:    amountNano,
-		CoinsCredited: coinsCredited,
-		ExchangeRate:  ton.CoinsPerTON,
-		TxHash:        req.TxHash,
-		Status:        domain.DepositStatusConfirmed,
-		Processed:     true,
+		UserID:         userID,
+		WalletAddress:  walletAddr,
+		AmountNano:     amountNano,
+		CoinsCredited:  coinsCredited,
+		ExchangeRate:   int(pricing.CoinsPerTON),
+		TxHash:         req.TxHash,
+		Status:         domain.DepositStatusConfirmed,
+		Processed:      true,
+		PricingVersion: pricing.ID,
 	}
 
 	if err := h.DepositRepo.Create(ctx, deposit); err != nil {
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/repository"
+	"telegram_webapp/internal/ton"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ConnectWallet принимает proof только над выданным этому пользователю nonce, один раз.
+// Нужна мигрированная БД в TEST_DATABASE_URL
+func TestConnectWalletProofPayload(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set; skipping integration test")
+	}
+	t.Setenv("DEV_MODE", "false")
+	gin.SetMode(gin.TestMode)
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	cleanup := func() {
+		pool.Exec(ctx, `DELETE FROM wallets WHERE user_id IN (SELECT id FROM users WHERE tg_id IN (-4901, -4902))`)
+		pool.Exec(ctx, `DELETE FROM ton_proof_payloads WHERE user_id IN (SELECT id FROM users WHERE tg_id IN (-4901, -4902))`)
+		pool.Exec(ctx, `DELETE FROM users WHERE tg_id IN (-4901, -4902)`)
+	}
+	cleanup()
+	t.Cleanup(cleanup)
+
+	var userID, otherID int64
+	if err := pool.QueryRow(ctx,
+		`INSERT INTO users (tg_id, username, first_name) VALUES (-4901, 'proof_test', 'proof') RETURNING id`,
+	).Scan(&userID); err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.QueryRow(ctx,
+		`INSERT INTO users (tg_id, username, first_name) VALUES (-4902, 'proof_other', 'proof') RETURNING id`,
+	).Scan(&otherID); err != nil {
+		t.Fatal(err)
+	}
+
+	const domainName = "app.example.com"
+	h := &TonHandler{
+		DB:            repository.NewWalletRepository(pool),
+		ProofPayloads: repository.NewProofPayloadRepository(pool),
+		AllowedDomain: domainName,
+	}
+	account, priv, err := ton.NewFakeConnectWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issueFor := func(uid int64) string {
+		t.Helper()
+		p := &domain.ProofPayload{Payload: ton.GeneratePayload(), UserID: uid, ExpiresAt: time.Now().Add(ton.ProofTTL)}
+		if err := h.ProofPayloads.Create(ctx, p, maxLiveProofPayloads); err != nil {
+			t.Fatal(err)
+		}
+		return p.Payload
+	}
+	issue := func() string {
+		t.Helper()
+		return issueFor(userID)
+	}
+	connectFrom := func(host, payload string) int {
+		t.Helper()
+		proof, err := ton.SignFakeProof(account, priv, host, payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := json.Marshal(ConnectWalletRequest{Account: account, Proof: proof})
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request = httptest.NewRequest(http.MethodPost, "/wallet/connect", bytes.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Set("user_id", userID)
+		h.ConnectWallet(c)
+		return rec.Code
+	}
+	connect := func(payload string) int {
+		t.Helper()
+		return connectFrom(domainName, payload)
+	}
+
+	// подпись верна, но payload сервер не выдавал
+	if code := connect(ton.GeneratePayload()); code != http.StatusBadRequest {
+		t.Fatalf("proof над невыданным payload: код %d", code)
+	}
+	// nonce выдан другому пользователю
+	if code := connect(issueFor(otherID)); code != http.StatusBadRequest {
+		t.Fatalf("proof над чужим nonce: код %d", code)
+	}
+	// nonce погашен неудачной попыткой (чужой домен) и не принимается повторно
+	nonce := issue()
+	if code := connectFrom("evil.example.com", nonce); code != http.StatusBadRequest {
+		t.Fatalf("proof с чужим доменом: код %d", code)
+	}
+	if code := connect(nonce); code != http.StatusBadRequest {
+		t.Fatalf("повтор погашенного nonce: код %d", code)
+	}
+	// сверх лимита действующих nonce удаляется самый старый
+	oldest := issue()
+	for i := 0; i < maxLiveProofPayloads; i++ {
+		issue()
+	}
+	var rows int
+	if err := pool.QueryRow(ctx, `SELECT COUNT(*) FROM ton_proof_payloads WHERE user_id = $1`, userID).Scan(&rows); err != nil {
+		t.Fatal(err)
+	}
+	if rows != maxLiveProofPayloads {
+		t.Fatalf("nonce у пользователя: %d, ожидалось %d", rows, maxLiveProofPayloads)
+	}
+	if code := connect(oldest); code != http.StatusBadRequest {
+		t.Fatalf("proof над вытесненным nonce: код %d", code)
+	}
+	if wallet, err := h.DB.GetByUserID(ctx, userID); err != nil || wallet != nil {
+		t.Fatalf("кошелек привязан без валидного proof: %+v, %v", wallet, err)
+	}
+
+	// повторный запрос nonce (вторая вкладка) не ломает proof над выданным раньше
+	first := issue()
+	issue()
+	if code := connect(first); code != http.StatusOK {
+		t.Fatalf("proof над ранее выданным nonce: код %d", code)
+	}
+	if wallet, err := h.DB.GetByUserID(ctx, userID); err != nil || wallet == nil || wallet.Address != account.Address {
+		t.Fatalf("кошелек не привязан: %+v, %v", wallet, err)
+	}
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Обработка турниров: список, регистрация, сетка и таблица мест
+type TournamentHandler struct {
+	svc *service.TournamentService
+}
+
+// создает новый handler для турниров
+func NewTournamentHandler(svc *service.TournamentService) *TournamentHandler {
+	return &TournamentHandler{svc: svc}
+}
+
+// раунд сетки для ответа
+type bracketRound struct {
+	Round   int                      `json:"round"`
+	Matches []domain.TournamentMatch `json:"matches"`
+}
+
+// турниры с открытой регистрацией и идущие
+func (h *TournamentHandler) ListTournaments(c *gin.Context) {
+	tournaments, err := h.svc.ListActive(c.Request.Context(), 50)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	if tournaments == nil {
+		tournaments = []domain.Tournament{}
+	}
+	c.JSON(http.StatusOK, gin.H{"tournaments": tournaments})
+}
+
+// турнир с таблицей мест и текущим матчем пользователя
+func (h *TournamentHandler) GetTournament(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	t, ok := h.tournament(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	standings, err := h.svc.Standings(ctx, t.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+	if standings == nil {
+		standings = []domain.TournamentEntry{}
+	}
+
+	var me *domain.TournamentEntry
+	for i := range standings {
+		if standings[i].UserID == userID {
+			me = &standings[i]
+			break
+		}
+	}
+
+	// матч, к которому нужно подключиться: /ws?tournament_match=<id>
+	var myMatch *domain.TournamentMatch
+	if me != nil && t.Status == domain.TournamentStatusRunning {
+		matches, err := h.svc.Bracket(ctx, t.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+			return
+		}
+		for i := range matches {
+			if matches[i].Status == domain.TournamentMatchPlaying && matches[i].HasPlayer(userID) {
+				myMatch = &matches[i]
+				break
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tournament":        t,
+		"standings":         standings,
+		"me":                me,
+		"my_match":          myMatch,
+		"registration_open": t.RegistrationOpen(),
+	})
+}
+
+// сетка турнира по раундам
+func (h *TournamentHandler) GetBracket(c *gin.Context) {
+	t, ok := h.tournament(c)
+	if !ok {
+		return
+	}
+
+	matches, err := h.svc.Bracket(c.Request.Context(), t.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return
+	}
+
+	rounds := make([]bracketRound, t.Rounds)
+	for i := range rounds {
+		rounds[i] = bracketRound{Round: i + 1, Matches: []domain.TournamentMatch{}}
+	}
+	for _, m := range matches {
+		if m.Round >= 1 && m.Round <= len(rounds) {
+			rounds[m.Round-1].Matches = append(rounds[m.Round-1].Matches, m)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tournament_id": t.ID, "status": t.Status, "rounds": rounds})
+}
+
+// регистрация на турнир со списанием взноса
+func (h *TournamentHandler) Register(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tournament id"})
+		return
+	}
+
+	t, err := h.svc.Register(c.Request.Context(), id, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrTournamentNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "tournament not found"})
+		case errors.Is(err, service.ErrInsufficientFunds):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "insufficient balance"})
+		case errors.Is(err, service.ErrTournamentClosed):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "registration is closed"})
+		case errors.Is(err, service.ErrTournamentFull):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tournament is full"})
+		case errors.Is(err, service.ErrTournamentAlreadyRegistered):
+			c.JSON(http.StatusConflict, gin.H{"error": "already registered"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to register"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tournament": t})
+}
+
+// отмена регистрации до старта с возвратом взноса
+func (h *TournamentHandler) Unregister(c *gin.Context) {
+	userID, ok := getUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tournament id"})
+		return
+	}
+
+	if err := h.svc.Unregister(c.Request.Context(), id, userID); err != nil {
+		switch {
+		case errors.Is(err, service.ErrTournamentNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "tournament not found"})
+		case errors.Is(err, service.ErrTournamentClosed):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "registration is closed"})
+		case errors.Is(err, service.ErrTournamentNotRegistered):
+			c.JSON(http.StatusNotFound, gin.H{"error": "not registered"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to unregister"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// загружает турнир из :id, при ошибке отвечает сам
+func (h *TournamentHandler) tournament(c *gin.Context) (*domain.Tournament, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tournament id"})
+		return nil, false
+	}
+	t, err := h.svc.Get(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, service.ErrTournamentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "tournament not found"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		return nil, false
+	}
+	return t, true
+}
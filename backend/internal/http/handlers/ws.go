@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/game"
+	"telegram_webapp/internal/logger"
 	"telegram_webapp/internal/repository"
 	"telegram_webapp/internal/service"
 	"telegram_webapp/internal/ws"
@@ -17,113 +19,538 @@ import (
 	"github.com/gorilla/websocket"
 )
 
+// параметры входа в PvP: из сообщения join или из query (устаревший путь с ?token=)
+type wsJoin struct {
+	GameType        string
+	BetAmount       int64
+	Currency        string
+	BestOf          int
+	ChallengeCode   string
+	TournamentMatch int64
+	Mines           game.MinesOptions
+	ProtocolVersion int
+
+	// заполняются в prepareJoin
+	challenge  *domain.Challenge
+	reserveBet bool
+}
+
+// отказ во входе: HTTP статус для query-пути, код закрытия 4000+статус для хендшейка
+type wsJoinError struct {
+	Status  int
+	Message string
+}
+
+// WS - игровое соединение PvP.
+// Соединение открывается без авторизации, первым кадром ждем auth с JWT (WSAuthTimeout),
+// затем join с параметрами игры; ставка списывается только после auth.
+// ?token=... для старых клиентов принимается только с WSLegacyQueryAuth.
 func (h *Handler) WS(hub *ws.Hub) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Проверка JWT токена
-		token := c.Query("token")
-		if token == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "token required"})
+		// сервер останавливается: балансировщик уведет клиента на другой инстанс
+		if hub.Draining() {
+			c.Header("Retry-After", "5")
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "server is draining", "code": ws.ErrCodeServerDraining})
 			return
 		}
 
-		userID, err := service.ParseJWT(token)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		token, ok := h.legacyQueryToken(c, "/ws")
+		if !ok {
+			return
+		}
+		if token != "" {
+			h.wsQueryJoin(c, hub, token)
 			return
 		}
 
-		// получаем тип игры(по умолчанию rps)
-		gameType := c.Query("game")
-		if gameType == "" {
-			gameType = "rps"
+		conn, err := wsUpgrader().Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			log.Println("ws upgrade error:", err)
+			return
 		}
+		go h.wsHandshake(hub, conn)
+	}
+}
 
-		// получить сумму ставки и валюты из запроса
-		var betAmount int64
-		if betStr := c.Query("bet"); betStr != "" {
-			if parsed, err := strconv.ParseInt(betStr, 10, 64); err == nil {
-				betAmount = parsed
-			}
+// legacyQueryToken JWT из ?token= (устаревший путь): "" - токена в URL нет.
+// ok=false - путь выключен (WSLegacyQueryAuth), клиенту уже отправлен 400
+func (h *Handler) legacyQueryToken(c *gin.Context, route string) (string, bool) {
+	token := c.Query("token")
+	if token == "" {
+		return "", true
+	}
+	if !h.WSLegacyQueryAuth {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "token in query is not supported, send auth as the first frame",
+			"code":  ws.ErrCodeAuthRequired,
+		})
+		return "", false
+	}
+	logger.Get().Warn("ws: устаревший ?token= в URL, клиент должен перейти на кадр auth", "route", route)
+	return token, true
+}
+
+// wsAuthTimeout ожидание кадров auth и join
+func (h *Handler) wsAuthTimeout() time.Duration {
+	if h.WSAuthTimeout <= 0 {
+		return ws.DefaultAuthTimeout
+	}
+	return h.WSAuthTimeout
+}
+
+// wsAuthenticate: auth -> auth_ok; при отказе соединение закрыто, ok=false
+func (h *Handler) wsAuthenticate(conn *websocket.Conn) (int64, bool) {
+	timeout := h.wsAuthTimeout()
+	token, herr := ws.ReadAuth(conn, timeout)
+	if herr != nil {
+		log.Printf("WS: handshake rejected before auth: %v", herr)
+		ws.RejectHandshake(conn, herr)
+		return 0, false
+	}
+	userID, err := service.ParseJWT(token)
+	if err != nil {
+		ws.RejectHandshake(conn, &ws.HandshakeError{Code: ws.ErrCodeAuthFailed, Message: "invalid token", CloseCode: ws.CloseAuthFailed})
+		return 0, false
+	}
+
+	if err := ws.SendAuthOK(conn, userID, time.Now().Add(timeout)); err != nil {
+		log.Printf("WS: user=%d auth_ok write failed: %v", userID, err)
+		_ = conn.Close()
+		return 0, false
+	}
+	return userID, true
+}
+
+// wsHandshake: auth -> auth_ok -> join -> резерв ставки -> игровой клиент
+func (h *Handler) wsHandshake(hub *ws.Hub, conn *websocket.Conn) {
+	userID, ok := h.wsAuthenticate(conn)
+	if !ok {
+		return
+	}
+
+	p, herr := ws.ReadJoin(conn, h.wsAuthTimeout())
+	if herr != nil {
+		log.Printf("WS: user=%d join rejected: %v", userID, herr)
+		ws.RejectHandshake(conn, herr)
+		return
+	}
+
+	join := &wsJoin{
+		GameType:        p.Game,
+		BetAmount:       p.Bet,
+		Currency:        p.Currency,
+		BestOf:          p.BestOf,
+		ChallengeCode:   p.Challenge,
+		TournamentMatch: p.TournamentMatch,
+		ProtocolVersion: p.ProtocolVersion,
+	}
+	if join.ProtocolVersion == 0 {
+		join.ProtocolVersion = ws.ProtocolVersion
+	}
+	if p.Mines != nil {
+		join.Mines = game.MinesOptions{
+			Cells:       p.Mines.Cells,
+			Mines:       p.Mines.Mines,
+			TurnTimeout: time.Duration(p.Mines.TurnSeconds) * time.Second,
 		}
-		currency := c.Query("currency")
-		if currency == "" {
-			currency = "gems" // валюта по умолчанию
+	}
+
+	if hub.Draining() {
+		ws.RejectHandshake(conn, ws.JoinRejected(http.StatusServiceUnavailable, "server is draining"))
+		return
+	}
+	if jerr := h.prepareJoin(hub, userID, join); jerr != nil {
+		ws.RejectHandshake(conn, ws.JoinRejected(jerr.Status, jerr.Message))
+		return
+	}
+	if jerr := h.reserveJoinBet(userID, join); jerr != nil {
+		ws.RejectHandshake(conn, ws.JoinRejected(jerr.Status, jerr.Message))
+		return
+	}
+
+	h.startWSClient(hub, conn, userID, join)
+}
+
+// устаревший путь (WSLegacyQueryAuth): токен и параметры игры в query, ставка списывается до upgrade
+func (h *Handler) wsQueryJoin(c *gin.Context, hub *ws.Hub, token string) {
+	userID, err := service.ParseJWT(token)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+
+	// версия протокола (без параметра - устаревший v1)
+	protocolVersion, ok := parseProtocolVersion(c)
+	if !ok {
+		return
+	}
+
+	join := &wsJoin{
+		GameType:        c.Query("game"),
+		Currency:        c.Query("currency"),
+		ChallengeCode:   c.Query("challenge"),
+		ProtocolVersion: protocolVersion,
+	}
+
+	// получить сумму ставки из запроса
+	if betStr := c.Query("bet"); betStr != "" {
+		if parsed, err := strconv.ParseInt(betStr, 10, 64); err == nil {
+			join.BetAmount = parsed
 		}
+	}
 
-		// списываем ставку с баланса пользователя при входе в игру
-		if betAmount > 0 {
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			defer cancel()
+	// длина серии: 1, 3 или 5 партий (банк выплачивается по итогам серии)
+	if v := c.Query("best_of"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "best_of must be 1, 3 or 5"})
+			return
+		}
+		join.BestOf = n
+	}
 
-			userRepo := repository.NewUserRepository(h.DB)
-			user, err := userRepo.GetByID(ctx, userID)
-			if err != nil || user == nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "user not found"})
+	// параметры поля мин: размер, число мин, таймер хода (входят в ключ очереди)
+	for _, p := range []struct {
+		name string
+		dst  *int
+	}{
+		{"cells", &join.Mines.Cells},
+		{"mines", &join.Mines.Mines},
+	} {
+		if v := c.Query(p.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + p.name})
 				return
 			}
+			*p.dst = n
+		}
+	}
+	if v := c.Query("turn"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid turn"})
+			return
+		}
+		join.Mines.TurnTimeout = time.Duration(n) * time.Second
+	}
 
-			// проверяем баланс
-			var balance int64
-			if currency == string(domain.CurrencyCoins) {
-				balance = user.Coins
-			} else {
-				balance = user.Gems
+	// матч турнира
+	if v := c.Query("tournament_match"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid tournament_match"})
+			return
+		}
+		join.TournamentMatch = id
+	}
+
+	if jerr := h.prepareJoin(hub, userID, join); jerr != nil {
+		c.JSON(jerr.Status, gin.H{"error": jerr.Message})
+		return
+	}
+	if jerr := h.reserveJoinBet(userID, join); jerr != nil {
+		c.JSON(jerr.Status, gin.H{"error": jerr.Message})
+		return
+	}
+
+	// обновление вебсокета
+	conn, err := wsUpgrader().Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Println("ws upgrade error:", err)
+		// возвращаем ставку если upgrade не удался
+		h.refundJoinBet(userID, join)
+		return
+	}
+
+	h.startWSClient(hub, conn, userID, join)
+}
+
+// prepareJoin проверяет параметры входа: серия, поле мин, вызов, турнирный матч, доступность игры
+func (h *Handler) prepareJoin(hub *ws.Hub, userID int64, j *wsJoin) *wsJoinError {
+	// получаем тип игры (по умолчанию rps)
+	if j.GameType == "" {
+		j.GameType = string(game.TypeRPS)
+	}
+	if j.Currency == "" {
+		j.Currency = "gems" // валюта по умолчанию
+	}
+
+	if j.BestOf == 0 {
+		j.BestOf = 1
+	}
+	if !ws.ValidBestOf(j.BestOf) {
+		return &wsJoinError{http.StatusBadRequest, "best_of must be 1, 3 or 5"}
+	}
+
+	if j.GameType == string(game.TypeMines) {
+		// таймер хода по умолчанию - из конфигурации уровня ставки
+		if j.Mines.TurnTimeout == 0 {
+			j.Mines.TurnTimeout = hub.GameRules(game.TypeMines, j.BetAmount).TurnTimeout
+		}
+		j.Mines = j.Mines.WithDefaults()
+		if err := j.Mines.Validate(); err != nil {
+			return &wsJoinError{http.StatusBadRequest, err.Error()}
+		}
+	} else {
+		j.Mines = game.MinesOptions{}
+	}
+
+	// приватный вызов: игра, ставка и валюта берутся из вызова, а не из запроса
+	if j.ChallengeCode != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		ch, err := repository.NewChallengeRepository(h.DB).GetByCode(ctx, j.ChallengeCode)
+		cancel()
+		if err != nil {
+			return &wsJoinError{http.StatusInternalServerError, "db error"}
+		}
+		if ch == nil {
+			return &wsJoinError{http.StatusNotFound, "challenge not found"}
+		}
+		if !ch.IsOpen() {
+			return &wsJoinError{http.StatusGone, "challenge is no longer available"}
+		}
+		if !ch.CanJoin(userID) {
+			return &wsJoinError{http.StatusForbidden, "challenge is for another user"}
+		}
+		j.challenge = ch
+		j.GameType = string(ch.GameType)
+		j.BetAmount = ch.BetAmount
+		j.Currency = ch.Currency
+		j.Mines = game.MinesOptions{}
+	}
+
+	// матч турнира: комната создана движком турнира, ставки нет (взнос уже в призовом фонде)
+	if j.TournamentMatch != 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		m, err := repository.NewTournamentRepository(h.DB).GetMatch(ctx, j.TournamentMatch)
+		cancel()
+		if err != nil {
+			return &wsJoinError{http.StatusInternalServerError, "db error"}
+		}
+		if m == nil || !m.HasPlayer(userID) {
+			return &wsJoinError{http.StatusNotFound, "tournament match not found"}
+		}
+		if m.Status != domain.TournamentMatchPlaying {
+			return &wsJoinError{http.StatusGone, "tournament match is not playing"}
+		}
+		j.BetAmount = 0
+		j.Mines = game.MinesOptions{}
+	}
+
+	// публичная очередь: игра может быть выключена для уровня ставки в pvp_game_configs
+	if j.challenge == nil && j.TournamentMatch == 0 {
+		if cfg := hub.GameConfig(game.GameType(j.GameType), j.BetAmount); cfg != nil && !cfg.Enabled {
+			return &wsJoinError{http.StatusForbidden, "game is disabled for this stake"}
+		}
+	}
+
+	// ставка создателя вызова уже списана при создании
+	j.reserveBet = j.BetAmount > 0 && (j.challenge == nil || j.challenge.CreatorID != userID)
+	return nil
+}
+
+// reserveJoinBet списывает ставку с баланса пользователя при входе в игру
+func (h *Handler) reserveJoinBet(userID int64, j *wsJoin) *wsJoinError {
+	if !j.reserveBet {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	userRepo := repository.NewUserRepository(h.DB)
+	user, err := userRepo.GetByID(ctx, userID)
+	if err != nil || user == nil {
+		return &wsJoinError{http.StatusBadRequest, "user not found"}
+	}
+
+	// проверяем баланс
+	var balance int64
+	if j.Currency == string(domain.CurrencyCoins) {
+		balance = user.Coins
+	} else {
+		balance = user.Gems
+	}
+
+	if balance < j.BetAmount {
+		return &wsJoinError{http.StatusBadRequest, "insufficient balance"}
+	}
+
+	// списываем ставку
+	if j.Currency == string(domain.CurrencyCoins) {
+		if _, err := userRepo.UpdateCoins(ctx, userID, -j.BetAmount); err != nil {
+			log.Printf("WS: failed to deduct coins: %v", err)
+			return &wsJoinError{http.StatusInternalServerError, "failed to reserve bet"}
+		}
+		log.Printf("WS: deducted %d coins from user=%d", j.BetAmount, userID)
+	} else {
+		if _, err := userRepo.UpdateGems(ctx, userID, -j.BetAmount); err != nil {
+			log.Printf("WS: failed to deduct gems: %v", err)
+			return &wsJoinError{http.StatusInternalServerError, "failed to reserve bet"}
+		}
+		log.Printf("WS: deducted %d gems from user=%d", j.BetAmount, userID)
+	}
+	return nil
+}
+
+// refundJoinBet возвращает ставку, если игровой клиент так и не запустился
+func (h *Handler) refundJoinBet(userID int64, j *wsJoin) {
+	if !j.reserveBet {
+		return
+	}
+	ctx := context.Background()
+	userRepo := repository.NewUserRepository(h.DB)
+	if j.Currency == string(domain.CurrencyCoins) {
+		userRepo.UpdateCoins(ctx, userID, j.BetAmount)
+	} else {
+		userRepo.UpdateGems(ctx, userID, j.BetAmount)
+	}
+	log.Printf("WS: refunded %d %s to user=%d after upgrade failure", j.BetAmount, j.Currency, userID)
+}
+
+// создание клиента с типом игры, суммой ставки и валютой
+func (h *Handler) startWSClient(hub *ws.Hub, conn *websocket.Conn, userID int64, j *wsJoin) {
+	client := ws.NewClient(userID, conn, hub, j.GameType, j.BetAmount, j.Currency)
+	client.Challenge = j.challenge
+	client.TournamentMatchID = j.TournamentMatch
+	client.BestOf = j.BestOf
+	client.MinesOptions = j.Mines
+	client.ProtocolVersion = j.ProtocolVersion
+
+	go client.Run()
+}
+
+func wsUpgrader() *websocket.Upgrader {
+	allowedOrigin := os.Getenv("ALLOWED_ORIGIN")
+	return &websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool {
+			if allowedOrigin == "" {
+				return true
 			}
+			return r.Header.Get("Origin") == allowedOrigin
+		},
+	}
+}
 
-			if balance < betAmount {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "insufficient balance"})
+// parseProtocolVersion читает protocol_version из query; при неподдерживаемой версии отвечает 400
+func parseProtocolVersion(c *gin.Context) (int, bool) {
+	v := c.Query("protocol_version")
+	if v == "" {
+		return 0, true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || !ws.SupportedProtocolVersion(n) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "unsupported protocol version",
+			"code":  ws.ErrCodeUnsupportedVersion,
+			"min":   ws.MinProtocolVersion,
+			"max":   ws.ProtocolVersion,
+		})
+		return 0, false
+	}
+	return n, true
+}
+
+// JSON Schema WebSocket протокола (генерируется из типизированных payload)
+func (h *Handler) WSSchema(c *gin.Context) {
+	c.JSON(http.StatusOK, ws.ProtocolSchema())
+}
+
+// подключение зрителя к живой PvP комнате (только чтение).
+// Как и /ws: первым кадром auth с JWT; ?token=... только с WSLegacyQueryAuth
+func (h *Handler) WSSpectate(hub *ws.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roomID := c.Query("room_id")
+		if roomID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "room_id required"})
+			return
+		}
+
+		token, ok := h.legacyQueryToken(c, "/ws/spectate")
+		if !ok {
+			return
+		}
+		var userID int64
+		if token != "" {
+			id, err := service.ParseJWT(token)
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
 				return
 			}
+			userID = id
+		}
 
-			// списываем ставку
-			if currency == string(domain.CurrencyCoins) {
-				if _, err := userRepo.UpdateCoins(ctx, userID, -betAmount); err != nil {
-					log.Printf("WS: failed to deduct coins: %v", err)
-					c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reserve bet"})
-					return
-				}
-				log.Printf("WS: deducted %d coins from user=%d", betAmount, userID)
-			} else {
-				if _, err := userRepo.UpdateGems(ctx, userID, -betAmount); err != nil {
-					log.Printf("WS: failed to deduct gems: %v", err)
-					c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reserve bet"})
+		conn, err := wsUpgrader().Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			log.Println("ws spectate upgrade error:", err)
+			return
+		}
+
+		go func() {
+			if userID == 0 {
+				if userID, ok = h.wsAuthenticate(conn); !ok {
 					return
 				}
-				log.Printf("WS: deducted %d gems from user=%d", betAmount, userID)
 			}
-		}
+			spectator := ws.NewSpectator(userID, conn)
+			if err := spectator.Run(hub, roomID); err != nil {
+				log.Printf("WSSpectate: user=%d room=%s rejected: %v", userID, roomID, err)
+				_ = conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.ClosePolicyViolation, err.Error()),
+					time.Now().Add(time.Second))
+				_ = conn.Close()
+			}
+		}()
+	}
+}
 
-		allowedOrigin := os.Getenv("ALLOWED_ORIGIN")
-		upgrader := websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool {
-				if allowedOrigin == "" {
-					return true
-				}
-				return r.Header.Get("Origin") == allowedOrigin
-			},
+// подписка на статистику лобби (онлайн, очереди, идущие матчи).
+// Как и /ws: первым кадром auth с JWT; ?token=... только с WSLegacyQueryAuth
+func (h *Handler) WSLobby(hub *ws.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := h.legacyQueryToken(c, "/ws/lobby")
+		if !ok {
+			return
+		}
+		var userID int64
+		if token != "" {
+			id, err := service.ParseJWT(token)
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+				return
+			}
+			userID = id
 		}
 
-		// обновление вебсокета
-		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		conn, err := wsUpgrader().Upgrade(c.Writer, c.Request, nil)
 		if err != nil {
-			log.Println("ws upgrade error:", err)
-			// возвращаем ставку если upgrade не удался
-			if betAmount > 0 {
-				ctx := context.Background()
-				userRepo := repository.NewUserRepository(h.DB)
-				if currency == string(domain.CurrencyCoins) {
-					userRepo.UpdateCoins(ctx, userID, betAmount)
-				} else {
-					userRepo.UpdateGems(ctx, userID, betAmount)
-				}
-				log.Printf("WS: refunded %d %s to user=%d after upgrade failure", betAmount, currency, userID)
-			}
+			log.Println("ws lobby upgrade error:", err)
 			return
 		}
 
-		// создание клиента с типом игры,суммой ставки и валюты
-		client := ws.NewClient(userID, conn, hub, gameType, betAmount, currency)
+		go func() {
+			if userID == 0 {
+				if userID, ok = h.wsAuthenticate(conn); !ok {
+					return
+				}
+			}
+			ws.NewLobbySubscriber(userID, conn).Run(hub)
+		}()
+	}
+}
 
-		go client.Run()
+// статистика лобби: онлайн, ожидающие по очередям, идущие матчи по играм
+func (h *Handler) LobbyStats(hub *ws.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, hub.LobbyStats())
+	}
+}
+
+// список идущих PvP матчей со ставками для зрителей
+func (h *Handler) LiveRooms(hub *ws.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rooms := hub.LiveRooms()
+		c.JSON(http.StatusOK, gin.H{"rooms": rooms, "count": len(rooms)})
 	}
 }
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strconv"
@@ -9,7 +10,9 @@ import (
 	"telegram_webapp/internal/config"
 	"telegram_webapp/internal/http/handlers"
 	"telegram_webapp/internal/http/middleware"
+	"telegram_webapp/internal/logger"
 	"telegram_webapp/internal/repository"
+	"telegram_webapp/internal/service"
 	"telegram_webapp/internal/ws"
 
 	"github.com/gin-gonic/gin"
@@ -19,6 +22,9 @@ import (
 // глобал ссылка на TON handler для установки  callback
 var globalTonHandler *handlers.TonHandler
 
+// глобал ссылка на PvP хаб для остановки при shutdown
+var globalHub *ws.Hub
+
 func RegisterRoutes(r *gin.Engine, db *pgxpool.Pool, botToken string, version string) {
 	RegisterRoutesWithConfig(r, db, botToken, version, nil)
 }
@@ -33,12 +39,23 @@ func SetWithdrawalNotifyCallback(callback handlers.WithdrawalNotifyFunc) {
 	}
 }
 
+// Плавная остановка PvP: закрывает матчмейкинг и дает живым матчам доиграть до дедлайна ctx
+func DrainPvP(ctx context.Context) {
+	if globalHub == nil {
+		return
+	}
+	res := globalHub.Drain(ctx)
+	logger.Info("DrainPvP: хаб остановлен", "cancelled", res.Cancelled, "finished", res.Finished, "aborted", res.Aborted)
+}
+
 func RegisterRoutesWithConfig(r *gin.Engine, db *pgxpool.Pool, botToken string, version string, cfg *config.Config) {
 	var h *handlers.Handler
 	if cfg != nil {
 		h = handlers.NewHandlerWithConfig(db, botToken, handlers.HandlerConfig{
-			MinBet: cfg.MinBet,
-			MaxBet: cfg.MaxBet,
+			MinBet:            cfg.MinBet,
+			MaxBet:            cfg.MaxBet,
+			WSAuthTimeout:     time.Duration(cfg.WSAuthTimeout) * time.Second,
+			WSLegacyQueryAuth: cfg.WSLegacyQueryAuth,
 		})
 	} else {
 		h = handlers.NewHandler(db, botToken)
@@ -85,23 +102,92 @@ func RegisterRoutesWithConfig(r *gin.Engine, db *pgxpool.Pool, botToken string,
 		gameRateWindow = time.Duration(cfg.GameRateWindow) * time.Second
 	}
 
+	// WebSocket для PvP игр
+	gameRepo := repository.NewGameRepository(db)
+	gameHistoryRepo := repository.NewGameHistoryRepository(db)
+	userRepo := repository.NewUserRepository(db)
+	hub := ws.NewHubWithUserRepo(gameRepo, gameHistoryRepo, userRepo)
+	hub.ChallengeRepo = repository.NewChallengeRepository(db)
+	hub.ChatRepo = repository.NewChatRepository(db)
+	replayRepo := repository.NewReplayRepository(db)
+	hub.ReplayRepo = replayRepo
+	if cfg != nil && cfg.PvPBotEnabled {
+		bot := ws.NewBotPolicy(time.Duration(cfg.PvPBotDelay)*time.Second, cfg.PvPBotDailyLimit, gameHistoryRepo)
+		for _, stake := range cfg.PvPBotStakes {
+			bot.AllowStake(stake.Currency, stake.Amount)
+		}
+		hub.Bot = bot
+	}
+	if cfg != nil {
+		hub.MaxSpectators = cfg.PvPMaxSpectators
+	}
+	// таймеры, рейк и лимит раундов по игре и уровню ставки (pvp_game_configs)
+	hub.Configs = ws.NewGameConfigs(repository.NewPvPConfigRepository(db))
+	hub.Configs.StartRefresh(ws.DefaultGameConfigRefresh)
+	// рейк пишется на счет платформы в transactions
+	hub.RakeRepo = repository.NewRakeRepository(db)
+	if cfg != nil {
+		for _, c := range cfg.PvPRakeCaps {
+			hub.SetRakeCap(c.Currency, c.Min, c.Max)
+		}
+	}
+	hub.StartCleanup()
+	hub.StartLobby(ws.DefaultLobbyInterval)
+	healthHandler.SetDrainCheck(hub.Draining)
+	globalHub = hub
+	r.GET("/ws", h.WS(hub))
+	r.GET("/ws/spectate", h.WSSpectate(hub))
+	r.GET("/ws/lobby", h.WSLobby(hub))
+	r.GET("/ws/schema", h.WSSchema)
+
+	// приватные PvP вызовы (один воркер истечения на оба набора роутов)
+	challengeService := service.NewChallengeService(db)
+	challengeService.StartExpiryWorker(30 * time.Second)
+	botUsername, webAppShortName := deepLinkNames()
+	challengeHandler := handlers.NewChallengeHandler(challengeService, botUsername, webAppShortName)
+	chatReportHandler := handlers.NewChatReportHandler(hub.ChatRepo)
+
+	// турниры: воркер закрывает регистрацию и восстанавливает матчи после рестарта
+	tournamentService := service.NewTournamentService(db)
+	tournamentService.SetRoomStarter(hub)
+	tournamentService.StartWorker(15 * time.Second)
+	tournamentHandler := handlers.NewTournamentHandler(tournamentService)
+
+	// запрет повторных матчей пары и пар под подозрением в сговоре
+	if cfg != nil && (cfg.PvPRematchBlockMinutes > 0 || cfg.PvPBlockFlaggedPairs) {
+		hub.Pairs = ws.NewPairPolicy(time.Duration(cfg.PvPRematchBlockMinutes) * time.Minute)
+	}
+
+	// детектор сговора: очередь подозрений для админ-бота
+	if cfg != nil && cfg.PvPCollusionScanMinutes > 0 {
+		collusionService := service.NewCollusionService(db, service.CollusionConfig{
+			Window:   time.Duration(cfg.PvPCollusionWindowHours) * time.Hour,
+			MinGames: cfg.PvPCollusionMinGames,
+		})
+		var onBlocked func([][2]int64)
+		if cfg.PvPBlockFlaggedPairs {
+			onBlocked = hub.Pairs.SetBlocked
+		}
+		collusionService.StartWorker(time.Duration(cfg.PvPCollusionScanMinutes)*time.Minute, onBlocked)
+	}
+
+	var adminTgIDs []int64
+	if cfg != nil {
+		adminTgIDs = cfg.AdminTelegramIDs
+	}
+	replayHandler := handlers.NewReplayHandler(replayRepo, userRepo, adminTgIDs)
+
 	// API v1 routes
 	v1 := r.Group("/api/v1")
 	v1.Use(middleware.RedisRateLimit(apiRateLimit, apiRateWindow))
 	registerAPIRoutes(v1, h, authRateLimit, authRateWindow, gameRateLimit, gameRateWindow)
+	registerPvPRoutes(v1, h, hub, challengeHandler, chatReportHandler, tournamentHandler, replayHandler)
 
 	// Legacy /api routes (deprecated, kept for backward compatibility)
 	api := r.Group("/api")
 	api.Use(middleware.RedisRateLimit(apiRateLimit, apiRateWindow))
 	registerAPIRoutes(api, h, authRateLimit, authRateWindow, gameRateLimit, gameRateWindow)
-
-	// WebSocket для PvP игр
-	gameRepo := repository.NewGameRepository(db)
-	gameHistoryRepo := repository.NewGameHistoryRepository(db)
-	userRepo := repository.NewUserRepository(db)
-	hub := ws.NewHubWithUserRepo(gameRepo, gameHistoryRepo, userRepo)
-	hub.StartCleanup()
-	r.GET("/ws", h.WS(hub))
+	registerPvPRoutes(api, h, hub, challengeHandler, chatReportHandler, tournamentHandler, replayHandler)
 
 	// Frontend static files
 	r.StaticFS("/assets", gin.Dir("../frontend", false))
@@ -168,14 +254,7 @@ func registerAPIRoutes(api *gin.RouterGroup, h *handlers.Handler, authRateLimit
 
 	// система рефок
 	referralRepo := repository.NewReferralRepository(h.DB)
-	botUsername := os.Getenv("BOT_USERNAME")
-	if botUsername == "" {
-		botUsername = "hard_mine_playbot"
-	}
-	webAppShortName := os.Getenv("WEBAPP_SHORT_NAME")
-	if webAppShortName == "" {
-		webAppShortName = "app"
-	}
+	botUsername, webAppShortName := deepLinkNames()
 	referralHandler := handlers.NewReferralHandler(referralRepo, botUsername, webAppShortName)
 	referral := api.Group("/referral")
 	referral.Use(middleware.JWT())
@@ -209,6 +288,7 @@ func registerAPIRoutes(api *gin.RouterGroup, h *handlers.Handler, authRateLimit
 		// Wallet "обработка"
 		ton.GET("/config", tonHandler.GetTonConfig)
 		ton.GET("/wallet", middleware.JWT(), tonHandler.GetWallet)
+		ton.POST("/proof/payload", middleware.JWT(), tonHandler.GetProofPayload)
 		ton.POST("/wallet/connect", middleware.JWT(), tonHandler.ConnectWallet)
 		ton.DELETE("/wallet", middleware.JWT(), tonHandler.DisconnectWallet)
 
@@ -228,3 +308,39 @@ func registerAPIRoutes(api *gin.RouterGroup, h *handlers.Handler, authRateLimit
 		ton.POST("/withdraw/cancel", middleware.JWT(), tonHandler.CancelWithdrawal)
 	}
 }
+
+// PvP: приватные вызовы по deep link, список матчей для зрителей, жалобы на чат, повторы и турниры
+func registerPvPRoutes(api *gin.RouterGroup, h *handlers.Handler, hub *ws.Hub, challengeHandler *handlers.ChallengeHandler, chatReportHandler *handlers.ChatReportHandler, tournamentHandler *handlers.TournamentHandler, replayHandler *handlers.ReplayHandler) {
+	// статистика лобби публичная: в ней нет данных пользователей
+	api.GET("/lobby/stats", h.LobbyStats(hub))
+
+	pvp := api.Group("/pvp")
+	pvp.Use(middleware.JWT())
+	{
+		pvp.POST("/challenges", challengeHandler.CreateChallenge)
+		pvp.GET("/challenges/:code", challengeHandler.GetChallenge)
+		pvp.DELETE("/challenges/:code", challengeHandler.CancelChallenge)
+		pvp.GET("/rooms/live", h.LiveRooms(hub))
+		pvp.POST("/chat/reports", chatReportHandler.CreateReport)
+		pvp.GET("/matches/:room_id/replay", replayHandler.GetReplay)
+
+		pvp.GET("/tournaments", tournamentHandler.ListTournaments)
+		pvp.GET("/tournaments/:id", tournamentHandler.GetTournament)
+		pvp.GET("/tournaments/:id/bracket", tournamentHandler.GetBracket)
+		pvp.POST("/tournaments/:id/register", tournamentHandler.Register)
+		pvp.DELETE("/tournaments/:id/register", tournamentHandler.Unregister)
+	}
+}
+
+// имя бота и short name веб аппки для ссылок t.me/<bot>/<app>
+func deepLinkNames() (botUsername, webAppShortName string) {
+	botUsername = os.Getenv("BOT_USERNAME")
+	if botUsername == "" {
+		botUsername = "hard_mine_playbot"
+	}
+	webAppShortName = os.Getenv("WEBAPP_SHORT_NAME")
+	if webAppShortName == "" {
+		webAppShortName = "app"
+	}
+	return botUsername, webAppShortName
+}
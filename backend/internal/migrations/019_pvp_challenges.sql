@@ -0,0 +1,28 @@
+-- приватные PvP вызовы (игра с другом по ссылке t.me/<bot>/<app>?startapp=ch_<code>)
+-- ставка создателя списывается при создании и хранится в вызове до старта комнаты
+
+CREATE TABLE IF NOT EXISTS pvp_challenges (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(32) UNIQUE NOT NULL,
+    creator_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+    -- приглашенный пользователь (NULL = любой, у кого есть ссылка)
+    invitee_id INT REFERENCES users(id) ON DELETE SET NULL,
+
+    game_type VARCHAR(20) NOT NULL,
+    bet_amount BIGINT NOT NULL DEFAULT 0,
+    currency VARCHAR(10) NOT NULL DEFAULT 'gems',
+
+    -- pending -> started (оба в комнате) | expired (возврат создателю) | cancelled (отменен создателем)
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'started', 'expired', 'cancelled')),
+
+    -- кто принял вызов
+    opponent_id INT REFERENCES users(id) ON DELETE SET NULL,
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    started_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_pvp_challenges_creator ON pvp_challenges(creator_id);
+CREATE INDEX IF NOT EXISTS idx_pvp_challenges_pending ON pvp_challenges(expires_at) WHERE status = 'pending';
@@ -0,0 +1,7 @@
+-- отметка игр против бота-соперника (бот занимает место, если человек не нашелся)
+ALTER TABLE game_history ADD COLUMN IF NOT EXISTS vs_bot BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- для дневного лимита игр с ботом
+CREATE INDEX IF NOT EXISTS idx_game_history_vs_bot ON game_history(user_id, created_at) WHERE vs_bot;
+
+COMMENT ON COLUMN game_history.vs_bot IS 'PvP игра, в которой соперником был бот платформы';
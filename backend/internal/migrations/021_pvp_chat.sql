@@ -0,0 +1,43 @@
+-- быстрый чат и эмоции в PvP комнатах
+-- хранятся все сообщения (в том числе отфильтрованные и заглушенные) для разбора жалоб
+
+CREATE TABLE IF NOT EXISTS pvp_chat_messages (
+    id BIGSERIAL PRIMARY KEY,
+    room_id VARCHAR(64) NOT NULL,
+    sender_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    recipient_id INT REFERENCES users(id) ON DELETE SET NULL,
+
+    -- emote: код из фиксированного набора, text: короткий текст (исходный, до фильтра)
+    kind VARCHAR(10) NOT NULL CHECK (kind IN ('emote', 'text')),
+    content VARCHAR(255) NOT NULL,
+
+    -- в тексте были замаскированы слова
+    filtered BOOLEAN NOT NULL DEFAULT FALSE,
+    -- доставлено сопернику (false, если соперник заглушил отправителя)
+    delivered BOOLEAN NOT NULL DEFAULT TRUE,
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_pvp_chat_messages_room ON pvp_chat_messages(room_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_pvp_chat_messages_sender ON pvp_chat_messages(sender_id, created_at);
+
+-- жалобы игроков на сообщения соперника
+CREATE TABLE IF NOT EXISTS pvp_chat_reports (
+    id SERIAL PRIMARY KEY,
+    room_id VARCHAR(64) NOT NULL,
+    reporter_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reported_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+
+    -- open -> reviewed (админ разобрал)
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'reviewed')),
+    reviewed_by BIGINT, -- tg id админа
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_pvp_chat_reports_open ON pvp_chat_reports(created_at) WHERE status = 'open';
+-- одна жалоба на соперника в комнате
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pvp_chat_reports_unique ON pvp_chat_reports(room_id, reporter_id, reported_id);
@@ -0,0 +1,72 @@
+-- турниры на выбывание (single elimination) для PvP игр
+-- взнос списывается при регистрации и попадает в призовой фонд
+
+CREATE TABLE IF NOT EXISTS tournaments (
+    id SERIAL PRIMARY KEY,
+    title VARCHAR(100) NOT NULL DEFAULT '',
+    game_type VARCHAR(20) NOT NULL CHECK (game_type IN ('rps', 'mines')),
+    currency VARCHAR(10) NOT NULL DEFAULT 'gems',
+    entry_fee BIGINT NOT NULL DEFAULT 0 CHECK (entry_fee >= 0),
+    max_players INT NOT NULL CHECK (max_players BETWEEN 2 AND 256),
+    best_of INT NOT NULL DEFAULT 1,
+
+    -- доли призового фонда в процентах по местам: 1, 2, 3-4, 5-8, ...
+    prize_percents INT[] NOT NULL DEFAULT '{100}',
+    prize_pool BIGINT NOT NULL DEFAULT 0,
+
+    -- сколько секунд игроки ждут друг друга в матче, после - техническое поражение
+    join_timeout_sec INT NOT NULL DEFAULT 120,
+
+    -- registration -> running -> finished | cancelled (взносы возвращены)
+    status VARCHAR(20) NOT NULL DEFAULT 'registration' CHECK (status IN ('registration', 'running', 'finished', 'cancelled')),
+    rounds INT NOT NULL DEFAULT 0,
+
+    created_by BIGINT, -- tg id админа
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_tournaments_status ON tournaments(status, starts_at);
+
+CREATE TABLE IF NOT EXISTS tournament_entries (
+    tournament_id INT NOT NULL REFERENCES tournaments(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    seed INT,
+
+    -- registered -> eliminated | refunded
+    status VARCHAR(20) NOT NULL DEFAULT 'registered' CHECK (status IN ('registered', 'eliminated', 'refunded')),
+    place INT,
+    prize BIGINT NOT NULL DEFAULT 0,
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (tournament_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_tournament_entries_user ON tournament_entries(user_id);
+
+CREATE TABLE IF NOT EXISTS tournament_matches (
+    id SERIAL PRIMARY KEY,
+    tournament_id INT NOT NULL REFERENCES tournaments(id) ON DELETE CASCADE,
+    round INT NOT NULL,
+    position INT NOT NULL,
+
+    player1_id INT REFERENCES users(id) ON DELETE SET NULL,
+    player2_id INT REFERENCES users(id) ON DELETE SET NULL,
+    winner_id INT REFERENCES users(id) ON DELETE SET NULL,
+
+    -- pending (ждет победителей предыдущего раунда) -> playing -> finished | void (никто не пришел)
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'playing', 'finished', 'void')),
+    -- win, opponent_left, no_show, walkover, tiebreak
+    reason VARCHAR(20),
+
+    room_id VARCHAR(64),
+    deadline TIMESTAMP WITH TIME ZONE,
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE,
+
+    UNIQUE (tournament_id, round, position)
+);
+
+CREATE INDEX IF NOT EXISTS idx_tournament_matches_playing ON tournament_matches(tournament_id) WHERE status = 'playing';
@@ -0,0 +1,32 @@
+-- полная запись PvP матчей для повторов и разбора споров
+-- заголовок матча + упорядоченный журнал событий комнаты
+
+CREATE TABLE IF NOT EXISTS pvp_match_replays (
+    room_id VARCHAR(64) PRIMARY KEY,
+    game_type VARCHAR(20) NOT NULL,
+    player1_id BIGINT NOT NULL,
+    player2_id BIGINT NOT NULL, -- -1 для бота
+    bet_amount BIGINT NOT NULL DEFAULT 0,
+    currency VARCHAR(10) NOT NULL DEFAULT 'gems',
+    best_of INT NOT NULL DEFAULT 1,
+    vs_bot BOOLEAN NOT NULL DEFAULT FALSE,
+    tournament_id BIGINT,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    finished_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_pvp_match_replays_player1 ON pvp_match_replays(player1_id, started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_pvp_match_replays_player2 ON pvp_match_replays(player2_id, started_at DESC);
+
+-- события комнаты: join, setup (расстановка мин), round_start, move, timeout,
+-- round_result, game_result, next_game, disconnect, cancelled, aborted
+CREATE TABLE IF NOT EXISTS pvp_match_events (
+    room_id VARCHAR(64) NOT NULL REFERENCES pvp_match_replays(room_id) ON DELETE CASCADE,
+    seq INT NOT NULL,
+    game_no INT NOT NULL DEFAULT 1, -- номер партии в серии
+    event_type VARCHAR(20) NOT NULL,
+    user_id BIGINT,
+    data JSONB,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (room_id, seq)
+);
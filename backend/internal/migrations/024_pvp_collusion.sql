@@ -0,0 +1,39 @@
+-- подозрения на сговор в PvP: пары игроков, которые часто играют друг с другом
+-- и переливают ставки; заполняется детектором, разбирается в админ-боте
+
+CREATE TABLE IF NOT EXISTS pvp_collusion_flags (
+    id SERIAL PRIMARY KEY,
+    -- пара упорядочена: user_a < user_b
+    user_a INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_b INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    CHECK (user_a < user_b),
+
+    -- сумма весов сработавших признаков и сами признаки
+    score INT NOT NULL,
+    signals TEXT[] NOT NULL DEFAULT '{}',
+
+    -- статистика пары за окно анализа
+    games INT NOT NULL DEFAULT 0,
+    wins_a INT NOT NULL DEFAULT 0,
+    wins_b INT NOT NULL DEFAULT 0,
+    volume BIGINT NOT NULL DEFAULT 0, -- сумма ставок
+    net_a BIGINT NOT NULL DEFAULT 0,  -- чистый выигрыш user_a у user_b
+    first_game_at TIMESTAMP WITH TIME ZONE,
+    last_game_at TIMESTAMP WITH TIME ZONE,
+
+    -- open -> dismissed (ложное срабатывание) | confirmed (сговор подтвержден)
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'dismissed', 'confirmed')),
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    reviewed_by BIGINT, -- tg id админа
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- одно открытое подозрение на пару (повторный анализ обновляет его)
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pvp_collusion_flags_open ON pvp_collusion_flags(user_a, user_b) WHERE status = 'open';
+CREATE INDEX IF NOT EXISTS idx_pvp_collusion_flags_pair ON pvp_collusion_flags(user_a, user_b, reviewed_at);
+
+-- поиск пар в истории PvP
+CREATE INDEX IF NOT EXISTS idx_game_history_pvp_pair ON game_history(user_id, opponent_id, created_at) WHERE mode = 'pvp' AND opponent_id IS NOT NULL;
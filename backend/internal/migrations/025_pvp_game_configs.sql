@@ -0,0 +1,28 @@
+-- параметры PvP игр по типу игры и уровню ставки: таймеры, рейк, лимит раундов
+-- комната берет строку с наибольшим min_stake <= ставки; правится в админ-боте
+
+CREATE TABLE IF NOT EXISTS pvp_game_configs (
+    id SERIAL PRIMARY KEY,
+    game_type VARCHAR(20) NOT NULL,
+    -- нижняя граница уровня ставки (0 - базовые параметры игры)
+    min_stake BIGINT NOT NULL DEFAULT 0 CHECK (min_stake >= 0),
+
+    setup_timeout_sec INT NOT NULL DEFAULT 10 CHECK (setup_timeout_sec BETWEEN 5 AND 120),
+    turn_timeout_sec INT NOT NULL DEFAULT 15 CHECK (turn_timeout_sec BETWEEN 5 AND 60),
+    -- доля банка, которую забирает платформа с выигрыша
+    rake_percent NUMERIC(5, 2) NOT NULL DEFAULT 0 CHECK (rake_percent >= 0 AND rake_percent < 50),
+    -- раундов вничью до ничьей партии
+    max_rounds INT NOT NULL DEFAULT 5 CHECK (max_rounds BETWEEN 1 AND 20),
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+
+    updated_by BIGINT, -- tg id админа
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    UNIQUE (game_type, min_stake)
+);
+
+-- значения, которые раньше были зашиты в код
+INSERT INTO pvp_game_configs (game_type, min_stake, setup_timeout_sec, turn_timeout_sec, rake_percent, max_rounds)
+VALUES ('rps', 0, 10, 20, 0, 5), ('mines', 0, 10, 15, 0, 5)
+ON CONFLICT (game_type, min_stake) DO NOTHING;
@@ -0,0 +1,10 @@
+-- рейк платформы с PvP банков: отдельная строка transactions (type = 'pvp_rake')
+-- на системный счет платформы; баланс счета не пополняется, выручка считается по transactions
+
+-- системный пользователь-счет платформы (tg_id 0 не выдается Telegram)
+INSERT INTO users (tg_id, username, first_name)
+VALUES (0, 'house', 'House')
+ON CONFLICT (tg_id) DO NOTHING;
+
+-- отчет о рейке по дням и играм
+CREATE INDEX IF NOT EXISTS idx_transactions_pvp_rake ON transactions (created_at) WHERE type = 'pvp_rake';
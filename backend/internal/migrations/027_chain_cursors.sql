@@ -0,0 +1,9 @@
+-- курсоры сканирования блокчейна: последнее обработанное логическое время (lt)
+-- deposit watcher листает транзакции кошелька платформы назад до курсора
+
+CREATE TABLE IF NOT EXISTS chain_cursors (
+    name VARCHAR(150) PRIMARY KEY, -- например deposits:<адрес кошелька>
+    last_lt BIGINT NOT NULL DEFAULT 0,
+    last_hash VARCHAR(100),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
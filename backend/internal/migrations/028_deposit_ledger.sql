@@ -0,0 +1,19 @@
+-- атомарное зачисление депозитов: депозит, коины, строка transactions и аудит пишутся одной транзакцией
+-- на каждый депозит ровно одна строка ton_deposit, по ней сверка находит депозиты без зачисления
+
+-- уникальность tx_hash (в 008 задана в CREATE TABLE, здесь - для баз, созданных без нее)
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint c
+        JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = ANY (c.conkey)
+        WHERE c.conrelid = 'deposits'::regclass AND c.contype = 'u'
+          AND array_length(c.conkey, 1) = 1 AND a.attname = 'tx_hash'
+    ) THEN
+        ALTER TABLE deposits ADD CONSTRAINT deposits_tx_hash_key UNIQUE (tx_hash);
+    END IF;
+END $$;
+
+-- одна запись зачисления на депозит
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_ton_deposit
+    ON transactions ((meta->>'deposit_id')) WHERE type = 'ton_deposit';
@@ -0,0 +1,9 @@
+-- депозиты и выводы в жетонах (USDT on TON)
+-- asset: TON или тикер жетона; amount_nano / ton_amount_nano хранят сумму в минимальных
+-- единицах актива (наноTON для TON, 10^-6 для USDT), exchange_rate - коинов за единицу актива
+
+ALTER TABLE deposits ADD COLUMN IF NOT EXISTS asset VARCHAR(20) NOT NULL DEFAULT 'TON';
+ALTER TABLE withdrawals ADD COLUMN IF NOT EXISTS asset VARCHAR(20) NOT NULL DEFAULT 'TON';
+
+CREATE INDEX IF NOT EXISTS idx_deposits_asset ON deposits(asset, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_withdrawals_asset ON withdrawals(asset, created_at DESC);
@@ -0,0 +1,26 @@
+-- коды депозитов вместо user id в memo: случайный код с контрольным символом
+-- постоянный код пользователя (expires_at NULL) или код под конкретное пополнение с истечением
+
+CREATE TABLE IF NOT EXISTS deposit_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code VARCHAR(20) NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITH TIME ZONE, -- NULL - постоянный код пользователя
+    used_at TIMESTAMP WITH TIME ZONE,    -- первый депозит по коду
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- один постоянный код на пользователя
+CREATE UNIQUE INDEX IF NOT EXISTS idx_deposit_codes_user_permanent
+    ON deposit_codes(user_id) WHERE expires_at IS NULL;
+
+-- очередь разбора: переводы с неизвестным memo сохраняются как депозиты без пользователя
+-- со статусом unmatched и причиной в unmatched_reason
+ALTER TABLE deposits ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE deposits ADD COLUMN IF NOT EXISTS unmatched_reason VARCHAR(50);
+
+ALTER TABLE deposits DROP CONSTRAINT IF EXISTS deposits_status_check;
+ALTER TABLE deposits ADD CONSTRAINT deposits_status_check
+    CHECK (status IN ('pending', 'confirmed', 'failed', 'expired', 'unmatched'));
+
+CREATE INDEX IF NOT EXISTS idx_deposits_unmatched ON deposits(created_at) WHERE status = 'unmatched';
@@ -0,0 +1,12 @@
+-- разбор несопоставленных депозитов: назначение пользователю или возврат отправителю
+-- refunding - возврат отправляется, refunded - отправлен (хэш и сумма за вычетом комиссии сети)
+
+ALTER TABLE deposits ADD COLUMN IF NOT EXISTS refund_tx_hash VARCHAR(100);
+ALTER TABLE deposits ADD COLUMN IF NOT EXISTS refund_amount BIGINT;
+
+ALTER TABLE deposits DROP CONSTRAINT IF EXISTS deposits_status_check;
+ALTER TABLE deposits ADD CONSTRAINT deposits_status_check
+    CHECK (status IN ('pending', 'confirmed', 'failed', 'expired', 'unmatched', 'refunding', 'refunded'));
+
+-- аудит действий над депозитами без пользователя (поступление в очередь, возврат)
+ALTER TABLE audit_logs ALTER COLUMN user_id DROP NOT NULL;
@@ -0,0 +1,12 @@
+-- nonce для TON Connect proof: выдается пользователю, принимается один раз и до истечения срока
+
+CREATE TABLE IF NOT EXISTS ton_proof_payloads (
+    id SERIAL PRIMARY KEY,
+    payload VARCHAR(64) NOT NULL UNIQUE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    consumed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ton_proof_payloads_expires ON ton_proof_payloads(expires_at);
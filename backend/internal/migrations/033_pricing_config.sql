@@ -0,0 +1,26 @@
+-- версии курса коинов и комиссии на вывод: действует строка с наибольшим effective_from <= момента операции
+-- новая версия добавляется из админ-бота, старые не меняются - депозиты и выводы ссылаются на них
+
+CREATE TABLE IF NOT EXISTS pricing_config (
+    id SERIAL PRIMARY KEY,
+    -- коинов за одну целую единицу актива
+    coins_per_ton INT NOT NULL CHECK (coins_per_ton > 0),
+    coins_per_usdt INT NOT NULL CHECK (coins_per_usdt > 0),
+    -- фиксированная комиссия платформы на вывод
+    withdraw_fee_coins BIGINT NOT NULL CHECK (withdraw_fee_coins >= 0),
+    max_withdraw_coins_per_day BIGINT NOT NULL CHECK (max_withdraw_coins_per_day > 0),
+    effective_from TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_by BIGINT, -- tg id админа
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_pricing_config_effective_from ON pricing_config(effective_from DESC);
+
+-- значения, которые раньше были зашиты в код
+INSERT INTO pricing_config (coins_per_ton, coins_per_usdt, withdraw_fee_coins, max_withdraw_coins_per_day, effective_from)
+SELECT 10, 2, 1, 1000, '1970-01-01T00:00:00Z'
+WHERE NOT EXISTS (SELECT 1 FROM pricing_config);
+
+-- версия, по которой посчитаны exchange_rate и комиссия операции
+ALTER TABLE deposits ADD COLUMN IF NOT EXISTS pricing_version INT REFERENCES pricing_config(id);
+ALTER TABLE withdrawals ADD COLUMN IF NOT EXISTS pricing_version INT REFERENCES pricing_config(id);
@@ -0,0 +1,5 @@
+-- журнал матча пишется в БД по ходу игры: заголовок создается с первыми событиями,
+-- finished_at ставится при закрытии комнаты (NULL - матч еще идет или прерван падением сервера)
+
+ALTER TABLE pvp_match_replays ALTER COLUMN finished_at DROP NOT NULL;
+ALTER TABLE pvp_match_replays ALTER COLUMN finished_at DROP DEFAULT;
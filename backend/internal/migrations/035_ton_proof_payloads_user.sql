@@ -0,0 +1,3 @@
+-- nonce пользователя: погашение и вытеснение самых старых при выдаче нового
+
+CREATE INDEX IF NOT EXISTS idx_ton_proof_payloads_user ON ton_proof_payloads(user_id, id);
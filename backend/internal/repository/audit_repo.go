@@ -29,7 +29,7 @@ func (r *AuditRepository) Create(ctx context.Context, log *domain.AuditLog) erro
 
 	_, err = r.db.Exec(ctx, `
 		INSERT INTO audit_logs (user_id, action, category, details, ip, user_agent)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		VALUES (NULLIF($1, 0), $2, $3, $4, $5, $6)
 	`, log.UserID, log.Action, log.Category, detailsJSON, log.IP, log.UserAgent)
 	return err
 }
@@ -43,7 +43,7 @@ func (r *AuditRepository) CreateWithTx(ctx context.Context, tx pgx.Tx, log *doma
 
 	_, err = tx.Exec(ctx, `
 		INSERT INTO audit_logs (user_id, action, category, details, ip, user_agent)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		VALUES (NULLIF($1, 0), $2, $3, $4, $5, $6)
 	`, log.UserID, log.Action, log.Category, detailsJSON, log.IP, log.UserAgent)
 	return err
 }
@@ -51,7 +51,7 @@ func (r *AuditRepository) CreateWithTx(ctx context.Context, tx pgx.Tx, log *doma
 // возвращает логи аудита для пользователя
 func (r *AuditRepository) GetByUserID(ctx context.Context, userID int64, limit int) ([]*domain.AuditLog, error) {
 	rows, err := r.db.Query(ctx, `
-		SELECT id, user_id, action, category, details, ip, user_agent, created_at
+		SELECT id, COALESCE(user_id, 0), action, category, details, ip, user_agent, created_at
 		FROM audit_logs
 		WHERE user_id = $1
 		ORDER BY created_at DESC
@@ -68,7 +68,7 @@ func (r *AuditRepository) GetByUserID(ctx context.Context, userID int64, limit i
 // возвращает логи аудита по категории
 func (r *AuditRepository) GetByCategory(ctx context.Context, category string, limit int) ([]*domain.AuditLog, error) {
 	rows, err := r.db.Query(ctx, `
-		SELECT id, user_id, action, category, details, ip, user_agent, created_at
+		SELECT id, COALESCE(user_id, 0), action, category, details, ip, user_agent, created_at
 		FROM audit_logs
 		WHERE category = $1
 		ORDER BY created_at DESC
@@ -85,7 +85,7 @@ func (r *AuditRepository) GetByCategory(ctx context.Context, category string, li
 // возвращает логи аудита по действию
 func (r *AuditRepository) GetByAction(ctx context.Context, action string, limit int) ([]*domain.AuditLog, error) {
 	rows, err := r.db.Query(ctx, `
-		SELECT id, user_id, action, category, details, ip, user_agent, created_at
+		SELECT id, COALESCE(user_id, 0), action, category, details, ip, user_agent, created_at
 		FROM audit_logs
 		WHERE action = $1
 		ORDER BY created_at DESC
@@ -102,7 +102,7 @@ func (r *AuditRepository) GetByAction(ctx context.Context, action string, limit
 //  возвращает самые последние логи аудита
 func (r *AuditRepository) GetRecent(ctx context.Context, limit int) ([]*domain.AuditLog, error) {
 	rows, err := r.db.Query(ctx, `
-		SELECT id, user_id, action, category, details, ip, user_agent, created_at
+		SELECT id, COALESCE(user_id, 0), action, category, details, ip, user_agent, created_at
 		FROM audit_logs
 		ORDER BY created_at DESC
 		LIMIT $1
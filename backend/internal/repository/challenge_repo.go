@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"telegram_webapp/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// операции с приватными PvP вызовами
+type ChallengeRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewChallengeRepository(db *pgxpool.Pool) *ChallengeRepository {
+	return &ChallengeRepository{db: db}
+}
+
+// генерирует случайный код вызова для deep link
+func GenerateChallengeCode() string {
+	bytes := make([]byte, 6)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
+
+const challengeColumns = `id, code, creator_id, invitee_id, game_type, bet_amount, currency,
+	status, opponent_id, created_at, expires_at, started_at`
+
+// создает вызов внутри транзакции (вместе со списанием ставки)
+func (r *ChallengeRepository) CreateWithTx(ctx context.Context, tx pgx.Tx, ch *domain.Challenge) error {
+	return tx.QueryRow(ctx, `
+		INSERT INTO pvp_challenges (code, creator_id, invitee_id, game_type, bet_amount, currency, status, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at
+	`, ch.Code, ch.CreatorID, ch.InviteeID, ch.GameType, ch.BetAmount, ch.Currency, ch.Status, ch.ExpiresAt).Scan(&ch.ID, &ch.CreatedAt)
+}
+
+// получает вызов по коду
+func (r *ChallengeRepository) GetByCode(ctx context.Context, code string) (*domain.Challenge, error) {
+	row := r.db.QueryRow(ctx, `SELECT `+challengeColumns+` FROM pvp_challenges WHERE code = $1`, code)
+	return scanChallenge(row)
+}
+
+// переводит вызов в started, если он еще открыт
+// возвращает false, если вызов уже истек или отменен
+func (r *ChallengeRepository) MarkStarted(ctx context.Context, code string, opponentID int64) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE pvp_challenges
+		SET status = 'started', opponent_id = $2, started_at = NOW()
+		WHERE code = $1 AND status = 'pending' AND expires_at > NOW()
+	`, code, opponentID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// закрывает открытый вызов внутри транзакции (expired или cancelled)
+// возвращает nil, если вызов уже не в статусе pending
+func (r *ChallengeRepository) CloseWithTx(ctx context.Context, tx pgx.Tx, id int64, status domain.ChallengeStatus) (*domain.Challenge, error) {
+	row := tx.QueryRow(ctx, `
+		UPDATE pvp_challenges SET status = $2
+		WHERE id = $1 AND status = 'pending'
+		RETURNING `+challengeColumns, id, status)
+	return scanChallenge(row)
+}
+
+// возвращает ID открытых вызовов, срок которых истек
+func (r *ChallengeRepository) GetExpiredIDs(ctx context.Context, now time.Time) ([]int64, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id FROM pvp_challenges
+		WHERE status = 'pending' AND expires_at <= $1
+		ORDER BY expires_at ASC
+	`, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// преобразует строку из БД в структуру Challenge
+func scanChallenge(row pgx.Row) (*domain.Challenge, error) {
+	var ch domain.Challenge
+	if err := row.Scan(
+		&ch.ID, &ch.Code, &ch.CreatorID, &ch.InviteeID, &ch.GameType, &ch.BetAmount, &ch.Currency,
+		&ch.Status, &ch.OpponentID, &ch.CreatedAt, &ch.ExpiresAt, &ch.StartedAt,
+	); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &ch, nil
+}
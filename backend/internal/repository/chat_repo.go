@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"telegram_webapp/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// жалоба на этого соперника в комнате уже есть
+var ErrChatReportExists = errors.New("chat report already exists")
+
+// операции с чатом PvP комнат и жалобами
+type ChatRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewChatRepository(db *pgxpool.Pool) *ChatRepository {
+	return &ChatRepository{db: db}
+}
+
+// сохраняет сообщение чата
+func (r *ChatRepository) CreateMessage(ctx context.Context, m *domain.ChatMessage) error {
+	return r.db.QueryRow(ctx, `
+		INSERT INTO pvp_chat_messages (room_id, sender_id, recipient_id, kind, content, filtered, delivered)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`, m.RoomID, m.SenderID, m.RecipientID, m.Kind, m.Content, m.Filtered, m.Delivered).Scan(&m.ID, &m.CreatedAt)
+}
+
+// писал ли отправитель получателю в комнате (жалобу можно подать только на полученные сообщения)
+func (r *ChatRepository) HasMessages(ctx context.Context, roomID string, senderID, recipientID int64) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM pvp_chat_messages
+			WHERE room_id = $1 AND sender_id = $2 AND recipient_id = $3
+		)
+	`, roomID, senderID, recipientID).Scan(&exists)
+	return exists, err
+}
+
+// создает жалобу; повторная жалоба на того же соперника в комнате - ErrChatReportExists
+func (r *ChatRepository) CreateReport(ctx context.Context, rep *domain.ChatReport) error {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO pvp_chat_reports (room_id, reporter_id, reported_id, reason)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (room_id, reporter_id, reported_id) DO NOTHING
+		RETURNING id, status, created_at
+	`, rep.RoomID, rep.ReporterID, rep.ReportedID, rep.Reason).Scan(&rep.ID, &rep.Status, &rep.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrChatReportExists
+	}
+	return err
+}
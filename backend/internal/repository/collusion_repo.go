@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"telegram_webapp/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// анализ PvP пар и очередь подозрений на сговор
+type CollusionRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewCollusionRepository(db *pgxpool.Pool) *CollusionRepository {
+	return &CollusionRepository{db: db}
+}
+
+// PairStats статистика матчей пары (user_a < user_b) за окно анализа
+type PairStats struct {
+	UserA          int64
+	UserB          int64
+	Games          int
+	WinsA          int
+	WinsB          int
+	Draws          int
+	Volume         int64   // сумма ставок
+	NetA           int64   // чистый выигрыш user_a у user_b
+	DistinctStakes int     // разных пар ставка+валюта
+	AvgGapSec      float64 // средняя пауза между соседними матчами пары
+	FirstGameAt    time.Time
+	LastGameAt     time.Time
+}
+
+// пары с не меньше minGames PvP матчами против людей начиная с since
+// каждая партия пишется обоим игрокам, берем строку игрока с меньшим id
+func (r *CollusionRepository) PairStats(ctx context.Context, since time.Time, minGames int) ([]PairStats, error) {
+	rows, err := r.db.Query(ctx, `
+		WITH pair AS (
+			SELECT user_id AS a, opponent_id AS b, result, bet_amount, win_amount, currency, created_at,
+			       EXTRACT(EPOCH FROM created_at - LAG(created_at) OVER (PARTITION BY user_id, opponent_id ORDER BY created_at)) AS gap
+			FROM game_history
+			WHERE mode = 'pvp' AND NOT vs_bot AND opponent_id IS NOT NULL
+			  AND user_id < opponent_id AND created_at >= $1
+		)
+		SELECT a, b,
+		       COUNT(*),
+		       COUNT(*) FILTER (WHERE result = 'win'),
+		       COUNT(*) FILTER (WHERE result = 'lose'),
+		       COUNT(*) FILTER (WHERE result = 'draw'),
+		       COALESCE(SUM(bet_amount), 0),
+		       COALESCE(SUM(win_amount), 0),
+		       COUNT(DISTINCT (bet_amount, currency)),
+		       COALESCE(AVG(gap), 0)::float8,
+		       MIN(created_at), MAX(created_at)
+		FROM pair
+		GROUP BY a, b
+		HAVING COUNT(*) >= $2
+		ORDER BY COUNT(*) DESC
+		LIMIT 500
+	`, since, minGames)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []PairStats
+	for rows.Next() {
+		var s PairStats
+		if err := rows.Scan(&s.UserA, &s.UserB, &s.Games, &s.WinsA, &s.WinsB, &s.Draws,
+			&s.Volume, &s.NetA, &s.DistinctStakes, &s.AvgGapSec, &s.FirstGameAt, &s.LastGameAt); err != nil {
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
+
+// есть ли у двух пользователей общий TON адрес (привязанный кошелек, депозиты, выводы)
+func (r *CollusionRepository) SharedWallet(ctx context.Context, a, b int64) (bool, error) {
+	var shared bool
+	err := r.db.QueryRow(ctx, `
+		WITH addrs AS (
+			SELECT user_id, address AS addr FROM wallets WHERE user_id IN ($1, $2)
+			UNION SELECT user_id, raw_address FROM wallets WHERE user_id IN ($1, $2) AND raw_address IS NOT NULL
+			UNION SELECT user_id, wallet_address FROM deposits WHERE user_id IN ($1, $2)
+			UNION SELECT user_id, wallet_address FROM withdrawals WHERE user_id IN ($1, $2)
+		)
+		SELECT EXISTS (
+			SELECT 1 FROM addrs x JOIN addrs y ON x.addr = y.addr
+			WHERE x.user_id = $1 AND y.user_id = $2
+		)
+	`, a, b).Scan(&shared)
+	return shared, err
+}
+
+// пригласил ли один пользователь другого
+func (r *CollusionRepository) ReferralLinked(ctx context.Context, a, b int64) (bool, error) {
+	var linked bool
+	err := r.db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM referrals
+			WHERE (referrer_id = $1 AND referred_id = $2) OR (referrer_id = $2 AND referred_id = $1)
+		) OR EXISTS (
+			SELECT 1 FROM users
+			WHERE (id = $1 AND referred_by = $2) OR (id = $2 AND referred_by = $1)
+		)
+	`, a, b).Scan(&linked)
+	return linked, err
+}
+
+// разбиралась ли пара админом после since (повторно такие пары не поднимаем)
+func (r *CollusionRepository) ReviewedSince(ctx context.Context, a, b int64, since time.Time) (bool, error) {
+	var reviewed bool
+	err := r.db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM pvp_collusion_flags
+			WHERE user_a = $1 AND user_b = $2 AND status <> 'open' AND reviewed_at >= $3
+		)
+	`, a, b, since).Scan(&reviewed)
+	return reviewed, err
+}
+
+// создает открытое подозрение или обновляет уже открытое по этой паре
+// возвращает true, если подозрение новое
+func (r *CollusionRepository) UpsertOpen(ctx context.Context, f *domain.CollusionFlag) (bool, error) {
+	var created bool
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO pvp_collusion_flags (user_a, user_b, score, signals, games, wins_a, wins_b, volume, net_a, first_game_at, last_game_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (user_a, user_b) WHERE status = 'open' DO UPDATE
+		SET score = EXCLUDED.score, signals = EXCLUDED.signals, games = EXCLUDED.games,
+		    wins_a = EXCLUDED.wins_a, wins_b = EXCLUDED.wins_b, volume = EXCLUDED.volume, net_a = EXCLUDED.net_a,
+		    first_game_at = EXCLUDED.first_game_at, last_game_at = EXCLUDED.last_game_at, updated_at = NOW()
+		RETURNING id, status, created_at, updated_at, (xmax = 0)
+	`, f.UserA, f.UserB, f.Score, f.Signals, f.Games, f.WinsA, f.WinsB, f.Volume, f.NetA,
+		f.FirstGameAt, f.LastGameAt).Scan(&f.ID, &f.Status, &f.CreatedAt, &f.UpdatedAt, &created)
+	return created, err
+}
+
+const collusionFlagColumns = `id, user_a, user_b, score, signals, games, wins_a, wins_b, volume, net_a,
+	first_game_at, last_game_at, status, note, reviewed_by, reviewed_at, created_at, updated_at`
+
+func scanCollusionFlag(row pgx.Row) (*domain.CollusionFlag, error) {
+	var f domain.CollusionFlag
+	err := row.Scan(&f.ID, &f.UserA, &f.UserB, &f.Score, &f.Signals, &f.Games, &f.WinsA, &f.WinsB,
+		&f.Volume, &f.NetA, &f.FirstGameAt, &f.LastGameAt, &f.Status, &f.Note,
+		&f.ReviewedBy, &f.ReviewedAt, &f.CreatedAt, &f.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// открытые подозрения, самые сильные первыми
+func (r *CollusionRepository) ListOpen(ctx context.Context, limit int) ([]*domain.CollusionFlag, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+collusionFlagColumns+`
+		FROM pvp_collusion_flags
+		WHERE status = 'open'
+		ORDER BY score DESC, updated_at DESC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var flags []*domain.CollusionFlag
+	for rows.Next() {
+		f, err := scanCollusionFlag(rows)
+		if err != nil {
+			return nil, err
+		}
+		flags = append(flags, f)
+	}
+	return flags, rows.Err()
+}
+
+// пары, которые не должны встречаться в матчмейкинге: открытые и подтвержденные подозрения
+func (r *CollusionRepository) BlockedPairs(ctx context.Context) ([][2]int64, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT DISTINCT user_a, user_b FROM pvp_collusion_flags WHERE status IN ('open', 'confirmed')
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pairs [][2]int64
+	for rows.Next() {
+		var p [2]int64
+		if err := rows.Scan(&p[0], &p[1]); err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, p)
+	}
+	return pairs, rows.Err()
+}
+
+func (r *CollusionRepository) GetByID(ctx context.Context, id int64) (*domain.CollusionFlag, error) {
+	f, err := scanCollusionFlag(r.db.QueryRow(ctx, `SELECT `+collusionFlagColumns+` FROM pvp_collusion_flags WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return f, err
+}
+
+// закрывает открытое подозрение решением админа; nil, если оно уже разобрано или не найдено
+func (r *CollusionRepository) Resolve(ctx context.Context, id int64, status domain.CollusionFlagStatus, adminTgID int64, note string) (*domain.CollusionFlag, error) {
+	f, err := scanCollusionFlag(r.db.QueryRow(ctx, `
+		UPDATE pvp_collusion_flags
+		SET status = $2, reviewed_by = $3, reviewed_at = NOW(), note = $4, updated_at = NOW()
+		WHERE id = $1 AND status = 'open'
+		RETURNING `+collusionFlagColumns, id, status, adminTgID, note))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return f, err
+}
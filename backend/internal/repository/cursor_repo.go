@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// курсоры сканирования блокчейна (последний обработанный lt)
+type ChainCursorRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewChainCursorRepository(db *pgxpool.Pool) *ChainCursorRepository {
+	return &ChainCursorRepository{db: db}
+}
+
+// последний обработанный lt; 0, если курсора еще нет
+func (r *ChainCursorRepository) Get(ctx context.Context, name string) (int64, error) {
+	var lt int64
+	err := r.db.QueryRow(ctx, `SELECT last_lt FROM chain_cursors WHERE name = $1`, name).Scan(&lt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	return lt, err
+}
+
+// сдвигает курсор вперед (назад не двигает)
+func (r *ChainCursorRepository) Advance(ctx context.Context, name string, lt int64, hash string) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO chain_cursors (name, last_lt, last_hash, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (name) DO UPDATE
+		SET last_lt = EXCLUDED.last_lt, last_hash = EXCLUDED.last_hash, updated_at = NOW()
+		WHERE chain_cursors.last_lt < EXCLUDED.last_lt
+	`, name, lt, hash)
+	return err
+}
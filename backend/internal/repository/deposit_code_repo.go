@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"telegram_webapp/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// коды депозитов для memo
+type DepositCodeRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewDepositCodeRepository(db *pgxpool.Pool) *DepositCodeRepository {
+	return &DepositCodeRepository{db: db}
+}
+
+// постоянный код пользователя; nil, если еще не выдан
+func (r *DepositCodeRepository) GetPermanent(ctx context.Context, userID int64) (*domain.DepositCode, error) {
+	row := r.db.QueryRow(ctx, `
+		SELECT id, user_id, code, expires_at, used_at, created_at
+		FROM deposit_codes
+		WHERE user_id = $1 AND expires_at IS NULL
+	`, userID)
+	return scanDepositCode(row)
+}
+
+// ищет код (регистр уже нормализован); nil, если такого кода нет
+func (r *DepositCodeRepository) GetByCode(ctx context.Context, code string) (*domain.DepositCode, error) {
+	row := r.db.QueryRow(ctx, `
+		SELECT id, user_id, code, expires_at, used_at, created_at
+		FROM deposit_codes
+		WHERE code = $1
+	`, code)
+	return scanDepositCode(row)
+}
+
+// сохраняет код; false - код занят или у пользователя уже есть постоянный код
+func (r *DepositCodeRepository) Create(ctx context.Context, c *domain.DepositCode) (bool, error) {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO deposit_codes (user_id, code, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+		RETURNING id, created_at
+	`, c.UserID, c.Code, c.ExpiresAt).Scan(&c.ID, &c.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// отмечает первый депозит по коду
+func (r *DepositCodeRepository) MarkUsed(ctx context.Context, id int64) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE deposit_codes SET used_at = $2 WHERE id = $1 AND used_at IS NULL
+	`, id, time.Now())
+	return err
+}
+
+func scanDepositCode(row pgx.Row) (*domain.DepositCode, error) {
+	var c domain.DepositCode
+	if err := row.Scan(&c.ID, &c.UserID, &c.Code, &c.ExpiresAt, &c.UsedAt, &c.CreatedAt); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &c, nil
+}
@@ -22,7 +22,8 @@ func NewDepositRepository(db *pgxpool.Pool) *DepositRepository {
 func (r *DepositRepository) GetByID(ctx context.Context, id int64) (*domain.Deposit, error) {
 	row := r.db.QueryRow(ctx, `
 		SELECT id, user_id, wallet_address, amount_nano, gems_credited, exchange_rate,
-		       tx_hash, tx_lt, status, memo, created_at, confirmed_at, processed
+		       tx_hash, tx_lt, status, memo, created_at, confirmed_at, processed, asset,
+		       COALESCE(unmatched_reason, ''), COALESCE(pricing_version, 0)
 		FROM deposits
 		WHERE id = $1
 	`, id)
@@ -34,7 +35,8 @@ func (r *DepositRepository) GetByID(ctx context.Context, id int64) (*domain.Depo
 func (r *DepositRepository) GetByTxHash(ctx context.Context, txHash string) (*domain.Deposit, error) {
 	row := r.db.QueryRow(ctx, `
 		SELECT id, user_id, wallet_address, amount_nano, gems_credited, exchange_rate,
-		       tx_hash, tx_lt, status, memo, created_at, confirmed_at, processed
+		       tx_hash, tx_lt, status, memo, created_at, confirmed_at, processed, asset,
+		       COALESCE(unmatched_reason, ''), COALESCE(pricing_version, 0)
 		FROM deposits
 		WHERE tx_hash = $1
 	`, txHash)
@@ -46,7 +48,8 @@ func (r *DepositRepository) GetByTxHash(ctx context.Context, txHash string) (*do
 func (r *DepositRepository) GetByUserID(ctx context.Context, userID int64, limit int) ([]domain.Deposit, error) {
 	rows, err := r.db.Query(ctx, `
 		SELECT id, user_id, wallet_address, amount_nano, gems_credited, exchange_rate,
-		       tx_hash, tx_lt, status, memo, created_at, confirmed_at, processed
+		       tx_hash, tx_lt, status, memo, created_at, confirmed_at, processed, asset,
+		       COALESCE(unmatched_reason, ''), COALESCE(pricing_version, 0)
 		FROM deposits
 		WHERE user_id = $1
 		ORDER BY created_at DESC
@@ -64,7 +67,8 @@ func (r *DepositRepository) GetByUserID(ctx context.Context, userID int64, limit
 func (r *DepositRepository) GetPending(ctx context.Context) ([]domain.Deposit, error) {
 	rows, err := r.db.Query(ctx, `
 		SELECT id, user_id, wallet_address, amount_nano, gems_credited, exchange_rate,
-		       tx_hash, tx_lt, status, memo, created_at, confirmed_at, processed
+		       tx_hash, tx_lt, status, memo, created_at, confirmed_at, processed, asset,
+		       COALESCE(unmatched_reason, ''), COALESCE(pricing_version, 0)
 		FROM deposits
 		WHERE status = 'pending' AND NOT processed
 		ORDER BY created_at ASC
@@ -80,10 +84,51 @@ func (r *DepositRepository) GetPending(ctx context.Context) ([]domain.Deposit, e
 // создает новую запись о депозите
 func (r *DepositRepository) Create(ctx context.Context, d *domain.Deposit) error {
 	return r.db.QueryRow(ctx, `
-		INSERT INTO deposits (user_id, wallet_address, amount_nano, gems_credited, exchange_rate, tx_hash, tx_lt, status, memo)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO deposits (user_id, wallet_address, amount_nano, gems_credited, exchange_rate, tx_hash, tx_lt, status, memo, pricing_version)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, 0))
 		RETURNING id, created_at
-	`, d.UserID, d.WalletAddress, d.AmountNano, d.GemsCredited, d.ExchangeRate, d.TxHash, d.TxLt, d.Status, d.Memo).Scan(&d.ID, &d.CreatedAt)
+	`, d.UserID, d.WalletAddress, d.AmountNano, d.GemsCredited, d.ExchangeRate, d.TxHash, d.TxLt, d.Status, d.Memo, d.PricingVersion).Scan(&d.ID, &d.CreatedAt)
+}
+
+// создает запись о депозите внутри транзакции; false - депозит с таким tx_hash уже есть
+func (r *DepositRepository) CreateWithTx(ctx context.Context, tx pgx.Tx, d *domain.Deposit) (bool, error) {
+	err := tx.QueryRow(ctx, `
+		INSERT INTO deposits (user_id, wallet_address, amount_nano, gems_credited, exchange_rate, tx_hash, tx_lt, status, memo, confirmed_at, processed, asset, unmatched_reason, pricing_version)
+		VALUES (NULLIF($1, 0), $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, COALESCE(NULLIF($12, ''), 'TON'), NULLIF($13, ''), NULLIF($14, 0))
+		ON CONFLICT (tx_hash) DO NOTHING
+		RETURNING id, created_at, asset
+	`, d.UserID, d.WalletAddress, d.AmountNano, d.GemsCredited, d.ExchangeRate, d.TxHash, d.TxLt, d.Status, d.Memo, d.ConfirmedAt, d.Processed, d.Asset, d.UnmatchedReason, d.PricingVersion).Scan(&d.ID, &d.CreatedAt, &d.Asset)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// возвращает подтвержденные депозиты с created_at >= since, для которых нет строки
+// ton_deposit в transactions (коины не зачислены или зачисление не записано)
+func (r *DepositRepository) GetMissingCredits(ctx context.Context, since time.Time, limit int) ([]domain.Deposit, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT d.id, d.user_id, d.wallet_address, d.amount_nano, d.gems_credited, d.exchange_rate,
+		       d.tx_hash, d.tx_lt, d.status, d.memo, d.created_at, d.confirmed_at, d.processed, d.asset,
+		       COALESCE(d.unmatched_reason, ''), COALESCE(d.pricing_version, 0)
+		FROM deposits d
+		WHERE d.status = 'confirmed' AND d.created_at >= $1
+		  AND NOT EXISTS (
+			SELECT 1 FROM transactions t
+			WHERE t.type = $2 AND t.meta->>'deposit_id' = d.id::text
+		  )
+		ORDER BY d.created_at ASC
+		LIMIT $3
+	`, since, domain.TransactionTypeTonDeposit, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanDeposits(rows)
 }
 
 // подтверждает депозит и начисляет драгоценные камни (gems)
@@ -105,6 +150,89 @@ func (r *DepositRepository) Fail(ctx context.Context, id int64) error {
 	return err
 }
 
+// несопоставленные депозиты (очередь разбора) и зависшие возвраты, старые первыми
+func (r *DepositRepository) GetUnmatched(ctx context.Context, limit int) ([]domain.Deposit, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, user_id, wallet_address, amount_nano, gems_credited, exchange_rate,
+		       tx_hash, tx_lt, status, memo, created_at, confirmed_at, processed, asset,
+		       COALESCE(unmatched_reason, ''), COALESCE(pricing_version, 0)
+		FROM deposits
+		WHERE status IN ('unmatched', 'refunding')
+		ORDER BY created_at ASC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanDeposits(rows)
+}
+
+// блокирует несопоставленный депозит до конца транзакции; nil, если его нет или он уже разобран
+func (r *DepositRepository) GetUnmatchedForUpdateWithTx(ctx context.Context, tx pgx.Tx, id int64) (*domain.Deposit, error) {
+	row := tx.QueryRow(ctx, `
+		SELECT id, user_id, wallet_address, amount_nano, gems_credited, exchange_rate,
+		       tx_hash, tx_lt, status, memo, created_at, confirmed_at, processed, asset,
+		       COALESCE(unmatched_reason, ''), COALESCE(pricing_version, 0)
+		FROM deposits
+		WHERE id = $1 AND status = 'unmatched'
+		FOR UPDATE
+	`, id)
+
+	return scanDeposit(row)
+}
+
+// назначает несопоставленный депозит пользователю и подтверждает его внутри транзакции
+func (r *DepositRepository) AssignWithTx(ctx context.Context, tx pgx.Tx, d *domain.Deposit) error {
+	now := time.Now()
+	_, err := tx.Exec(ctx, `
+		UPDATE deposits
+		SET user_id = $2, status = 'confirmed', confirmed_at = $3, processed = true,
+		    gems_credited = $4, exchange_rate = $5, pricing_version = NULLIF($6, 0)
+		WHERE id = $1 AND status = 'unmatched'
+	`, d.ID, d.UserID, now, d.GemsCredited, d.ExchangeRate, d.PricingVersion)
+	if err != nil {
+		return err
+	}
+	d.Status = domain.DepositStatusConfirmed
+	d.ConfirmedAt = &now
+	d.Processed = true
+	return nil
+}
+
+// переводит несопоставленный депозит в refunding перед отправкой возврата; false - уже разобран
+func (r *DepositRepository) ClaimForRefund(ctx context.Context, id int64) (bool, error) {
+	result, err := r.db.Exec(ctx, `
+		UPDATE deposits SET status = 'refunding' WHERE id = $1 AND status = 'unmatched'
+	`, id)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+// возвращает депозит в очередь разбора, если отправка возврата не удалась
+func (r *DepositRepository) ReleaseRefund(ctx context.Context, id int64) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE deposits SET status = 'unmatched' WHERE id = $1 AND status = 'refunding'
+	`, id)
+	return err
+}
+
+// отмечает возврат отправленным внутри транзакции; false - депозит уже разобран
+func (r *DepositRepository) MarkRefundedWithTx(ctx context.Context, tx pgx.Tx, id int64, refundTxHash string, refundAmount int64) (bool, error) {
+	result, err := tx.Exec(ctx, `
+		UPDATE deposits
+		SET status = 'refunded', refund_tx_hash = $2, refund_amount = $3, processed = true
+		WHERE id = $1 AND status IN ('unmatched', 'refunding')
+	`, id, refundTxHash, refundAmount)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
 // проверяет, существует ли уже хэш транзакции
 func (r *DepositRepository) TxHashExists(ctx context.Context, txHash string) (bool, error) {
 	var exists bool
@@ -128,13 +256,15 @@ func (r *DepositRepository) GetTotalDeposited(ctx context.Context, userID int64)
 // преобразует строку из БД в структуру Deposit
 func scanDeposit(row pgx.Row) (*domain.Deposit, error) {
 	var d domain.Deposit
+	var userID *int64
 	var txLt *int64
 	var memo *string
 	var confirmedAt *time.Time
 
 	if err := row.Scan(
-		&d.ID, &d.UserID, &d.WalletAddress, &d.AmountNano, &d.GemsCredited, &d.ExchangeRate,
-		&d.TxHash, &txLt, &d.Status, &memo, &d.CreatedAt, &confirmedAt, &d.Processed,
+		&d.ID, &userID, &d.WalletAddress, &d.AmountNano, &d.GemsCredited, &d.ExchangeRate,
+		&d.TxHash, &txLt, &d.Status, &memo, &d.CreatedAt, &confirmedAt, &d.Processed, &d.Asset,
+		&d.UnmatchedReason, &d.PricingVersion,
 	); err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
@@ -142,6 +272,10 @@ func scanDeposit(row pgx.Row) (*domain.Deposit, error) {
 		return nil, err
 	}
 
+	// у несопоставленного депозита пользователя нет
+	if userID != nil {
+		d.UserID = *userID
+	}
 	if txLt != nil {
 		d.TxLt = *txLt
 	}
@@ -163,17 +297,22 @@ func scanDeposits(rows pgx.Rows) ([]domain.Deposit, error) {
 
 	for rows.Next() {
 		var d domain.Deposit
+		var userID *int64
 		var txLt *int64
 		var memo *string
 		var confirmedAt *time.Time
 
 		if err := rows.Scan(
-			&d.ID, &d.UserID, &d.WalletAddress, &d.AmountNano, &d.GemsCredited, &d.ExchangeRate,
-			&d.TxHash, &txLt, &d.Status, &memo, &d.CreatedAt, &confirmedAt, &d.Processed,
+			&d.ID, &userID, &d.WalletAddress, &d.AmountNano, &d.GemsCredited, &d.ExchangeRate,
+			&d.TxHash, &txLt, &d.Status, &memo, &d.CreatedAt, &confirmedAt, &d.Processed, &d.Asset,
+			&d.UnmatchedReason, &d.PricingVersion,
 		); err != nil {
 			return nil, err
 		}
 
+		if userID != nil {
+			d.UserID = *userID
+		}
 		if txLt != nil {
 			d.TxLt = *txLt
 		}
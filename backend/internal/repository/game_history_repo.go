@@ -27,8 +27,8 @@ func (r *GameHistoryRepository) Create(ctx context.Context, gh *domain.GameHisto
 
 	err = r.db.QueryRow(ctx,
 		`INSERT INTO game_history
-			(user_id, game_type, mode, opponent_id, room_id, result, bet_amount, win_amount, details)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			(user_id, game_type, mode, opponent_id, room_id, result, bet_amount, win_amount, details, vs_bot)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		 RETURNING id, created_at`,
 		gh.UserID,
 		gh.GameType,
@@ -39,6 +39,7 @@ func (r *GameHistoryRepository) Create(ctx context.Context, gh *domain.GameHisto
 		gh.BetAmount,
 		gh.WinAmount,
 		detailsJSON,
+		gh.VsBot,
 	).Scan(&gh.ID, &gh.CreatedAt)
 
 	return err
@@ -152,10 +153,11 @@ func (r *GameHistoryRepository) GetTopUsers(ctx context.Context, limit int) ([]*
 		 FROM users u
 		 LEFT JOIN game_history gh ON u.id = gh.user_id
 			AND gh.created_at >= now() - interval '1 month'
+		 WHERE u.tg_id <> $2
 		 GROUP BY u.id, u.username, u.first_name, u.gems
 		 ORDER BY wins DESC, games DESC, u.gems DESC
 		 LIMIT $1`,
-		limit,
+		limit, domain.HouseAccountTgID,
 	)
 	if err != nil {
 		return nil, err
@@ -174,6 +176,16 @@ func (r *GameHistoryRepository) GetTopUsers(ctx context.Context, limit int) ([]*
 	return result, nil
 }
 
+// количество PvP игр пользователя против бота с указанного момента
+func (r *GameHistoryRepository) CountBotGames(ctx context.Context, userID int64, since time.Time) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx,
+		`SELECT COUNT(*) FROM game_history WHERE user_id = $1 AND vs_bot AND created_at >= $2`,
+		userID, since,
+	).Scan(&count)
+	return count, err
+}
+
 //подсчитывает действия пользователя для квестов
 func (r *GameHistoryRepository) CountUserActions(ctx context.Context, userID int64, actionType domain.ActionType, gameType *string, since time.Time) (int, error) {
 	var count int
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"telegram_webapp/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// версии курса коинов и комиссии на вывод
+type PricingRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPricingRepository(db *pgxpool.Pool) *PricingRepository {
+	return &PricingRepository{db: db}
+}
+
+const pricingColumns = `id, coins_per_ton, coins_per_usdt, withdraw_fee_coins, max_withdraw_coins_per_day,
+	effective_from, created_by, created_at`
+
+func scanPricing(row pgx.Row) (*domain.PricingConfig, error) {
+	var p domain.PricingConfig
+	err := row.Scan(&p.ID, &p.CoinsPerTON, &p.CoinsPerUSDT, &p.WithdrawFeeCoins, &p.MaxWithdrawCoinsPerDay,
+		&p.EffectiveFrom, &p.CreatedBy, &p.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// версия, действующая в момент at; nil, если ни одна еще не вступила в силу
+func (r *PricingRepository) GetAt(ctx context.Context, at time.Time) (*domain.PricingConfig, error) {
+	p, err := scanPricing(r.db.QueryRow(ctx, `
+		SELECT `+pricingColumns+` FROM pricing_config
+		WHERE effective_from <= $1
+		ORDER BY effective_from DESC, id DESC
+		LIMIT 1
+	`, at))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return p, err
+}
+
+func (r *PricingRepository) GetByID(ctx context.Context, id int64) (*domain.PricingConfig, error) {
+	p, err := scanPricing(r.db.QueryRow(ctx, `SELECT `+pricingColumns+` FROM pricing_config WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return p, err
+}
+
+// последние версии, включая запланированные, от новых к старым
+func (r *PricingRepository) GetHistory(ctx context.Context, limit int) ([]*domain.PricingConfig, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+pricingColumns+` FROM pricing_config
+		ORDER BY effective_from DESC, id DESC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []*domain.PricingConfig
+	for rows.Next() {
+		p, err := scanPricing(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, p)
+	}
+	return versions, rows.Err()
+}
+
+// добавляет версию в транзакции (вместе с записью аудита)
+func (r *PricingRepository) CreateWithTx(ctx context.Context, tx pgx.Tx, p *domain.PricingConfig) error {
+	return tx.QueryRow(ctx, `
+		INSERT INTO pricing_config (coins_per_ton, coins_per_usdt, withdraw_fee_coins, max_withdraw_coins_per_day, effective_from, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, p.CoinsPerTON, p.CoinsPerUSDT, p.WithdrawFeeCoins, p.MaxWithdrawCoinsPerDay, p.EffectiveFrom, p.CreatedBy).
+		Scan(&p.ID, &p.CreatedAt)
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+
+	"telegram_webapp/internal/domain"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// nonce TON Connect proof
+type ProofPayloadRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewProofPayloadRepository(db *pgxpool.Pool) *ProofPayloadRepository {
+	return &ProofPayloadRepository{db: db}
+}
+
+// сохраняет выданный пользователю nonce. У пользователя остается не больше maxLive действующих nonce:
+// его истекшие, использованные и самые старые сверх лимита удаляются той же транзакцией
+func (r *ProofPayloadRepository) Create(ctx context.Context, p *domain.ProofPayload, maxLive int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM ton_proof_payloads
+		WHERE user_id = $1 AND id NOT IN (
+			SELECT id FROM ton_proof_payloads
+			WHERE user_id = $1 AND consumed_at IS NULL AND expires_at > NOW()
+			ORDER BY id DESC
+			LIMIT $2
+		)
+	`, p.UserID, maxLive-1); err != nil {
+		return err
+	}
+
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO ton_proof_payloads (payload, user_id, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`, p.Payload, p.UserID, p.ExpiresAt).Scan(&p.ID, &p.CreatedAt); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// отмечает nonce использованным; false - nonce не выдавался этому пользователю, уже использован или истек
+func (r *ProofPayloadRepository) Consume(ctx context.Context, payload string, userID int64) (bool, error) {
+	result, err := r.db.Exec(ctx, `
+		UPDATE ton_proof_payloads SET consumed_at = NOW()
+		WHERE payload = $1 AND user_id = $2 AND consumed_at IS NULL AND expires_at > NOW()
+	`, payload, userID)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"telegram_webapp/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// параметры PvP игр по уровням ставок
+type PvPConfigRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPvPConfigRepository(db *pgxpool.Pool) *PvPConfigRepository {
+	return &PvPConfigRepository{db: db}
+}
+
+const pvpConfigColumns = `id, game_type, min_stake, setup_timeout_sec, turn_timeout_sec,
+	rake_percent::float8, max_rounds, enabled, updated_by, updated_at`
+
+func scanPvPConfig(row pgx.Row) (*domain.PvPGameConfig, error) {
+	var c domain.PvPGameConfig
+	err := row.Scan(&c.ID, &c.GameType, &c.MinStake, &c.SetupTimeoutSec, &c.TurnTimeoutSec,
+		&c.RakePercent, &c.MaxRounds, &c.Enabled, &c.UpdatedBy, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// все уровни, по игре и возрастанию ставки
+func (r *PvPConfigRepository) List(ctx context.Context) ([]*domain.PvPGameConfig, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+pvpConfigColumns+` FROM pvp_game_configs ORDER BY game_type, min_stake`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var configs []*domain.PvPGameConfig
+	for rows.Next() {
+		c, err := scanPvPConfig(rows)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, c)
+	}
+	return configs, rows.Err()
+}
+
+func (r *PvPConfigRepository) Get(ctx context.Context, gameType domain.GameType, minStake int64) (*domain.PvPGameConfig, error) {
+	c, err := scanPvPConfig(r.db.QueryRow(ctx, `
+		SELECT `+pvpConfigColumns+` FROM pvp_game_configs WHERE game_type = $1 AND min_stake = $2
+	`, gameType, minStake))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return c, err
+}
+
+// создает уровень или обновляет параметры существующего
+func (r *PvPConfigRepository) Upsert(ctx context.Context, c *domain.PvPGameConfig) error {
+	return r.db.QueryRow(ctx, `
+		INSERT INTO pvp_game_configs (game_type, min_stake, setup_timeout_sec, turn_timeout_sec, rake_percent, max_rounds, enabled, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (game_type, min_stake) DO UPDATE
+		SET setup_timeout_sec = EXCLUDED.setup_timeout_sec, turn_timeout_sec = EXCLUDED.turn_timeout_sec,
+		    rake_percent = EXCLUDED.rake_percent, max_rounds = EXCLUDED.max_rounds, enabled = EXCLUDED.enabled,
+		    updated_by = EXCLUDED.updated_by, updated_at = NOW()
+		RETURNING id, updated_at
+	`, c.GameType, c.MinStake, c.SetupTimeoutSec, c.TurnTimeoutSec, c.RakePercent, c.MaxRounds, c.Enabled, c.UpdatedBy).
+		Scan(&c.ID, &c.UpdatedAt)
+}
+
+// удаляет уровень ставки; false, если его не было
+func (r *PvPConfigRepository) Delete(ctx context.Context, gameType domain.GameType, minStake int64) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM pvp_game_configs WHERE game_type = $1 AND min_stake = $2`, gameType, minStake)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"telegram_webapp/internal/domain"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// выплата PvP выигрыша с рейком и отчеты по выручке
+type RakeRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRakeRepository(db *pgxpool.Pool) *RakeRepository {
+	return &RakeRepository{db: db}
+}
+
+// PayWinner зачисляет выигрыш победителю и пишет рейк на счет платформы одной транзакцией БД
+// rake.UserID заполняется id счета платформы
+func (r *RakeRepository) PayWinner(ctx context.Context, winnerID int64, currency domain.Currency, payout int64, rake *domain.Transaction) error {
+	column := "gems"
+	if currency == domain.CurrencyCoins {
+		column = "coins"
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := tx.QueryRow(ctx, `SELECT id FROM users WHERE tg_id = $1`, domain.HouseAccountTgID).Scan(&rake.UserID); err != nil {
+		return fmt.Errorf("счет платформы не найден: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE users SET `+column+` = `+column+` + $2 WHERE id = $1`, winnerID, payout); err != nil {
+		return err
+	}
+
+	metaJSON, err := json.Marshal(rake.Meta)
+	if err != nil {
+		metaJSON = []byte("{}")
+	}
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO transactions (user_id, type, amount, meta)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, rake.UserID, rake.Type, rake.Amount, metaJSON).Scan(&rake.ID, &rake.CreatedAt); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RakeRevenue выручка рейка за день или по игре в одной валюте
+type RakeRevenue struct {
+	Day      time.Time // нулевой в отчете по играм
+	GameType string    // пустой в отчете по дням
+	Currency string
+	Amount   int64
+	Games    int64 // партий с рейком
+}
+
+// RevenueByDay рейк по дням и валютам начиная с since, новые дни первыми
+func (r *RakeRepository) RevenueByDay(ctx context.Context, since time.Time) ([]RakeRevenue, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT date_trunc('day', created_at), COALESCE(meta->>'currency', ''), SUM(amount), COUNT(*)
+		FROM transactions
+		WHERE type = $1 AND created_at >= $2
+		GROUP BY 1, 2
+		ORDER BY 1 DESC, 2
+	`, domain.TransactionTypePvPRake, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []RakeRevenue
+	for rows.Next() {
+		var rev RakeRevenue
+		if err := rows.Scan(&rev.Day, &rev.Currency, &rev.Amount, &rev.Games); err != nil {
+			return nil, err
+		}
+		result = append(result, rev)
+	}
+	return result, rows.Err()
+}
+
+// RevenueByGame рейк по играм и валютам начиная с since
+func (r *RakeRepository) RevenueByGame(ctx context.Context, since time.Time) ([]RakeRevenue, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT COALESCE(meta->>'game_type', ''), COALESCE(meta->>'currency', ''), SUM(amount), COUNT(*)
+		FROM transactions
+		WHERE type = $1 AND created_at >= $2
+		GROUP BY 1, 2
+		ORDER BY 1, 2
+	`, domain.TransactionTypePvPRake, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []RakeRevenue
+	for rows.Next() {
+		var rev RakeRevenue
+		if err := rows.Scan(&rev.GameType, &rev.Currency, &rev.Amount, &rev.Games); err != nil {
+			return nil, err
+		}
+		result = append(result, rev)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"telegram_webapp/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// записи PvP матчей для повторов
+type ReplayRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewReplayRepository(db *pgxpool.Pool) *ReplayRepository {
+	return &ReplayRepository{db: db}
+}
+
+// дописывает события матча одной транзакцией: заголовок создается с первой записью,
+// finished_at ставится, когда он задан; уже записанные события (по seq) пропускаются
+func (r *ReplayRepository) Append(ctx context.Context, rep *domain.MatchReplay) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO pvp_match_replays
+			(room_id, game_type, player1_id, player2_id, bet_amount, currency, best_of, vs_bot, tournament_id, started_at, finished_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (room_id) DO UPDATE SET finished_at = COALESCE(EXCLUDED.finished_at, pvp_match_replays.finished_at)
+	`, rep.RoomID, rep.GameType, rep.Player1ID, rep.Player2ID, rep.BetAmount, rep.Currency, rep.BestOf,
+		rep.VsBot, rep.TournamentID, rep.StartedAt, rep.FinishedAt); err != nil {
+		return err
+	}
+
+	batch := &pgx.Batch{}
+	for _, e := range rep.Events {
+		var data []byte
+		if e.Data != nil {
+			if data, err = json.Marshal(e.Data); err != nil {
+				return err
+			}
+		}
+		batch.Queue(`
+			INSERT INTO pvp_match_events (room_id, seq, game_no, event_type, user_id, data, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (room_id, seq) DO NOTHING
+		`, rep.RoomID, e.Seq, e.Game, e.Type, e.UserID, data, e.CreatedAt)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// запись матча с событиями по порядку; nil, если матча нет
+func (r *ReplayRepository) Get(ctx context.Context, roomID string) (*domain.MatchReplay, error) {
+	rep := &domain.MatchReplay{}
+	err := r.db.QueryRow(ctx, `
+		SELECT room_id, game_type, player1_id, player2_id, bet_amount, currency, best_of, vs_bot,
+		       tournament_id, started_at, finished_at
+		FROM pvp_match_replays
+		WHERE room_id = $1
+	`, roomID).Scan(&rep.RoomID, &rep.GameType, &rep.Player1ID, &rep.Player2ID, &rep.BetAmount, &rep.Currency,
+		&rep.BestOf, &rep.VsBot, &rep.TournamentID, &rep.StartedAt, &rep.FinishedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT seq, game_no, event_type, user_id, data, created_at
+		FROM pvp_match_events
+		WHERE room_id = $1
+		ORDER BY seq
+	`, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rep.Events = []domain.MatchEvent{}
+	for rows.Next() {
+		var e domain.MatchEvent
+		var data []byte
+		if err := rows.Scan(&e.Seq, &e.Game, &e.Type, &e.UserID, &data, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		if data != nil {
+			e.Data = json.RawMessage(data)
+		}
+		rep.Events = append(rep.Events, e)
+	}
+	return rep, rows.Err()
+}
@@ -0,0 +1,339 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"telegram_webapp/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// операции с турнирами, участниками и сеткой
+type TournamentRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTournamentRepository(db *pgxpool.Pool) *TournamentRepository {
+	return &TournamentRepository{db: db}
+}
+
+const tournamentColumns = `t.id, t.title, t.game_type, t.currency, t.entry_fee, t.max_players, t.best_of,
+	t.prize_percents, t.prize_pool, t.join_timeout_sec, t.status, t.rounds,
+	(SELECT COUNT(*) FROM tournament_entries e WHERE e.tournament_id = t.id AND e.status <> 'refunded'),
+	t.created_by, t.starts_at, t.started_at, t.finished_at, t.created_at`
+
+const tournamentMatchColumns = `id, tournament_id, round, position, player1_id, player2_id, winner_id,
+	status, reason, room_id, deadline, started_at, finished_at`
+
+// создает турнир
+func (r *TournamentRepository) Create(ctx context.Context, t *domain.Tournament) error {
+	return r.db.QueryRow(ctx, `
+		INSERT INTO tournaments (title, game_type, currency, entry_fee, max_players, best_of,
+		                         prize_percents, join_timeout_sec, created_by, starts_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, status, created_at
+	`, t.Title, t.GameType, t.Currency, t.EntryFee, t.MaxPlayers, t.BestOf,
+		t.PrizePercents, t.JoinTimeoutSec, t.CreatedBy, t.StartsAt).Scan(&t.ID, &t.Status, &t.CreatedAt)
+}
+
+// получает турнир по id
+func (r *TournamentRepository) GetByID(ctx context.Context, id int64) (*domain.Tournament, error) {
+	row := r.db.QueryRow(ctx, `SELECT `+tournamentColumns+` FROM tournaments t WHERE t.id = $1`, id)
+	return scanTournament(row)
+}
+
+// получает и блокирует турнир внутри транзакции
+func (r *TournamentRepository) GetForUpdateWithTx(ctx context.Context, tx pgx.Tx, id int64) (*domain.Tournament, error) {
+	row := tx.QueryRow(ctx, `SELECT `+tournamentColumns+` FROM tournaments t WHERE t.id = $1 FOR UPDATE`, id)
+	return scanTournament(row)
+}
+
+// список турниров в указанных статусах, ближайшие первыми
+func (r *TournamentRepository) List(ctx context.Context, statuses []domain.TournamentStatus, limit int) ([]domain.Tournament, error) {
+	names := make([]string, len(statuses))
+	for i, s := range statuses {
+		names[i] = string(s)
+	}
+	rows, err := r.db.Query(ctx, `
+		SELECT `+tournamentColumns+`
+		FROM tournaments t
+		WHERE t.status = ANY($1)
+		ORDER BY t.starts_at DESC
+		LIMIT $2
+	`, names, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tournaments []domain.Tournament
+	for rows.Next() {
+		t, err := scanTournament(rows)
+		if err != nil {
+			return nil, err
+		}
+		tournaments = append(tournaments, *t)
+	}
+	return tournaments, rows.Err()
+}
+
+// возвращает id турниров, у которых закончилась регистрация
+func (r *TournamentRepository) GetDueIDs(ctx context.Context, now time.Time) ([]int64, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id FROM tournaments
+		WHERE status = 'registration' AND starts_at <= $1
+		ORDER BY starts_at ASC
+	`, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// меняет статус турнира внутри транзакции
+func (r *TournamentRepository) SetStatusWithTx(ctx context.Context, tx pgx.Tx, id int64, status domain.TournamentStatus) error {
+	query := `UPDATE tournaments SET status = $2 WHERE id = $1`
+	switch status {
+	case domain.TournamentStatusRunning:
+		query = `UPDATE tournaments SET status = $2, started_at = NOW() WHERE id = $1`
+	case domain.TournamentStatusFinished, domain.TournamentStatusCancelled:
+		query = `UPDATE tournaments SET status = $2, finished_at = NOW() WHERE id = $1`
+	}
+	_, err := tx.Exec(ctx, query, id, status)
+	return err
+}
+
+// сохраняет число раундов сетки
+func (r *TournamentRepository) SetRoundsWithTx(ctx context.Context, tx pgx.Tx, id int64, rounds int) error {
+	_, err := tx.Exec(ctx, `UPDATE tournaments SET rounds = $2 WHERE id = $1`, id, rounds)
+	return err
+}
+
+// изменяет призовой фонд на delta
+func (r *TournamentRepository) AddPrizePoolWithTx(ctx context.Context, tx pgx.Tx, id int64, delta int64) error {
+	_, err := tx.Exec(ctx, `UPDATE tournaments SET prize_pool = prize_pool + $2 WHERE id = $1`, id, delta)
+	return err
+}
+
+// регистрирует участника; false, если он уже зарегистрирован
+func (r *TournamentRepository) AddEntryWithTx(ctx context.Context, tx pgx.Tx, tournamentID, userID int64) (bool, error) {
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO tournament_entries (tournament_id, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT (tournament_id, user_id) DO NOTHING
+	`, tournamentID, userID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// удаляет участника до старта; false, если его не было
+func (r *TournamentRepository) RemoveEntryWithTx(ctx context.Context, tx pgx.Tx, tournamentID, userID int64) (bool, error) {
+	tag, err := tx.Exec(ctx, `
+		DELETE FROM tournament_entries
+		WHERE tournament_id = $1 AND user_id = $2 AND status = 'registered'
+	`, tournamentID, userID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// участники турнира: сначала по месту, затем по посеву
+func (r *TournamentRepository) GetEntries(ctx context.Context, tournamentID int64) ([]domain.TournamentEntry, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT e.tournament_id, e.user_id, COALESCE(u.username, u.first_name, ''), e.seed, e.status, e.place, e.prize, e.created_at
+		FROM tournament_entries e
+		JOIN users u ON u.id = e.user_id
+		WHERE e.tournament_id = $1
+		ORDER BY e.place ASC NULLS FIRST, e.seed ASC NULLS LAST, e.created_at ASC
+	`, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	return collectTournamentEntries(rows)
+}
+
+// участники внутри транзакции (для посева, возвратов и призов)
+func (r *TournamentRepository) GetEntriesWithTx(ctx context.Context, tx pgx.Tx, tournamentID int64) ([]domain.TournamentEntry, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT e.tournament_id, e.user_id, '', e.seed, e.status, e.place, e.prize, e.created_at
+		FROM tournament_entries e
+		WHERE e.tournament_id = $1
+		ORDER BY e.created_at ASC
+	`, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	return collectTournamentEntries(rows)
+}
+
+// обновляет посев, статус, место и приз участника
+func (r *TournamentRepository) UpdateEntryWithTx(ctx context.Context, tx pgx.Tx, e *domain.TournamentEntry) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE tournament_entries SET seed = $3, status = $4, place = $5, prize = $6
+		WHERE tournament_id = $1 AND user_id = $2
+	`, e.TournamentID, e.UserID, e.Seed, e.Status, e.Place, e.Prize)
+	return err
+}
+
+// отмечает выбывание участника с местом
+func (r *TournamentRepository) EliminateEntryWithTx(ctx context.Context, tx pgx.Tx, tournamentID, userID int64, place int) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE tournament_entries SET status = 'eliminated', place = $3
+		WHERE tournament_id = $1 AND user_id = $2
+	`, tournamentID, userID, place)
+	return err
+}
+
+// создает матч сетки
+func (r *TournamentRepository) CreateMatchWithTx(ctx context.Context, tx pgx.Tx, m *domain.TournamentMatch) error {
+	return tx.QueryRow(ctx, `
+		INSERT INTO tournament_matches (tournament_id, round, position, player1_id, player2_id, status)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, m.TournamentID, m.Round, m.Position, m.Player1ID, m.Player2ID, m.Status).Scan(&m.ID)
+}
+
+// получает и блокирует матч по id
+func (r *TournamentRepository) GetMatchForUpdateWithTx(ctx context.Context, tx pgx.Tx, id int64) (*domain.TournamentMatch, error) {
+	row := tx.QueryRow(ctx, `SELECT `+tournamentMatchColumns+` FROM tournament_matches WHERE id = $1 FOR UPDATE`, id)
+	return scanTournamentMatch(row)
+}
+
+// получает и блокирует матч по месту в сетке
+func (r *TournamentRepository) GetMatchAtWithTx(ctx context.Context, tx pgx.Tx, tournamentID int64, round, position int) (*domain.TournamentMatch, error) {
+	row := tx.QueryRow(ctx, `
+		SELECT `+tournamentMatchColumns+` FROM tournament_matches
+		WHERE tournament_id = $1 AND round = $2 AND position = $3
+		FOR UPDATE
+	`, tournamentID, round, position)
+	return scanTournamentMatch(row)
+}
+
+// получает матч по id
+func (r *TournamentRepository) GetMatch(ctx context.Context, id int64) (*domain.TournamentMatch, error) {
+	row := r.db.QueryRow(ctx, `SELECT `+tournamentMatchColumns+` FROM tournament_matches WHERE id = $1`, id)
+	return scanTournamentMatch(row)
+}
+
+// сохраняет состояние матча
+func (r *TournamentRepository) UpdateMatchWithTx(ctx context.Context, tx pgx.Tx, m *domain.TournamentMatch) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE tournament_matches
+		SET player1_id = $2, player2_id = $3, winner_id = $4, status = $5, reason = $6,
+		    deadline = $7, started_at = $8, finished_at = $9
+		WHERE id = $1
+	`, m.ID, m.Player1ID, m.Player2ID, m.WinnerID, m.Status, m.Reason, m.Deadline, m.StartedAt, m.FinishedAt)
+	return err
+}
+
+// запоминает комнату, в которой играется матч
+func (r *TournamentRepository) SetMatchRoom(ctx context.Context, id int64, roomID string) error {
+	_, err := r.db.Exec(ctx, `UPDATE tournament_matches SET room_id = $2 WHERE id = $1`, id, roomID)
+	return err
+}
+
+// переносит дедлайн подключения (перезапуск матча после рестарта)
+func (r *TournamentRepository) SetMatchDeadline(ctx context.Context, id int64, deadline time.Time) error {
+	_, err := r.db.Exec(ctx, `UPDATE tournament_matches SET deadline = $2 WHERE id = $1`, id, deadline)
+	return err
+}
+
+// все матчи турнира по раундам
+func (r *TournamentRepository) GetMatches(ctx context.Context, tournamentID int64) ([]domain.TournamentMatch, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+tournamentMatchColumns+` FROM tournament_matches
+		WHERE tournament_id = $1
+		ORDER BY round ASC, position ASC
+	`, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	return collectTournamentMatches(rows)
+}
+
+// матчи в игре по всем идущим турнирам
+func (r *TournamentRepository) GetPlayingMatches(ctx context.Context) ([]domain.TournamentMatch, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+tournamentMatchColumns+` FROM tournament_matches
+		WHERE status = 'playing'
+		  AND tournament_id IN (SELECT id FROM tournaments WHERE status = 'running')
+		ORDER BY id ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return collectTournamentMatches(rows)
+}
+
+func scanTournament(row pgx.Row) (*domain.Tournament, error) {
+	var t domain.Tournament
+	if err := row.Scan(
+		&t.ID, &t.Title, &t.GameType, &t.Currency, &t.EntryFee, &t.MaxPlayers, &t.BestOf,
+		&t.PrizePercents, &t.PrizePool, &t.JoinTimeoutSec, &t.Status, &t.Rounds, &t.Players,
+		&t.CreatedBy, &t.StartsAt, &t.StartedAt, &t.FinishedAt, &t.CreatedAt,
+	); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+func scanTournamentMatch(row pgx.Row) (*domain.TournamentMatch, error) {
+	var m domain.TournamentMatch
+	if err := row.Scan(
+		&m.ID, &m.TournamentID, &m.Round, &m.Position, &m.Player1ID, &m.Player2ID, &m.WinnerID,
+		&m.Status, &m.Reason, &m.RoomID, &m.Deadline, &m.StartedAt, &m.FinishedAt,
+	); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &m, nil
+}
+
+func collectTournamentMatches(rows pgx.Rows) ([]domain.TournamentMatch, error) {
+	defer rows.Close()
+
+	var matches []domain.TournamentMatch
+	for rows.Next() {
+		m, err := scanTournamentMatch(rows)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, *m)
+	}
+	return matches, rows.Err()
+}
+
+func collectTournamentEntries(rows pgx.Rows) ([]domain.TournamentEntry, error) {
+	defer rows.Close()
+
+	var entries []domain.TournamentEntry
+	for rows.Next() {
+		var e domain.TournamentEntry
+		if err := rows.Scan(&e.TournamentID, &e.UserID, &e.Username, &e.Seed, &e.Status,
+			&e.Place, &e.Prize, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -108,8 +108,9 @@ func (r *UserRepository) GetTopByWins(ctx context.Context, limit int) ([]struct
 		LEFT JOIN (
 			SELECT winner_id, COUNT(*) as wins FROM games WHERE winner_id IS NOT NULL GROUP BY winner_id
 		) wc ON wc.winner_id = u.id
+		WHERE u.tg_id <> $2
 		ORDER BY wins DESC
-		LIMIT $1`, limit)
+		LIMIT $1`, limit, domain.HouseAccountTgID)
 	if err != nil {
 		return nil, err
 	}
@@ -237,8 +238,9 @@ func (r *UserRepository) GetMonthlyTop(ctx context.Context, limit int) ([]TopUse
 			WHERE created_at >= date_trunc('month', CURRENT_DATE) AND result = 'win'
 			GROUP BY user_id
 		) w ON w.user_id = u.id
+		WHERE u.tg_id <> $2
 		ORDER BY wins_count DESC
-		LIMIT $1`, limit)
+		LIMIT $1`, limit, domain.HouseAccountTgID)
 	if err != nil {
 		return nil, err
 	}
@@ -279,9 +281,10 @@ func (r *UserRepository) GetUserRank(ctx context.Context, userID int64) (int, in
 			       RANK() OVER (ORDER BY COALESCE(w.wins, 0) DESC) as rank
 			FROM users u
 			LEFT JOIN user_wins w ON w.user_id = u.id
+			WHERE u.tg_id <> $2
 		)
 		SELECT rank, wins_count FROM ranked WHERE id = $1
-	`, userID).Scan(&rank, &winsCount)
+	`, userID, domain.HouseAccountTgID).Scan(&rank, &winsCount)
 	if err != nil {
 		return 0, 0, err
 	}
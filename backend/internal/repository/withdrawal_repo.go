@@ -23,7 +23,7 @@ func (r *WithdrawalRepository) GetByID(ctx context.Context, id int64) (*domain.W
 	row := r.db.QueryRow(ctx, `
 		SELECT id, user_id, wallet_address, coins_amount, ton_amount_nano, fee_coins, exchange_rate,
 		       status, tx_hash, tx_lt, admin_notes, created_at, processed_at, completed_at,
-		       gems_amount, fee_gems
+		       gems_amount, fee_gems, asset, COALESCE(pricing_version, 0)
 		FROM withdrawals
 		WHERE id = $1
 	`, id)
@@ -36,7 +36,7 @@ func (r *WithdrawalRepository) GetByUserID(ctx context.Context, userID int64, li
 	rows, err := r.db.Query(ctx, `
 		SELECT id, user_id, wallet_address, coins_amount, ton_amount_nano, fee_coins, exchange_rate,
 		       status, tx_hash, tx_lt, admin_notes, created_at, processed_at, completed_at,
-		       gems_amount, fee_gems
+		       gems_amount, fee_gems, asset, COALESCE(pricing_version, 0)
 		FROM withdrawals
 		WHERE user_id = $1
 		ORDER BY created_at DESC
@@ -55,7 +55,7 @@ func (r *WithdrawalRepository) GetPending(ctx context.Context) ([]domain.Withdra
 	rows, err := r.db.Query(ctx, `
 		SELECT id, user_id, wallet_address, coins_amount, ton_amount_nano, fee_coins, exchange_rate,
 		       status, tx_hash, tx_lt, admin_notes, created_at, processed_at, completed_at,
-		       gems_amount, fee_gems
+		       gems_amount, fee_gems, asset, COALESCE(pricing_version, 0)
 		FROM withdrawals
 		WHERE status = 'pending'
 		ORDER BY created_at ASC
@@ -71,10 +71,10 @@ func (r *WithdrawalRepository) GetPending(ctx context.Context) ([]domain.Withdra
 // создает новый запрос на вывод средств (в монетах)
 func (r *WithdrawalRepository) Create(ctx context.Context, w *domain.Withdrawal) error {
 	return r.db.QueryRow(ctx, `
-		INSERT INTO withdrawals (user_id, wallet_address, coins_amount, ton_amount_nano, fee_coins, exchange_rate, status)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING id, created_at
-	`, w.UserID, w.WalletAddress, w.CoinsAmount, w.TonAmountNano, w.FeeCoins, w.ExchangeRate, w.Status).Scan(&w.ID, &w.CreatedAt)
+		INSERT INTO withdrawals (user_id, wallet_address, coins_amount, ton_amount_nano, fee_coins, exchange_rate, status, asset, pricing_version)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, COALESCE(NULLIF($8, ''), 'TON'), NULLIF($9, 0))
+		RETURNING id, created_at, asset
+	`, w.UserID, w.WalletAddress, w.CoinsAmount, w.TonAmountNano, w.FeeCoins, w.ExchangeRate, w.Status, w.Asset, w.PricingVersion).Scan(&w.ID, &w.CreatedAt, &w.Asset)
 }
 
 // обновляет статус вывода средств
@@ -102,6 +102,37 @@ func (r *WithdrawalRepository) MarkSent(ctx context.Context, id int64, txHash st
 	return err
 }
 
+// забирает ожидающий вывод в обработку; false - вывод уже не pending (обработан админом или отменен)
+func (r *WithdrawalRepository) ClaimPending(ctx context.Context, id int64) (bool, error) {
+	result, err := r.db.Exec(ctx, `
+		UPDATE withdrawals SET status = 'processing', processed_at = NOW()
+		WHERE id = $1 AND status = 'pending'
+	`, id)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+// возвращает выводы, отправленные в сеть и ожидающие подтверждения
+func (r *WithdrawalRepository) GetSent(ctx context.Context, limit int) ([]domain.Withdrawal, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, user_id, wallet_address, coins_amount, ton_amount_nano, fee_coins, exchange_rate,
+		       status, tx_hash, tx_lt, admin_notes, created_at, processed_at, completed_at,
+		       gems_amount, fee_gems, asset, COALESCE(pricing_version, 0)
+		FROM withdrawals
+		WHERE status = 'sent'
+		ORDER BY processed_at ASC NULLS FIRST
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanWithdrawals(rows)
+}
+
 // помечает вывод средств как завершенный
 func (r *WithdrawalRepository) MarkCompleted(ctx context.Context, id int64) error {
 	now := time.Now()
@@ -188,7 +219,7 @@ func scanWithdrawal(row pgx.Row) (*domain.Withdrawal, error) {
 	if err := row.Scan(
 		&w.ID, &w.UserID, &w.WalletAddress, &w.CoinsAmount, &w.TonAmountNano, &w.FeeCoins, &w.ExchangeRate,
 		&w.Status, &txHash, &txLt, &adminNotes, &w.CreatedAt, &processedAt, &completedAt,
-		&w.GemsAmount, &w.FeeGems,
+		&w.GemsAmount, &w.FeeGems, &w.Asset, &w.PricingVersion,
 	); err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
@@ -224,7 +255,7 @@ func scanWithdrawals(rows pgx.Rows) ([]domain.Withdrawal, error) {
 		if err := rows.Scan(
 			&w.ID, &w.UserID, &w.WalletAddress, &w.CoinsAmount, &w.TonAmountNano, &w.FeeCoins, &w.ExchangeRate,
 			&w.Status, &txHash, &txLt, &adminNotes, &w.CreatedAt, &processedAt, &completedAt,
-			&w.GemsAmount, &w.FeeGems,
+			&w.GemsAmount, &w.FeeGems, &w.Asset, &w.PricingVersion,
 		); err != nil {
 			return nil, err
 		}
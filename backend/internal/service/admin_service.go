@@ -2,11 +2,14 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 	"time"
 
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/repository"
 	"telegram_webapp/internal/ton"
 
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -14,13 +17,24 @@ import (
 
 // предоставляет административную статистику и операции
 type AdminService struct {
-	db     *pgxpool.Pool
-	wallet *ton.Wallet
+	db          *pgxpool.Pool
+	wallet      *ton.Wallet
+	tournaments *TournamentService
+	collusion   *CollusionService
+	pvpConfigs  *PvPConfigService
+	pricing     *PricingService
+	deposits    *DepositWatcher
 }
 
 // создает новый административный сервис
 func NewAdminService(db *pgxpool.Pool) *AdminService {
-	return &AdminService{db: db}
+	return &AdminService{
+		db:          db,
+		tournaments: NewTournamentService(db),
+		collusion:   NewCollusionService(db, DefaultCollusionConfig()),
+		pvpConfigs:  NewPvPConfigService(db),
+		pricing:     NewPricingService(db),
+	}
 }
 
 // устанавливает TON кошелек для автоматических выводов
@@ -28,6 +42,60 @@ func (s *AdminService) SetWallet(wallet *ton.Wallet) {
 	s.wallet = wallet
 }
 
+// устанавливает deposit watcher для бэкфилла депозитов
+func (s *AdminService) SetDepositWatcher(w *DepositWatcher) {
+	s.deposits = w
+}
+
+var ErrDepositWatcherOff = errors.New("deposit watcher не запущен (TON_PLATFORM_WALLET не настроен)")
+
+var ErrDepositExists = errors.New("депозит с таким tx_hash уже существует")
+
+// последнее обработанное логическое время депозитов
+func (s *AdminService) DepositCursor() (int64, error) {
+	if s.deposits == nil {
+		return 0, ErrDepositWatcherOff
+	}
+	return s.deposits.Cursor(), nil
+}
+
+// повторно сканирует входящие переводы в диапазоне lt и зачисляет пропущенные депозиты
+func (s *AdminService) BackfillDeposits(ctx context.Context, fromLt, toLt int64) (*BackfillResult, error) {
+	if s.deposits == nil {
+		return nil, ErrDepositWatcherOff
+	}
+	return s.deposits.Backfill(ctx, fromLt, toLt)
+}
+
+// ReconcileDeposits подтвержденные депозиты за последние window без записи о зачислении коинов
+func (s *AdminService) ReconcileDeposits(ctx context.Context, window time.Duration) ([]domain.Deposit, error) {
+	return findMissingCredits(ctx, s.db, window)
+}
+
+// GetUnmatchedDeposits очередь разбора: переводы, не сопоставленные с пользователем
+func (s *AdminService) GetUnmatchedDeposits(ctx context.Context, limit int) ([]domain.Deposit, error) {
+	return repository.NewDepositRepository(s.db).GetUnmatched(ctx, limit)
+}
+
+// AssignUnmatchedDeposit назначает несопоставленный депозит пользователю и начисляет коины
+func (s *AdminService) AssignUnmatchedDeposit(ctx context.Context, depositID, userID, adminTgID int64) (*AssignDepositResult, error) {
+	return assignUnmatchedDeposit(ctx, s.db, depositID, userID, adminTgID)
+}
+
+// RefundUnmatchedDeposit возвращает несопоставленный перевод отправителю за вычетом комиссии сети
+func (s *AdminService) RefundUnmatchedDeposit(ctx context.Context, depositID int64, manualTxHash string, adminTgID int64) (*RefundDepositResult, error) {
+	var wallet withdrawalSender // nil *ton.Wallet в интерфейсе не равен nil
+	if s.wallet != nil {
+		wallet = s.wallet
+	}
+	return refundUnmatchedDeposit(ctx, s.db, wallet, depositID, manualTxHash, adminTgID)
+}
+
+// GetTreasury баланс горячего кошелька, ожидающие выводы по активам и сумма коинов пользователей
+func (s *AdminService) GetTreasury(ctx context.Context) (*TreasurySnapshot, error) {
+	return readTreasury(ctx, s.db, s.wallet)
+}
+
 // представляет статистику платформы
 type Stats struct {
 	TotalUsers       int64 `json:"total_users"`
@@ -47,8 +115,14 @@ type Stats struct {
 	CoinsPurchasedWeek  int64 `json:"coins_purchased_week"`
 	CoinsPurchasedMonth int64 `json:"coins_purchased_month"`
 	CoinsPurchasedTotal int64 `json:"coins_purchased_total"`
+	// рейк PvP: по дням за неделю и по играм за 30 дней
+	RakeByDay  []RakeRevenue `json:"rake_by_day"`
+	RakeByGame []RakeRevenue `json:"rake_by_game"`
 }
 
+// выручка рейка PvP за день или по игре
+type RakeRevenue = repository.RakeRevenue
+
 // возвращает статистику платформы
 func (s *AdminService) GetStats(ctx context.Context) (*Stats, error) {
 	stats := &Stats{}
@@ -56,8 +130,8 @@ func (s *AdminService) GetStats(ctx context.Context) (*Stats, error) {
 	weekAgo := today.Add(-7 * 24 * time.Hour)
 	monthAgo := today.Add(-30 * 24 * time.Hour)
 
-	// общее количество пользователей
-	_ = s.db.QueryRow(ctx, `SELECT COUNT(*) FROM users`).Scan(&stats.TotalUsers)
+	// общее количество пользователей (без счета платформы)
+	_ = s.db.QueryRow(ctx, `SELECT COUNT(*) FROM users WHERE tg_id <> $1`, domain.HouseAccountTgID).Scan(&stats.TotalUsers)
 
 	// активные пользователи сегодня (сыграли хотя бы одну игру)
 	_ = s.db.QueryRow(ctx, `
@@ -78,10 +152,10 @@ func (s *AdminService) GetStats(ctx context.Context) (*Stats, error) {
 	`, today).Scan(&stats.GamesToday)
 
 	// общее количество драгоценных камней в обращении
-	_ = s.db.QueryRow(ctx, `SELECT COALESCE(SUM(gems), 0) FROM users`).Scan(&stats.TotalGems)
+	_ = s.db.QueryRow(ctx, `SELECT COALESCE(SUM(gems), 0) FROM users WHERE tg_id <> $1`, domain.HouseAccountTgID).Scan(&stats.TotalGems)
 
 	// общее количество монет в обращении
-	_ = s.db.QueryRow(ctx, `SELECT COALESCE(SUM(coins), 0) FROM users`).Scan(&stats.TotalCoins)
+	_ = s.db.QueryRow(ctx, `SELECT COALESCE(SUM(coins), 0) FROM users WHERE tg_id <> $1`, domain.HouseAccountTgID).Scan(&stats.TotalCoins)
 
 	// общая сумма ставок (за все время) - только ставки в монетах
 	_ = s.db.QueryRow(ctx, `
@@ -126,6 +200,11 @@ func (s *AdminService) GetStats(ctx context.Context) (*Stats, error) {
 		SELECT COALESCE(SUM(gems_credited), 0) FROM deposits WHERE status = 'confirmed'
 	`).Scan(&stats.CoinsPurchasedTotal)
 
+	// рейк PvP
+	rakeRepo := repository.NewRakeRepository(s.db)
+	stats.RakeByDay, _ = rakeRepo.RevenueByDay(ctx, today.Add(-6*24*time.Hour))
+	stats.RakeByGame, _ = rakeRepo.RevenueByGame(ctx, monthAgo)
+
 	return stats, nil
 }
 
@@ -216,10 +295,10 @@ func (s *AdminService) GetTopUsers(ctx context.Context, limit int) ([]UserInfo,
 	rows, err := s.db.Query(ctx, `
 		SELECT id, tg_id, username, first_name, gems, created_at
 		FROM users
-		WHERE gems >= 0
+		WHERE gems >= 0 AND tg_id <> $2
 		ORDER BY gems DESC
 		LIMIT $1
-	`, limit)
+	`, limit, domain.HouseAccountTgID)
 	if err != nil {
 		return nil, err
 	}
@@ -292,7 +371,7 @@ type PendingWithdrawal struct {
 func (s *AdminService) GetPendingWithdrawals(ctx context.Context) ([]PendingWithdrawal, error) {
 	rows, err := s.db.Query(ctx, `
 		SELECT w.id, w.user_id, COALESCE(u.username, u.first_name, ''), w.wallet_address, w.coins_amount,
-		       w.ton_amount_nano, w.status, w.created_at
+		       w.ton_amount_nano, w.asset, w.status, w.created_at
 		FROM withdrawals w
 		JOIN users u ON u.id = w.user_id
 		WHERE w.status IN ('pending', 'processing')
@@ -307,11 +386,12 @@ func (s *AdminService) GetPendingWithdrawals(ctx context.Context) ([]PendingWith
 	for rows.Next() {
 		var w PendingWithdrawal
 		var tonNano int64
+		var asset string
 		if err := rows.Scan(&w.ID, &w.UserID, &w.Username, &w.WalletAddress,
-			&w.CoinsAmount, &tonNano, &w.Status, &w.CreatedAt); err != nil {
+			&w.CoinsAmount, &tonNano, &asset, &w.Status, &w.CreatedAt); err != nil {
 			continue
 		}
-		w.TonAmount = fmt.Sprintf("%.4f TON", float64(tonNano)/1e9)
+		w.TonAmount = ton.FormatUnits(ton.Asset(asset), tonNano)
 		withdrawals = append(withdrawals, w)
 	}
 	return withdrawals, nil
@@ -319,10 +399,11 @@ func (s *AdminService) GetPendingWithdrawals(ctx context.Context) ([]PendingWith
 
 // ApproveWithdrawalResult результат одобрения вывода
 type ApproveWithdrawalResult struct {
-	TxHash    string
-	AutoSent  bool
-	TonAmount float64
-	UserTgID  int64 // telegram ID пользователя для уведомления
+	TxHash   string
+	AutoSent bool
+	Amount   float64 // в единицах актива
+	Asset    string
+	UserTgID int64 // telegram ID пользователя для уведомления
 }
 
 // одобряет вывод средств и автоматически отправляет TON (если настроен кошелек)
@@ -331,15 +412,15 @@ func (s *AdminService) ApproveWithdrawal(ctx context.Context, id int64, manualTx
 	var walletAddress string
 	var rawAddress *string
 	var tonAmountNano int64
-	var status string
+	var status, assetName string
 	var userTgID int64
 	err := s.db.QueryRow(ctx, `
-		SELECT w.wallet_address, wal.raw_address, w.ton_amount_nano, w.status, u.tg_id
+		SELECT w.wallet_address, wal.raw_address, w.ton_amount_nano, w.status, u.tg_id, w.asset
 		FROM withdrawals w
 		LEFT JOIN users u ON u.id = w.user_id
 		LEFT JOIN wallets wal ON wal.user_id = u.id
 		WHERE w.id = $1
-	`, id).Scan(&walletAddress, &rawAddress, &tonAmountNano, &status, &userTgID)
+	`, id).Scan(&walletAddress, &rawAddress, &tonAmountNano, &status, &userTgID, &assetName)
 	if err != nil {
 		return nil, fmt.Errorf("вывод не найден: %w", err)
 	}
@@ -348,9 +429,12 @@ func (s *AdminService) ApproveWithdrawal(ctx context.Context, id int64, manualTx
 		return nil, fmt.Errorf("вывод уже обработан (статус: %s)", status)
 	}
 
+	// ton_amount_nano хранит сумму в минимальных единицах актива
+	asset := ton.Asset(assetName)
 	result := &ApproveWithdrawalResult{
-		TonAmount: float64(tonAmountNano) / 1e9,
-		UserTgID:  userTgID,
+		Amount:   ton.UnitsToFloat(asset, tonAmountNano),
+		Asset:    assetName,
+		UserTgID: userTgID,
 	}
 
 	// Определяем адрес для отправки: предпочитаем raw_address (формат 0:hex)
@@ -361,10 +445,33 @@ func (s *AdminService) ApproveWithdrawal(ctx context.Context, id int64, manualTx
 
 	// Если есть кошелек - отправляем автоматически
 	if s.wallet != nil && manualTxHash == "" {
-		// Отправляем TON
-		sendResult, err := s.wallet.SendTON(ctx, sendAddress, uint64(tonAmountNano), fmt.Sprintf("Withdrawal #%d", id))
+		// processing - вывод отправляет воркер или отправка не завершилась: повторная отправка
+		// может задвоить перевод, подтверждать только с хэшем после проверки в сети
+		if status == "processing" {
+			return nil, fmt.Errorf("вывод в обработке, проверьте перевод в сети и укажите хэш: /approve %d tx_hash", id)
+		}
+		if !s.wallet.SupportsAsset(asset) {
+			return nil, fmt.Errorf("кошелек не настроен для %s, укажите хэш ручного перевода", asset)
+		}
+		claimed, err := repository.NewWithdrawalRepository(s.db).ClaimPending(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if !claimed {
+			return nil, fmt.Errorf("вывод уже обрабатывается")
+		}
+
+		// Отправляем TON или жетон
+		sendResult, err := s.wallet.SendAsset(ctx, asset, sendAddress, uint64(tonAmountNano), fmt.Sprintf("Withdrawal #%d", id))
+		if errors.Is(err, ton.ErrSendUnconfirmed) {
+			// перевод мог уйти: вывод остается processing, иначе воркер отправит его повторно
+			_, _ = s.db.Exec(ctx, `UPDATE withdrawals SET admin_notes = $2 WHERE id = $1`, id, "approve: "+err.Error())
+			return nil, fmt.Errorf("перевод мог быть отправлен (%v), проверьте перевод в сети и укажите хэш: /approve %d tx_hash", err, id)
+		}
 		if err != nil {
-			return nil, fmt.Errorf("ошибка отправки TON: %w", err)
+			// перевод не отправлялся, вывод снова ждет /approve
+			_, _ = s.db.Exec(ctx, `UPDATE withdrawals SET status = 'pending' WHERE id = $1 AND status = 'processing'`, id)
+			return nil, fmt.Errorf("ошибка отправки %s: %w", asset, err)
 		}
 		result.TxHash = sendResult.TxHash
 		result.AutoSent = true
@@ -411,7 +518,11 @@ func (s *AdminService) RejectWithdrawal(ctx context.Context, id int64, reason st
 		SELECT w.user_id, w.coins_amount, u.tg_id
 		FROM withdrawals w
 		JOIN users u ON u.id = w.user_id
-		WHERE w.id = $1 AND w.status = 'pending'
+		WHERE w.id = $1
+		  AND (w.status = 'pending'
+		       -- зависший processing (отправка не завершилась): отклоняется после проверки в сети
+		       OR (w.status = 'processing' AND w.processed_at < NOW() - INTERVAL '5 minutes'))
+		FOR UPDATE OF w
 	`, id).Scan(&userID, &coinsAmount, &userTgID)
 	if err != nil {
 		return nil, err
@@ -444,7 +555,8 @@ func (s *AdminService) RejectWithdrawal(ctx context.Context, id int64, reason st
 // отправляет сообщение всем пользователям (возвращает количество)
 // примечание: это только сохраняет сообщение - фактическая отправка происходит через бота
 func (s *AdminService) GetAllUserTgIDs(ctx context.Context) ([]int64, error) {
-	query := `SELECT tg_id FROM users WHERE tg_id IS NOT NULL ORDER BY id`
+	// счет платформы (tg_id 0) не получает рассылку
+	query := `SELECT tg_id FROM users WHERE tg_id IS NOT NULL AND tg_id <> 0 ORDER BY id`
 	rows, err := s.db.Query(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка запроса: %w", err)
@@ -719,6 +831,7 @@ type WithdrawalNotification struct {
 	WalletAddress string
 	CoinsAmount   int64
 	TonAmount     float64
+	Asset         string
 }
 
 // возвращает информацию о выводе для административного уведомления
@@ -727,15 +840,15 @@ func (s *AdminService) GetWithdrawalNotification(ctx context.Context, withdrawal
 	var tonNano int64
 	err := s.db.QueryRow(ctx, `
 		SELECT w.id, w.user_id, COALESCE(u.username, u.first_name, ''), u.tg_id,
-		       w.wallet_address, w.coins_amount, w.ton_amount_nano
+		       w.wallet_address, w.coins_amount, w.ton_amount_nano, w.asset
 		FROM withdrawals w
 		JOIN users u ON u.id = w.user_id
 		WHERE w.id = $1
-	`, withdrawalID).Scan(&w.ID, &w.UserID, &w.Username, &w.TgID, &w.WalletAddress, &w.CoinsAmount, &tonNano)
+	`, withdrawalID).Scan(&w.ID, &w.UserID, &w.Username, &w.TgID, &w.WalletAddress, &w.CoinsAmount, &tonNano, &w.Asset)
 	if err != nil {
 		return nil, err
 	}
-	w.TonAmount = float64(tonNano) / 1e9
+	w.TonAmount = ton.UnitsToFloat(ton.Asset(w.Asset), tonNano)
 	return &w, nil
 }
 
@@ -825,6 +938,7 @@ type DepositInfo struct {
 	TgID          int64     `json:"tg_id"`
 	WalletAddress string    `json:"wallet_address"`
 	AmountNano    int64     `json:"amount_nano"`
+	Asset         string    `json:"asset"`
 	CoinsCredited int64     `json:"coins_credited"`
 	TxHash        string    `json:"tx_hash"`
 	Status        string    `json:"status"`
@@ -836,7 +950,7 @@ type DepositInfo struct {
 func (s *AdminService) GetRecentDeposits(ctx context.Context, limit int) ([]DepositInfo, error) {
 	rows, err := s.db.Query(ctx, `
 		SELECT d.id, d.user_id, COALESCE(u.username, u.first_name, ''), u.tg_id,
-		       d.wallet_address, d.amount_nano, d.gems_credited, d.tx_hash, d.status,
+		       d.wallet_address, d.amount_nano, d.asset, d.gems_credited, d.tx_hash, d.status,
 		       COALESCE(d.memo, ''), d.created_at
 		FROM deposits d
 		JOIN users u ON u.id = d.user_id
@@ -852,7 +966,7 @@ func (s *AdminService) GetRecentDeposits(ctx context.Context, limit int) ([]Depo
 	for rows.Next() {
 		var d DepositInfo
 		if err := rows.Scan(&d.ID, &d.UserID, &d.Username, &d.TgID, &d.WalletAddress,
-			&d.AmountNano, &d.CoinsCredited, &d.TxHash, &d.Status, &d.Memo, &d.CreatedAt); err != nil {
+			&d.AmountNano, &d.Asset, &d.CoinsCredited, &d.TxHash, &d.Status, &d.Memo, &d.CreatedAt); err != nil {
 			continue
 		}
 		deposits = append(deposits, d)
@@ -867,49 +981,45 @@ type ManualDepositResult struct {
 }
 
 // создает ручной депозит (для админа)
-// принимает txHash, tgID пользователя и сумму в TON
+// принимает txHash, tgID пользователя и сумму в TON, коины - по действующему курсу
 func (s *AdminService) CreateManualDeposit(ctx context.Context, txHash string, userTgID int64, tonAmount float64) (*ManualDepositResult, error) {
-	// конвертация: 1 TON = 1000 coins
+	pricing, err := s.pricing.Current(ctx)
+	if err != nil {
+		return nil, err
+	}
 	amountNano := int64(tonAmount * 1e9)
-	coinsCredited := int64(tonAmount * 1000)
+	coinsCredited := PricingRates(pricing).UnitsToCoins(ton.AssetTON, amountNano)
 
 	// получаем внутренний ID пользователя по tg_id
 	var userID int64
-	err := s.db.QueryRow(ctx, `SELECT id FROM users WHERE tg_id = $1`, userTgID).Scan(&userID)
+	err = s.db.QueryRow(ctx, `SELECT id FROM users WHERE tg_id = $1`, userTgID).Scan(&userID)
 	if err != nil {
 		return nil, fmt.Errorf("пользователь с TG ID %d не найден", userTgID)
 	}
 
-	tx, err := s.db.Begin(ctx)
-	if err != nil {
-		return nil, err
-	}
-	defer tx.Rollback(ctx)
-
-	// создаем запись депозита
+	// депозит, коины, строка transactions и аудит - одной транзакцией
 	// используем gems_credited т.к. туда сохраняются коины (legacy naming)
-	var depositID int64
-	err = tx.QueryRow(ctx, `
-		INSERT INTO deposits (user_id, wallet_address, amount_nano, gems_credited, exchange_rate, tx_hash, tx_lt, status, memo, processed)
-		VALUES ($1, 'manual_admin', $2, $3, 1000, $4, 0, 'confirmed', 'manual_admin', true)
-		RETURNING id
-	`, userID, amountNano, coinsCredited, txHash).Scan(&depositID)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка создания депозита: %w", err)
+	deposit := &domain.Deposit{
+		UserID:         userID,
+		WalletAddress:  "manual_admin",
+		AmountNano:     amountNano,
+		GemsCredited:   coinsCredited,
+		CoinsCredited:  coinsCredited,
+		ExchangeRate:   int(pricing.CoinsPerTON),
+		PricingVersion: pricing.ID,
+		TxHash:         txHash,
+		Memo:           "manual_admin",
 	}
-
-	// начисляем коины пользователю
-	_, err = tx.Exec(ctx, `UPDATE users SET coins = coins + $1 WHERE id = $2`, coinsCredited, userID)
+	_, credited, err := creditDeposit(ctx, s.db, deposit, map[string]interface{}{"source": "manual_admin"})
 	if err != nil {
-		return nil, fmt.Errorf("ошибка начисления коинов: %w", err)
-	}
-
-	if err := tx.Commit(ctx); err != nil {
 		return nil, err
 	}
+	if !credited {
+		return nil, ErrDepositExists
+	}
 
 	return &ManualDepositResult{
-		ID:            depositID,
+		ID:            deposit.ID,
 		CoinsCredited: coinsCredited,
 	}, nil
 }
@@ -931,7 +1041,7 @@ type WithdrawalHistoryItem struct {
 func (s *AdminService) GetWithdrawalsHistory(ctx context.Context, limit int) ([]WithdrawalHistoryItem, error) {
 	rows, err := s.db.Query(ctx, `
 		SELECT w.id, w.user_id, COALESCE(u.username, u.first_name, ''), w.wallet_address, w.coins_amount,
-		       w.ton_amount_nano, w.status, COALESCE(w.tx_hash, ''), w.created_at
+		       w.ton_amount_nano, w.asset, w.status, COALESCE(w.tx_hash, ''), w.created_at
 		FROM withdrawals w
 		JOIN users u ON u.id = w.user_id
 		ORDER BY w.created_at DESC
@@ -946,11 +1056,12 @@ func (s *AdminService) GetWithdrawalsHistory(ctx context.Context, limit int) ([]
 	for rows.Next() {
 		var w WithdrawalHistoryItem
 		var tonNano int64
+		var asset string
 		if err := rows.Scan(&w.ID, &w.UserID, &w.Username, &w.WalletAddress,
-			&w.CoinsAmount, &tonNano, &w.Status, &w.TxHash, &w.CreatedAt); err != nil {
+			&w.CoinsAmount, &tonNano, &asset, &w.Status, &w.TxHash, &w.CreatedAt); err != nil {
 			continue
 		}
-		w.TonAmount = fmt.Sprintf("%.4f TON", float64(tonNano)/1e9)
+		w.TonAmount = ton.FormatUnits(ton.Asset(asset), tonNano)
 		withdrawals = append(withdrawals, w)
 	}
 	return withdrawals, nil
@@ -963,6 +1074,7 @@ type DepositHistoryItem struct {
 	Username      string    `json:"username"`
 	TgID          int64     `json:"tg_id"`
 	AmountNano    int64     `json:"amount_nano"`
+	Asset         string    `json:"asset"`
 	CoinsCredited int64     `json:"coins_credited"`
 	Status        string    `json:"status"`
 	TxHash        string    `json:"tx_hash"`
@@ -973,7 +1085,7 @@ type DepositHistoryItem struct {
 func (s *AdminService) GetDepositsHistory(ctx context.Context, limit int) ([]DepositHistoryItem, error) {
 	rows, err := s.db.Query(ctx, `
 		SELECT d.id, d.user_id, COALESCE(u.username, u.first_name, ''), u.tg_id,
-		       d.amount_nano, d.gems_credited, d.status, d.tx_hash, d.created_at
+		       d.amount_nano, d.asset, d.gems_credited, d.status, d.tx_hash, d.created_at
 		FROM deposits d
 		JOIN users u ON u.id = d.user_id
 		ORDER BY d.created_at DESC
@@ -988,10 +1100,239 @@ func (s *AdminService) GetDepositsHistory(ctx context.Context, limit int) ([]Dep
 	for rows.Next() {
 		var d DepositHistoryItem
 		if err := rows.Scan(&d.ID, &d.UserID, &d.Username, &d.TgID,
-			&d.AmountNano, &d.CoinsCredited, &d.Status, &d.TxHash, &d.CreatedAt); err != nil {
+			&d.AmountNano, &d.Asset, &d.CoinsCredited, &d.Status, &d.TxHash, &d.CreatedAt); err != nil {
 			continue
 		}
 		deposits = append(deposits, d)
 	}
 	return deposits, nil
-}
\ No newline at end of file
+}
+// ChatReportInfo жалоба на сообщения в PvP чате
+type ChatReportInfo struct {
+	ID               int64
+	RoomID           string
+	ReporterID       int64
+	ReporterUsername string
+	ReportedID       int64
+	ReportedUsername string
+	ReportedTgID     int64
+	Reason           string
+	Status           string
+	CreatedAt        time.Time
+}
+
+// ChatLogLine сообщение чата для разбора жалобы
+type ChatLogLine struct {
+	SenderID  int64
+	Kind      string
+	Content   string
+	Filtered  bool
+	Delivered bool
+	CreatedAt time.Time
+}
+
+const chatReportSelect = `
+	SELECT r.id, r.room_id, r.reporter_id, COALESCE(ru.username, ru.first_name, ''),
+	       r.reported_id, COALESCE(du.username, du.first_name, ''), du.tg_id,
+	       r.reason, r.status, r.created_at
+	FROM pvp_chat_reports r
+	JOIN users ru ON ru.id = r.reporter_id
+	JOIN users du ON du.id = r.reported_id
+`
+
+func scanChatReport(row interface{ Scan(dest ...any) error }) (ChatReportInfo, error) {
+	var r ChatReportInfo
+	err := row.Scan(&r.ID, &r.RoomID, &r.ReporterID, &r.ReporterUsername,
+		&r.ReportedID, &r.ReportedUsername, &r.ReportedTgID,
+		&r.Reason, &r.Status, &r.CreatedAt)
+	return r, err
+}
+
+// возвращает открытые жалобы на чат, старые первыми
+func (s *AdminService) GetOpenChatReports(ctx context.Context, limit int) ([]ChatReportInfo, error) {
+	rows, err := s.db.Query(ctx, chatReportSelect+`
+		WHERE r.status = 'open'
+		ORDER BY r.created_at ASC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reports []ChatReportInfo
+	for rows.Next() {
+		r, err := scanChatReport(rows)
+		if err != nil {
+			continue
+		}
+		reports = append(reports, r)
+	}
+	return reports, nil
+}
+
+// возвращает жалобу и переписку двух игроков в комнате за сутки до нее
+func (s *AdminService) GetChatReportLog(ctx context.Context, reportID int64, limit int) (*ChatReportInfo, []ChatLogLine, error) {
+	report, err := scanChatReport(s.db.QueryRow(ctx, chatReportSelect+`WHERE r.id = $1`, reportID))
+	if err != nil {
+		return nil, nil, fmt.Errorf("жалоба не найдена")
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT sender_id, kind, content, filtered, delivered, created_at
+		FROM (
+			SELECT sender_id, kind, content, filtered, delivered, created_at
+			FROM pvp_chat_messages
+			WHERE room_id = $1
+			  AND sender_id IN ($2, $3)
+			  AND created_at <= $4
+			  AND created_at > $4 - INTERVAL '1 day'
+			ORDER BY created_at DESC
+			LIMIT $5
+		) m
+		ORDER BY created_at ASC
+	`, report.RoomID, report.ReporterID, report.ReportedID, report.CreatedAt, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var lines []ChatLogLine
+	for rows.Next() {
+		var l ChatLogLine
+		if err := rows.Scan(&l.SenderID, &l.Kind, &l.Content, &l.Filtered, &l.Delivered, &l.CreatedAt); err != nil {
+			continue
+		}
+		lines = append(lines, l)
+	}
+	return &report, lines, nil
+}
+
+// закрывает жалобу на чат
+func (s *AdminService) ResolveChatReport(ctx context.Context, reportID int64, adminTgID int64) error {
+	tag, err := s.db.Exec(ctx, `
+		UPDATE pvp_chat_reports
+		SET status = 'reviewed', reviewed_by = $2, reviewed_at = NOW()
+		WHERE id = $1 AND status = 'open'
+	`, reportID, adminTgID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("жалоба не найдена или уже разобрана")
+	}
+	return nil
+}
+
+// создает турнир от имени админа
+func (s *AdminService) CreateTournament(ctx context.Context, p CreateTournamentParams) (*domain.Tournament, error) {
+	return s.tournaments.Create(ctx, p)
+}
+
+// отменяет турнир с возвратом взносов, возвращает число возвратов
+func (s *AdminService) CancelTournament(ctx context.Context, id int64) (*domain.Tournament, int, error) {
+	return s.tournaments.Cancel(ctx, id)
+}
+
+// турниры с открытой регистрацией и идущие
+func (s *AdminService) GetActiveTournaments(ctx context.Context) ([]domain.Tournament, error) {
+	return s.tournaments.ListActive(ctx, 20)
+}
+
+// запись PvP матча для разбора спора
+func (s *AdminService) GetMatchReplay(ctx context.Context, roomID string) (*domain.MatchReplay, error) {
+	rep, err := repository.NewReplayRepository(s.db).Get(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if rep == nil {
+		return nil, fmt.Errorf("запись матча не найдена")
+	}
+	return rep, nil
+}
+
+// CollusionFlagInfo подозрение на сговор с именами игроков
+type CollusionFlagInfo struct {
+	*domain.CollusionFlag
+	UsernameA string
+	UsernameB string
+	TgIDA     int64
+	TgIDB     int64
+}
+
+// подставляет имена и tg id игроков пары
+func (s *AdminService) collusionInfo(ctx context.Context, f *domain.CollusionFlag) CollusionFlagInfo {
+	info := CollusionFlagInfo{CollusionFlag: f}
+	_ = s.db.QueryRow(ctx, `
+		SELECT COALESCE(a.username, a.first_name, ''), a.tg_id, COALESCE(b.username, b.first_name, ''), b.tg_id
+		FROM users a, users b
+		WHERE a.id = $1 AND b.id = $2
+	`, f.UserA, f.UserB).Scan(&info.UsernameA, &info.TgIDA, &info.UsernameB, &info.TgIDB)
+	return info
+}
+
+// открытые подозрения на сговор, самые сильные первыми
+func (s *AdminService) GetOpenCollusionFlags(ctx context.Context, limit int) ([]CollusionFlagInfo, error) {
+	flags, err := s.collusion.ListOpen(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]CollusionFlagInfo, 0, len(flags))
+	for _, f := range flags {
+		infos = append(infos, s.collusionInfo(ctx, f))
+	}
+	return infos, nil
+}
+
+// подозрение на сговор по id
+func (s *AdminService) GetCollusionFlag(ctx context.Context, id int64) (*CollusionFlagInfo, error) {
+	f, err := s.collusion.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if f == nil {
+		return nil, fmt.Errorf("подозрение не найдено")
+	}
+	info := s.collusionInfo(ctx, f)
+	return &info, nil
+}
+
+// закрывает подозрение решением админа (dismiss | confirm)
+func (s *AdminService) ResolveCollusionFlag(ctx context.Context, id int64, decision string, adminTgID int64, note string) (*domain.CollusionFlag, error) {
+	return s.collusion.Resolve(ctx, id, decision, adminTgID, note)
+}
+
+// внеочередной анализ пар, возвращает число новых подозрений
+func (s *AdminService) ScanCollusion(ctx context.Context) (int, error) {
+	return s.collusion.Scan(ctx)
+}
+
+// уровни ставок PvP игр с таймерами, рейком и лимитом раундов
+func (s *AdminService) GetPvPConfigs(ctx context.Context) ([]*domain.PvPGameConfig, error) {
+	return s.pvpConfigs.List(ctx)
+}
+
+// создает или меняет уровень ставки PvP игры
+func (s *AdminService) SetPvPConfig(ctx context.Context, gameType domain.GameType, minStake int64, params map[string]string, adminTgID int64) (*domain.PvPGameConfig, error) {
+	return s.pvpConfigs.Set(ctx, gameType, minStake, params, adminTgID)
+}
+
+// удаляет уровень ставки PvP игры
+func (s *AdminService) DeletePvPConfig(ctx context.Context, gameType domain.GameType, minStake int64) error {
+	return s.pvpConfigs.Delete(ctx, gameType, minStake)
+}
+
+// действующий курс коинов и комиссия на вывод
+func (s *AdminService) GetPricing(ctx context.Context) (*domain.PricingConfig, error) {
+	return s.pricing.Current(ctx)
+}
+
+// последние версии курса, включая запланированные
+func (s *AdminService) GetPricingHistory(ctx context.Context, limit int) ([]*domain.PricingConfig, error) {
+	return s.pricing.History(ctx, limit)
+}
+
+// добавляет версию курса и комиссии на вывод
+func (s *AdminService) SetPricing(ctx context.Context, params map[string]string, adminTgID int64) (*domain.PricingConfig, error) {
+	return s.pricing.Set(ctx, params, adminTgID)
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/logger"
+	"telegram_webapp/internal/repository"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrChallengeNotFound   = errors.New("вызов не найден")
+	ErrChallengeClosed     = errors.New("вызов уже закрыт")
+	ErrChallengeNotCreator = errors.New("только создатель может отменить вызов")
+	ErrChallengeInvalid    = errors.New("неверные параметры вызова")
+)
+
+// создает, отменяет и истекает приватные PvP вызовы
+// ставка создателя списывается при создании и возвращается, если соперник так и не пришел
+type ChallengeService struct {
+	db   *pgxpool.Pool
+	repo *repository.ChallengeRepository
+	ttl  time.Duration
+}
+
+// создает новый сервис вызовов
+func NewChallengeService(db *pgxpool.Pool) *ChallengeService {
+	return &ChallengeService{
+		db:   db,
+		repo: repository.NewChallengeRepository(db),
+		ttl:  domain.ChallengeTTL,
+	}
+}
+
+// параметры нового вызова
+type CreateChallengeParams struct {
+	GameType  domain.GameType
+	BetAmount int64
+	Currency  string
+	InviteeID *int64
+}
+
+// создает вызов и резервирует ставку создателя в одной транзакции
+func (s *ChallengeService) Create(ctx context.Context, creatorID int64, p CreateChallengeParams) (*domain.Challenge, error) {
+	if p.GameType != domain.GameTypeRPS && p.GameType != domain.GameTypeMines {
+		return nil, ErrChallengeInvalid
+	}
+	if p.Currency != string(domain.CurrencyGems) && p.Currency != string(domain.CurrencyCoins) {
+		return nil, ErrChallengeInvalid
+	}
+	if p.BetAmount < 0 {
+		return nil, ErrInvalidAmount
+	}
+	if p.InviteeID != nil && *p.InviteeID == creatorID {
+		return nil, ErrChallengeInvalid
+	}
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if p.BetAmount > 0 {
+		if err := debitWithTx(ctx, tx, creatorID, p.Currency, p.BetAmount); err != nil {
+			return nil, err
+		}
+	}
+
+	ch := &domain.Challenge{
+		Code:      repository.GenerateChallengeCode(),
+		CreatorID: creatorID,
+		InviteeID: p.InviteeID,
+		GameType:  p.GameType,
+		BetAmount: p.BetAmount,
+		Currency:  p.Currency,
+		Status:    domain.ChallengeStatusPending,
+		ExpiresAt: time.Now().Add(s.ttl),
+	}
+	if err := s.repo.CreateWithTx(ctx, tx, ch); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return ch, nil
+}
+
+// возвращает вызов по коду
+func (s *ChallengeService) Get(ctx context.Context, code string) (*domain.Challenge, error) {
+	ch, err := s.repo.GetByCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if ch == nil {
+		return nil, ErrChallengeNotFound
+	}
+	return ch, nil
+}
+
+// отменяет открытый вызов и возвращает ставку создателю
+func (s *ChallengeService) Cancel(ctx context.Context, code string, userID int64) (*domain.Challenge, error) {
+	ch, err := s.Get(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if ch.CreatorID != userID {
+		return nil, ErrChallengeNotCreator
+	}
+	closed, err := s.close(ctx, ch.ID, domain.ChallengeStatusCancelled)
+	if err != nil {
+		return nil, err
+	}
+	if closed == nil {
+		return nil, ErrChallengeClosed
+	}
+	return closed, nil
+}
+
+// истекает просроченные вызовы с возвратом ставок, возвращает количество
+func (s *ChallengeService) ExpireDue(ctx context.Context) (int, error) {
+	ids, err := s.repo.GetExpiredIDs(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, id := range ids {
+		ch, err := s.close(ctx, id, domain.ChallengeStatusExpired)
+		if err != nil {
+			logger.Error("ChallengeService.ExpireDue: не удалось закрыть вызов", "error", err, "challenge_id", id)
+			continue
+		}
+		if ch != nil {
+			expired++
+			logger.Info("ChallengeService.ExpireDue: вызов истек, ставка возвращена",
+				"code", ch.Code, "creator_id", ch.CreatorID, "bet", ch.BetAmount, "currency", ch.Currency)
+		}
+	}
+	return expired, nil
+}
+
+// периодически истекает просроченные вызовы
+func (s *ChallengeService) StartExpiryWorker(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			if _, err := s.ExpireDue(ctx); err != nil {
+				logger.Error("ChallengeService: ошибка истечения вызовов", "error", err)
+			}
+			cancel()
+		}
+	}()
+}
+
+// закрывает pending вызов и возвращает ставку создателю атомарно
+// возвращает nil, если вызов уже не pending (стартовал или закрыт другим процессом)
+func (s *ChallengeService) close(ctx context.Context, id int64, status domain.ChallengeStatus) (*domain.Challenge, error) {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	ch, err := s.repo.CloseWithTx(ctx, tx, id, status)
+	if err != nil || ch == nil {
+		return nil, err
+	}
+
+	if ch.BetAmount > 0 {
+		if err := creditWithTx(ctx, tx, ch.CreatorID, ch.Currency, ch.BetAmount); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return ch, nil
+}
+
+// списывает ставку в нужной валюте внутри транзакции
+func debitWithTx(ctx context.Context, tx pgx.Tx, userID int64, currency string, amount int64) error {
+	query := `UPDATE users SET gems = gems - $1 WHERE id = $2 AND gems >= $1`
+	if currency == string(domain.CurrencyCoins) {
+		query = `UPDATE users SET coins = coins - $1 WHERE id = $2 AND coins >= $1`
+	}
+	tag, err := tx.Exec(ctx, query, amount, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrInsufficientFunds
+	}
+	return nil
+}
+
+// начисляет сумму в нужной валюте внутри транзакции
+func creditWithTx(ctx context.Context, tx pgx.Tx, userID int64, currency string, amount int64) error {
+	query := `UPDATE users SET gems = gems + $1 WHERE id = $2`
+	if currency == string(domain.CurrencyCoins) {
+		query = `UPDATE users SET coins = coins + $1 WHERE id = $2`
+	}
+	_, err := tx.Exec(ctx, query, amount, userID)
+	return err
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/logger"
+	"telegram_webapp/internal/repository"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrCollusionFlagNotFound = errors.New("подозрение не найдено или уже разобрано")
+	ErrCollusionDecision     = errors.New("решение: dismiss или confirm")
+)
+
+// пороги детектора сговора
+type CollusionConfig struct {
+	Window        time.Duration // окно анализа game_history
+	MinGames      int           // пары с меньшим числом матчей не анализируются
+	RepeatGames   int           // repeated_pairing: матчей пары за окно
+	OneSidedRatio float64       // one_sided: доля побед одного среди решенных партий
+	ChipFlowMin   int64         // chip_flow: чистый перелив от суммы
+	RapidGapSec   float64       // rapid_rematch: средняя пауза между матчами меньше
+	FlagScore     int           // пара попадает в очередь при таком score
+}
+
+func DefaultCollusionConfig() CollusionConfig {
+	return CollusionConfig{
+		Window:        24 * time.Hour,
+		MinGames:      5,
+		RepeatGames:   10,
+		OneSidedRatio: 0.8,
+		ChipFlowMin:   1000,
+		RapidGapSec:   90,
+		FlagScore:     4,
+	}
+}
+
+// веса признаков: общий кошелек почти наверняка один владелец, остальное - косвенные улики
+var collusionWeights = map[string]int{
+	domain.CollusionSignalRepeatedPairing: 2,
+	domain.CollusionSignalOneSided:        2,
+	domain.CollusionSignalChipFlow:        2,
+	domain.CollusionSignalSameStake:       1,
+	domain.CollusionSignalRapidRematch:    1,
+	domain.CollusionSignalSharedWallet:    3,
+	domain.CollusionSignalReferral:        1,
+}
+
+// ищет пары игроков, переливающие ставки друг другу, и ставит их в очередь разбора
+type CollusionService struct {
+	repo *repository.CollusionRepository
+	cfg  CollusionConfig
+}
+
+func NewCollusionService(db *pgxpool.Pool, cfg CollusionConfig) *CollusionService {
+	d := DefaultCollusionConfig()
+	if cfg.Window <= 0 {
+		cfg.Window = d.Window
+	}
+	if cfg.MinGames <= 0 {
+		cfg.MinGames = d.MinGames
+	}
+	if cfg.RepeatGames <= 0 {
+		cfg.RepeatGames = d.RepeatGames
+	}
+	if cfg.OneSidedRatio <= 0 {
+		cfg.OneSidedRatio = d.OneSidedRatio
+	}
+	if cfg.ChipFlowMin <= 0 {
+		cfg.ChipFlowMin = d.ChipFlowMin
+	}
+	if cfg.RapidGapSec <= 0 {
+		cfg.RapidGapSec = d.RapidGapSec
+	}
+	if cfg.FlagScore <= 0 {
+		cfg.FlagScore = d.FlagScore
+	}
+	return &CollusionService{
+		repo: repository.NewCollusionRepository(db),
+		cfg:  cfg,
+	}
+}
+
+// evaluatePair считает score пары по статистике матчей и связям аккаунтов
+func evaluatePair(s repository.PairStats, sharedWallet, referral bool, cfg CollusionConfig) (int, []string) {
+	var signals []string
+
+	if s.Games >= cfg.RepeatGames {
+		signals = append(signals, domain.CollusionSignalRepeatedPairing)
+	}
+
+	decided := s.WinsA + s.WinsB
+	if decided >= cfg.MinGames {
+		top := s.WinsA
+		if s.WinsB > top {
+			top = s.WinsB
+		}
+		if float64(top)/float64(decided) >= cfg.OneSidedRatio {
+			signals = append(signals, domain.CollusionSignalOneSided)
+		}
+	}
+
+	net := s.NetA
+	if net < 0 {
+		net = -net
+	}
+	if net >= cfg.ChipFlowMin {
+		signals = append(signals, domain.CollusionSignalChipFlow)
+	}
+
+	if s.DistinctStakes == 1 && s.Games >= cfg.MinGames {
+		signals = append(signals, domain.CollusionSignalSameStake)
+	}
+
+	// пауза считается только между матчами пары, нужна хотя бы пара интервалов
+	if s.Games >= 3 && s.AvgGapSec > 0 && s.AvgGapSec < cfg.RapidGapSec {
+		signals = append(signals, domain.CollusionSignalRapidRematch)
+	}
+
+	if sharedWallet {
+		signals = append(signals, domain.CollusionSignalSharedWallet)
+	}
+	if referral {
+		signals = append(signals, domain.CollusionSignalReferral)
+	}
+
+	score := 0
+	for _, sig := range signals {
+		score += collusionWeights[sig]
+	}
+	return score, signals
+}
+
+// Scan анализирует PvP матчи за окно и открывает/обновляет подозрения
+// возвращает число новых подозрений
+func (s *CollusionService) Scan(ctx context.Context) (int, error) {
+	since := time.Now().Add(-s.cfg.Window)
+	pairs, err := s.repo.PairStats(ctx, since, s.cfg.MinGames)
+	if err != nil {
+		return 0, err
+	}
+
+	flagged := 0
+	for _, p := range pairs {
+		// админ уже разобрал пару в этом окне - не поднимаем снова
+		reviewed, err := s.repo.ReviewedSince(ctx, p.UserA, p.UserB, since)
+		if err != nil {
+			return flagged, err
+		}
+		if reviewed {
+			continue
+		}
+
+		shared, err := s.repo.SharedWallet(ctx, p.UserA, p.UserB)
+		if err != nil {
+			return flagged, err
+		}
+		referral, err := s.repo.ReferralLinked(ctx, p.UserA, p.UserB)
+		if err != nil {
+			return flagged, err
+		}
+
+		score, signals := evaluatePair(p, shared, referral, s.cfg)
+		if score < s.cfg.FlagScore {
+			continue
+		}
+
+		first, last := p.FirstGameAt, p.LastGameAt
+		f := &domain.CollusionFlag{
+			UserA:       p.UserA,
+			UserB:       p.UserB,
+			Score:       score,
+			Signals:     signals,
+			Games:       p.Games,
+			WinsA:       p.WinsA,
+			WinsB:       p.WinsB,
+			Volume:      p.Volume,
+			NetA:        p.NetA,
+			FirstGameAt: &first,
+			LastGameAt:  &last,
+		}
+		created, err := s.repo.UpsertOpen(ctx, f)
+		if err != nil {
+			return flagged, err
+		}
+		if created {
+			flagged++
+			logger.Info("CollusionService.Scan: пара поставлена в очередь проверки",
+				"flag_id", f.ID, "user_a", p.UserA, "user_b", p.UserB, "score", score, "signals", signals)
+		}
+	}
+	return flagged, nil
+}
+
+// пары, которым запрещено встречаться в матчмейкинге (открытые и подтвержденные подозрения)
+func (s *CollusionService) BlockedPairs(ctx context.Context) ([][2]int64, error) {
+	return s.repo.BlockedPairs(ctx)
+}
+
+// открытые подозрения для админ-бота
+func (s *CollusionService) ListOpen(ctx context.Context, limit int) ([]*domain.CollusionFlag, error) {
+	return s.repo.ListOpen(ctx, limit)
+}
+
+func (s *CollusionService) Get(ctx context.Context, id int64) (*domain.CollusionFlag, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// Resolve закрывает подозрение: dismiss - ложное срабатывание, confirm - пара больше не матчится
+func (s *CollusionService) Resolve(ctx context.Context, id int64, decision string, adminTgID int64, note string) (*domain.CollusionFlag, error) {
+	var status domain.CollusionFlagStatus
+	switch decision {
+	case "dismiss":
+		status = domain.CollusionFlagDismissed
+	case "confirm":
+		status = domain.CollusionFlagConfirmed
+	default:
+		return nil, ErrCollusionDecision
+	}
+
+	f, err := s.repo.Resolve(ctx, id, status, adminTgID, note)
+	if err != nil {
+		return nil, err
+	}
+	if f == nil {
+		return nil, ErrCollusionFlagNotFound
+	}
+	return f, nil
+}
+
+// StartWorker периодически анализирует матчи и отдает список заблокированных пар (onBlocked может быть nil)
+func (s *CollusionService) StartWorker(interval time.Duration, onBlocked func([][2]int64)) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			if _, err := s.Scan(ctx); err != nil {
+				logger.Error("CollusionService: ошибка анализа пар", "error", err)
+			}
+			if onBlocked != nil {
+				if pairs, err := s.BlockedPairs(ctx); err != nil {
+					logger.Error("CollusionService: не удалось получить заблокированные пары", "error", err)
+				} else {
+					onBlocked(pairs)
+				}
+			}
+			cancel()
+		}
+	}()
+}
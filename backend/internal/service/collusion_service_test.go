@@ -0,0 +1,48 @@
+package service
+
+import (
+	"testing"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/repository"
+)
+
+func hasSignal(signals []string, sig string) bool {
+	for _, s := range signals {
+		if s == sig {
+			return true
+		}
+	}
+	return false
+}
+
+func TestEvaluatePair(t *testing.T) {
+	cfg := DefaultCollusionConfig()
+
+	// обычная пара: несколько матчей, победы поровну, разные ставки
+	fair := repository.PairStats{Games: 6, WinsA: 3, WinsB: 3, NetA: 0, DistinctStakes: 3, AvgGapSec: 600}
+	if score, signals := evaluatePair(fair, false, false, cfg); score >= cfg.FlagScore {
+		t.Fatalf("честная пара помечена: score %d, %v", score, signals)
+	}
+
+	// перелив: один всегда проигрывает одну и ту же ставку подряд
+	dump := repository.PairStats{Games: 12, WinsA: 0, WinsB: 12, NetA: -6000, DistinctStakes: 1, AvgGapSec: 30}
+	score, signals := evaluatePair(dump, false, false, cfg)
+	if score < cfg.FlagScore {
+		t.Fatalf("перелив не помечен: score %d", score)
+	}
+	for _, sig := range []string{
+		domain.CollusionSignalRepeatedPairing, domain.CollusionSignalOneSided, domain.CollusionSignalChipFlow,
+		domain.CollusionSignalSameStake, domain.CollusionSignalRapidRematch,
+	} {
+		if !hasSignal(signals, sig) {
+			t.Fatalf("нет признака %s: %v", sig, signals)
+		}
+	}
+
+	// общий кошелек + реферал добивают пару до порога
+	_, signals = evaluatePair(fair, true, true, cfg)
+	if !hasSignal(signals, domain.CollusionSignalSharedWallet) || !hasSignal(signals, domain.CollusionSignalReferral) {
+		t.Fatalf("нет связей аккаунтов: %v", signals)
+	}
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/repository"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// алфавит Crockford base32: без I, L, O, U, чтобы код не путали при ручном вводе
+const depositCodeAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+const (
+	depositCodeBody     = 9                // случайных символов, последний символ кода - контрольный
+	depositCodeAttempts = 5                // попыток сгенерировать незанятый код
+	DepositIntentTTL    = 30 * time.Minute // срок кода под одно пополнение
+)
+
+// причины, по которым memo не сопоставлено с пользователем (unmatched_reason)
+const (
+	MemoUnknownCode = "unknown_code" // код корректный, но не выдавался
+	MemoExpiredCode = "expired_code" // срок кода пополнения истек
+	MemoBadChecksum = "bad_checksum" // похоже на код, но контрольный символ не сходится (опечатка)
+	MemoNotACode    = "not_a_code"   // произвольный комментарий
+	MemoEmpty       = "no_memo"      // без комментария, кошелек отправителя не привязан
+)
+
+var ErrDepositCodeExhausted = errors.New("не удалось сгенерировать уникальный код депозита")
+
+// depositCodeChecksum контрольный символ Luhn mod 32: ловит одиночную опечатку и перестановку соседних символов
+// (кроме пары 0 и Z)
+func depositCodeChecksum(body string) byte {
+	const n = len(depositCodeAlphabet)
+	factor, sum := 2, 0
+	for i := len(body) - 1; i >= 0; i-- {
+		addend := factor * strings.IndexByte(depositCodeAlphabet, body[i])
+		sum += addend/n + addend%n
+		factor = 3 - factor
+	}
+	return depositCodeAlphabet[(n-sum%n)%n]
+}
+
+// newDepositCode случайный код с контрольным символом
+func newDepositCode() (string, error) {
+	buf := make([]byte, depositCodeBody)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	for i, b := range buf {
+		buf[i] = depositCodeAlphabet[int(b)%len(depositCodeAlphabet)]
+	}
+	body := string(buf)
+	return body + string(depositCodeChecksum(body)), nil
+}
+
+// normalizeDepositCode приводит memo к виду кода: верхний регистр без пробелов и дефисов,
+// O -> 0, I/L -> 1; ok=false - memo не похоже на код
+func normalizeDepositCode(memo string) (string, bool) {
+	var sb strings.Builder
+	for _, r := range strings.ToUpper(strings.TrimSpace(memo)) {
+		switch r {
+		case ' ', '-':
+			continue
+		case 'O':
+			r = '0'
+		case 'I', 'L':
+			r = '1'
+		}
+		if r > 127 || strings.IndexByte(depositCodeAlphabet, byte(r)) < 0 {
+			return "", false
+		}
+		sb.WriteRune(r)
+	}
+	code := sb.String()
+	return code, len(code) == depositCodeBody+1
+}
+
+// validDepositCode контрольный символ сходится
+func validDepositCode(code string) bool {
+	return len(code) == depositCodeBody+1 && depositCodeChecksum(code[:depositCodeBody]) == code[depositCodeBody]
+}
+
+// DepositCodeService выдает коды депозитов и сопоставляет memo с пользователем
+type DepositCodeService struct {
+	repo *repository.DepositCodeRepository
+}
+
+func NewDepositCodeService(db *pgxpool.Pool) *DepositCodeService {
+	return &DepositCodeService{repo: repository.NewDepositCodeRepository(db)}
+}
+
+// UserCode постоянный код пользователя, выдается при первом запросе
+func (s *DepositCodeService) UserCode(ctx context.Context, userID int64) (*domain.DepositCode, error) {
+	for i := 0; i < depositCodeAttempts; i++ {
+		existing, err := s.repo.GetPermanent(ctx, userID)
+		if err != nil || existing != nil {
+			return existing, err
+		}
+		code, err := newDepositCode()
+		if err != nil {
+			return nil, err
+		}
+		c := &domain.DepositCode{UserID: userID, Code: code}
+		created, err := s.repo.Create(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		// при конфликте: код занят или код выдан параллельным запросом - перечитываем
+		if created {
+			return c, nil
+		}
+	}
+	return nil, ErrDepositCodeExhausted
+}
+
+// IntentCode код под одно пополнение, действует DepositIntentTTL
+func (s *DepositCodeService) IntentCode(ctx context.Context, userID int64) (*domain.DepositCode, error) {
+	expiresAt := time.Now().Add(DepositIntentTTL)
+	for i := 0; i < depositCodeAttempts; i++ {
+		code, err := newDepositCode()
+		if err != nil {
+			return nil, err
+		}
+		c := &domain.DepositCode{UserID: userID, Code: code, ExpiresAt: &expiresAt}
+		created, err := s.repo.Create(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		if created {
+			return c, nil
+		}
+	}
+	return nil, ErrDepositCodeExhausted
+}
+
+// Resolve код депозита по memo; если memo не сопоставлено - nil и причина (MemoUnknownCode и т.д.)
+func (s *DepositCodeService) Resolve(ctx context.Context, memo string, at time.Time) (*domain.DepositCode, string, error) {
+	code, ok := normalizeDepositCode(memo)
+	if !ok {
+		return nil, MemoNotACode, nil
+	}
+	if !validDepositCode(code) {
+		return nil, MemoBadChecksum, nil
+	}
+
+	c, err := s.repo.GetByCode(ctx, code)
+	if err != nil {
+		return nil, "", err
+	}
+	if c == nil {
+		return nil, MemoUnknownCode, nil
+	}
+	if c.ExpiresAt != nil && at.After(*c.ExpiresAt) {
+		return nil, MemoExpiredCode, nil
+	}
+	return c, "", nil
+}
+
+// MarkUsed отмечает первый депозит по коду
+func (s *DepositCodeService) MarkUsed(ctx context.Context, c *domain.DepositCode) error {
+	if c.UsedAt != nil {
+		return nil
+	}
+	return s.repo.MarkUsed(ctx, c.ID)
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDepositCode(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		code, err := newDepositCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(code) != depositCodeBody+1 || !validDepositCode(code) {
+			t.Fatalf("код %q не проходит проверку", code)
+		}
+
+		// пользователь ввел код строчными буквами с дефисом
+		typed := strings.ToLower(code[:5] + "-" + code[5:])
+		normalized, ok := normalizeDepositCode(typed)
+		if !ok || normalized != code {
+			t.Fatalf("%q нормализован в %q, ожидался %q", typed, normalized, code)
+		}
+
+		// одиночная опечатка и перестановка соседних символов ловятся контрольным символом
+		pos := i % depositCodeBody
+		idx := strings.IndexByte(depositCodeAlphabet, code[pos])
+		typo := code[:pos] + string(depositCodeAlphabet[(idx+1)%len(depositCodeAlphabet)]) + code[pos+1:]
+		if validDepositCode(typo) {
+			t.Fatalf("опечатка %q в коде %q не обнаружена", typo, code)
+		}
+		// Luhn mod 32, как и mod 10, не видит перестановку пары 0 и Z
+		if pair := code[pos : pos+2]; pair[0] != pair[1] && pair != "0Z" && pair != "Z0" {
+			swapped := code[:pos] + string(code[pos+1]) + string(code[pos]) + code[pos+2:]
+			if validDepositCode(swapped) {
+				t.Fatalf("перестановка %q в коде %q не обнаружена", swapped, code)
+			}
+		}
+	}
+
+	for _, memo := range []string{"", "thanks", "deposit_123", "123", "ABCDEFGHJKMN"} {
+		if _, ok := normalizeDepositCode(memo); ok {
+			t.Fatalf("%q принят за код депозита", memo)
+		}
+	}
+}
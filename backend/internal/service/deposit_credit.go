@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/repository"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	depositReconcileInterval = time.Hour      // период фоновой сверки зачислений
+	depositReconcileWindow   = 24 * time.Hour // за сколько последних часов сверять
+	depositReconcileLimit    = 100            // депозитов в одном отчете
+)
+
+// creditDeposit одной транзакцией БД создает подтвержденный депозит, начисляет коины,
+// пишет строку ton_deposit в transactions и аудит. Если депозит с таким tx_hash уже есть,
+// ничего не меняет и возвращает false. При ошибке не остается ни депозита, ни коинов,
+// поэтому транзакцию можно обработать повторно
+func creditDeposit(ctx context.Context, db *pgxpool.Pool, d *domain.Deposit, auditDetails map[string]interface{}) (newBalance int64, credited bool, err error) {
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return 0, false, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	now := time.Now()
+	d.Status = domain.DepositStatusConfirmed
+	d.Processed = true
+	d.ConfirmedAt = &now
+
+	created, err := repository.NewDepositRepository(db).CreateWithTx(ctx, tx, d)
+	if err != nil {
+		return 0, false, fmt.Errorf("ошибка создания депозита: %w", err)
+	}
+	if !created {
+		return 0, false, nil // уже обработано
+	}
+
+	newBalance, err = applyDepositCreditWithTx(ctx, tx, db, d, domain.AuditActionDeposit, auditDetails)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, false, err
+	}
+	return newBalance, true, nil
+}
+
+// applyDepositCreditWithTx начисляет коины депозита d пользователю d.UserID, пишет строку ton_deposit
+// в transactions и аудит с действием action внутри транзакции tx
+func applyDepositCreditWithTx(ctx context.Context, tx pgx.Tx, db *pgxpool.Pool, d *domain.Deposit, action string, auditDetails map[string]interface{}) (newBalance int64, err error) {
+	err = tx.QueryRow(ctx,
+		`UPDATE users SET coins = coins + $1 WHERE id = $2 RETURNING coins`,
+		d.CoinsCredited, d.UserID,
+	).Scan(&newBalance)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, ErrUserNotFound
+		}
+		return 0, fmt.Errorf("ошибка начисления коинов: %w", err)
+	}
+
+	ledger := &domain.Transaction{
+		UserID: d.UserID,
+		Type:   domain.TransactionTypeTonDeposit,
+		Amount: d.CoinsCredited,
+		Meta: map[string]interface{}{
+			"deposit_id":  d.ID,
+			"tx_hash":     d.TxHash,
+			"amount_nano": d.AmountNano,
+		},
+	}
+	if err := repository.NewTransactionRepository(db).CreateWithTx(ctx, tx, ledger); err != nil {
+		return 0, fmt.Errorf("ошибка записи транзакции: %w", err)
+	}
+
+	details := map[string]interface{}{}
+	for k, v := range auditDetails {
+		details[k] = v
+	}
+	details["deposit_id"] = d.ID
+	details["amount"] = d.CoinsCredited
+	details["amount_nano"] = d.AmountNano
+	details["tx_hash"] = d.TxHash
+	details["asset"] = d.Asset
+	audit := &domain.AuditLog{
+		UserID:   d.UserID,
+		Action:   action,
+		Category: domain.AuditCategoryPayment,
+		Details:  details,
+	}
+	if err := repository.NewAuditRepository(db).CreateWithTx(ctx, tx, audit); err != nil {
+		return 0, fmt.Errorf("ошибка записи аудита: %w", err)
+	}
+
+	return newBalance, nil
+}
+
+// queueUnmatchedDeposit сохраняет перевод, который не удалось сопоставить с пользователем,
+// в очередь разбора (статус unmatched, без пользователя, коины не начисляются) и пишет аудит.
+// false - депозит с таким tx_hash уже есть
+func queueUnmatchedDeposit(ctx context.Context, db *pgxpool.Pool, d *domain.Deposit) (bool, error) {
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	d.UserID = 0
+	d.Status = domain.DepositStatusUnmatched
+	d.Processed = false
+	d.ConfirmedAt = nil
+
+	created, err := repository.NewDepositRepository(db).CreateWithTx(ctx, tx, d)
+	if err != nil {
+		return false, fmt.Errorf("ошибка сохранения несопоставленного депозита: %w", err)
+	}
+	if !created {
+		return false, nil
+	}
+
+	audit := &domain.AuditLog{
+		Action:   domain.AuditActionDepositUnmatched,
+		Category: domain.AuditCategoryPayment,
+		Details: map[string]interface{}{
+			"deposit_id":  d.ID,
+			"amount_nano": d.AmountNano,
+			"tx_hash":     d.TxHash,
+			"asset":       d.Asset,
+			"memo":        d.Memo,
+			"reason":      d.UnmatchedReason,
+			"sender":      d.WalletAddress,
+		},
+	}
+	if err := repository.NewAuditRepository(db).CreateWithTx(ctx, tx, audit); err != nil {
+		return false, fmt.Errorf("ошибка записи аудита: %w", err)
+	}
+	return true, tx.Commit(ctx)
+}
+
+// findMissingCredits подтвержденные депозиты за окно window без записи о зачислении коинов
+func findMissingCredits(ctx context.Context, db *pgxpool.Pool, window time.Duration) ([]domain.Deposit, error) {
+	return repository.NewDepositRepository(db).GetMissingCredits(ctx, time.Now().Add(-window), depositReconcileLimit)
+}
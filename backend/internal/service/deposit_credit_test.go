@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"telegram_webapp/internal/domain"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// мигрированная БД из TEST_DATABASE_URL; без нее интеграционный тест пропускается
+func openTestDB(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set; skipping integration test")
+	}
+	pool, err := pgxpool.New(context.Background(), dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+// пользователь для теста зачисления: tg_id уникален для теста, строки удаляются после него
+func creditTestUser(t *testing.T, pool *pgxpool.Pool, tgID int64, hash string) int64 {
+	t.Helper()
+	ctx := context.Background()
+	cleanup := func() {
+		pool.Exec(ctx, `DELETE FROM transactions WHERE meta->>'tx_hash' = $1`, hash)
+		pool.Exec(ctx, `DELETE FROM audit_logs WHERE details->>'tx_hash' = $1`, hash)
+		pool.Exec(ctx, `DELETE FROM deposits WHERE tx_hash = $1`, hash)
+		pool.Exec(ctx, `DELETE FROM users WHERE tg_id = $1`, tgID)
+	}
+	cleanup()
+	t.Cleanup(cleanup)
+
+	var userID int64
+	if err := pool.QueryRow(ctx,
+		`INSERT INTO users (tg_id, username, first_name) VALUES ($1, 'credit_test', 'credit') RETURNING id`, tgID,
+	).Scan(&userID); err != nil {
+		t.Fatal(err)
+	}
+	return userID
+}
+
+func testDeposit(userID int64, hash string) *domain.Deposit {
+	return &domain.Deposit{
+		UserID:        userID,
+		WalletAddress: "0:5555555555555555555555555555555555555555555555555555555555555555",
+		AmountNano:    2_000_000_000,
+		GemsCredited:  20,
+		CoinsCredited: 20,
+		ExchangeRate:  10,
+		TxHash:        hash,
+		TxLt:          1,
+	}
+}
+
+// число строк каждой из четырех записей зачисления и баланс пользователя
+type creditRows struct {
+	deposits, ledger, audit int
+	coins                   int64
+}
+
+func countCreditRows(t *testing.T, pool *pgxpool.Pool, userID int64, hash string) creditRows {
+	t.Helper()
+	ctx := context.Background()
+	var rows creditRows
+	if err := pool.QueryRow(ctx, `SELECT COUNT(*) FROM deposits WHERE tx_hash = $1`, hash).Scan(&rows.deposits); err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.QueryRow(ctx,
+		`SELECT COUNT(*) FROM transactions WHERE type = $1 AND meta->>'tx_hash' = $2`, domain.TransactionTypeTonDeposit, hash,
+	).Scan(&rows.ledger); err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.QueryRow(ctx, `SELECT COUNT(*) FROM audit_logs WHERE details->>'tx_hash' = $1`, hash).Scan(&rows.audit); err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.QueryRow(ctx, `SELECT coins FROM users WHERE id = $1`, userID).Scan(&rows.coins); err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+// ошибка на последней записи (аудит) откатывает депозит, коины и строку transactions
+func TestCreditDepositRollsBack(t *testing.T) {
+	pool := openTestDB(t)
+	ctx := context.Background()
+	const hash = "4201420142014201420142014201420142014201420142014201420142014201"
+	userID := creditTestUser(t, pool, -4201, hash)
+
+	// jsonb не принимает \u0000: падает вставка аудита, три записи до нее уже сделаны в транзакции
+	_, credited, err := creditDeposit(ctx, pool, testDeposit(userID, hash), map[string]interface{}{"memo": "\x00"})
+	if err == nil || credited {
+		t.Fatalf("ожидалась ошибка записи аудита, credited=%v err=%v", credited, err)
+	}
+	if got := countCreditRows(t, pool, userID, hash); got != (creditRows{}) {
+		t.Fatalf("после отката остались записи: %+v", got)
+	}
+
+	// после отката тот же перевод зачисляется заново
+	balance, credited, err := creditDeposit(ctx, pool, testDeposit(userID, hash), nil)
+	if err != nil || !credited || balance != 20 {
+		t.Fatalf("повторная обработка: баланс %d, credited=%v, err=%v", balance, credited, err)
+	}
+	if got := countCreditRows(t, pool, userID, hash); got != (creditRows{deposits: 1, ledger: 1, audit: 1, coins: 20}) {
+		t.Fatalf("после зачисления: %+v", got)
+	}
+}
+
+// повтор того же tx_hash не начисляет коины второй раз
+func TestCreditDepositReplayedHash(t *testing.T) {
+	pool := openTestDB(t)
+	ctx := context.Background()
+	const hash = "4202420242024202420242024202420242024202420242024202420242024202"
+	userID := creditTestUser(t, pool, -4202, hash)
+
+	if _, credited, err := creditDeposit(ctx, pool, testDeposit(userID, hash), nil); err != nil || !credited {
+		t.Fatalf("первое зачисление: credited=%v, err=%v", credited, err)
+	}
+	for i := 0; i < 2; i++ {
+		balance, credited, err := creditDeposit(ctx, pool, testDeposit(userID, hash), nil)
+		if err != nil || credited || balance != 0 {
+			t.Fatalf("повтор %d: баланс %d, credited=%v, err=%v", i+1, balance, credited, err)
+		}
+	}
+	if got := countCreditRows(t, pool, userID, hash); got != (creditRows{deposits: 1, ledger: 1, audit: 1, coins: 20}) {
+		t.Fatalf("после повторов: %+v", got)
+	}
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/repository"
+	"telegram_webapp/internal/ton"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrUnmatchedDepositNotFound = errors.New("депозит не найден или уже разобран")
+	ErrRefundBelowFee           = errors.New("сумма перевода не покрывает комиссию сети")
+	ErrRefundNoSender           = errors.New("адрес отправителя неизвестен")
+)
+
+// AssignDepositResult итог назначения несопоставленного депозита пользователю
+type AssignDepositResult struct {
+	Deposit    domain.Deposit
+	UserTgID   int64
+	NewBalance int64
+}
+
+// RefundDepositResult итог возврата несопоставленного депозита отправителю
+type RefundDepositResult struct {
+	Deposit      domain.Deposit
+	RefundAmount int64 // в минимальных единицах актива, за вычетом комиссии сети
+	Fee          int64 // удержанная комиссия сети
+	TxHash       string
+	AutoSent     bool
+}
+
+// assignUnmatchedDeposit одной транзакцией БД назначает несопоставленный депозит пользователю userID:
+// подтверждает депозит, начисляет коины по курсу актива на момент поступления, пишет строку ton_deposit и аудит deposit_assign
+func assignUnmatchedDeposit(ctx context.Context, db *pgxpool.Pool, depositID, userID, adminTgID int64) (*AssignDepositResult, error) {
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	repo := repository.NewDepositRepository(db)
+	d, err := repo.GetUnmatchedForUpdateWithTx(ctx, tx, depositID)
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, ErrUnmatchedDepositNotFound
+	}
+
+	// курс на момент поступления перевода, а не разбора
+	pricingService := NewPricingService(db)
+	var pricing *domain.PricingConfig
+	if d.PricingVersion != 0 {
+		pricing, err = pricingService.Version(ctx, d.PricingVersion)
+	} else {
+		pricing, err = pricingService.At(ctx, d.CreatedAt)
+	}
+	if err != nil {
+		return nil, err
+	}
+	rates := PricingRates(pricing)
+	asset := ton.Asset(d.Asset)
+	d.UserID = userID
+	d.CoinsCredited = rates.UnitsToCoins(asset, d.AmountNano)
+	d.GemsCredited = d.CoinsCredited
+	d.ExchangeRate = int(rates[asset])
+	d.PricingVersion = pricing.ID
+	if err := repo.AssignWithTx(ctx, tx, d); err != nil {
+		return nil, fmt.Errorf("ошибка назначения депозита: %w", err)
+	}
+
+	newBalance, err := applyDepositCreditWithTx(ctx, tx, db, d, domain.AuditActionDepositAssign, map[string]interface{}{
+		"source":           "admin",
+		"admin_tg_id":      adminTgID,
+		"memo":             d.Memo,
+		"unmatched_reason": d.UnmatchedReason,
+		"sender":           d.WalletAddress,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AssignDepositResult{NewBalance: newBalance}
+	if err := tx.QueryRow(ctx, `SELECT tg_id FROM users WHERE id = $1`, userID).Scan(&result.UserTgID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	result.Deposit = *d
+	return result, nil
+}
+
+// refundUnmatchedDeposit возвращает несопоставленный перевод отправителю за вычетом комиссии сети
+// (Rates.RefundFeeUnits по действующему курсу). С кошельком и без manualTxHash перевод отправляется автоматически, иначе
+// записывается хэш ручного возврата. Статус refunded и аудит deposit_refund пишутся одной транзакцией БД
+func refundUnmatchedDeposit(ctx context.Context, db *pgxpool.Pool, wallet withdrawalSender, depositID int64, manualTxHash string, adminTgID int64) (*RefundDepositResult, error) {
+	repo := repository.NewDepositRepository(db)
+	d, err := repo.GetByID(ctx, depositID)
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, ErrUnmatchedDepositNotFound
+	}
+	switch d.Status {
+	case domain.DepositStatusUnmatched:
+	case domain.DepositStatusRefunding:
+		// отправка не завершилась: повторная может задвоить возврат, подтверждать только с хэшем
+		if manualTxHash == "" {
+			return nil, fmt.Errorf("возврат уже отправляется, проверьте перевод в сети и укажите хэш: /refunddeposit %d tx_hash", d.ID)
+		}
+	default:
+		return nil, ErrUnmatchedDepositNotFound
+	}
+	if d.WalletAddress == "" {
+		return nil, ErrRefundNoSender
+	}
+
+	pricing, err := NewPricingService(db).Current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	asset := ton.Asset(d.Asset)
+	result := &RefundDepositResult{Fee: PricingRates(pricing).RefundFeeUnits(asset)}
+	result.RefundAmount = d.AmountNano - result.Fee
+	if result.RefundAmount <= 0 {
+		return nil, ErrRefundBelowFee
+	}
+
+	if manualTxHash == "" {
+		if wallet == nil || !wallet.SupportsAsset(asset) {
+			return nil, fmt.Errorf("кошелек не настроен для %s, укажите хэш ручного возврата", asset)
+		}
+		claimed, err := repo.ClaimForRefund(ctx, d.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !claimed {
+			return nil, ErrUnmatchedDepositNotFound
+		}
+
+		sendResult, err := wallet.SendAsset(ctx, asset, d.WalletAddress, uint64(result.RefundAmount), fmt.Sprintf("Refund of deposit #%d", d.ID))
+		if errors.Is(err, ton.ErrSendUnconfirmed) {
+			// как и при выводе: перевод мог уйти, депозит остается refunding до проверки админом
+			return nil, fmt.Errorf("возврат мог быть отправлен (%v), проверьте перевод в сети и укажите хэш: /refunddeposit %d tx_hash", err, d.ID)
+		}
+		if err != nil {
+			// перевод не отправлялся, депозит снова ждет разбора
+			_ = repo.ReleaseRefund(ctx, d.ID)
+			return nil, fmt.Errorf("ошибка отправки возврата %s: %w", asset, err)
+		}
+		result.TxHash = sendResult.TxHash
+		result.AutoSent = true
+	} else {
+		result.TxHash = manualTxHash
+	}
+
+	if err := markDepositRefunded(ctx, db, d, result, adminTgID); err != nil {
+		if result.AutoSent {
+			return nil, fmt.Errorf("возврат отправлен (%s), но не записан: %w", result.TxHash, err)
+		}
+		return nil, err
+	}
+	d.Status = domain.DepositStatusRefunded
+	result.Deposit = *d
+	return result, nil
+}
+
+// markDepositRefunded отмечает возврат и пишет аудит одной транзакцией БД
+func markDepositRefunded(ctx context.Context, db *pgxpool.Pool, d *domain.Deposit, result *RefundDepositResult, adminTgID int64) error {
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	marked, err := repository.NewDepositRepository(db).MarkRefundedWithTx(ctx, tx, d.ID, result.TxHash, result.RefundAmount)
+	if err != nil {
+		return err
+	}
+	if !marked {
+		return ErrUnmatchedDepositNotFound
+	}
+
+	audit := &domain.AuditLog{
+		Action:   domain.AuditActionDepositRefund,
+		Category: domain.AuditCategoryPayment,
+		Details: map[string]interface{}{
+			"deposit_id":     d.ID,
+			"tx_hash":        d.TxHash,
+			"asset":          d.Asset,
+			"amount_nano":    d.AmountNano,
+			"refund_amount":  result.RefundAmount,
+			"fee":            result.Fee,
+			"refund_tx_hash": result.TxHash,
+			"auto_sent":      result.AutoSent,
+			"to":             d.WalletAddress,
+			"admin_tg_id":    adminTgID,
+		},
+	}
+	if err := repository.NewAuditRepository(db).CreateWithTx(ctx, tx, audit); err != nil {
+		return fmt.Errorf("ошибка записи аудита: %w", err)
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/ton"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// несопоставленный перевод в очереди разбора: без пользователя, с адресом отправителя
+func insertUnmatchedDeposit(t *testing.T, pool *pgxpool.Pool, hash string) int64 {
+	t.Helper()
+	var id int64
+	if err := pool.QueryRow(context.Background(), `
+		INSERT INTO deposits (wallet_address, amount_nano, gems_credited, exchange_rate, tx_hash, tx_lt, status, asset, unmatched_reason)
+		VALUES ('0:6666666666666666666666666666666666666666666666666666666666666666', 2000000000, 0, 0, $1, 1, 'unmatched', 'TON', 'no_memo')
+		RETURNING id
+	`, hash).Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func depositStatus(t *testing.T, pool *pgxpool.Pool, id int64) domain.DepositStatus {
+	t.Helper()
+	var status string
+	if err := pool.QueryRow(context.Background(), `SELECT status FROM deposits WHERE id = $1`, id).Scan(&status); err != nil {
+		t.Fatal(err)
+	}
+	return domain.DepositStatus(status)
+}
+
+// кошелек возврата: запоминает статус депозита в момент отправки
+type refundSender struct {
+	fakeSender
+	pool         *pgxpool.Pool
+	depositID    int64
+	statusAtSend domain.DepositStatus
+}
+
+func (s *refundSender) SendAsset(ctx context.Context, asset ton.Asset, toAddress string, amount uint64, comment string) (*ton.SendResult, error) {
+	var status string
+	if err := s.pool.QueryRow(ctx, `SELECT status FROM deposits WHERE id = $1`, s.depositID).Scan(&status); err != nil {
+		return nil, err
+	}
+	s.statusAtSend = domain.DepositStatus(status)
+	return s.fakeSender.SendAsset(ctx, asset, toAddress, amount, comment)
+}
+
+// назначение начисляет коины один раз; повторное назначение того же депозита отклоняется
+func TestAssignUnmatchedDepositOnce(t *testing.T) {
+	pool := openTestDB(t)
+	ctx := context.Background()
+	const hash = "4801480148014801480148014801480148014801480148014801480148014801"
+	userID := creditTestUser(t, pool, -4801, hash)
+	depositID := insertUnmatchedDeposit(t, pool, hash)
+
+	result, err := assignUnmatchedDeposit(ctx, pool, depositID, userID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credited := result.Deposit.CoinsCredited
+	if credited <= 0 || result.NewBalance != credited || result.UserTgID != -4801 {
+		t.Fatalf("назначение: %+v", result)
+	}
+	if status := depositStatus(t, pool, depositID); status != domain.DepositStatusConfirmed {
+		t.Fatalf("статус после назначения: %s", status)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := assignUnmatchedDeposit(ctx, pool, depositID, userID, 1); !errors.Is(err, ErrUnmatchedDepositNotFound) {
+			t.Fatalf("повтор %d: %v", i+1, err)
+		}
+	}
+	if got := countCreditRows(t, pool, userID, hash); got != (creditRows{deposits: 1, ledger: 1, audit: 1, coins: credited}) {
+		t.Fatalf("после повторов: %+v", got)
+	}
+}
+
+// возврат: unmatched -> refunding на время отправки -> refunded с хэшем перевода
+func TestRefundUnmatchedDeposit(t *testing.T) {
+	pool := openTestDB(t)
+	ctx := context.Background()
+	const hash = "4802480248024802480248024802480248024802480248024802480248024802"
+	creditTestUser(t, pool, -4802, hash)
+	depositID := insertUnmatchedDeposit(t, pool, hash)
+
+	sender := &refundSender{pool: pool, depositID: depositID}
+	result, err := refundUnmatchedDeposit(ctx, pool, sender, depositID, "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sender.statusAtSend != domain.DepositStatusRefunding {
+		t.Fatalf("статус при отправке: %q", sender.statusAtSend)
+	}
+	if len(sender.sent) != 1 || !result.AutoSent || result.RefundAmount != 2_000_000_000-result.Fee {
+		t.Fatalf("возврат: %+v, отправлено %v", result, sender.sent)
+	}
+
+	var refundHash string
+	if err := pool.QueryRow(ctx, `SELECT refund_tx_hash FROM deposits WHERE id = $1`, depositID).Scan(&refundHash); err != nil {
+		t.Fatal(err)
+	}
+	if status := depositStatus(t, pool, depositID); status != domain.DepositStatusRefunded || refundHash != result.TxHash {
+		t.Fatalf("после возврата: статус %s, хэш %q", status, refundHash)
+	}
+
+	// возвращенный депозит повторно не отправляется
+	if _, err := refundUnmatchedDeposit(ctx, pool, sender, depositID, "", 1); !errors.Is(err, ErrUnmatchedDepositNotFound) {
+		t.Fatalf("повторный возврат: %v", err)
+	}
+	if len(sender.sent) != 1 {
+		t.Fatalf("повторная отправка: %v", sender.sent)
+	}
+}
+
+// неподтвержденная отправка оставляет депозит refunding: без хэша повтор не отправляет, с хэшем закрывает возврат
+func TestRefundUnconfirmedStaysRefunding(t *testing.T) {
+	pool := openTestDB(t)
+	ctx := context.Background()
+	const hash = "4803480348034803480348034803480348034803480348034803480348034803"
+	creditTestUser(t, pool, -4803, hash)
+	depositID := insertUnmatchedDeposit(t, pool, hash)
+
+	sender := &fakeSender{err: fmt.Errorf("%w: seqno не изменился", ton.ErrSendUnconfirmed)}
+	if _, err := refundUnmatchedDeposit(ctx, pool, sender, depositID, "", 1); err == nil {
+		t.Fatal("ожидалась ошибка неподтвержденной отправки")
+	}
+	if status := depositStatus(t, pool, depositID); status != domain.DepositStatusRefunding {
+		t.Fatalf("статус после неподтвержденной отправки: %s", status)
+	}
+
+	sender.err = nil
+	if _, err := refundUnmatchedDeposit(ctx, pool, sender, depositID, "", 1); err == nil {
+		t.Fatal("повтор без хэша должен быть отклонен")
+	}
+	if len(sender.sent) != 0 || depositStatus(t, pool, depositID) != domain.DepositStatusRefunding {
+		t.Fatalf("повтор без хэша: отправлено %v", sender.sent)
+	}
+
+	const manual = "4804480448044804480448044804480448044804480448044804480448044804"
+	result, err := refundUnmatchedDeposit(ctx, pool, sender, depositID, manual, 1)
+	if err != nil || result.AutoSent || result.TxHash != manual {
+		t.Fatalf("ручной хэш: %+v, err=%v", result, err)
+	}
+	if status := depositStatus(t, pool, depositID); status != domain.DepositStatusRefunded {
+		t.Fatalf("статус после ручного хэша: %s", status)
+	}
+}
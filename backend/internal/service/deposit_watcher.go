@@ -2,10 +2,9 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"regexp"
-	"strconv"
-	"strings"
+	"sort"
 	"sync"
 	"time"
 
@@ -23,43 +22,84 @@ type DepositNotification struct {
 	Username      string
 	TgID          int64
 	WalletAddress string
-	AmountNano    int64
-	AmountTON     float64
+	Asset         ton.Asset
+	AmountNano    int64   // в минимальных единицах актива
+	Amount        float64 // в целых единицах актива
 	CoinsCredited int64
 	TxHash        string
 	NewBalance    int64
 }
 
+const (
+	depositPageSize   = 50   // транзакций за запрос к API
+	depositMaxPages   = 100  // страниц за один опрос, дальше - догоняем следующими опросами
+	backfillMaxPages  = 2000 // страниц за один бэкфилл
+	depositCursorName = "deposits:"
+)
+
+var ErrBackfillRange = errors.New("неверный диапазон lt: нужно 0 <= from < to")
+
+// BackfillResult итог догоняющего сканирования диапазона lt
+type BackfillResult struct {
+	Scanned  int // транзакций в диапазоне
+	Incoming int // входящих переводов
+	Credited int // новых зачислений
+	Failed   int // ошибок обработки
+}
+
+// depositGap непросмотренный участок истории между курсором и транзакциями, полученными опросом
+type depositGap struct {
+	beforeLt int64 // следующий опрос листает от этой lt вниз к курсору
+	topLt    int64 // самая новая обработанная транзакция, курсор встает на нее, когда участок пройден
+	topHash  string
+}
+
+// txPageFetcher страница транзакций кошелька старше beforeLt (0 - самые новые), от новых к старым
+type txPageFetcher func(ctx context.Context, beforeLt int64) ([]ton.Transaction, error)
+
 // DepositWatcher отслеживает входящие TON транзакции и начисляет коины
 type DepositWatcher struct {
-	db              *pgxpool.Pool
-	tonClient       *ton.Client
-	depositRepo     *repository.DepositRepository
-	userRepo        *repository.UserRepository
-	walletRepo      *repository.WalletRepository
-	platformWallet  string
-	lastLt          int64 // последнее обработанное логическое время
-	interval        time.Duration
-	mu              sync.Mutex
+	db                 *pgxpool.Pool
+	indexer            ton.ChainIndexer
+	depositRepo        *repository.DepositRepository
+	userRepo           *repository.UserRepository
+	walletRepo         *repository.WalletRepository
+	cursors            *repository.ChainCursorRepository
+	platformWallet     string
+	lastLt             int64 // последнее обработанное логическое время (хранится в chain_cursors)
+	cursorLoaded       bool
+	gap                *depositGap // курсор не достигнут за опрос: участок догоняют следующие опросы
+	scanMu             sync.Mutex  // опрос и бэкфилл не обрабатывают транзакции одновременно
+	interval           time.Duration
+	mu                 sync.Mutex
 	stop               chan struct{}
 	running            bool
 	notifyCallback     func(DepositNotification) // callback для уведомления админов о депозите
 	userNotifyCallback func(DepositNotification) // callback для уведомления пользователя о депозите
+	jettonMasters      map[ton.Asset]string      // мастер-контракты принимаемых жетонов
+	jettonWallets      map[string]ton.Asset      // кошельки жетонов платформы (raw адрес) -> актив
+	reconcileCallback  func([]domain.Deposit)    // callback для уведомления админов о депозитах без зачисления
+	codes              *DepositCodeService       // коды депозитов в memo
+	pricing            *PricingService           // курс коинов на момент перевода
+	unmatchedCallback  func(domain.Deposit)      // callback для уведомления админов о переводе на разбор
 }
 
 // NewDepositWatcher создает новый watcher для депозитов
 func NewDepositWatcher(
 	db *pgxpool.Pool,
-	tonClient *ton.Client,
+	indexer ton.ChainIndexer,
 	platformWallet string,
 	interval time.Duration,
 ) *DepositWatcher {
 	return &DepositWatcher{
 		db:             db,
-		tonClient:      tonClient,
+		indexer:        indexer,
 		depositRepo:    repository.NewDepositRepository(db),
 		userRepo:       repository.NewUserRepository(db),
 		walletRepo:     repository.NewWalletRepository(db),
+		cursors:        repository.NewChainCursorRepository(db),
+		codes:          NewDepositCodeService(db),
+		pricing:        NewPricingService(db),
 		platformWallet: platformWallet,
 		interval:       interval,
 		stop:           make(chan struct{}),
@@ -84,11 +124,15 @@ func (w *DepositWatcher) Start() {
 
 	ticker := time.NewTicker(w.interval)
 	defer ticker.Stop()
+	reconcileTicker := time.NewTicker(depositReconcileInterval)
+	defer reconcileTicker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
 			w.checkDeposits()
+		case <-reconcileTicker.C:
+			w.reconcile()
 		case <-w.stop:
 			log.Info("остановка deposit watcher")
 			return
@@ -120,20 +164,200 @@ func (w *DepositWatcher) SetUserNotifyCallback(callback func(DepositNotification
 	w.userNotifyCallback = callback
 }
 
-// checkDeposits проверяет новые транзакции
-func (w *DepositWatcher) checkDeposits() {
+// SetJettonMaster включает прием жетона asset с мастер-контрактом master
+func (w *DepositWatcher) SetJettonMaster(asset ton.Asset, master string) {
+	w.scanMu.Lock()
+	defer w.scanMu.Unlock()
+	if w.jettonMasters == nil {
+		w.jettonMasters = make(map[ton.Asset]string)
+	}
+	w.jettonMasters[asset] = master
+}
+
+// jettonAsset актив, чьим кошельком жетона платформы является jettonWallet; "" - чужой кошелек.
+// Кошельки жетонов платформы запрашиваются у API один раз (до первого получения жетона их может не быть)
+func (w *DepositWatcher) jettonAsset(ctx context.Context, jettonWallet string) (ton.Asset, error) {
+	addr, err := ton.NormalizeAddress(jettonWallet)
+	if err != nil || addr == "" {
+		addr = jettonWallet
+	}
+	if asset, ok := w.jettonWallets[addr]; ok {
+		return asset, nil
+	}
+
+	resolver, ok := w.indexer.(ton.JettonWalletResolver)
+	if !ok {
+		return "", errors.New("индексатор не поддерживает поиск кошельков жетонов")
+	}
+	for asset, master := range w.jettonMasters {
+		if w.hasJettonWallet(asset) {
+			continue
+		}
+		resolved, err := resolver.GetJettonWalletAddress(ctx, w.platformWallet, master)
+		if err != nil {
+			return "", err
+		}
+		if resolved == "" {
+			continue
+		}
+		if normalized, err := ton.NormalizeAddress(resolved); err == nil && normalized != "" {
+			resolved = normalized
+		}
+		if w.jettonWallets == nil {
+			w.jettonWallets = make(map[string]ton.Asset)
+		}
+		w.jettonWallets[resolved] = asset
+		logger.Get().Info("deposit watcher: кошелек жетона платформы", "asset", asset, "wallet", resolved)
+	}
+
+	return w.jettonWallets[addr], nil
+}
+
+func (w *DepositWatcher) hasJettonWallet(asset ton.Asset) bool {
+	for _, a := range w.jettonWallets {
+		if a == asset {
+			return true
+		}
+	}
+	return false
+}
+
+// SetUnmatchedNotifyCallback устанавливает callback для уведомления о несопоставленных переводах
+func (w *DepositWatcher) SetUnmatchedNotifyCallback(callback func(domain.Deposit)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.unmatchedCallback = callback
+}
+
+// SetReconcileNotifyCallback устанавливает callback для уведомлений админов о депозитах без зачисления
+func (w *DepositWatcher) SetReconcileNotifyCallback(callback func([]domain.Deposit)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.reconcileCallback = callback
+}
+
+// reconcile ищет подтвержденные депозиты за последние сутки, для которых не записано зачисление
+func (w *DepositWatcher) reconcile() {
 	log := logger.Get()
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	missing, err := findMissingCredits(ctx, w.db, depositReconcileWindow)
+	if err != nil {
+		log.Error("deposit watcher: ошибка сверки зачислений", "error", err)
+		return
+	}
+	if len(missing) == 0 {
+		return
+	}
+
+	ids := make([]int64, 0, len(missing))
+	for _, d := range missing {
+		ids = append(ids, d.ID)
+	}
+	log.Error("deposit watcher: подтвержденные депозиты без зачисления", "count", len(missing), "depositIDs", ids)
+
+	if w.reconcileCallback != nil {
+		go w.reconcileCallback(missing)
+	}
+}
+
+// collectSince листает транзакции от новых к старым начиная с beforeLt (0 - с самых новых),
+// пока не дойдет до курсора (lt <= cursor), конца истории или maxPages.
+// Возвращает транзакции новее курсора по возрастанию lt и признак, что курсор достигнут
+func collectSince(ctx context.Context, fetch txPageFetcher, beforeLt, cursor int64, pageSize, maxPages int) ([]ton.Transaction, bool, error) {
+	var collected []ton.Transaction
+	reached := false
+
+	for page := 0; page < maxPages && !reached; page++ {
+		txs, err := fetch(ctx, beforeLt)
+		if err != nil {
+			return nil, false, err
+		}
+
+		oldest := beforeLt
+		for _, tx := range txs {
+			if tx.Lt <= cursor {
+				reached = true
+				continue
+			}
+			collected = append(collected, tx)
+			if oldest == 0 || tx.Lt < oldest {
+				oldest = tx.Lt
+			}
+		}
+
+		// история кошелька закончилась или API не сдвинулся назад
+		if len(txs) < pageSize || oldest == beforeLt {
+			reached = true
+		}
+		beforeLt = oldest
+	}
+
+	sort.Slice(collected, func(i, j int) bool { return collected[i].Lt < collected[j].Lt })
+	return collected, reached, nil
+}
+
+func (w *DepositWatcher) fetchPage(ctx context.Context, beforeLt int64) ([]ton.Transaction, error) {
+	return w.indexer.GetTransactions(ctx, w.platformWallet, depositPageSize, beforeLt)
+}
+
+// хэши входящих переводов среди транзакций кошелька
+func (w *DepositWatcher) incomingHashes(txs []ton.Transaction) map[string]bool {
+	incoming := make(map[string]bool)
+	for _, tx := range ton.ParseIncomingTransactions(txs, w.platformWallet) {
+		incoming[tx.Hash] = true
+	}
+	return incoming
+}
+
+// Cursor последнее обработанное логическое время
+func (w *DepositWatcher) Cursor() int64 {
+	w.scanMu.Lock()
+	defer w.scanMu.Unlock()
+	return w.lastLt
+}
+
+// checkDeposits обрабатывает транзакции новее сохраненного курсора и сдвигает курсор
+func (w *DepositWatcher) checkDeposits() {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
 	if w.platformWallet == "" {
 		log.Warn("deposit watcher: адрес платформы не настроен")
 		return
 	}
 
-	// получаем последние транзакции
-	log.Info("deposit watcher: проверка транзакций", "wallet", w.platformWallet)
-	txs, err := w.tonClient.GetTransactions(ctx, w.platformWallet, 50, 0)
+	w.scanMu.Lock()
+	defer w.scanMu.Unlock()
+
+	cursorName := depositCursorName + w.platformWallet
+	if !w.cursorLoaded {
+		lt, err := w.cursors.Get(ctx, cursorName)
+		if err != nil {
+			log.Error("deposit watcher: ошибка чтения курсора", "error", err)
+			return
+		}
+		w.lastLt = lt
+		w.cursorLoaded = true
+		log.Info("deposit watcher: курсор загружен", "lastLt", lt)
+	}
+
+	// без курсора (первый запуск) берем только последнюю страницу, старую историю - бэкфиллом
+	maxPages := depositMaxPages
+	if w.lastLt == 0 {
+		maxPages = 1
+	}
+
+	// незакрытый участок листаем дальше с самой старой полученной транзакции, новые ждут следующего опроса
+	beforeLt := int64(0)
+	if w.gap != nil {
+		beforeLt = w.gap.beforeLt
+	}
+
+	log.Info("deposit watcher: проверка транзакций", "wallet", w.platformWallet, "lastLt", w.lastLt, "beforeLt", beforeLt)
+	txs, reached, err := collectSince(ctx, w.fetchPage, beforeLt, w.lastLt, depositPageSize, maxPages)
 	if err != nil {
 		log.Error("deposit watcher: ошибка получения транзакций",
 			"error", err,
@@ -142,53 +366,181 @@ func (w *DepositWatcher) checkDeposits() {
 		return
 	}
 
-	log.Info("deposit watcher: получено транзакций", "count", len(txs))
+	// курсор сдвигается по транзакциям, только если между ним и ними ничего не осталось
+	advance := w.gap == nil && (reached || w.lastLt == 0)
+
+	incoming := w.incomingHashes(txs)
+	log.Info("deposit watcher: получено транзакций", "count", len(txs), "incoming", len(incoming))
+
+	newLt, newHash := w.lastLt, ""
+	failed := false
+	for i := range txs {
+		tx := &txs[i]
+		if incoming[tx.Hash] {
+			if _, err := w.processTransaction(ctx, tx); err != nil {
+				// курсор останавливается перед транзакцией, повтор на следующем опросе
+				log.Error("deposit watcher: ошибка обработки транзакции",
+					"hash", tx.Hash,
+					"lt", tx.Lt,
+					"error", err)
+				failed = true
+				break
+			}
+		}
+		if advance {
+			newLt, newHash = tx.Lt, tx.Hash
+		}
+	}
+
+	// участок истории между курсором и полученными транзакциями; при ошибке обработки
+	// состояние не меняется и те же страницы запрашиваются снова (зачисленные пропускаются по хэшу)
+	switch {
+	case advance || failed:
+	case w.gap == nil:
+		w.gap = &depositGap{beforeLt: txs[0].Lt, topLt: txs[len(txs)-1].Lt, topHash: txs[len(txs)-1].Hash}
+		log.Warn("deposit watcher: курсор не достигнут, история догоняется следующими опросами",
+			"lastLt", w.lastLt,
+			"pages", maxPages,
+			"oldestFetchedLt", w.gap.beforeLt)
+	case reached:
+		newLt, newHash = w.gap.topLt, w.gap.topHash
+		log.Info("deposit watcher: пропущенный участок истории обработан", "lastLt", w.lastLt, "newLt", newLt)
+		w.gap = nil
+	default:
+		w.gap.beforeLt = txs[0].Lt
+		log.Warn("deposit watcher: курсор все еще не достигнут", "lastLt", w.lastLt, "oldestFetchedLt", w.gap.beforeLt)
+	}
+
+	if newLt > w.lastLt {
+		if err := w.cursors.Advance(ctx, cursorName, newLt, newHash); err != nil {
+			log.Error("deposit watcher: ошибка сохранения курсора", "lt", newLt, "error", err)
+			return
+		}
+		w.lastLt = newLt
+	}
+}
+
+// Backfill повторно обрабатывает транзакции с lt в [fromLt, toLt]; курсор не меняется.
+// Уже зачисленные депозиты пропускаются по хэшу транзакции
+func (w *DepositWatcher) Backfill(ctx context.Context, fromLt, toLt int64) (*BackfillResult, error) {
+	if fromLt < 0 || toLt <= fromLt {
+		return nil, ErrBackfillRange
+	}
 
-	// фильтруем входящие транзакции
-	incoming := ton.ParseIncomingTransactions(txs, w.platformWallet)
-	log.Info("deposit watcher: входящих транзакций", "count", len(incoming))
+	w.scanMu.Lock()
+	defer w.scanMu.Unlock()
+
+	log := logger.Get()
+	log.Info("deposit watcher: бэкфилл", "fromLt", fromLt, "toLt", toLt)
+
+	// before_lt не включает границу
+	txs, reached, err := collectSince(ctx, w.fetchPage, toLt+1, fromLt-1, depositPageSize, backfillMaxPages)
+	if err != nil {
+		return nil, err
+	}
+	if !reached {
+		return nil, fmt.Errorf("диапазон больше %d страниц, разбейте его", backfillMaxPages)
+	}
 
-	for _, tx := range incoming {
-		if err := w.processTransaction(ctx, &tx); err != nil {
-			log.Error("deposit watcher: ошибка обработки транзакции",
+	incoming := w.incomingHashes(txs)
+	result := &BackfillResult{Scanned: len(txs), Incoming: len(incoming)}
+	for i := range txs {
+		tx := &txs[i]
+		if !incoming[tx.Hash] {
+			continue
+		}
+		credited, err := w.processTransaction(ctx, tx)
+		if err != nil {
+			result.Failed++
+			log.Error("deposit watcher: бэкфилл, ошибка обработки транзакции",
 				"hash", tx.Hash,
+				"lt", tx.Lt,
 				"error", err)
+			continue
+		}
+		if credited {
+			result.Credited++
 		}
 	}
+
+	log.Info("deposit watcher: бэкфилл завершен",
+		"scanned", result.Scanned,
+		"incoming", result.Incoming,
+		"credited", result.Credited,
+		"failed", result.Failed)
+	return result, nil
 }
 
-// processTransaction обрабатывает одну транзакцию
-func (w *DepositWatcher) processTransaction(ctx context.Context, tx *ton.Transaction) error {
+// processTransaction обрабатывает одну транзакцию; true - депозит зачислен
+func (w *DepositWatcher) processTransaction(ctx context.Context, tx *ton.Transaction) (bool, error) {
 	log := logger.Get()
 
 	// проверяем не обрабатывали ли уже
 	exists, err := w.depositRepo.TxHashExists(ctx, tx.Hash)
 	if err != nil {
-		return fmt.Errorf("ошибка проверки хэша: %w", err)
+		return false, fmt.Errorf("ошибка проверки хэша: %w", err)
 	}
 	if exists {
-		return nil // уже обработано
+		return false, nil // уже обработано
 	}
 
 	var userID int64
 	var memo string
 
-	// Способ 1: извлекаем userID из memo
+	// актив, сумма в минимальных единицах и адрес владельца, с которого пришли средства
+	asset := ton.AssetTON
+	var amountUnits int64
+	senderAddress := ""
+	if tx.InMsg != nil && tx.InMsg.Source != nil {
+		senderAddress = tx.InMsg.Source.Address
+	}
 	memo = ton.ExtractMemo(tx)
+	if tx.InMsg != nil {
+		amountUnits = tx.InMsg.Value
+	}
+
+	// перевод жетона: уведомление засчитывается, только если пришло от кошелька жетона платформы
+	if notify, ok := ton.ParseJettonNotify(tx); ok {
+		jettonAsset, err := w.jettonAsset(ctx, notify.JettonWallet)
+		if err != nil {
+			return false, fmt.Errorf("ошибка проверки кошелька жетона: %w", err)
+		}
+		if jettonAsset != "" {
+			asset = jettonAsset
+			amountUnits = notify.Amount
+			senderAddress = notify.Sender
+			memo = notify.Comment
+		} else {
+			log.Warn("deposit watcher: уведомление о жетоне не от кошелька платформы, учитывается только TON",
+				"jettonWallet", notify.JettonWallet,
+				"hash", tx.Hash)
+		}
+	}
+
+	// Способ 1: код депозита в memo
+	var depositCode *domain.DepositCode
+	unmatchedReason := ""
 	if memo != "" {
-		parsedID, err := parseUserIDFromMemo(memo)
-		if err == nil && parsedID > 0 {
-			userID = parsedID
-			log.Debug("deposit watcher: userID найден в memo",
+		code, reason, err := w.codes.Resolve(ctx, memo, time.Unix(tx.Utime, 0))
+		if err != nil {
+			return false, fmt.Errorf("ошибка проверки кода депозита: %w", err)
+		}
+		if code != nil {
+			depositCode = code
+			userID = code.UserID
+			log.Debug("deposit watcher: userID найден по коду депозита",
 				"userID", userID,
 				"memo", memo,
 				"hash", tx.Hash)
+		} else {
+			unmatchedReason = reason
 		}
 	}
 
-	// Способ 2: fallback - ищем по адресу отправителя (привязанный кошелёк)
-	if userID == 0 && tx.InMsg != nil && tx.InMsg.Source != nil && tx.InMsg.Source.Address != "" {
-		sourceAddress := tx.InMsg.Source.Address
+	// Способ 2: fallback - ищем по адресу отправителя (привязанный кошелёк), только без memo:
+	// нераспознанный memo (опечатка в коде) уходит в очередь разбора, а не на баланс владельца кошелька
+	if memo == "" && senderAddress != "" {
+		sourceAddress := senderAddress
 		log.Info("deposit watcher: поиск пользователя по адресу",
 			"sourceAddress", sourceAddress,
 			"hash", tx.Hash)
@@ -234,17 +586,28 @@ func (w *DepositWatcher) processTransaction(ctx context.Context, tx *ton.Transac
 		}
 	}
 
-	// если userID не найден ни одним способом - пропускаем
+	// курс, действовавший в момент перевода (бэкфилл старых транзакций не пересчитывается по новому)
+	pricing, err := w.pricing.At(ctx, time.Unix(tx.Utime, 0))
+	if err != nil {
+		return false, fmt.Errorf("ошибка получения курса: %w", err)
+	}
+	rates := PricingRates(pricing)
+
+	// если userID не найден ни одним способом - перевод уходит в очередь разбора
 	if userID == 0 {
-		sourceAddr := ""
-		if tx.InMsg != nil && tx.InMsg.Source != nil {
-			sourceAddr = tx.InMsg.Source.Address
-		}
 		log.Debug("deposit watcher: не удалось идентифицировать пользователя",
 			"memo", memo,
-			"source", sourceAddr,
+			"source", senderAddress,
+			"asset", asset,
 			"hash", tx.Hash)
-		return nil
+		// вернувшиеся (bounced) сообщения и суммы меньше минимальной не разбираются
+		if (tx.InMsg != nil && tx.InMsg.Bounced) || amountUnits < asset.MinDepositUnits() {
+			return false, nil
+		}
+		if memo == "" {
+			unmatchedReason = MemoEmpty
+		}
+		return false, w.queueUnmatched(ctx, tx, pricing, asset, amountUnits, senderAddress, memo, unmatchedReason)
 	}
 
 	// проверяем существует ли пользователь
@@ -253,63 +616,66 @@ func (w *DepositWatcher) processTransaction(ctx context.Context, tx *ton.Transac
 		log.Warn("deposit watcher: пользователь не найден",
 			"userID", userID,
 			"hash", tx.Hash)
-		return nil
+		return false, nil
 	}
 
-	// получаем сумму
-	amountNano := tx.InMsg.Value
-	if amountNano < ton.MinDepositNano {
+	// проверяем сумму (в минимальных единицах актива)
+	if amountUnits < asset.MinDepositUnits() {
 		log.Debug("deposit watcher: сумма меньше минимальной",
-			"amount", amountNano,
-			"min", ton.MinDepositNano)
-		return nil
+			"asset", asset,
+			"amount", amountUnits,
+			"min", asset.MinDepositUnits())
+		return false, nil
 	}
 
-	// конвертируем в коины
-	coinsCredited := ton.NanoToCoins(amountNano)
+	// конвертируем в коины по курсу актива
+	coinsCredited := rates.UnitsToCoins(asset, amountUnits)
 
 	log.Info("deposit watcher: обнаружен новый депозит",
 		"userID", userID,
-		"amountNano", amountNano,
-		"amountTON", ton.NanoToTON(amountNano),
+		"asset", asset,
+		"amountUnits", amountUnits,
+		"amount", ton.UnitsToFloat(asset, amountUnits),
 		"coins", coinsCredited,
 		"hash", tx.Hash)
 
-	// получаем адрес отправителя
-	sourceAddress := ""
-	if tx.InMsg != nil && tx.InMsg.Source != nil {
-		sourceAddress = tx.InMsg.Source.Address
-	}
-
-	// создаем запись депозита
+	// депозит, коины, строка transactions и аудит пишутся одной транзакцией БД
 	// GemsCredited используется для обратной совместимости с БД (колонка gems_credited)
 	// но фактически хранит coins
 	deposit := &domain.Deposit{
 		UserID:        userID,
-		WalletAddress: sourceAddress,
-		AmountNano:    amountNano,
+		WalletAddress: senderAddress,
+		AmountNano:    amountUnits,
 		GemsCredited:  coinsCredited, // сохраняем в gems_credited для совместимости с БД
 		CoinsCredited: coinsCredited, // также в CoinsCredited для JSON ответов
-		ExchangeRate:  ton.CoinsPerTON,
+		ExchangeRate:  int(rates[asset]),
 		TxHash:        tx.Hash,
 		TxLt:          tx.Lt,
-		Status:        domain.DepositStatusConfirmed,
 		Memo:          memo,
-		Processed:     true,
+		Asset:         string(asset),
+		// версия курса, по которой посчитаны коины
+		PricingVersion: pricing.ID,
 	}
 
-	if err := w.depositRepo.Create(ctx, deposit); err != nil {
-		return fmt.Errorf("ошибка создания депозита: %w", err)
+	newBalance, credited, err := creditDeposit(ctx, w.db, deposit, map[string]interface{}{
+		"source": "watcher",
+		"memo":   memo,
+	})
+	if err == nil && credited && depositCode != nil {
+		if err := w.codes.MarkUsed(ctx, depositCode); err != nil {
+			log.Warn("deposit watcher: не удалось отметить код депозита", "code", depositCode.Code, "error", err)
+		}
 	}
-
-	// начисляем коины пользователю
-	newBalance, err := w.userRepo.UpdateCoins(ctx, userID, coinsCredited)
 	if err != nil {
-		log.Error("deposit watcher: ошибка начисления коинов",
+		log.Error("deposit watcher: ошибка зачисления депозита",
 			"userID", userID,
 			"coins", coinsCredited,
+			"hash", tx.Hash,
 			"error", err)
-		return fmt.Errorf("ошибка начисления коинов: %w", err)
+		return false, err
+	}
+	if !credited {
+		return false, nil // обработано параллельно (опрос или бэкфилл)
 	}
 
 	log.Info("deposit watcher: депозит успешно обработан",
@@ -324,9 +690,10 @@ func (w *DepositWatcher) processTransaction(ctx context.Context, tx *ton.Transac
 		UserID:        userID,
 		Username:      user.Username,
 		TgID:          user.TgID,
-		WalletAddress: sourceAddress,
-		AmountNano:    amountNano,
-		AmountTON:     ton.NanoToTON(amountNano),
+		WalletAddress: senderAddress,
+		Asset:         asset,
+		AmountNano:    amountUnits,
+		Amount:        ton.UnitsToFloat(asset, amountUnits),
 		CoinsCredited: coinsCredited,
 		TxHash:        tx.Hash,
 		NewBalance:    newBalance,
@@ -342,29 +709,47 @@ func (w *DepositWatcher) processTransaction(ctx context.Context, tx *ton.Transac
 		go w.userNotifyCallback(notification)
 	}
 
-	return nil
+	return true, nil
 }
 
-// parseUserIDFromMemo извлекает userID из memo
-// поддерживает форматы: "deposit_123", "123", "user_123"
-func parseUserIDFromMemo(memo string) (int64, error) {
-	memo = strings.TrimSpace(memo)
+// queueUnmatched сохраняет несопоставленный перевод в очередь разбора и уведомляет админов
+func (w *DepositWatcher) queueUnmatched(ctx context.Context, tx *ton.Transaction, pricing *domain.PricingConfig, asset ton.Asset, amountUnits int64, senderAddress, memo, reason string) error {
+	log := logger.Get()
 
-	// формат deposit_123 или user_123
-	re := regexp.MustCompile(`(?:deposit_|user_)?(\d+)`)
-	matches := re.FindStringSubmatch(memo)
-	if len(matches) < 2 {
-		return 0, fmt.Errorf("неверный формат memo: %s", memo)
+	deposit := &domain.Deposit{
+		WalletAddress:   senderAddress,
+		AmountNano:      amountUnits,
+		ExchangeRate:    int(PricingRates(pricing)[asset]),
+		TxHash:          tx.Hash,
+		TxLt:            tx.Lt,
+		Memo:            memo,
+		Asset:           string(asset),
+		UnmatchedReason: reason,
+		// при назначении коины начисляются по этой версии курса
+		PricingVersion: pricing.ID,
 	}
-
-	userID, err := strconv.ParseInt(matches[1], 10, 64)
+	queued, err := queueUnmatchedDeposit(ctx, w.db, deposit)
 	if err != nil {
-		return 0, fmt.Errorf("ошибка парсинга userID: %w", err)
+		log.Error("deposit watcher: ошибка сохранения несопоставленного депозита",
+			"hash", tx.Hash,
+			"error", err)
+		return err
 	}
-
-	if userID <= 0 {
-		return 0, fmt.Errorf("некорректный userID: %d", userID)
+	if !queued {
+		return nil
 	}
 
-	return userID, nil
+	log.Warn("deposit watcher: перевод не сопоставлен с пользователем, отправлен на разбор",
+		"depositID", deposit.ID,
+		"memo", memo,
+		"reason", reason,
+		"source", senderAddress,
+		"asset", asset,
+		"amount", amountUnits,
+		"hash", tx.Hash)
+
+	if w.unmatchedCallback != nil {
+		go w.unmatchedCallback(*deposit)
+	}
+	return nil
 }
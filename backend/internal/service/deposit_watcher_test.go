@@ -0,0 +1,282 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/ton"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// кошелек с транзакциями lt 1..n; страницы отдаются от новых к старым, как в API
+func fakeWallet(n int64, pageSize int, calls *int) txPageFetcher {
+	return func(ctx context.Context, beforeLt int64) ([]ton.Transaction, error) {
+		*calls++
+		start := n
+		if beforeLt > 0 {
+			start = beforeLt - 1
+		}
+		var page []ton.Transaction
+		for lt := start; lt >= 1 && len(page) < pageSize; lt-- {
+			page = append(page, ton.Transaction{Lt: lt})
+		}
+		return page, nil
+	}
+}
+
+func TestCollectSince(t *testing.T) {
+	cases := []struct {
+		name      string
+		before    int64
+		cursor    int64
+		maxPages  int
+		wantFirst int64
+		wantLast  int64
+		wantCount int
+		reached   bool
+	}{
+		// всплеск из 120 переводов между опросами: листаем 3 страницы до курсора
+		{"burst", 0, 30, 10, 31, 150, 120, true},
+		{"nothing new", 0, 150, 10, 0, 0, 0, true},
+		// лимит страниц: курсор не достигнут
+		{"page limit", 0, 10, 2, 51, 150, 100, false},
+		// бэкфилл диапазона [40, 60]
+		{"backfill", 61, 39, 100, 40, 60, 21, true},
+		{"history end", 0, 0, 10, 1, 150, 150, true},
+	}
+	for _, tc := range cases {
+		calls := 0
+		txs, reached, err := collectSince(context.Background(), fakeWallet(150, 50, &calls), tc.before, tc.cursor, 50, tc.maxPages)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if reached != tc.reached || len(txs) != tc.wantCount {
+			t.Fatalf("%s: reached=%v count=%d, ожидалось %v %d", tc.name, reached, len(txs), tc.reached, tc.wantCount)
+		}
+		if tc.wantCount > 0 && (txs[0].Lt != tc.wantFirst || txs[len(txs)-1].Lt != tc.wantLast) {
+			t.Fatalf("%s: lt %d..%d, ожидалось %d..%d", tc.name, txs[0].Lt, txs[len(txs)-1].Lt, tc.wantFirst, tc.wantLast)
+		}
+		for i := 1; i < len(txs); i++ {
+			if txs[i].Lt <= txs[i-1].Lt {
+				t.Fatalf("%s: транзакции не по возрастанию lt", tc.name)
+			}
+		}
+	}
+}
+
+// DepositWatcher целиком на фейковом индексаторе: транзакции из фикстуры появляются в два приема.
+// Нужна мигрированная БД в TEST_DATABASE_URL
+func TestDepositWatcherReplay(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set; skipping integration test")
+	}
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	const (
+		platform = "0:1111111111111111111111111111111111111111111111111111111111111111"
+		sender   = "0:2222222222222222222222222222222222222222222222222222222222222222"
+	)
+	txs, err := ton.LoadTransactionsFixture("testdata/deposit_watcher_txs.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// код с опечаткой в контрольном символе с привязанного кошелька
+	code, err := newDepositCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrong := depositCodeAlphabet[(strings.IndexByte(depositCodeAlphabet, code[depositCodeBody])+1)%len(depositCodeAlphabet)]
+	typo := ton.Transaction{
+		Hash:    "6969696969696969696969696969696969696969696969696969696969696969",
+		Lt:      105,
+		Utime:   1760000105,
+		Success: true,
+		InMsg: &ton.Message{
+			Value:       3_000_000_000,
+			Source:      &ton.AccountAddress{Address: sender},
+			Destination: &ton.AccountAddress{Address: platform},
+			OpCode:      "0x00000000",
+			DecodedBody: &ton.DecodedBody{Text: code[:depositCodeBody] + string(wrong)},
+		},
+	}
+
+	hashes := make([]string, 0, len(txs)+1)
+	for _, tx := range txs {
+		hashes = append(hashes, tx.Hash)
+	}
+	hashes = append(hashes, typo.Hash)
+
+	cleanup := func() {
+		pool.Exec(ctx, `DELETE FROM transactions WHERE meta->>'tx_hash' = ANY($1)`, hashes)
+		pool.Exec(ctx, `DELETE FROM audit_logs WHERE details->>'tx_hash' = ANY($1)`, hashes)
+		pool.Exec(ctx, `DELETE FROM deposits WHERE tx_hash = ANY($1)`, hashes)
+		pool.Exec(ctx, `DELETE FROM wallets WHERE address = $1`, sender)
+		pool.Exec(ctx, `DELETE FROM chain_cursors WHERE name = $1`, depositCursorName+platform)
+		pool.Exec(ctx, `DELETE FROM users WHERE tg_id = -4501`)
+	}
+	cleanup()
+	t.Cleanup(cleanup)
+
+	var userID int64
+	if err := pool.QueryRow(ctx,
+		`INSERT INTO users (tg_id, username, first_name) VALUES (-4501, 'watcher_replay', 'replay') RETURNING id`,
+	).Scan(&userID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.Exec(ctx, `INSERT INTO wallets (user_id, address, raw_address) VALUES ($1, $2, $2)`, userID, sender); err != nil {
+		t.Fatal(err)
+	}
+
+	indexer := ton.NewFakeIndexer()
+	w := NewDepositWatcher(pool, indexer, platform, time.Minute)
+	coins := func() int64 {
+		var c int64
+		if err := pool.QueryRow(ctx, `SELECT coins FROM users WHERE id = $1`, userID).Scan(&c); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	// фикстура от новых к старым: сначала в сети только lt 100-101
+	indexer.AddTransactions(platform, txs[3:]...)
+	w.checkDeposits()
+	if got := coins(); got != 10 {
+		t.Fatalf("после первого опроса %d коинов, ожидалось 10", got)
+	}
+
+	// lt 102 меньше минимума, lt 103 - 2.5 TON с привязанного кошелька, но с memo не кодом,
+	// lt 104 - от неизвестного кошелька без memo
+	indexer.AddTransactions(platform, txs...)
+	w.checkDeposits()
+	w.checkDeposits()
+	if got := coins(); got != 10 {
+		t.Fatalf("после повторных опросов %d коинов, ожидалось 10", got)
+	}
+	if w.Cursor() != 104 {
+		t.Fatalf("курсор %d, ожидалось 104", w.Cursor())
+	}
+
+	unmatched := func(hash, want string) {
+		t.Helper()
+		var status, reason string
+		if err := pool.QueryRow(ctx,
+			`SELECT status, COALESCE(unmatched_reason, '') FROM deposits WHERE tx_hash = $1`, hash,
+		).Scan(&status, &reason); err != nil {
+			t.Fatal(err)
+		}
+		if status != string(domain.DepositStatusUnmatched) || reason != want {
+			t.Fatalf("депозит %s: %s/%s, ожидалось unmatched/%s", hash, status, reason, want)
+		}
+	}
+	unmatched(txs[0].Hash, MemoEmpty)
+	unmatched(txs[1].Hash, MemoNotACode)
+
+	// индексатор лежит: курсор и баланс не меняются
+	indexer.SetError(errors.New("indexer down"))
+	w.checkDeposits()
+	if w.Cursor() != 104 || coins() != 10 {
+		t.Fatal("ошибка индексатора изменила состояние")
+	}
+
+	// опечатка в коде не зачисляется владельцу привязанного кошелька, а ждет разбора
+	indexer.SetError(nil)
+	indexer.AddTransactions(platform, typo)
+	w.checkDeposits()
+	if w.Cursor() != 105 || coins() != 10 {
+		t.Fatalf("после опечатки в коде: курсор %d, %d коинов", w.Cursor(), coins())
+	}
+	unmatched(typo.Hash, MemoBadChecksum)
+}
+
+// за опрос курсор не достигнут: история догоняется следующими опросами, курсор сдвигается, когда участок пройден
+func TestDepositWatcherCursorNotReached(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set; skipping integration test")
+	}
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	const (
+		platform = "0:3333333333333333333333333333333333333333333333333333333333333333"
+		sender   = "0:4444444444444444444444444444444444444444444444444444444444444444"
+	)
+	// lt 1-10 до курсора, затем всплеск больше depositMaxPages страниц; перевод сразу за курсором
+	total := int64(10 + depositMaxPages*depositPageSize + depositPageSize)
+	txs := make([]ton.Transaction, 0, total)
+	for lt := int64(1); lt <= total; lt++ {
+		txs = append(txs, ton.Transaction{Hash: fmt.Sprintf("%064x", 0x4700000+lt), Lt: lt, Utime: 1760000000 + lt, Success: true})
+	}
+	deposit := &txs[10]
+	deposit.InMsg = &ton.Message{
+		Value:       1_000_000_000,
+		Source:      &ton.AccountAddress{Address: sender},
+		Destination: &ton.AccountAddress{Address: platform},
+	}
+
+	cleanup := func() {
+		pool.Exec(ctx, `DELETE FROM transactions WHERE meta->>'tx_hash' = $1`, deposit.Hash)
+		pool.Exec(ctx, `DELETE FROM audit_logs WHERE details->>'tx_hash' = $1`, deposit.Hash)
+		pool.Exec(ctx, `DELETE FROM deposits WHERE tx_hash = $1`, deposit.Hash)
+		pool.Exec(ctx, `DELETE FROM wallets WHERE address = $1`, sender)
+		pool.Exec(ctx, `DELETE FROM chain_cursors WHERE name = $1`, depositCursorName+platform)
+		pool.Exec(ctx, `DELETE FROM users WHERE tg_id = -4502`)
+	}
+	cleanup()
+	t.Cleanup(cleanup)
+
+	var userID int64
+	if err := pool.QueryRow(ctx,
+		`INSERT INTO users (tg_id, username, first_name) VALUES (-4502, 'watcher_gap', 'gap') RETURNING id`,
+	).Scan(&userID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.Exec(ctx, `INSERT INTO wallets (user_id, address, raw_address) VALUES ($1, $2, $2)`, userID, sender); err != nil {
+		t.Fatal(err)
+	}
+
+	indexer := ton.NewFakeIndexer()
+	w := NewDepositWatcher(pool, indexer, platform, time.Minute)
+	indexer.AddTransactions(platform, txs[:10]...)
+	w.checkDeposits()
+	if w.Cursor() != 10 {
+		t.Fatalf("начальный курсор %d, ожидалось 10", w.Cursor())
+	}
+
+	indexer.AddTransactions(platform, txs[10:]...)
+	w.checkDeposits()
+	if w.Cursor() != 10 || w.gap == nil {
+		t.Fatalf("курсор сдвинут до прохождения участка: %d", w.Cursor())
+	}
+
+	// второй опрос листает с самой старой полученной транзакции и доходит до курсора
+	w.checkDeposits()
+	if w.Cursor() != total || w.gap != nil {
+		t.Fatalf("курсор %d, ожидалось %d", w.Cursor(), total)
+	}
+	var credited int
+	if err := pool.QueryRow(ctx, `SELECT COUNT(*) FROM deposits WHERE tx_hash = $1 AND user_id = $2`, deposit.Hash, userID).Scan(&credited); err != nil {
+		t.Fatal(err)
+	}
+	if credited != 1 {
+		t.Fatal("перевод за курсором не зачислен")
+	}
+}
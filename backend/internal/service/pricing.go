@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/repository"
+	"telegram_webapp/internal/ton"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var ErrPricingNotConfigured = errors.New("курс коинов не задан (pricing_config пуст)")
+
+// форматы времени вступления в силу для /setpricing
+var pricingTimeLayouts = []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02"}
+
+// версии курса коинов и комиссии на вывод (таблица pricing_config)
+type PricingService struct {
+	db   *pgxpool.Pool
+	repo *repository.PricingRepository
+}
+
+func NewPricingService(db *pgxpool.Pool) *PricingService {
+	return &PricingService{db: db, repo: repository.NewPricingRepository(db)}
+}
+
+// Current версия, действующая сейчас
+func (s *PricingService) Current(ctx context.Context) (*domain.PricingConfig, error) {
+	return s.At(ctx, time.Now())
+}
+
+// At версия, действовавшая в момент at (время транзакции в сети, создания депозита)
+func (s *PricingService) At(ctx context.Context, at time.Time) (*domain.PricingConfig, error) {
+	p, err := s.repo.GetAt(ctx, at)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, ErrPricingNotConfigured
+	}
+	return p, nil
+}
+
+// Version версия по id (снимок курса в депозите или выводе)
+func (s *PricingService) Version(ctx context.Context, id int64) (*domain.PricingConfig, error) {
+	p, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, ErrPricingNotConfigured
+	}
+	return p, nil
+}
+
+// History последние версии, включая запланированные
+func (s *PricingService) History(ctx context.Context, limit int) ([]*domain.PricingConfig, error) {
+	return s.repo.GetHistory(ctx, limit)
+}
+
+// PricingRates курсы активов версии
+func PricingRates(p *domain.PricingConfig) ton.Rates {
+	return ton.Rates{
+		ton.AssetTON:  p.CoinsPerTON,
+		ton.AssetUSDT: p.CoinsPerUSDT,
+	}
+}
+
+// applyPricingParam меняет один параметр (ton, usdt, fee, maxday, from) с проверкой диапазона
+func applyPricingParam(p *domain.PricingConfig, key, value string) error {
+	switch key {
+	case "ton", "usdt":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < 1 || n > 1_000_000 {
+			return fmt.Errorf("%s: от 1 до 1000000 коинов за единицу", key)
+		}
+		if key == "ton" {
+			p.CoinsPerTON = n
+		} else {
+			p.CoinsPerUSDT = n
+		}
+	case "fee":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < 0 || n >= ton.MinWithdrawCoins {
+			return fmt.Errorf("fee: от 0 до %d коинов", ton.MinWithdrawCoins-1)
+		}
+		p.WithdrawFeeCoins = n
+	case "maxday":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < ton.MinWithdrawCoins {
+			return fmt.Errorf("maxday: не меньше %d коинов", ton.MinWithdrawCoins)
+		}
+		p.MaxWithdrawCoinsPerDay = n
+	case "from":
+		for _, layout := range pricingTimeLayouts {
+			if t, err := time.ParseInLocation(layout, value, time.UTC); err == nil {
+				p.EffectiveFrom = t
+				return nil
+			}
+		}
+		return fmt.Errorf("from: время в UTC, например 2026-01-31T12:00")
+	default:
+		return fmt.Errorf("неизвестный параметр %s (ton, usdt, fee, maxday, from)", key)
+	}
+	return nil
+}
+
+// Set добавляет версию на основе действующей; params - пары параметр=значение.
+// Без from версия вступает в силу сразу. Версия и аудит admin_set_pricing пишутся одной транзакцией БД
+func (s *PricingService) Set(ctx context.Context, params map[string]string, adminTgID int64) (*domain.PricingConfig, error) {
+	if len(params) == 0 {
+		return nil, fmt.Errorf("укажите хотя бы один параметр: ton, usdt, fee, maxday")
+	}
+	current, err := s.Current(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p := *current
+	p.EffectiveFrom = time.Now()
+	for key, value := range params {
+		if err := applyPricingParam(&p, key, value); err != nil {
+			return nil, err
+		}
+	}
+	if p.EffectiveFrom.Before(time.Now().Add(-time.Minute)) {
+		return nil, fmt.Errorf("from: задним числом менять курс нельзя")
+	}
+	p.CreatedBy = &adminTgID
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := s.repo.CreateWithTx(ctx, tx, &p); err != nil {
+		return nil, fmt.Errorf("ошибка записи курса: %w", err)
+	}
+	audit := &domain.AuditLog{
+		Action:   domain.AuditActionAdminSetPricing,
+		Category: domain.AuditCategoryAdmin,
+		Details: map[string]interface{}{
+			"pricing_version":            p.ID,
+			"previous_version":           current.ID,
+			"coins_per_ton":              p.CoinsPerTON,
+			"coins_per_usdt":             p.CoinsPerUSDT,
+			"withdraw_fee_coins":         p.WithdrawFeeCoins,
+			"max_withdraw_coins_per_day": p.MaxWithdrawCoinsPerDay,
+			"effective_from":             p.EffectiveFrom,
+			"admin_tg_id":                adminTgID,
+		},
+	}
+	if err := repository.NewAuditRepository(s.db).CreateWithTx(ctx, tx, audit); err != nil {
+		return nil, fmt.Errorf("ошибка записи аудита: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
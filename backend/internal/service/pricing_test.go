@@ -0,0 +1,29 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"telegram_webapp/internal/domain"
+)
+
+func TestApplyPricingParam(t *testing.T) {
+	p := &domain.PricingConfig{CoinsPerTON: 10, CoinsPerUSDT: 2, WithdrawFeeCoins: 1, MaxWithdrawCoinsPerDay: 1000}
+	params := map[string]string{"ton": "12", "usdt": "3", "fee": "0", "maxday": "500", "from": "2026-01-31T12:00"}
+	for key, value := range params {
+		if err := applyPricingParam(p, key, value); err != nil {
+			t.Fatalf("%s=%s: %v", key, value, err)
+		}
+	}
+	want := time.Date(2026, 1, 31, 12, 0, 0, 0, time.UTC)
+	if p.CoinsPerTON != 12 || p.CoinsPerUSDT != 3 || p.WithdrawFeeCoins != 0 || p.MaxWithdrawCoinsPerDay != 500 || !p.EffectiveFrom.Equal(want) {
+		t.Fatalf("параметры применены неверно: %+v", p)
+	}
+
+	// курс 0, комиссия не меньше минимального вывода, лимит меньше минимального вывода
+	for key, value := range map[string]string{"ton": "0", "fee": "10", "maxday": "5", "from": "завтра", "rate": "10"} {
+		if err := applyPricingParam(p, key, value); err == nil {
+			t.Errorf("%s=%s принят", key, value)
+		}
+	}
+}
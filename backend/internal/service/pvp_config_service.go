@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/game"
+	"telegram_webapp/internal/repository"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrPvPConfigGame     = errors.New("игра: rps или mines")
+	ErrPvPConfigNotFound = errors.New("уровень ставки не найден")
+	ErrPvPConfigBaseTier = errors.New("базовый уровень (min_stake 0) удалить нельзя, выключите его через enabled=off")
+)
+
+// параметры PvP игр по уровням ставок (таблица pvp_game_configs)
+type PvPConfigService struct {
+	repo *repository.PvPConfigRepository
+}
+
+func NewPvPConfigService(db *pgxpool.Pool) *PvPConfigService {
+	return &PvPConfigService{repo: repository.NewPvPConfigRepository(db)}
+}
+
+func (s *PvPConfigService) List(ctx context.Context) ([]*domain.PvPGameConfig, error) {
+	return s.repo.List(ctx)
+}
+
+func validPvPConfigGame(gameType domain.GameType) bool {
+	return gameType == domain.GameTypeRPS || gameType == domain.GameTypeMines
+}
+
+// новый уровень наследует параметры ближайшего нижнего уровня, иначе встроенные значения игры
+func (s *PvPConfigService) baseFor(ctx context.Context, gameType domain.GameType, minStake int64) (*domain.PvPGameConfig, error) {
+	configs, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var base *domain.PvPGameConfig
+	for _, c := range configs {
+		if c.GameType == gameType && c.MinStake <= minStake {
+			base = c
+		}
+	}
+	if base != nil {
+		copied := *base
+		return &copied, nil
+	}
+	rules := game.DefaultRules(game.GameType(gameType))
+	return &domain.PvPGameConfig{
+		SetupTimeoutSec: int(rules.SetupTimeout.Seconds()),
+		TurnTimeoutSec:  int(rules.TurnTimeout.Seconds()),
+		MaxRounds:       rules.MaxRounds,
+		Enabled:         true,
+	}, nil
+}
+
+// applyPvPConfigParam меняет один параметр (setup, turn, rake, rounds, enabled) с проверкой диапазона
+func applyPvPConfigParam(c *domain.PvPGameConfig, key, value string) error {
+	switch key {
+	case "setup":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 5 || n > 120 {
+			return fmt.Errorf("setup: от 5 до 120 секунд")
+		}
+		c.SetupTimeoutSec = n
+	case "turn":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 5 || n > 60 {
+			return fmt.Errorf("turn: от 5 до 60 секунд")
+		}
+		c.TurnTimeoutSec = n
+	case "rake":
+		f, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil || f < 0 || f >= 50 {
+			return fmt.Errorf("rake: от 0 до 50%%")
+		}
+		c.RakePercent = f
+	case "rounds":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > 20 {
+			return fmt.Errorf("rounds: от 1 до 20")
+		}
+		c.MaxRounds = n
+	case "enabled":
+		switch value {
+		case "on", "true", "1":
+			c.Enabled = true
+		case "off", "false", "0":
+			c.Enabled = false
+		default:
+			return fmt.Errorf("enabled: on или off")
+		}
+	default:
+		return fmt.Errorf("неизвестный параметр %s (setup, turn, rake, rounds, enabled)", key)
+	}
+	return nil
+}
+
+// Set создает или меняет уровень ставки; params - пары параметр=значение
+func (s *PvPConfigService) Set(ctx context.Context, gameType domain.GameType, minStake int64, params map[string]string, adminTgID int64) (*domain.PvPGameConfig, error) {
+	if !validPvPConfigGame(gameType) {
+		return nil, ErrPvPConfigGame
+	}
+	if minStake < 0 {
+		return nil, fmt.Errorf("min_stake не может быть отрицательным")
+	}
+
+	c, err := s.repo.Get(ctx, gameType, minStake)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		if c, err = s.baseFor(ctx, gameType, minStake); err != nil {
+			return nil, err
+		}
+	}
+	for key, value := range params {
+		if err := applyPvPConfigParam(c, key, value); err != nil {
+			return nil, err
+		}
+	}
+
+	c.GameType = gameType
+	c.MinStake = minStake
+	c.UpdatedBy = &adminTgID
+	if err := s.repo.Upsert(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Delete удаляет уровень ставки (ставки этого уровня переходят на нижний)
+func (s *PvPConfigService) Delete(ctx context.Context, gameType domain.GameType, minStake int64) error {
+	if !validPvPConfigGame(gameType) {
+		return ErrPvPConfigGame
+	}
+	if minStake == 0 {
+		return ErrPvPConfigBaseTier
+	}
+	deleted, err := s.repo.Delete(ctx, gameType, minStake)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrPvPConfigNotFound
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package service
+
+// размер сетки: ближайшая степень двойки, не меньшая числа участников
+func bracketSize(players int) int {
+	size := 1
+	for size < players {
+		size *= 2
+	}
+	return size
+}
+
+// число раундов для сетки размера size
+func bracketRounds(size int) int {
+	rounds := 0
+	for n := size; n > 1; n /= 2 {
+		rounds++
+	}
+	return rounds
+}
+
+// bracketOrder возвращает порядок посевов в первом раунде: 1 против size, 2 против size-1 и т.д.,
+// сильнейшие посевы встречаются как можно позже, а свободные места (посевы > числа игроков)
+// достаются первым посевам
+func bracketOrder(size int) []int {
+	order := []int{1}
+	for n := 1; n < size; n *= 2 {
+		next := make([]int, 0, len(order)*2)
+		for _, seed := range order {
+			next = append(next, seed, 2*n+1-seed)
+		}
+		order = next
+	}
+	return order
+}
+
+// место выбывшего в раунде round из rounds (проигравший финала - 2, полуфинала - 3, ...)
+func eliminationPlace(round, rounds int) int {
+	return 1<<(rounds-round) + 1
+}
+
+// prizeShares делит призовой фонд по местам.
+// percents[i] - доля i-й группы мест (1, 2, 3-4, 5-8, ...), делится поровну внутри группы.
+// Остаток от деления и доли пустых групп достаются лучшей занятой группе.
+func prizeShares(pool int64, percents []int, places map[int64]int) map[int64]int64 {
+	groups := make(map[int][]int64)
+	best := 0
+	for userID, place := range places {
+		groups[place] = append(groups[place], userID)
+		if best == 0 || place < best {
+			best = place
+		}
+	}
+
+	shares := make(map[int64]int64)
+	if best == 0 || pool <= 0 {
+		return shares
+	}
+
+	var paid int64
+	for i, percent := range percents {
+		place := 1
+		if i > 0 {
+			place = 1<<(i-1) + 1
+		}
+		members := groups[place]
+		if len(members) == 0 {
+			continue
+		}
+		each := pool * int64(percent) / 100 / int64(len(members))
+		for _, userID := range members {
+			shares[userID] += each
+			paid += each
+		}
+	}
+
+	if rest := pool - paid; rest > 0 {
+		members := groups[best]
+		each := rest / int64(len(members))
+		for _, userID := range members {
+			shares[userID] += each
+		}
+		// неделимый остаток - первому по id, чтобы результат не зависел от порядка обхода map
+		first := members[0]
+		for _, userID := range members {
+			if userID < first {
+				first = userID
+			}
+		}
+		shares[first] += rest - each*int64(len(members))
+	}
+	return shares
+}
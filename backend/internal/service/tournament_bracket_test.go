@@ -0,0 +1,48 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBracketOrder(t *testing.T) {
+	if got := bracketOrder(8); !reflect.DeepEqual(got, []int{1, 8, 4, 5, 2, 7, 3, 6}) {
+		t.Fatalf("порядок посевов на 8: %v", got)
+	}
+
+	// 5 игроков в сетке на 8: свободные места (6, 7, 8) не встречаются друг с другом
+	order := bracketOrder(bracketSize(5))
+	for i := 0; i < len(order); i += 2 {
+		if order[i] > 5 && order[i+1] > 5 {
+			t.Fatalf("матч из двух свободных мест: %v", order)
+		}
+	}
+	if bracketRounds(bracketSize(5)) != 3 {
+		t.Fatal("на 5 игроков нужно 3 раунда")
+	}
+	if eliminationPlace(3, 3) != 2 || eliminationPlace(2, 3) != 3 || eliminationPlace(1, 3) != 5 {
+		t.Fatal("неверные места выбывших")
+	}
+}
+
+func TestPrizeShares(t *testing.T) {
+	// 1 место, 2 место, два полуфиналиста
+	places := map[int64]int{10: 1, 20: 2, 30: 3, 40: 3}
+	shares := prizeShares(1000, []int{50, 30, 20}, places)
+	want := map[int64]int64{10: 500, 20: 300, 30: 100, 40: 100}
+	if !reflect.DeepEqual(shares, want) {
+		t.Fatalf("доли: %v", shares)
+	}
+
+	// остаток от деления и доли пустых групп уходят чемпиону
+	shares = prizeShares(101, []int{60, 30, 10}, map[int64]int{1: 1, 2: 2})
+	if shares[1] != 71 || shares[2] != 30 {
+		t.Fatalf("остаток: %v", shares)
+	}
+
+	// финал не сыгран: лучшая занятая группа - вторые места
+	shares = prizeShares(100, []int{70, 30}, map[int64]int{1: 2, 2: 2})
+	if shares[1]+shares[2] != 100 {
+		t.Fatalf("без чемпиона фонд распределен не полностью: %v", shares)
+	}
+}
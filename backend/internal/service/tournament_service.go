@@ -0,0 +1,686 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/logger"
+	"telegram_webapp/internal/repository"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrTournamentNotFound          = errors.New("турнир не найден")
+	ErrTournamentInvalid           = errors.New("неверные параметры турнира")
+	ErrTournamentClosed            = errors.New("регистрация на турнир закрыта")
+	ErrTournamentFull              = errors.New("турнир заполнен")
+	ErrTournamentAlreadyRegistered = errors.New("уже зарегистрирован на турнир")
+	ErrTournamentNotRegistered     = errors.New("не зарегистрирован на турнир")
+	ErrTournamentFinished          = errors.New("турнир уже завершен")
+)
+
+// время на подключение к матчу по умолчанию
+const defaultTournamentJoinTimeout = 2 * time.Minute
+
+// TournamentRoomStarter создает PvP комнату для матча сетки (реализует ws.Hub).
+// report вызывается один раз с победителем (nil - ничья или никто не пришел) и причиной.
+type TournamentRoomStarter interface {
+	StartTournamentMatch(t *domain.Tournament, m *domain.TournamentMatch, report func(winnerID *int64, reason string)) (string, error)
+}
+
+// проводит турниры на выбывание: регистрация со взносом, сетка, матчи в комнатах, призы
+type TournamentService struct {
+	db    *pgxpool.Pool
+	repo  *repository.TournamentRepository
+	rooms TournamentRoomStarter
+
+	mu       sync.Mutex
+	launched map[int64]bool // матчи, комнаты которых созданы этим процессом
+}
+
+// создает новый сервис турниров
+func NewTournamentService(db *pgxpool.Pool) *TournamentService {
+	return &TournamentService{
+		db:       db,
+		repo:     repository.NewTournamentRepository(db),
+		launched: make(map[int64]bool),
+	}
+}
+
+// устанавливает хаб, в котором играются матчи (без него турниры только регистрируются)
+func (s *TournamentService) SetRoomStarter(rooms TournamentRoomStarter) {
+	s.rooms = rooms
+}
+
+// параметры нового турнира
+type CreateTournamentParams struct {
+	Title         string
+	GameType      domain.GameType
+	Currency      string
+	EntryFee      int64
+	MaxPlayers    int
+	BestOf        int
+	PrizePercents []int
+	StartsAt      time.Time
+	JoinTimeout   time.Duration
+	CreatedBy     int64
+}
+
+// создает турнир с открытой регистрацией
+func (s *TournamentService) Create(ctx context.Context, p CreateTournamentParams) (*domain.Tournament, error) {
+	if p.GameType != domain.GameTypeRPS && p.GameType != domain.GameTypeMines {
+		return nil, ErrTournamentInvalid
+	}
+	if p.Currency != string(domain.CurrencyGems) && p.Currency != string(domain.CurrencyCoins) {
+		return nil, ErrTournamentInvalid
+	}
+	if p.EntryFee < 0 || p.MaxPlayers < domain.TournamentMinPlayers || p.MaxPlayers > 256 {
+		return nil, ErrTournamentInvalid
+	}
+	if p.BestOf == 0 {
+		p.BestOf = 1
+	}
+	if p.BestOf != 1 && p.BestOf != 3 && p.BestOf != 5 {
+		return nil, ErrTournamentInvalid
+	}
+	if len(p.PrizePercents) == 0 {
+		p.PrizePercents = []int{100}
+	}
+	total := 0
+	for _, percent := range p.PrizePercents {
+		if percent <= 0 {
+			return nil, ErrTournamentInvalid
+		}
+		total += percent
+	}
+	// групп мест не больше, чем раундов + 1 (чемпион, финалист, полуфиналисты, ...)
+	if total != 100 || len(p.PrizePercents) > bracketRounds(bracketSize(p.MaxPlayers))+1 {
+		return nil, ErrTournamentInvalid
+	}
+	if !p.StartsAt.After(time.Now()) {
+		return nil, ErrTournamentInvalid
+	}
+	if p.JoinTimeout <= 0 {
+		p.JoinTimeout = defaultTournamentJoinTimeout
+	}
+
+	t := &domain.Tournament{
+		Title:          p.Title,
+		GameType:       p.GameType,
+		Currency:       p.Currency,
+		EntryFee:       p.EntryFee,
+		MaxPlayers:     p.MaxPlayers,
+		BestOf:         p.BestOf,
+		PrizePercents:  p.PrizePercents,
+		JoinTimeoutSec: int(p.JoinTimeout.Seconds()),
+		StartsAt:       p.StartsAt,
+	}
+	if p.CreatedBy != 0 {
+		t.CreatedBy = &p.CreatedBy
+	}
+	if err := s.repo.Create(ctx, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// возвращает турнир по id
+func (s *TournamentService) Get(ctx context.Context, id int64) (*domain.Tournament, error) {
+	t, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, ErrTournamentNotFound
+	}
+	return t, nil
+}
+
+// турниры с открытой регистрацией и идущие
+func (s *TournamentService) ListActive(ctx context.Context, limit int) ([]domain.Tournament, error) {
+	return s.repo.List(ctx, []domain.TournamentStatus{domain.TournamentStatusRegistration, domain.TournamentStatusRunning}, limit)
+}
+
+// участники турнира с местами и призами
+func (s *TournamentService) Standings(ctx context.Context, id int64) ([]domain.TournamentEntry, error) {
+	return s.repo.GetEntries(ctx, id)
+}
+
+// матчи сетки по раундам
+func (s *TournamentService) Bracket(ctx context.Context, id int64) ([]domain.TournamentMatch, error) {
+	return s.repo.GetMatches(ctx, id)
+}
+
+// регистрирует игрока и списывает взнос в призовой фонд
+func (s *TournamentService) Register(ctx context.Context, id, userID int64) (*domain.Tournament, error) {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	t, err := s.repo.GetForUpdateWithTx(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, ErrTournamentNotFound
+	}
+	if !t.RegistrationOpen() {
+		return nil, ErrTournamentClosed
+	}
+	if t.Players >= t.MaxPlayers {
+		return nil, ErrTournamentFull
+	}
+
+	added, err := s.repo.AddEntryWithTx(ctx, tx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !added {
+		return nil, ErrTournamentAlreadyRegistered
+	}
+	if t.EntryFee > 0 {
+		if err := debitWithTx(ctx, tx, userID, t.Currency, t.EntryFee); err != nil {
+			return nil, err
+		}
+		if err := s.repo.AddPrizePoolWithTx(ctx, tx, id, t.EntryFee); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	t.Players++
+	t.PrizePool += t.EntryFee
+	return t, nil
+}
+
+// снимает игрока с турнира до старта и возвращает взнос
+func (s *TournamentService) Unregister(ctx context.Context, id, userID int64) error {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	t, err := s.repo.GetForUpdateWithTx(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		return ErrTournamentNotFound
+	}
+	if !t.RegistrationOpen() {
+		return ErrTournamentClosed
+	}
+
+	removed, err := s.repo.RemoveEntryWithTx(ctx, tx, id, userID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrTournamentNotRegistered
+	}
+	if t.EntryFee > 0 {
+		if err := creditWithTx(ctx, tx, userID, t.Currency, t.EntryFee); err != nil {
+			return err
+		}
+		if err := s.repo.AddPrizePoolWithTx(ctx, tx, id, -t.EntryFee); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// отменяет турнир и возвращает взносы всем участникам
+// результаты матчей, которые еще играются, после отмены игнорируются
+func (s *TournamentService) Cancel(ctx context.Context, id int64) (*domain.Tournament, int, error) {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	t, err := s.repo.GetForUpdateWithTx(ctx, tx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	if t == nil {
+		return nil, 0, ErrTournamentNotFound
+	}
+	if t.Status != domain.TournamentStatusRegistration && t.Status != domain.TournamentStatusRunning {
+		return nil, 0, ErrTournamentFinished
+	}
+
+	refunded, err := s.cancelWithTx(ctx, tx, t)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, 0, err
+	}
+	t.Status = domain.TournamentStatusCancelled
+	return t, refunded, nil
+}
+
+func (s *TournamentService) cancelWithTx(ctx context.Context, tx pgx.Tx, t *domain.Tournament) (int, error) {
+	entries, err := s.repo.GetEntriesWithTx(ctx, tx, t.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	refunded := 0
+	for i := range entries {
+		e := &entries[i]
+		if e.Status == domain.TournamentEntryRefunded {
+			continue
+		}
+		if t.EntryFee > 0 {
+			if err := creditWithTx(ctx, tx, e.UserID, t.Currency, t.EntryFee); err != nil {
+				return 0, err
+			}
+		}
+		e.Status = domain.TournamentEntryRefunded
+		if err := s.repo.UpdateEntryWithTx(ctx, tx, e); err != nil {
+			return 0, err
+		}
+		refunded++
+	}
+
+	if err := s.repo.SetStatusWithTx(ctx, tx, t.ID, domain.TournamentStatusCancelled); err != nil {
+		return 0, err
+	}
+	return refunded, nil
+}
+
+// запускает турниры, у которых закончилась регистрация; возвращает количество
+func (s *TournamentService) StartDue(ctx context.Context) (int, error) {
+	ids, err := s.repo.GetDueIDs(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	started := 0
+	for _, id := range ids {
+		ok, err := s.start(ctx, id)
+		if err != nil {
+			logger.Error("TournamentService.StartDue: не удалось запустить турнир", "error", err, "tournament_id", id)
+			continue
+		}
+		if ok {
+			started++
+		}
+	}
+	return started, nil
+}
+
+// формирует сетку и запускает первый раунд
+// при недоборе участников турнир отменяется с возвратом взносов
+func (s *TournamentService) start(ctx context.Context, id int64) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	t, err := s.repo.GetForUpdateWithTx(ctx, tx, id)
+	if err != nil || t == nil || t.Status != domain.TournamentStatusRegistration {
+		return false, err
+	}
+
+	entries, err := s.repo.GetEntriesWithTx(ctx, tx, id)
+	if err != nil {
+		return false, err
+	}
+	if len(entries) < domain.TournamentMinPlayers {
+		refunded, err := s.cancelWithTx(ctx, tx, t)
+		if err != nil {
+			return false, err
+		}
+		logger.Info("TournamentService: недобор участников, турнир отменен", "tournament_id", id, "refunded", refunded)
+		return false, tx.Commit(ctx)
+	}
+
+	// посев случайный: порядок регистрации не дает преимущества
+	rand.Shuffle(len(entries), func(i, j int) { entries[i], entries[j] = entries[j], entries[i] })
+	for i := range entries {
+		seed := i + 1
+		entries[i].Seed = &seed
+		if err := s.repo.UpdateEntryWithTx(ctx, tx, &entries[i]); err != nil {
+			return false, err
+		}
+	}
+
+	size := bracketSize(len(entries))
+	rounds := bracketRounds(size)
+	t.Rounds = rounds
+	if err := s.repo.SetRoundsWithTx(ctx, tx, id, rounds); err != nil {
+		return false, err
+	}
+	if err := s.repo.SetStatusWithTx(ctx, tx, id, domain.TournamentStatusRunning); err != nil {
+		return false, err
+	}
+
+	order := bracketOrder(size)
+	playerAt := func(seed int) *int64 {
+		if seed > len(entries) {
+			return nil
+		}
+		return &entries[seed-1].UserID
+	}
+
+	var first []*domain.TournamentMatch
+	for round := 1; round <= rounds; round++ {
+		matches := size >> round
+		for pos := 0; pos < matches; pos++ {
+			m := &domain.TournamentMatch{
+				TournamentID: id,
+				Round:        round,
+				Position:     pos,
+				Status:       domain.TournamentMatchPending,
+			}
+			if round == 1 {
+				m.Player1ID = playerAt(order[2*pos])
+				m.Player2ID = playerAt(order[2*pos+1])
+				first = append(first, m)
+			}
+			if err := s.repo.CreateMatchWithTx(ctx, tx, m); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	// свободные места в сетке - проход без игры, остальные матчи стартуют
+	var ready []*domain.TournamentMatch
+	for _, m := range first {
+		next, err := s.settleWithTx(ctx, tx, t, m)
+		if err != nil {
+			return false, err
+		}
+		ready = append(ready, next...)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+
+	logger.Info("TournamentService: турнир запущен", "tournament_id", id, "players", len(entries), "rounds", rounds)
+	s.launch(t, ready)
+	return true, nil
+}
+
+// ReportResult принимает итог матча из комнаты и двигает сетку
+// winnerID == nil: ничья (победитель по жребию) или никто не пришел (reason no_show)
+func (s *TournamentService) ReportResult(ctx context.Context, matchID int64, winnerID *int64, reason string) error {
+	m, err := s.repo.GetMatch(ctx, matchID)
+	if err != nil || m == nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// блокировка турнира сериализует продвижение по сетке
+	t, err := s.repo.GetForUpdateWithTx(ctx, tx, m.TournamentID)
+	if err != nil || t == nil {
+		return err
+	}
+	if t.Status != domain.TournamentStatusRunning {
+		logger.Info("TournamentService.ReportResult: турнир не идет, результат проигнорирован", "tournament_id", t.ID, "match_id", matchID)
+		return nil
+	}
+
+	m, err = s.repo.GetMatchForUpdateWithTx(ctx, tx, matchID)
+	if err != nil || m == nil || m.Status != domain.TournamentMatchPlaying {
+		return err
+	}
+
+	if winnerID != nil && !m.HasPlayer(*winnerID) {
+		winnerID = nil
+	}
+	if winnerID == nil && reason != domain.TournamentReasonNoShow {
+		// ничья после всех партий - жребий
+		winnerID = m.Player1ID
+		if rand.Intn(2) == 1 {
+			winnerID = m.Player2ID
+		}
+		reason = domain.TournamentReasonTiebreak
+	}
+
+	ready, err := s.resolveWithTx(ctx, tx, t, m, winnerID, reason)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	logger.Info("TournamentService: матч завершен", "tournament_id", t.ID, "match_id", matchID,
+		"round", m.Round, "winner_id", winnerID, "reason", reason)
+	s.launch(t, ready)
+	return nil
+}
+
+// settleWithTx решает, что делать с матчем, чьи участники известны:
+// двое - матч стартует, один - проход без игры, никого - матч пуст
+func (s *TournamentService) settleWithTx(ctx context.Context, tx pgx.Tx, t *domain.Tournament, m *domain.TournamentMatch) ([]*domain.TournamentMatch, error) {
+	switch {
+	case m.Player1ID != nil && m.Player2ID != nil:
+		now := time.Now()
+		deadline := now.Add(time.Duration(t.JoinTimeoutSec) * time.Second)
+		m.Status = domain.TournamentMatchPlaying
+		m.StartedAt = &now
+		m.Deadline = &deadline
+		if err := s.repo.UpdateMatchWithTx(ctx, tx, m); err != nil {
+			return nil, err
+		}
+		return []*domain.TournamentMatch{m}, nil
+
+	case m.Player1ID != nil:
+		return s.resolveWithTx(ctx, tx, t, m, m.Player1ID, domain.TournamentReasonWalkover)
+	case m.Player2ID != nil:
+		return s.resolveWithTx(ctx, tx, t, m, m.Player2ID, domain.TournamentReasonWalkover)
+	}
+	return s.resolveWithTx(ctx, tx, t, m, nil, domain.TournamentReasonWalkover)
+}
+
+// resolveWithTx фиксирует итог матча, проводит победителя в следующий раунд
+// и возвращает матчи, которые можно запускать
+func (s *TournamentService) resolveWithTx(ctx context.Context, tx pgx.Tx, t *domain.Tournament, m *domain.TournamentMatch, winnerID *int64, reason string) ([]*domain.TournamentMatch, error) {
+	now := time.Now()
+	m.WinnerID = winnerID
+	m.Reason = &reason
+	m.FinishedAt = &now
+	m.Status = domain.TournamentMatchFinished
+	if winnerID == nil {
+		m.Status = domain.TournamentMatchVoid
+	}
+	if err := s.repo.UpdateMatchWithTx(ctx, tx, m); err != nil {
+		return nil, err
+	}
+
+	place := eliminationPlace(m.Round, t.Rounds)
+	for _, playerID := range []*int64{m.Player1ID, m.Player2ID} {
+		if playerID == nil || (winnerID != nil && *playerID == *winnerID) {
+			continue
+		}
+		if err := s.repo.EliminateEntryWithTx(ctx, tx, t.ID, *playerID, place); err != nil {
+			return nil, err
+		}
+	}
+
+	if m.Round == t.Rounds {
+		return nil, s.finishWithTx(ctx, tx, t, winnerID)
+	}
+
+	next, err := s.repo.GetMatchAtWithTx(ctx, tx, t.ID, m.Round+1, m.Position/2)
+	if err != nil {
+		return nil, err
+	}
+	if m.Position%2 == 0 {
+		next.Player1ID = winnerID
+	} else {
+		next.Player2ID = winnerID
+	}
+
+	sibling, err := s.repo.GetMatchAtWithTx(ctx, tx, t.ID, m.Round, m.Position^1)
+	if err != nil {
+		return nil, err
+	}
+	if !sibling.Resolved() {
+		return nil, s.repo.UpdateMatchWithTx(ctx, tx, next)
+	}
+	return s.settleWithTx(ctx, tx, t, next)
+}
+
+// finishWithTx распределяет призовой фонд по местам и завершает турнир
+func (s *TournamentService) finishWithTx(ctx context.Context, tx pgx.Tx, t *domain.Tournament, championID *int64) error {
+	entries, err := s.repo.GetEntriesWithTx(ctx, tx, t.ID)
+	if err != nil {
+		return err
+	}
+
+	places := make(map[int64]int)
+	for i := range entries {
+		e := &entries[i]
+		if championID != nil && e.UserID == *championID {
+			first := 1
+			e.Place = &first
+		}
+		if e.Place != nil && e.Status != domain.TournamentEntryRefunded {
+			places[e.UserID] = *e.Place
+		}
+	}
+
+	shares := prizeShares(t.PrizePool, t.PrizePercents, places)
+	for i := range entries {
+		e := &entries[i]
+		if _, placed := places[e.UserID]; !placed {
+			continue
+		}
+		e.Prize = shares[e.UserID]
+		if e.Prize > 0 {
+			if err := creditWithTx(ctx, tx, e.UserID, t.Currency, e.Prize); err != nil {
+				return err
+			}
+		}
+		if err := s.repo.UpdateEntryWithTx(ctx, tx, e); err != nil {
+			return err
+		}
+	}
+
+	logger.Info("TournamentService: турнир завершен", "tournament_id", t.ID, "champion_id", championID, "prize_pool", t.PrizePool)
+	return s.repo.SetStatusWithTx(ctx, tx, t.ID, domain.TournamentStatusFinished)
+}
+
+// создает комнаты для готовых матчей
+func (s *TournamentService) launch(t *domain.Tournament, matches []*domain.TournamentMatch) {
+	if s.rooms == nil {
+		return
+	}
+
+	for _, m := range matches {
+		s.mu.Lock()
+		if s.launched[m.ID] {
+			s.mu.Unlock()
+			continue
+		}
+		// матч остается помеченным и после завершения: устаревший список из ResumeMatches не перезапустит его
+		s.launched[m.ID] = true
+		s.mu.Unlock()
+
+		matchID := m.ID
+		roomID, err := s.rooms.StartTournamentMatch(t, m, func(winnerID *int64, reason string) {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if err := s.ReportResult(ctx, matchID, winnerID, reason); err != nil {
+				logger.Error("TournamentService: не удалось сохранить итог матча", "error", err, "match_id", matchID)
+			}
+		})
+		if err != nil {
+			logger.Error("TournamentService: не удалось создать комнату матча", "error", err, "match_id", matchID)
+			s.mu.Lock()
+			delete(s.launched, matchID)
+			s.mu.Unlock()
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := s.repo.SetMatchRoom(ctx, matchID, roomID); err != nil {
+			logger.Error("TournamentService: не удалось сохранить комнату матча", "error", err, "match_id", matchID)
+		}
+		cancel()
+	}
+}
+
+// ResumeMatches пересоздает комнаты матчей, которые играются, но не принадлежат этому процессу
+// (комнаты живут в памяти и теряются при рестарте); игроки получают новый дедлайн подключения
+func (s *TournamentService) ResumeMatches(ctx context.Context) error {
+	if s.rooms == nil {
+		return nil
+	}
+
+	matches, err := s.repo.GetPlayingMatches(ctx)
+	if err != nil {
+		return err
+	}
+
+	tournaments := make(map[int64]*domain.Tournament)
+	for i := range matches {
+		m := &matches[i]
+		s.mu.Lock()
+		launched := s.launched[m.ID]
+		s.mu.Unlock()
+		if launched {
+			continue
+		}
+
+		t := tournaments[m.TournamentID]
+		if t == nil {
+			if t, err = s.repo.GetByID(ctx, m.TournamentID); err != nil || t == nil {
+				continue
+			}
+			tournaments[t.ID] = t
+		}
+
+		deadline := time.Now().Add(time.Duration(t.JoinTimeoutSec) * time.Second)
+		if err := s.repo.SetMatchDeadline(ctx, m.ID, deadline); err != nil {
+			return err
+		}
+		m.Deadline = &deadline
+		logger.Info("TournamentService: перезапуск матча", "tournament_id", t.ID, "match_id", m.ID)
+		s.launch(t, []*domain.TournamentMatch{m})
+	}
+	return nil
+}
+
+// периодически запускает турниры и восстанавливает матчи
+func (s *TournamentService) StartWorker(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			if _, err := s.StartDue(ctx); err != nil {
+				logger.Error("TournamentService: ошибка запуска турниров", "error", err)
+			}
+			if err := s.ResumeMatches(ctx); err != nil {
+				logger.Error("TournamentService: ошибка восстановления матчей", "error", err)
+			}
+			cancel()
+		}
+	}()
+}
@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/logger"
+	"telegram_webapp/internal/ton"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	treasuryBalanceGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "treasury_hot_wallet_balance",
+		Help: "Hot wallet balance in whole units of the asset",
+	}, []string{"asset"})
+	treasuryPendingGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "treasury_pending_withdrawals",
+		Help: "Pending and processing withdrawals in whole units of the asset",
+	}, []string{"asset"})
+	treasuryPendingCountGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "treasury_pending_withdrawals_count",
+		Help: "Number of pending and processing withdrawals",
+	}, []string{"asset"})
+	treasuryCoverageGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "treasury_coverage_ratio",
+		Help: "Hot wallet balance divided by pending withdrawals (+Inf when nothing is pending)",
+	}, []string{"asset"})
+	treasuryUserCoinsGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "treasury_user_coins",
+		Help: "Total coins on user balances",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(treasuryBalanceGauge)
+	prometheus.MustRegister(treasuryPendingGauge)
+	prometheus.MustRegister(treasuryPendingCountGauge)
+	prometheus.MustRegister(treasuryCoverageGauge)
+	prometheus.MustRegister(treasuryUserCoinsGauge)
+}
+
+// TreasuryAsset баланс горячего кошелька и обязательства по выводам в одном активе
+type TreasuryAsset struct {
+	Asset        ton.Asset
+	HotBalance   int64   // в минимальных единицах актива
+	Pending      int64   // выводы pending и processing, в минимальных единицах
+	PendingCount int     // число таких выводов
+	Coverage     float64 // HotBalance / Pending; +Inf, если выводов нет
+	BalanceErr   string  // баланс не прочитан (кошелек не настроен или ошибка сети)
+}
+
+// TreasurySnapshot состояние казны платформы
+type TreasurySnapshot struct {
+	Assets    []TreasuryAsset
+	UserCoins int64 // сумма коинов на балансах пользователей (без счета дома)
+	CheckedAt time.Time
+}
+
+// TreasuryAlert покрытие ожидающих выводов упало ниже порога
+type TreasuryAlert struct {
+	Asset       TreasuryAsset
+	MinCoverage float64
+}
+
+func coverageRatio(balance, pending int64) float64 {
+	if pending <= 0 {
+		return math.Inf(1)
+	}
+	return float64(balance) / float64(pending)
+}
+
+// readTreasury читает балансы горячего кошелька (wallet может быть nil), ожидающие выводы по активам
+// и сумму коинов пользователей
+func readTreasury(ctx context.Context, db *pgxpool.Pool, wallet *ton.Wallet) (*TreasurySnapshot, error) {
+	assets := make(map[ton.Asset]*TreasuryAsset)
+	get := func(asset ton.Asset) *TreasuryAsset {
+		if a, ok := assets[asset]; ok {
+			return a
+		}
+		a := &TreasuryAsset{Asset: asset}
+		assets[asset] = a
+		return a
+	}
+
+	rows, err := db.Query(ctx, `
+		SELECT asset, COALESCE(SUM(ton_amount_nano), 0), COUNT(*)
+		FROM withdrawals
+		WHERE status IN ('pending', 'processing')
+		GROUP BY asset
+	`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var asset string
+		var pending int64
+		var count int
+		if err := rows.Scan(&asset, &pending, &count); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		a := get(ton.Asset(asset))
+		a.Pending, a.PendingCount = pending, count
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	snapshot := &TreasurySnapshot{CheckedAt: time.Now()}
+	if err := db.QueryRow(ctx, `SELECT COALESCE(SUM(coins), 0) FROM users WHERE tg_id <> $1`, domain.HouseAccountTgID).Scan(&snapshot.UserCoins); err != nil {
+		return nil, err
+	}
+
+	if wallet != nil {
+		for _, asset := range wallet.Assets() {
+			get(asset)
+		}
+	} else {
+		get(ton.AssetTON)
+	}
+
+	for asset, a := range assets {
+		switch {
+		case wallet == nil:
+			a.BalanceErr = "кошелек не настроен"
+		case !wallet.SupportsAsset(asset):
+			a.BalanceErr = "актив не настроен в кошельке"
+		default:
+			balance, err := wallet.GetAssetBalance(ctx, asset)
+			if err != nil {
+				a.BalanceErr = err.Error()
+			} else {
+				a.HotBalance = int64(balance)
+			}
+		}
+		a.Coverage = coverageRatio(a.HotBalance, a.Pending)
+		snapshot.Assets = append(snapshot.Assets, *a)
+	}
+	sort.Slice(snapshot.Assets, func(i, j int) bool { return snapshot.Assets[i].Asset < snapshot.Assets[j].Asset })
+	return snapshot, nil
+}
+
+// TreasuryMonitor периодически сверяет баланс горячего кошелька с ожидающими выводами
+type TreasuryMonitor struct {
+	db            *pgxpool.Pool
+	wallet        *ton.Wallet
+	minCoverage   float64
+	interval      time.Duration
+	low           map[ton.Asset]bool // активы, по которым алерт уже отправлен
+	mu            sync.Mutex
+	stop          chan struct{}
+	running       bool
+	alertCallback func(TreasuryAlert)
+}
+
+// NewTreasuryMonitor создает монитор казны
+func NewTreasuryMonitor(db *pgxpool.Pool, wallet *ton.Wallet, minCoverage float64, interval time.Duration) *TreasuryMonitor {
+	return &TreasuryMonitor{
+		db:          db,
+		wallet:      wallet,
+		minCoverage: minCoverage,
+		interval:    interval,
+		low:         make(map[ton.Asset]bool),
+		stop:        make(chan struct{}),
+	}
+}
+
+// SetAlertCallback устанавливает callback для алертов о нехватке ликвидности
+func (m *TreasuryMonitor) SetAlertCallback(callback func(TreasuryAlert)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.alertCallback = callback
+}
+
+// Start запускает монитор в фоновом режиме
+func (m *TreasuryMonitor) Start() {
+	m.mu.Lock()
+	if m.running {
+		m.mu.Unlock()
+		return
+	}
+	m.running = true
+	m.mu.Unlock()
+
+	log := logger.Get()
+	log.Info("запуск treasury monitor", "interval", m.interval, "minCoverage", m.minCoverage)
+
+	m.check()
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.check()
+		case <-m.stop:
+			log.Info("остановка treasury monitor")
+			return
+		}
+	}
+}
+
+// Stop останавливает монитор
+func (m *TreasuryMonitor) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.running {
+		close(m.stop)
+		m.running = false
+	}
+}
+
+func (m *TreasuryMonitor) check() {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	snapshot, err := readTreasury(ctx, m.db, m.wallet)
+	if err != nil {
+		log.Error("treasury monitor: ошибка чтения казны", "error", err)
+		return
+	}
+
+	treasuryUserCoinsGauge.Set(float64(snapshot.UserCoins))
+	for _, a := range snapshot.Assets {
+		label := string(a.Asset)
+		treasuryPendingGauge.WithLabelValues(label).Set(ton.UnitsToFloat(a.Asset, a.Pending))
+		treasuryPendingCountGauge.WithLabelValues(label).Set(float64(a.PendingCount))
+		if a.BalanceErr != "" {
+			log.Warn("treasury monitor: баланс не прочитан", "asset", a.Asset, "error", a.BalanceErr)
+			continue
+		}
+		treasuryBalanceGauge.WithLabelValues(label).Set(ton.UnitsToFloat(a.Asset, a.HotBalance))
+		treasuryCoverageGauge.WithLabelValues(label).Set(a.Coverage)
+	}
+
+	for _, a := range m.evaluate(snapshot) {
+		log.Error("treasury monitor: покрытие выводов ниже порога",
+			"asset", a.Asset,
+			"balance", a.HotBalance,
+			"pending", a.Pending,
+			"coverage", a.Coverage,
+			"minCoverage", m.minCoverage)
+		if m.alertCallback != nil {
+			go m.alertCallback(TreasuryAlert{Asset: a, MinCoverage: m.minCoverage})
+		}
+	}
+}
+
+// evaluate активы, по которым нужно отправить алерт: покрытие упало ниже порога с прошлой проверки.
+// Повторный алерт - только после восстановления покрытия
+func (m *TreasuryMonitor) evaluate(snapshot *TreasurySnapshot) []TreasuryAsset {
+	var alerts []TreasuryAsset
+	for _, a := range snapshot.Assets {
+		if a.BalanceErr != "" {
+			continue
+		}
+		if a.Coverage >= m.minCoverage {
+			delete(m.low, a.Asset)
+			continue
+		}
+		if !m.low[a.Asset] {
+			m.low[a.Asset] = true
+			alerts = append(alerts, a)
+		}
+	}
+	return alerts
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"math"
+	"testing"
+
+	"telegram_webapp/internal/ton"
+)
+
+func TestTreasuryEvaluate(t *testing.T) {
+	m := NewTreasuryMonitor(nil, nil, 1.5, 0)
+	snapshot := func(balance, pending int64) *TreasurySnapshot {
+		return &TreasurySnapshot{Assets: []TreasuryAsset{{
+			Asset:      ton.AssetTON,
+			HotBalance: balance,
+			Pending:    pending,
+			Coverage:   coverageRatio(balance, pending),
+		}}}
+	}
+
+	if got := m.evaluate(snapshot(10, 0)); len(got) != 0 {
+		t.Fatal("алерт без ожидающих выводов")
+	}
+	if got := m.evaluate(snapshot(10, 8)); len(got) != 1 {
+		t.Fatal("нет алерта при покрытии 1.25 и пороге 1.5")
+	}
+	// пока покрытие не восстановилось, алерт не повторяется
+	if got := m.evaluate(snapshot(5, 8)); len(got) != 0 {
+		t.Fatal("повторный алерт без восстановления покрытия")
+	}
+	m.evaluate(snapshot(20, 8))
+	if got := m.evaluate(snapshot(10, 8)); len(got) != 1 {
+		t.Fatal("нет алерта после восстановления и нового падения")
+	}
+
+	// баланс не прочитан - покрытие неизвестно, алерта нет
+	s := snapshot(0, 8)
+	s.Assets[0].Asset, s.Assets[0].BalanceErr = ton.AssetUSDT, "timeout"
+	if got := m.evaluate(s); len(got) != 0 {
+		t.Fatal("алерт по активу с непрочитанным балансом")
+	}
+
+	if !math.IsInf(coverageRatio(0, 0), 1) {
+		t.Fatal("покрытие без выводов должно быть бесконечным")
+	}
+}
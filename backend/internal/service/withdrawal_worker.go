@@ -0,0 +1,617 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/logger"
+	"telegram_webapp/internal/repository"
+	"telegram_webapp/internal/ton"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	withdrawBatchSize   = 20               // выводов за один проход
+	withdrawSendTimeout = 90 * time.Second // отправка ждет включения транзакции в блок
+)
+
+// хэш транзакции кошелька платформы (ручные хэши вида manual_<id>_<ts> не проверяются)
+var chainHashRe = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+// WithdrawAutoRules правила автоодобрения выводов
+type WithdrawAutoRules struct {
+	Enabled       bool
+	MaxCoins      int64         // сумма вывода не больше
+	MinAccountAge time.Duration // с регистрации пользователя
+}
+
+// WithdrawalEventKind что произошло с выводом
+type WithdrawalEventKind string
+
+const (
+	WithdrawalEventSent   WithdrawalEventKind = "sent"   // отправлен по автоодобрению
+	WithdrawalEventFailed WithdrawalEventKind = "failed" // не прошел в сети, коины возвращены
+	WithdrawalEventStuck  WithdrawalEventKind = "stuck"  // нужна ручная проверка админом
+)
+
+// WithdrawalEvent уведомление о работе воркера выводов
+type WithdrawalEvent struct {
+	Kind         WithdrawalEventKind
+	WithdrawalID int64
+	UserID       int64
+	TgID         int64
+	CoinsAmount  int64
+	Asset        ton.Asset
+	Amount       float64 // в единицах актива
+	TxHash       string
+	Reason       string
+}
+
+// кандидат на автоодобрение
+type withdrawCandidate struct {
+	ID            int64
+	UserID        int64
+	TgID          int64
+	SendAddress   string
+	CoinsAmount   int64
+	Asset         ton.Asset
+	TonAmountNano int64 // в минимальных единицах актива
+	UserCreatedAt time.Time
+	RiskFlags     int // открытые и подтвержденные флаги сговора PvP
+}
+
+// withdrawalSender кошелек для исходящих переводов: выводы и возвраты депозитов (ton.Wallet)
+type withdrawalSender interface {
+	SendAsset(ctx context.Context, asset ton.Asset, toAddress string, amount uint64, comment string) (*ton.SendResult, error)
+	SupportsAsset(asset ton.Asset) bool
+	Assets() []ton.Asset
+}
+
+// WithdrawalWorker отправляет выводы по правилам автоодобрения и подтверждает отправленные в сети
+type WithdrawalWorker struct {
+	db             *pgxpool.Pool
+	wallet         withdrawalSender // nil - только подтверждение отправленных админом
+	indexer        ton.ChainIndexer
+	repo           *repository.WithdrawalRepository
+	userRepo       *repository.UserRepository
+	audit          *AuditService
+	rules          WithdrawAutoRules
+	confirmTimeout time.Duration
+	interval       time.Duration
+	alerted        map[int64]bool     // выводы, о которых админы уже уведомлены
+	lowBalance     map[ton.Asset]bool // админы уже уведомлены о нехватке актива на кошельке
+	mu             sync.Mutex
+	stop           chan struct{}
+	running        bool
+	notifyCallback func(WithdrawalEvent)
+}
+
+// NewWithdrawalWorker создает воркер выводов
+func NewWithdrawalWorker(
+	db *pgxpool.Pool,
+	wallet *ton.Wallet,
+	indexer ton.ChainIndexer,
+	rules WithdrawAutoRules,
+	confirmTimeout time.Duration,
+	interval time.Duration,
+) *WithdrawalWorker {
+	w := &WithdrawalWorker{
+		db:             db,
+		indexer:        indexer,
+		repo:           repository.NewWithdrawalRepository(db),
+		userRepo:       repository.NewUserRepository(db),
+		audit:          NewAuditService(db),
+		rules:          rules,
+		confirmTimeout: confirmTimeout,
+		interval:       interval,
+		alerted:        make(map[int64]bool),
+		lowBalance:     make(map[ton.Asset]bool),
+		stop:           make(chan struct{}),
+	}
+	if wallet != nil {
+		w.wallet = wallet
+	}
+	return w
+}
+
+// SetNotifyCallback устанавливает callback для уведомлений пользователя и админов
+func (w *WithdrawalWorker) SetNotifyCallback(callback func(WithdrawalEvent)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.notifyCallback = callback
+}
+
+// Start запускает воркер в фоновом режиме
+func (w *WithdrawalWorker) Start() {
+	w.mu.Lock()
+	if w.running {
+		w.mu.Unlock()
+		return
+	}
+	w.running = true
+	w.mu.Unlock()
+
+	log := logger.Get()
+	log.Info("запуск withdrawal worker",
+		"interval", w.interval,
+		"auto", w.rules.Enabled && w.wallet != nil,
+		"maxCoins", w.rules.MaxCoins,
+		"minAccountAge", w.rules.MinAccountAge)
+
+	w.process()
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.process()
+		case <-w.stop:
+			log.Info("остановка withdrawal worker")
+			return
+		}
+	}
+}
+
+// Stop останавливает воркер
+func (w *WithdrawalWorker) Stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.running {
+		close(w.stop)
+		w.running = false
+	}
+}
+
+func (w *WithdrawalWorker) process() {
+	w.confirmSent()
+	w.sendApproved()
+}
+
+// autoApprove проверяет правила автоодобрения; иначе вывод ждет /approve
+func autoApprove(rules WithdrawAutoRules, c withdrawCandidate, now time.Time, supportsAsset func(ton.Asset) bool) (bool, string) {
+	if !rules.Enabled {
+		return false, "автоодобрение выключено"
+	}
+	if c.CoinsAmount > rules.MaxCoins {
+		return false, fmt.Sprintf("сумма %d больше лимита %d", c.CoinsAmount, rules.MaxCoins)
+	}
+	if now.Sub(c.UserCreatedAt) < rules.MinAccountAge {
+		return false, "аккаунт моложе минимального возраста"
+	}
+	if c.RiskFlags > 0 {
+		return false, fmt.Sprintf("флагов сговора: %d", c.RiskFlags)
+	}
+	if c.SendAddress == "" || c.TonAmountNano <= 0 {
+		return false, "нет адреса или суммы"
+	}
+	if !supportsAsset(c.Asset) {
+		return false, fmt.Sprintf("кошелек не настроен для %s", c.Asset)
+	}
+	return true, ""
+}
+
+// итог отправленного вывода в сети
+type chainState int
+
+const (
+	chainPending   chainState = iota // перевод еще идет по цепочке сообщений
+	chainCompleted                   // дошел до получателя
+	chainFailed                      // не прошел или вернулся (bounce)
+)
+
+// transferMsg исходящий перевод транзакции (не возврат)
+func transferMsg(tx *ton.Transaction) *ton.Message {
+	for i := range tx.OutMsgs {
+		if msg := &tx.OutMsgs[i]; msg.Value > 0 && !msg.Bounced {
+			return msg
+		}
+	}
+	return nil
+}
+
+// hasBounce транзакция вернула входящий перевод отправителю
+func hasBounce(tx *ton.Transaction) bool {
+	for _, msg := range tx.OutMsgs {
+		if msg.Bounced {
+			return true
+		}
+	}
+	return false
+}
+
+// chainOutcome итог отправленного вывода: от транзакции кошелька платформы перевод прослеживается
+// по исходящим сообщениям до получателя. TON - транзакция получателя; жетон - транзакция кошелька
+// жетона платформы, затем кошелька жетона получателя. Промежуточная транзакция должна быть успешной,
+// на последней проверяется только bounce: перевод на неинициализированный кошелек без bounce
+// не исполняется, но средства остаются у получателя
+func chainOutcome(ctx context.Context, indexer ton.ChainIndexer, tx *ton.Transaction, asset ton.Asset) (chainState, string, error) {
+	if !tx.Success {
+		return chainFailed, "транзакция кошелька завершилась с ошибкой", nil
+	}
+
+	hops := 1
+	if asset.IsJetton() {
+		hops = 2
+	}
+	cur := tx
+	for hop := 1; hop <= hops; hop++ {
+		msg := transferMsg(cur)
+		if msg == nil {
+			if hop == 1 {
+				return chainFailed, "исходящий перевод не создан", nil
+			}
+			return chainFailed, "кошелек жетона не передал перевод получателю", nil
+		}
+		if msg.Hash == "" {
+			return chainPending, "", nil // индексатор не отдал хэш сообщения, проверит админ
+		}
+
+		next, err := indexer.GetTransactionByInMsg(ctx, msg.Hash)
+		if err != nil {
+			return chainPending, "", err
+		}
+		if next == nil {
+			return chainPending, "", nil
+		}
+		if hasBounce(next) {
+			if asset.IsJetton() && hop == 1 {
+				return chainFailed, "перевод жетона отклонен кошельком жетона платформы", nil
+			}
+			return chainFailed, "перевод вернулся отправителю (bounce)", nil
+		}
+		if hop < hops && !next.Success {
+			return chainFailed, "перевод жетона отклонен кошельком жетона платформы", nil
+		}
+		cur = next
+	}
+	return chainCompleted, "", nil
+}
+
+// sendApproved отправляет ожидающие выводы, прошедшие правила автоодобрения
+func (w *WithdrawalWorker) sendApproved() {
+	if !w.rules.Enabled || w.wallet == nil {
+		return
+	}
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	candidates, err := w.pendingCandidates(ctx, time.Now())
+	if err != nil {
+		log.Error("withdrawal worker: ошибка получения выводов", "error", err)
+		return
+	}
+
+	now := time.Now()
+	short := make(map[ton.Asset]bool) // актива не хватило: остальные выводы в нем ждут пополнения
+	for _, c := range candidates {
+		ok, reason := autoApprove(w.rules, c, now, w.wallet.SupportsAsset)
+		if !ok {
+			log.Debug("withdrawal worker: вывод ждет админа", "withdrawalID", c.ID, "reason", reason)
+			continue
+		}
+		if short[c.Asset] {
+			continue
+		}
+		if w.send(c) == sendLowBalance {
+			short[c.Asset] = true
+		}
+	}
+}
+
+// итог отправки одного вывода
+type sendOutcome int
+
+const (
+	sendSkipped    sendOutcome = iota // вывод уже не pending
+	sendDone                          // перевод включен в блок
+	sendStuck                         // перевод мог уйти, нужна проверка админом
+	sendLowBalance                    // не хватило средств, вывод снова pending
+	sendFailed                        // ошибка до отправки, вывод снова pending
+)
+
+// send отправляет один вывод
+func (w *WithdrawalWorker) send(c withdrawCandidate) sendOutcome {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), withdrawSendTimeout)
+	defer cancel()
+
+	claimed, err := w.repo.ClaimPending(ctx, c.ID)
+	if err != nil || !claimed {
+		return sendSkipped // отменен пользователем или уже обрабатывается админом
+	}
+
+	result, err := w.wallet.SendAsset(ctx, c.Asset, c.SendAddress, uint64(c.TonAmountNano), fmt.Sprintf("Withdrawal #%d", c.ID))
+	if errors.Is(err, ton.ErrInsufficientBalance) {
+		// перевод не отправлялся: вывод возвращается в очередь
+		_, _ = w.db.Exec(ctx, `UPDATE withdrawals SET status = 'pending' WHERE id = $1 AND status = 'processing'`, c.ID)
+		if !w.lowBalance[c.Asset] {
+			w.lowBalance[c.Asset] = true
+			w.notify(WithdrawalEvent{
+				Kind:         WithdrawalEventStuck,
+				WithdrawalID: c.ID,
+				UserID:       c.UserID,
+				CoinsAmount:  c.CoinsAmount,
+				Asset:        c.Asset,
+				Amount:       ton.UnitsToFloat(c.Asset, c.TonAmountNano),
+				Reason:       fmt.Sprintf("недостаточно средств на кошельке платформы: %v", err),
+			})
+		}
+		return sendLowBalance
+	}
+	w.lowBalance[c.Asset] = false
+	if errors.Is(err, ton.ErrSendUnconfirmed) {
+		// неизвестно, ушел ли перевод: вывод остается processing до проверки админом
+		log.Error("withdrawal worker: ошибка отправки", "withdrawalID", c.ID, "error", err)
+		_, _ = w.db.Exec(ctx, `UPDATE withdrawals SET admin_notes = $2 WHERE id = $1`, c.ID, "auto: "+err.Error())
+		w.notify(WithdrawalEvent{
+			Kind:         WithdrawalEventStuck,
+			WithdrawalID: c.ID,
+			UserID:       c.UserID,
+			TgID:         c.TgID,
+			CoinsAmount:  c.CoinsAmount,
+			Asset:        c.Asset,
+			Amount:       ton.UnitsToFloat(c.Asset, c.TonAmountNano),
+			Reason:       fmt.Sprintf("ошибка отправки, статус processing: %v", err),
+		})
+		return sendStuck
+	}
+	if err != nil {
+		// перевод не отправлялся (адрес, кошелек жетона, seqno): вывод снова ждет отправки
+		log.Warn("withdrawal worker: вывод не отправлен", "withdrawalID", c.ID, "error", err)
+		_, _ = w.db.Exec(ctx, `
+			UPDATE withdrawals SET status = 'pending', admin_notes = $2
+			WHERE id = $1 AND status = 'processing'
+		`, c.ID, "auto: "+err.Error())
+		return sendFailed
+	}
+
+	if err := w.repo.MarkSent(ctx, c.ID, result.TxHash, 0); err != nil {
+		log.Error("withdrawal worker: перевод отправлен, но статус не обновлен",
+			"withdrawalID", c.ID, "hash", result.TxHash, "error", err)
+	}
+	w.audit.Log(ctx, c.UserID, domain.AuditActionWithdrawSend, domain.AuditCategoryWithdrawal, map[string]interface{}{
+		"withdrawal_id": c.ID,
+		"coins":         c.CoinsAmount,
+		"asset":         c.Asset,
+		"amount_units":  c.TonAmountNano,
+		"tx_hash":       result.TxHash,
+	})
+
+	log.Info("withdrawal worker: вывод отправлен", "withdrawalID", c.ID, "coins", c.CoinsAmount, "hash", result.TxHash)
+	w.notify(WithdrawalEvent{
+		Kind:         WithdrawalEventSent,
+		WithdrawalID: c.ID,
+		UserID:       c.UserID,
+		TgID:         c.TgID,
+		CoinsAmount:  c.CoinsAmount,
+		Asset:        c.Asset,
+		Amount:       ton.UnitsToFloat(c.Asset, c.TonAmountNano),
+		TxHash:       result.TxHash,
+	})
+	return sendDone
+}
+
+// pendingCandidates ожидающие выводы, проходящие правила автоодобрения по сумме, возрасту аккаунта,
+// флагам сговора и активу. Правила проверяются в запросе: выводы, которые ждут админа,
+// не занимают пачку и не задерживают более новые
+func (w *WithdrawalWorker) pendingCandidates(ctx context.Context, now time.Time) ([]withdrawCandidate, error) {
+	assets := []string{}
+	for _, asset := range w.wallet.Assets() {
+		assets = append(assets, string(asset))
+	}
+	rows, err := w.db.Query(ctx, `
+		SELECT w.id, w.user_id, u.tg_id,
+		       COALESCE(CASE WHEN wal.address = w.wallet_address THEN NULLIF(wal.raw_address, '') END, w.wallet_address),
+		       w.coins_amount, w.asset, w.ton_amount_nano, u.created_at, flags.n
+		FROM withdrawals w
+		JOIN users u ON u.id = w.user_id
+		LEFT JOIN wallets wal ON wal.user_id = w.user_id
+		CROSS JOIN LATERAL (
+		    SELECT COUNT(*) AS n FROM pvp_collusion_flags f
+		    WHERE (f.user_a = w.user_id OR f.user_b = w.user_id) AND f.status IN ('open', 'confirmed')
+		) flags
+		WHERE w.status = 'pending'
+		  AND w.coins_amount <= $2
+		  AND u.created_at <= $3
+		  AND w.asset = ANY($4)
+		  AND w.ton_amount_nano > 0
+		  AND w.wallet_address <> ''
+		  AND flags.n = 0
+		ORDER BY w.created_at ASC, w.id ASC
+		LIMIT $1
+	`, withdrawBatchSize, w.rules.MaxCoins, now.Add(-w.rules.MinAccountAge), assets)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []withdrawCandidate
+	for rows.Next() {
+		var c withdrawCandidate
+		var asset string
+		if err := rows.Scan(&c.ID, &c.UserID, &c.TgID, &c.SendAddress,
+			&c.CoinsAmount, &asset, &c.TonAmountNano, &c.UserCreatedAt, &c.RiskFlags); err != nil {
+			return nil, err
+		}
+		c.Asset = ton.Asset(asset)
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
+
+// confirmSent переводит отправленные выводы в completed или failed по данным сети
+func (w *WithdrawalWorker) confirmSent() {
+	log := logger.Get()
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	sent, err := w.repo.GetSent(ctx, withdrawBatchSize)
+	if err != nil {
+		log.Error("withdrawal worker: ошибка получения отправленных выводов", "error", err)
+		return
+	}
+
+	for i := range sent {
+		wd := &sent[i]
+		if !chainHashRe.MatchString(wd.TxHash) {
+			continue // ручной хэш, подтверждает админ
+		}
+
+		tx, err := w.indexer.GetTransaction(ctx, wd.TxHash)
+		if err != nil {
+			log.Warn("withdrawal worker: ошибка запроса транзакции", "withdrawalID", wd.ID, "hash", wd.TxHash, "error", err)
+			continue
+		}
+
+		state, reason := chainPending, "транзакция не найдена в сети"
+		if tx != nil {
+			state, reason, err = chainOutcome(ctx, w.indexer, tx, ton.Asset(wd.Asset))
+			if err != nil {
+				log.Warn("withdrawal worker: ошибка отслеживания перевода", "withdrawalID", wd.ID, "hash", wd.TxHash, "error", err)
+				continue
+			}
+			if reason == "" {
+				reason = "перевод не дошел до получателя"
+			}
+		}
+
+		if state == chainPending {
+			sentAt := wd.CreatedAt
+			if wd.ProcessedAt != nil {
+				sentAt = *wd.ProcessedAt
+			}
+			if time.Since(sentAt) > w.confirmTimeout && !w.alerted[wd.ID] {
+				w.alerted[wd.ID] = true
+				w.notify(WithdrawalEvent{
+					Kind:         WithdrawalEventStuck,
+					WithdrawalID: wd.ID,
+					UserID:       wd.UserID,
+					CoinsAmount:  wd.CoinsAmount,
+					Asset:        ton.Asset(wd.Asset),
+					Amount:       ton.UnitsToFloat(ton.Asset(wd.Asset), wd.TonAmountNano),
+					TxHash:       wd.TxHash,
+					Reason:       fmt.Sprintf("%s дольше %s", reason, w.confirmTimeout),
+				})
+			}
+			continue
+		}
+		delete(w.alerted, wd.ID)
+
+		if state == chainCompleted {
+			if err := w.repo.MarkCompleted(ctx, wd.ID); err != nil {
+				log.Error("withdrawal worker: ошибка подтверждения вывода", "withdrawalID", wd.ID, "error", err)
+				continue
+			}
+			w.audit.Log(ctx, wd.UserID, domain.AuditActionWithdrawComplete, domain.AuditCategoryWithdrawal, map[string]interface{}{
+				"withdrawal_id": wd.ID,
+				"tx_hash":       wd.TxHash,
+				"tx_lt":         tx.Lt,
+			})
+			log.Info("withdrawal worker: вывод подтвержден", "withdrawalID", wd.ID, "hash", wd.TxHash)
+			continue
+		}
+
+		refunded, err := refundWithdrawal(ctx, w.db, wd, reason)
+		if err != nil {
+			log.Error("withdrawal worker: ошибка возврата коинов", "withdrawalID", wd.ID, "error", err)
+			continue
+		}
+		if !refunded {
+			continue
+		}
+		log.Warn("withdrawal worker: вывод не прошел, коины возвращены",
+			"withdrawalID", wd.ID, "coins", wd.CoinsAmount, "reason", reason)
+
+		var tgID int64
+		if user, err := w.userRepo.GetByID(ctx, wd.UserID); err == nil && user != nil {
+			tgID = user.TgID
+		}
+		w.notify(WithdrawalEvent{
+			Kind:         WithdrawalEventFailed,
+			WithdrawalID: wd.ID,
+			UserID:       wd.UserID,
+			TgID:         tgID,
+			CoinsAmount:  wd.CoinsAmount,
+			Asset:        ton.Asset(wd.Asset),
+			Amount:       ton.UnitsToFloat(ton.Asset(wd.Asset), wd.TonAmountNano),
+			TxHash:       wd.TxHash,
+			Reason:       reason,
+		})
+	}
+}
+
+// refundWithdrawal одной транзакцией помечает отправленный вывод failed, возвращает коины
+// и пишет строку withdraw_refund и аудит; false - вывод уже не в статусе sent
+func refundWithdrawal(ctx context.Context, db *pgxpool.Pool, wd *domain.Withdrawal, reason string) (bool, error) {
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	result, err := tx.Exec(ctx, `
+		UPDATE withdrawals SET status = 'failed', admin_notes = $2
+		WHERE id = $1 AND status = 'sent'
+	`, wd.ID, reason)
+	if err != nil {
+		return false, err
+	}
+	if result.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE users SET coins = coins + $1 WHERE id = $2`, wd.CoinsAmount, wd.UserID); err != nil {
+		return false, err
+	}
+
+	ledger := &domain.Transaction{
+		UserID: wd.UserID,
+		Type:   domain.TransactionTypeWithdrawRefund,
+		Amount: wd.CoinsAmount,
+		Meta: map[string]interface{}{
+			"withdrawal_id": wd.ID,
+			"asset":         wd.Asset,
+			"tx_hash":       wd.TxHash,
+			"reason":        reason,
+		},
+	}
+	if err := repository.NewTransactionRepository(db).CreateWithTx(ctx, tx, ledger); err != nil {
+		return false, err
+	}
+
+	audit := &domain.AuditLog{
+		UserID:   wd.UserID,
+		Action:   domain.AuditActionWithdrawFail,
+		Category: domain.AuditCategoryWithdrawal,
+		Details: map[string]interface{}{
+			"withdrawal_id": wd.ID,
+			"coins":         wd.CoinsAmount,
+			"tx_hash":       wd.TxHash,
+			"reason":        reason,
+		},
+	}
+	if err := repository.NewAuditRepository(db).CreateWithTx(ctx, tx, audit); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (w *WithdrawalWorker) notify(ev WithdrawalEvent) {
+	if w.notifyCallback != nil {
+		go w.notifyCallback(ev)
+	}
+}
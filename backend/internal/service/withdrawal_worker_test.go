@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"telegram_webapp/internal/ton"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestAutoApprove(t *testing.T) {
+	now := time.Now()
+	rules := WithdrawAutoRules{Enabled: true, MaxCoins: 100, MinAccountAge: 72 * time.Hour}
+	supported := func(a ton.Asset) bool { return a == ton.AssetTON }
+	base := withdrawCandidate{
+		ID:            1,
+		SendAddress:   "0:abc",
+		CoinsAmount:   50,
+		Asset:         ton.AssetTON,
+		TonAmountNano: 4_900_000_000,
+		UserCreatedAt: now.Add(-30 * 24 * time.Hour),
+	}
+
+	if ok, reason := autoApprove(rules, base, now, supported); !ok {
+		t.Fatalf("обычный вывод не одобрен: %s", reason)
+	}
+
+	cases := map[string]func(c *withdrawCandidate, r *WithdrawAutoRules){
+		"выключено":         func(c *withdrawCandidate, r *WithdrawAutoRules) { r.Enabled = false },
+		"больше лимита":     func(c *withdrawCandidate, r *WithdrawAutoRules) { c.CoinsAmount = 101 },
+		"новый аккаунт":     func(c *withdrawCandidate, r *WithdrawAutoRules) { c.UserCreatedAt = now.Add(-time.Hour) },
+		"флаги сговора":     func(c *withdrawCandidate, r *WithdrawAutoRules) { c.RiskFlags = 1 },
+		"без адреса":        func(c *withdrawCandidate, r *WithdrawAutoRules) { c.SendAddress = "" },
+		"нулевая сумма":     func(c *withdrawCandidate, r *WithdrawAutoRules) { c.TonAmountNano = 0 },
+		"жетон без мастера": func(c *withdrawCandidate, r *WithdrawAutoRules) { c.Asset = ton.AssetUSDT },
+	}
+	for name, mutate := range cases {
+		c, r := base, rules
+		mutate(&c, &r)
+		if ok, _ := autoApprove(r, c, now, supported); ok {
+			t.Fatalf("%s: вывод одобрен автоматически", name)
+		}
+	}
+}
+
+func TestChainOutcome(t *testing.T) {
+	ctx := context.Background()
+	hot := func(msgHash string) *ton.Transaction {
+		return &ton.Transaction{Hash: "hot", Success: true, OutMsgs: []ton.Message{{Hash: msgHash, Value: 4_900_000_000}}}
+	}
+	incoming := func(hash, msgHash string, success bool, out ...ton.Message) ton.Transaction {
+		return ton.Transaction{Hash: hash, Success: success, InMsg: &ton.Message{Hash: msgHash}, OutMsgs: out}
+	}
+
+	cases := []struct {
+		name  string
+		asset ton.Asset
+		chain []ton.Transaction
+		want  chainState
+	}{
+		{"TON дошел", ton.AssetTON, []ton.Transaction{incoming("rcv", "m1", true)}, chainCompleted},
+		// перевод без bounce на неинициализированный кошелек: средства у получателя
+		{"TON на пустой кошелек", ton.AssetTON, []ton.Transaction{incoming("rcv", "m1", false)}, chainCompleted},
+		{"TON вернулся", ton.AssetTON, []ton.Transaction{
+			incoming("rcv", "m1", false, ton.Message{Hash: "b1", Value: 4_800_000_000, Bounced: true}),
+		}, chainFailed},
+		{"TON еще в пути", ton.AssetTON, nil, chainPending},
+		{"жетон дошел", ton.AssetUSDT, []ton.Transaction{
+			incoming("jw", "m1", true, ton.Message{Hash: "m2", Value: 50_000_000}),
+			incoming("rjw", "m2", true),
+		}, chainCompleted},
+		{"жетон: ошибка кошелька жетона", ton.AssetUSDT, []ton.Transaction{
+			incoming("jw", "m1", false, ton.Message{Hash: "b1", Value: 40_000_000, Bounced: true}),
+		}, chainFailed},
+		{"жетон: кошелек жетона без перевода", ton.AssetUSDT, []ton.Transaction{incoming("jw", "m1", true)}, chainFailed},
+		{"жетон: получатель вернул перевод", ton.AssetUSDT, []ton.Transaction{
+			incoming("jw", "m1", true, ton.Message{Hash: "m2", Value: 50_000_000}),
+			incoming("rjw", "m2", false, ton.Message{Hash: "b2", Value: 40_000_000, Bounced: true}),
+		}, chainFailed},
+		{"жетон еще в пути", ton.AssetUSDT, []ton.Transaction{
+			incoming("jw", "m1", true, ton.Message{Hash: "m2", Value: 50_000_000}),
+		}, chainPending},
+	}
+	for _, c := range cases {
+		indexer := ton.NewFakeIndexer()
+		indexer.AddTransactions("chain", c.chain...)
+		state, reason, err := chainOutcome(ctx, indexer, hot("m1"), c.asset)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if state != c.want {
+			t.Fatalf("%s: итог %d, ожидался %d (%s)", c.name, state, c.want, reason)
+		}
+	}
+
+	// ошибка действия проигнорирована кошельком: транзакция успешна, но перевода нет
+	indexer := ton.NewFakeIndexer()
+	if state, _, _ := chainOutcome(ctx, indexer, &ton.Transaction{Success: true}, ton.AssetTON); state != chainFailed {
+		t.Fatal("транзакция без исходящего перевода не помечена неудачной")
+	}
+	failed := hot("m1")
+	failed.Success = false
+	if state, _, _ := chainOutcome(ctx, indexer, failed, ton.AssetTON); state != chainFailed {
+		t.Fatal("неуспешная транзакция не помечена неудачной")
+	}
+}
+
+// кошелек без сети: запоминает отправленные выводы и возвращает заданную ошибку
+type fakeSender struct {
+	err  error
+	sent []string
+}
+
+func (f *fakeSender) SendAsset(ctx context.Context, asset ton.Asset, toAddress string, amount uint64, comment string) (*ton.SendResult, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.sent = append(f.sent, comment)
+	return &ton.SendResult{TxHash: fmt.Sprintf("%064x", len(f.sent)), Success: true}, nil
+}
+
+func (f *fakeSender) SupportsAsset(asset ton.Asset) bool { return asset == ton.AssetTON }
+func (f *fakeSender) Assets() []ton.Asset                { return []ton.Asset{ton.AssetTON} }
+
+// воркер на тестовой БД с фейковым кошельком; пользователи tgIDs и их выводы удаляются после теста
+func withdrawalTestWorker(t *testing.T, pool *pgxpool.Pool, sender *fakeSender, tgIDs ...int64) *WithdrawalWorker {
+	t.Helper()
+	ctx := context.Background()
+	cleanup := func() {
+		for _, tgID := range tgIDs {
+			pool.Exec(ctx, `DELETE FROM audit_logs WHERE user_id IN (SELECT id FROM users WHERE tg_id = $1)`, tgID)
+			pool.Exec(ctx, `DELETE FROM withdrawals WHERE user_id IN (SELECT id FROM users WHERE tg_id = $1)`, tgID)
+			pool.Exec(ctx, `DELETE FROM users WHERE tg_id = $1`, tgID)
+		}
+	}
+	cleanup()
+	t.Cleanup(cleanup)
+
+	rules := WithdrawAutoRules{Enabled: true, MaxCoins: 100, MinAccountAge: 72 * time.Hour}
+	w := NewWithdrawalWorker(pool, nil, ton.NewFakeIndexer(), rules, time.Hour, time.Minute)
+	w.wallet = sender
+	return w
+}
+
+func insertWithdrawalUser(t *testing.T, pool *pgxpool.Pool, tgID int64, createdAt time.Time) int64 {
+	t.Helper()
+	var id int64
+	if err := pool.QueryRow(context.Background(),
+		`INSERT INTO users (tg_id, username, first_name, created_at) VALUES ($1, 'withdraw_test', 'withdraw', $2) RETURNING id`,
+		tgID, createdAt,
+	).Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func insertPendingWithdrawal(t *testing.T, pool *pgxpool.Pool, userID, coins int64, createdAt time.Time) int64 {
+	t.Helper()
+	var id int64
+	if err := pool.QueryRow(context.Background(), `
+		INSERT INTO withdrawals (user_id, wallet_address, coins_amount, ton_amount_nano, exchange_rate, asset, status, created_at)
+		VALUES ($1, '0:6666666666666666666666666666666666666666666666666666666666666666', $2, $3, 10, 'TON', 'pending', $4)
+		RETURNING id
+	`, userID, coins, coins*10_000_000, createdAt).Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func withdrawalStatus(t *testing.T, pool *pgxpool.Pool, id int64) (status, notes string) {
+	t.Helper()
+	if err := pool.QueryRow(context.Background(),
+		`SELECT status, COALESCE(admin_notes, '') FROM withdrawals WHERE id = $1`, id,
+	).Scan(&status, &notes); err != nil {
+		t.Fatal(err)
+	}
+	return status, notes
+}
+
+// выводы, которые ждут админа, не занимают пачку: новый подходящий вывод отправляется за ними
+func TestWithdrawalWorkerSkipsManualReview(t *testing.T) {
+	pool := openTestDB(t)
+	sender := &fakeSender{}
+	w := withdrawalTestWorker(t, pool, sender, -4301, -4302)
+
+	now := time.Now()
+	veteran := insertWithdrawalUser(t, pool, -4301, now.Add(-30*24*time.Hour))
+	newcomer := insertWithdrawalUser(t, pool, -4302, now.Add(-time.Hour))
+
+	created := now.Add(-time.Hour)
+	for i := 0; i < withdrawBatchSize; i++ {
+		insertPendingWithdrawal(t, pool, veteran, 500, created) // больше лимита
+		insertPendingWithdrawal(t, pool, newcomer, 50, created) // аккаунт моложе минимума
+		created = created.Add(time.Second)
+	}
+	eligible := insertPendingWithdrawal(t, pool, veteran, 50, now)
+
+	w.sendApproved()
+
+	if len(sender.sent) != 1 || sender.sent[0] != fmt.Sprintf("Withdrawal #%d", eligible) {
+		t.Fatalf("отправлено %v, ожидался только вывод #%d", sender.sent, eligible)
+	}
+	if status, _ := withdrawalStatus(t, pool, eligible); status != "sent" {
+		t.Fatalf("подходящий вывод в статусе %s", status)
+	}
+	var waiting int
+	if err := pool.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM withdrawals WHERE user_id IN ($1, $2) AND status = 'pending'`, veteran, newcomer,
+	).Scan(&waiting); err != nil {
+		t.Fatal(err)
+	}
+	if waiting != 2*withdrawBatchSize {
+		t.Fatalf("ждут админа %d выводов, ожидалось %d", waiting, 2*withdrawBatchSize)
+	}
+}
+
+// processing остается только при неизвестном исходе отправки; ошибка до отправки возвращает вывод в pending
+func TestWithdrawalWorkerSendErrors(t *testing.T) {
+	pool := openTestDB(t)
+	sender := &fakeSender{}
+	w := withdrawalTestWorker(t, pool, sender, -4303)
+	userID := insertWithdrawalUser(t, pool, -4303, time.Now().Add(-30*24*time.Hour))
+
+	cases := []struct {
+		name    string
+		err     error
+		outcome sendOutcome
+		status  string
+	}{
+		{"перевод мог уйти", fmt.Errorf("%w: failed to send transaction: timeout", ton.ErrSendUnconfirmed), sendStuck, "processing"},
+		{"ошибка до отправки", errors.New("invalid destination address"), sendFailed, "pending"},
+	}
+	for _, tc := range cases {
+		id := insertPendingWithdrawal(t, pool, userID, 50, time.Now())
+		sender.err = tc.err
+		c := withdrawCandidate{ID: id, UserID: userID, SendAddress: "0:abc", CoinsAmount: 50, Asset: ton.AssetTON, TonAmountNano: 500_000_000}
+		if got := w.send(c); got != tc.outcome {
+			t.Fatalf("%s: итог %d, ожидался %d", tc.name, got, tc.outcome)
+		}
+		status, notes := withdrawalStatus(t, pool, id)
+		if status != tc.status || notes != "auto: "+tc.err.Error() {
+			t.Fatalf("%s: статус %s (%q), ожидался %s", tc.name, status, notes, tc.status)
+		}
+	}
+}
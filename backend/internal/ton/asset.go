@@ -0,0 +1,182 @@
+package ton
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/xssnick/tonutils-go/tlb"
+)
+
+// Asset актив депозита или вывода: нативный TON или жетон
+type Asset string
+
+const (
+	AssetTON  Asset = "TON"
+	AssetUSDT Asset = "USDT"
+)
+
+// мастер-контракт USDT (Tether) в mainnet; для testnet задается через TON_USDT_MASTER
+const USDTMasterMainnet = "EQCxE6mUtQJKFnGfaROTKOt1lZbDiiX1kCixRv7Nw2Id_sDs"
+
+// op transfer_notification: кошелек жетона уведомляет владельца о входящем переводе
+const JettonNotifyOpCode = "0x7362d09c"
+
+// знаков после запятой в минимальной единице актива (TON - наноTON, USDT - 6)
+var assetDecimals = map[Asset]int{
+	AssetTON:  9,
+	AssetUSDT: 6,
+}
+
+// минимальный депозит в минимальных единицах актива
+var minDepositUnits = map[Asset]int64{
+	AssetTON:  MinDepositNano,
+	AssetUSDT: MinDepositUSDTUnits,
+}
+
+// USDTMaster мастер-контракт USDT для сети: override из TON_USDT_MASTER, иначе mainnet-адрес.
+// В testnet без override жетоны выключены
+func USDTMaster(network Network, override string) string {
+	if override != "" {
+		return override
+	}
+	if network == NetworkTestnet {
+		return ""
+	}
+	return USDTMasterMainnet
+}
+
+// ParseAsset разбирает актив из запроса (пустая строка - TON)
+func ParseAsset(s string) (Asset, bool) {
+	if s == "" {
+		return AssetTON, true
+	}
+	a := Asset(strings.ToUpper(strings.TrimSpace(s)))
+	_, ok := assetDecimals[a]
+	return a, ok
+}
+
+// IsJetton актив переводится жетоном, а не нативным TON
+func (a Asset) IsJetton() bool {
+	return a != AssetTON
+}
+
+// Decimals знаков после запятой в минимальной единице актива
+func (a Asset) Decimals() int {
+	return assetDecimals[a]
+}
+
+// MinDepositUnits минимальный депозит в минимальных единицах
+func (a Asset) MinDepositUnits() int64 {
+	return minDepositUnits[a]
+}
+
+func pow10(n int) int64 {
+	p := int64(1)
+	for i := 0; i < n; i++ {
+		p *= 10
+	}
+	return p
+}
+
+// Rates курс коинов за одну целую единицу актива (действующая версия pricing_config)
+type Rates map[Asset]int64
+
+// UnitsToCoins переводит минимальные единицы актива в коины по курсу (с округлением вниз)
+func (r Rates) UnitsToCoins(a Asset, units int64) int64 {
+	one := pow10(a.Decimals())
+	rate := r[a]
+	return units/one*rate + units%one*rate/one
+}
+
+// CoinsToUnits переводит коины в минимальные единицы актива по курсу
+func (r Rates) CoinsToUnits(a Asset, coins int64) int64 {
+	rate := r[a]
+	if rate == 0 {
+		return 0
+	}
+	return coins * pow10(a.Decimals()) / rate
+}
+
+// UnitsToFloat сумма в целых единицах актива (для уведомлений)
+func UnitsToFloat(a Asset, units int64) float64 {
+	return float64(units) / float64(pow10(a.Decimals()))
+}
+
+// RefundFeeUnits комиссия сети за возврат перевода в минимальных единицах актива: для TON -
+// RefundNetworkFeeNano, для жетона - газ перевода жетона, пересчитанный по курсам коинов
+func (r Rates) RefundFeeUnits(a Asset) int64 {
+	if !a.IsJetton() {
+		return RefundNetworkFeeNano
+	}
+	if r[a] == 0 {
+		return 0
+	}
+	gasNano := tlb.MustFromTON(jettonTransferGas).Nano().Int64()
+	return gasNano * r[AssetTON] * pow10(a.Decimals()) / (NanoTON * r[a])
+}
+
+// FormatUnits сумма с тикером, например "12.5000 USDT"
+func FormatUnits(a Asset, units int64) string {
+	return fmt.Sprintf("%.4f %s", UnitsToFloat(a, units), a)
+}
+
+// JettonNotify входящий перевод жетона (transfer_notification на кошелек платформы)
+type JettonNotify struct {
+	JettonWallet string // отправитель уведомления - кошелек жетона платформы
+	Sender       string // владелец кошелька, с которого пришли жетоны
+	Amount       int64  // в минимальных единицах жетона
+	Comment      string
+}
+
+// ParseJettonNotify извлекает перевод жетона из транзакции; false - это не transfer_notification.
+// Подлинность (уведомление пришло от кошелька жетона платформы) проверяет вызывающий
+func ParseJettonNotify(tx *Transaction) (*JettonNotify, bool) {
+	if tx.InMsg == nil || tx.InMsg.DecodedBody == nil || tx.InMsg.Source == nil {
+		return nil, false
+	}
+	if tx.InMsg.OpCode != JettonNotifyOpCode && tx.InMsg.DecodedOpName != "jetton_notify" {
+		return nil, false
+	}
+	body := tx.InMsg.DecodedBody
+
+	amount, err := parseRawAmount(body.Amount)
+	if err != nil || amount <= 0 {
+		return nil, false
+	}
+
+	var sender string
+	_ = json.Unmarshal(body.Sender, &sender)
+
+	notify := &JettonNotify{
+		JettonWallet: tx.InMsg.Source.Address,
+		Sender:       sender,
+		Amount:       amount,
+	}
+
+	// forward_payload: {"is_right": ..., "value": {"sum_type": "TextComment", "value": {"text": "..."}}}
+	var payload struct {
+		Value struct {
+			SumType string `json:"sum_type"`
+			Value   struct {
+				Text string `json:"text"`
+			} `json:"value"`
+		} `json:"value"`
+	}
+	if len(body.ForwardPayload) > 0 && json.Unmarshal(body.ForwardPayload, &payload) == nil &&
+		payload.Value.SumType == "TextComment" {
+		notify.Comment = payload.Value.Value.Text
+	}
+
+	return notify, true
+}
+
+// сумма жетона приходит строкой (большие числа) или числом
+func parseRawAmount(raw json.RawMessage) (int64, error) {
+	s := strings.Trim(string(raw), `"`)
+	if s == "" {
+		return 0, fmt.Errorf("нет суммы")
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
@@ -0,0 +1,71 @@
+package ton
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestUnitsToCoins(t *testing.T) {
+	rates := Rates{AssetTON: 10, AssetUSDT: 2}
+	cases := []struct {
+		asset Asset
+		units int64
+		coins int64
+	}{
+		{AssetTON, 1_000_000_000, 10},
+		{AssetTON, 150_000_000, 1}, // 0.15 TON = 1.5 коина, округляем вниз
+		{AssetUSDT, 5_000_000, 10},
+		{AssetUSDT, 1_500_000, 3},
+	}
+	for _, c := range cases {
+		if got := rates.UnitsToCoins(c.asset, c.units); got != c.coins {
+			t.Errorf("%s %d: получили %d коинов, ожидали %d", c.asset, c.units, got, c.coins)
+		}
+	}
+
+	if got := rates.CoinsToUnits(AssetUSDT, 10); got != 5_000_000 {
+		t.Errorf("10 коинов в USDT: получили %d, ожидали 5000000", got)
+	}
+	// курс актива не задан
+	if got := (Rates{}).CoinsToUnits(AssetUSDT, 10); got != 0 {
+		t.Errorf("без курса: получили %d, ожидали 0", got)
+	}
+}
+
+func TestParseJettonNotify(t *testing.T) {
+	body := &DecodedBody{
+		Amount:         json.RawMessage(`"12500000"`),
+		Sender:         json.RawMessage(`"0:sender"`),
+		ForwardPayload: json.RawMessage(`{"is_right":false,"value":{"sum_type":"TextComment","value":{"text":"deposit_42"}}}`),
+	}
+	tx := &Transaction{InMsg: &Message{
+		OpCode:      JettonNotifyOpCode,
+		Source:      &AccountAddress{Address: "0:jettonwallet"},
+		DecodedBody: body,
+	}}
+
+	n, ok := ParseJettonNotify(tx)
+	if !ok {
+		t.Fatal("transfer_notification не распознан")
+	}
+	if n.Amount != 12_500_000 || n.Sender != "0:sender" || n.Comment != "deposit_42" || n.JettonWallet != "0:jettonwallet" {
+		t.Fatalf("неверно разобран перевод: %+v", n)
+	}
+
+	// обычный перевод TON с комментарием - не жетон
+	tx.InMsg.OpCode = "0x00000000"
+	if _, ok := ParseJettonNotify(tx); ok {
+		t.Fatal("перевод TON распознан как жетон")
+	}
+}
+
+func TestRefundFeeUnits(t *testing.T) {
+	rates := Rates{AssetTON: 10, AssetUSDT: 2}
+	if got := rates.RefundFeeUnits(AssetTON); got != RefundNetworkFeeNano {
+		t.Errorf("комиссия возврата TON: получили %d, ожидали %d", got, RefundNetworkFeeNano)
+	}
+	// газ 0.05 TON = 0.5 коина = 0.25 USDT
+	if got := rates.RefundFeeUnits(AssetUSDT); got != 250_000 {
+		t.Errorf("комиссия возврата USDT: получили %d, ожидали 250000", got)
+	}
+}
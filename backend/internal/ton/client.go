@@ -81,6 +81,11 @@ type Message struct {
 type DecodedBody struct {
 	Text    string `json:"text"`
 	Payload string `json:"payload"`
+
+	// поля jetton_notify; raw, т.к. в других операциях формат может отличаться
+	Amount         json.RawMessage `json:"amount,omitempty"`
+	Sender         json.RawMessage `json:"sender,omitempty"`
+	ForwardPayload json.RawMessage `json:"forward_payload,omitempty"`
 }
 
 // AccountInfo представляет информацию об аккаунте
@@ -122,6 +127,42 @@ func (c *Client) GetAccountInfo(ctx context.Context, address string) (*AccountIn
 	return &account, nil
 }
 
+// получает адрес кошелька жетона master, принадлежащего owner ("" - кошелька еще нет)
+func (c *Client) GetJettonWalletAddress(ctx context.Context, owner, master string) (string, error) {
+	reqURL := fmt.Sprintf("%s/accounts/%s/jettons/%s", c.baseURL, owner, master)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	c.setAuthHeader(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	// у владельца еще нет кошелька этого жетона
+	if resp.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("ошибка API: %s - %s", resp.Status, string(body))
+	}
+
+	var result struct {
+		WalletAddress AccountAddress `json:"wallet_address"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	return result.WalletAddress.Address, nil
+}
+
 // получает последние транзакции для адреса
 func (c *Client) GetTransactions(ctx context.Context, address string, limit int, beforeLt int64) ([]Transaction, error) {
 	// Используем blockchain endpoint для получения транзакций
@@ -192,6 +233,41 @@ func (c *Client) GetTransaction(ctx context.Context, hash string) (*Transaction,
 	return &tx, nil
 }
 
+// получает транзакцию, которую породило входящее сообщение msgHash
+func (c *Client) GetTransactionByInMsg(ctx context.Context, msgHash string) (*Transaction, error) {
+	reqURL := fmt.Sprintf("%s/blockchain/messages/%s/transaction", c.baseURL, msgHash)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c.setAuthHeader(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// сообщение еще не доставлено
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("ошибка API: %s - %s", resp.Status, string(body))
+	}
+
+	var tx Transaction
+	if err := json.NewDecoder(resp.Body).Decode(&tx); err != nil {
+		return nil, err
+	}
+
+	return &tx, nil
+}
+
 // ожидает появления транзакции в блокчейне
 func (c *Client) WaitForTransaction(ctx context.Context, hash string, timeout time.Duration) (*Transaction, error) {
 	deadline := time.Now().Add(timeout)
@@ -3,10 +3,6 @@ package ton
 import "time"
 
 const (
-	// коэффициент обмена: сколько монет за 1 TON
-	// 1 TON = 10 монет (премиум валюта)
-	CoinsPerTON = 10
-
 	// сохраняется для обратной совместимости (бесплатная валюта, не выводится)
 	// 1 TON = 10000 гемов (только для справки, драгоценные камни нельзя купить за TON)
 	GemsPerTON = 10000
@@ -17,17 +13,18 @@ const (
 	// минимальная сумма депозита в наноTON (1 TON = 10 монет)
 	MinDepositNano = 1_000_000_000
 
-	// минимальная сумма вывода в монетах (10 монет = 1 TON)
-	MinWithdrawCoins = 10
+	// минимальная сумма депозита USDT в минимальных единицах (1 USDT = 10^6)
+	MinDepositUSDTUnits = 1_000_000
 
-	// фиксированная комиссия платформы на вывод (1 монета = 0.1 TON)
-	WithdrawFeeCoinsFixed = 1
+	// минимальная сумма вывода в монетах
+	// курс монет, комиссия на вывод и дневной лимит задаются в pricing_config
+	MinWithdrawCoins = 10
 
 	// сохраняется для обратной совместимости, но не используется (заменена фиксированной комиссией)
 	WithdrawFeePercent = 5
 
-	// максимальный вывод в день в монетах (1000 монет = 100 TON)
-	MaxWithdrawCoinsPerDay = 1000
+	// сетевая комиссия, удерживаемая при возврате несопоставленного TON-депозита отправителю
+	RefundNetworkFeeNano = 10_000_000
 
 	// количество необходимых подтверждений для депозита
 	DepositConfirmations = 1
@@ -55,8 +52,9 @@ const (
 	TonAPIMainnet = "https://tonapi.io/v2"
 	TonAPITestnet = "https://testnet.tonapi.io/v2"
 
-	TonCenterMainnet = "https://toncenter.com/api/v2"
-	TonCenterTestnet = "https://testnet.toncenter.com/api/v2"
+	// toncenter v3: постраничная выборка по lt без хэша транзакции
+	TonCenterMainnet = "https://toncenter.com/api/v3"
+	TonCenterTestnet = "https://testnet.toncenter.com/api/v3"
 )
 
 // конвертирует TON в наноTON
@@ -81,38 +79,6 @@ func GemsToNano(gems int64) int64 {
 	return TONToNano(ton)
 }
 
-// конвертирует наноTON в монеты (1 TON = 10 монет)
-func NanoToCoins(nano int64) int64 {
-	ton := NanoToTON(nano)
-	return int64(ton * CoinsPerTON)
-}
-
-// конвертирует монеты в наноTON (10 монет = 1 TON)
-func CoinsToNano(coins int64) int64 {
-	ton := float64(coins) / CoinsPerTON
-	return TONToNano(ton)
-}
-
-// конвертирует монеты в TON
-func CoinsToTON(coins int64) float64 {
-	return float64(coins) / CoinsPerTON
-}
-
-// конвертирует TON в монеты
-func TONToCoins(ton float64) int64 {
-	return int64(ton * CoinsPerTON)
-}
-
-// рассчитывает комиссию на вывод в монетах (фиксированная 0.1 TON = 1 монета)
-func CalculateWithdrawFeeCoins(coinsAmount int64) int64 {
-	return WithdrawFeeCoinsFixed
-}
-
-// рассчитывает чистые монеты после комиссии
-func CalculateWithdrawNetCoins(coinsAmount int64) int64 {
-	return coinsAmount - WithdrawFeeCoinsFixed
-}
-
 // устаревшие функции для обратной совместимости
 func CalculateWithdrawFee(gemsAmount int64) int64 {
 	return gemsAmount * WithdrawFeePercent / 100
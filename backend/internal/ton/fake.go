@@ -0,0 +1,188 @@
+package ton
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FakeIndexer индексатор в памяти для тестов: отдает транзакции из фикстур без сети.
+// Транзакции добавляются по ходу теста (AddTransactions), как если бы они появлялись в сети
+type FakeIndexer struct {
+	mu            sync.Mutex
+	txs           map[string][]Transaction // raw адрес -> транзакции по убыванию lt
+	byHash        map[string]*Transaction
+	byInMsg       map[string]string // хэш входящего сообщения -> хэш транзакции
+	balances      map[string]int64
+	jettonWallets map[string]string // owner|master -> кошелек жетона
+	err           error
+	calls         int
+}
+
+// NewFakeIndexer создает пустой фейковый индексатор
+func NewFakeIndexer() *FakeIndexer {
+	return &FakeIndexer{
+		txs:           make(map[string][]Transaction),
+		byHash:        make(map[string]*Transaction),
+		byInMsg:       make(map[string]string),
+		balances:      make(map[string]int64),
+		jettonWallets: make(map[string]string),
+	}
+}
+
+// LoadTransactionsFixture читает транзакции из JSON в формате ответа tonapi ({"transactions": [...]})
+func LoadTransactionsFixture(path string) ([]Transaction, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fixture struct {
+		Transactions []Transaction `json:"transactions"`
+	}
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, err
+	}
+	return fixture.Transactions, nil
+}
+
+func fakeKey(address string) string {
+	if normalized, err := NormalizeAddress(address); err == nil && normalized != "" {
+		return strings.ToLower(normalized)
+	}
+	return strings.ToLower(address)
+}
+
+// AddTransactions добавляет транзакции аккаунта address (повтор по хэшу игнорируется)
+func (f *FakeIndexer) AddTransactions(address string, txs ...Transaction) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := fakeKey(address)
+	for _, tx := range txs {
+		if _, ok := f.byHash[tx.Hash]; ok {
+			continue
+		}
+		f.txs[key] = append(f.txs[key], tx)
+		f.byHash[tx.Hash] = nil
+		if tx.InMsg != nil && tx.InMsg.Hash != "" {
+			f.byInMsg[tx.InMsg.Hash] = tx.Hash
+		}
+	}
+	list := f.txs[key]
+	sort.Slice(list, func(i, j int) bool { return list[i].Lt > list[j].Lt })
+	// append и сортировка двигают элементы, указатели пересобираются
+	for i := range list {
+		f.byHash[list[i].Hash] = &list[i]
+	}
+}
+
+// SetBalance задает баланс аккаунта в наноTON
+func (f *FakeIndexer) SetBalance(address string, nano int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.balances[fakeKey(address)] = nano
+}
+
+// SetJettonWallet задает кошелек жетона master у владельца owner
+func (f *FakeIndexer) SetJettonWallet(owner, master, wallet string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.jettonWallets[fakeKey(owner)+"|"+master] = wallet
+}
+
+// SetError заставляет все запросы возвращать err (nil - снова отвечать)
+func (f *FakeIndexer) SetError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+// Calls количество запросов к индексатору
+func (f *FakeIndexer) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func (f *FakeIndexer) GetTransactions(ctx context.Context, address string, limit int, beforeLt int64) ([]Transaction, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	var page []Transaction
+	for _, tx := range f.txs[fakeKey(address)] {
+		if beforeLt > 0 && tx.Lt >= beforeLt {
+			continue
+		}
+		page = append(page, tx)
+		if len(page) == limit {
+			break
+		}
+	}
+	return page, nil
+}
+
+func (f *FakeIndexer) GetTransaction(ctx context.Context, hash string) (*Transaction, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	tx, ok := f.byHash[hash]
+	if !ok {
+		return nil, nil
+	}
+	copied := *tx
+	return &copied, nil
+}
+
+func (f *FakeIndexer) GetTransactionByInMsg(ctx context.Context, msgHash string) (*Transaction, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	hash, ok := f.byInMsg[msgHash]
+	if !ok {
+		return nil, nil
+	}
+	copied := *f.byHash[hash]
+	return &copied, nil
+}
+
+func (f *FakeIndexer) GetAccountInfo(ctx context.Context, address string) (*AccountInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	key := fakeKey(address)
+	info := &AccountInfo{Address: key, Balance: f.balances[key], Status: "active"}
+	if txs := f.txs[key]; len(txs) > 0 {
+		info.LastTxLt = txs[0].Lt
+		info.LastTxHash = txs[0].Hash
+	}
+	return info, nil
+}
+
+func (f *FakeIndexer) GetJettonWalletAddress(ctx context.Context, owner, master string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.err != nil {
+		return "", f.err
+	}
+	return f.jettonWallets[fakeKey(owner)+"|"+master], nil
+}
@@ -0,0 +1,49 @@
+package ton
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/xssnick/tonutils-go/tlb"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+)
+
+// NewFakeConnectWallet кошелек v4 со случайным ключом для тестов TON Connect proof:
+// данные seqno(32) subwallet(32) pubkey(256), адрес - хэш StateInit
+func NewFakeConnectWallet() (WalletAccount, ed25519.PrivateKey, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return WalletAccount{}, nil, err
+	}
+	data := cell.BeginCell().MustStoreUInt(0, 32).MustStoreUInt(698983191, 32).MustStoreSlice(pub, 256).EndCell()
+	code := cell.BeginCell().MustStoreUInt(0xff00, 16).EndCell()
+	stateInit, err := tlb.ToCell(&tlb.StateInit{Code: code, Data: data})
+	if err != nil {
+		return WalletAccount{}, nil, err
+	}
+	addr := (tlb.StateInit{Code: code, Data: data}).CalcAddress(0)
+	return WalletAccount{
+		Address:         addr.String(),
+		Chain:           "-239",
+		PublicKey:       hex.EncodeToString(pub),
+		WalletStateInit: base64.StdEncoding.EncodeToString(stateInit.ToBOC()),
+	}, priv, nil
+}
+
+// SignFakeProof подписывает proof для domain и payload ключом priv, как это делает кошелек
+func SignFakeProof(account WalletAccount, priv ed25519.PrivateKey, domain, payload string) (ConnectProof, error) {
+	proof := ConnectProof{
+		Timestamp: time.Now().Unix(),
+		Domain:    Domain{LengthBytes: len(domain), Value: domain},
+		Payload:   payload,
+	}
+	addr, err := parseAnyAddress(account.Address)
+	if err != nil {
+		return ConnectProof{}, err
+	}
+	proof.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(priv, buildProofMessage(addr, proof)))
+	return proof, nil
+}
@@ -0,0 +1,214 @@
+package ton
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"telegram_webapp/internal/logger"
+)
+
+// ChainIndexer источник данных о транзакциях и аккаунтах (tonapi, toncenter или фейк в тестах).
+// Транзакции отдаются в формате tonapi v2: хэш в hex, адреса в raw
+type ChainIndexer interface {
+	// транзакции адреса старше beforeLt (0 - самые новые), от новых к старым
+	GetTransactions(ctx context.Context, address string, limit int, beforeLt int64) ([]Transaction, error)
+	// транзакция по хэшу; nil, nil - не найдена
+	GetTransaction(ctx context.Context, hash string) (*Transaction, error)
+	// транзакция, порожденная входящим сообщением msgHash (hex); nil, nil - еще не обработано
+	GetTransactionByInMsg(ctx context.Context, msgHash string) (*Transaction, error)
+	GetAccountInfo(ctx context.Context, address string) (*AccountInfo, error)
+}
+
+// JettonWalletResolver индексатор, который умеет находить кошелек жетона владельца
+type JettonWalletResolver interface {
+	GetJettonWalletAddress(ctx context.Context, owner, master string) (string, error)
+}
+
+// имена бэкендов для TON_INDEXERS
+const (
+	IndexerTonAPI    = "tonapi"
+	IndexerTonCenter = "toncenter"
+)
+
+// сколько бэкенд пропускается после ошибки, пока есть живые
+const indexerCooldown = time.Minute
+
+var ErrNoIndexers = errors.New("не задан ни один индексатор")
+
+// NewChainIndexer индексатор из списка бэкендов через запятую в порядке приоритета
+// (пусто - "tonapi,toncenter"); при нескольких бэкендах запросы переключаются на следующий при ошибке
+func NewChainIndexer(network Network, backends, tonapiKey, toncenterKey string) (ChainIndexer, error) {
+	if strings.TrimSpace(backends) == "" {
+		backends = IndexerTonAPI + "," + IndexerTonCenter
+	}
+
+	var names []string
+	var indexers []ChainIndexer
+	for _, name := range strings.Split(backends, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "":
+			continue
+		case IndexerTonAPI:
+			indexers = append(indexers, NewClient(network, tonapiKey))
+		case IndexerTonCenter:
+			indexers = append(indexers, NewTonCenterClient(network, toncenterKey))
+		default:
+			return nil, fmt.Errorf("неизвестный индексатор %q", name)
+		}
+		names = append(names, name)
+	}
+
+	switch len(indexers) {
+	case 0:
+		return nil, ErrNoIndexers
+	case 1:
+		return indexers[0], nil
+	}
+	return NewFailoverIndexer(names, indexers), nil
+}
+
+// FailoverIndexer опрашивает бэкенды по приоритету; упавший бэкенд пропускается на indexerCooldown
+type FailoverIndexer struct {
+	names     []string
+	backends  []ChainIndexer
+	mu        sync.Mutex
+	downUntil []time.Time
+	now       func() time.Time
+}
+
+// NewFailoverIndexer создает индексатор с переключением между backends (names - для логов)
+func NewFailoverIndexer(names []string, backends []ChainIndexer) *FailoverIndexer {
+	return &FailoverIndexer{
+		names:     names,
+		backends:  backends,
+		downUntil: make([]time.Time, len(backends)),
+		now:       time.Now,
+	}
+}
+
+// order индексы бэкендов: сначала живые по приоритету, затем упавшие (если живых не осталось)
+func (f *FailoverIndexer) order() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.now()
+	order := make([]int, 0, len(f.backends))
+	var down []int
+	for i := range f.backends {
+		if now.Before(f.downUntil[i]) {
+			down = append(down, i)
+			continue
+		}
+		order = append(order, i)
+	}
+	return append(order, down...)
+}
+
+func (f *FailoverIndexer) markDown(i int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.downUntil[i] = f.now().Add(indexerCooldown)
+}
+
+func (f *FailoverIndexer) markUp(i int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.downUntil[i] = time.Time{}
+}
+
+// do выполняет запрос на первом ответившем бэкенде
+func (f *FailoverIndexer) do(ctx context.Context, op string, call func(ChainIndexer) error) error {
+	var lastErr error
+	for _, i := range f.order() {
+		err := call(f.backends[i])
+		if err == nil {
+			f.markUp(i)
+			return nil
+		}
+		// отмена контекста - не поломка бэкенда
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		f.markDown(i)
+		lastErr = err
+		logger.Get().Warn("индексатор недоступен, переключаемся на следующий",
+			"indexer", f.names[i],
+			"op", op,
+			"error", err)
+	}
+	return fmt.Errorf("все индексаторы недоступны: %w", lastErr)
+}
+
+func (f *FailoverIndexer) GetTransactions(ctx context.Context, address string, limit int, beforeLt int64) ([]Transaction, error) {
+	var txs []Transaction
+	err := f.do(ctx, "transactions", func(ix ChainIndexer) error {
+		var err error
+		txs, err = ix.GetTransactions(ctx, address, limit, beforeLt)
+		return err
+	})
+	return txs, err
+}
+
+func (f *FailoverIndexer) GetTransaction(ctx context.Context, hash string) (*Transaction, error) {
+	var tx *Transaction
+	err := f.do(ctx, "transaction", func(ix ChainIndexer) error {
+		var err error
+		tx, err = ix.GetTransaction(ctx, hash)
+		return err
+	})
+	return tx, err
+}
+
+func (f *FailoverIndexer) GetTransactionByInMsg(ctx context.Context, msgHash string) (*Transaction, error) {
+	var tx *Transaction
+	err := f.do(ctx, "transaction_by_msg", func(ix ChainIndexer) error {
+		var err error
+		tx, err = ix.GetTransactionByInMsg(ctx, msgHash)
+		return err
+	})
+	return tx, err
+}
+
+func (f *FailoverIndexer) GetAccountInfo(ctx context.Context, address string) (*AccountInfo, error) {
+	var info *AccountInfo
+	err := f.do(ctx, "account", func(ix ChainIndexer) error {
+		var err error
+		info, err = ix.GetAccountInfo(ctx, address)
+		return err
+	})
+	return info, err
+}
+
+// GetJettonWalletAddress спрашивает бэкенды, которые умеют искать кошельки жетонов
+// (остальные не считаются упавшими)
+func (f *FailoverIndexer) GetJettonWalletAddress(ctx context.Context, owner, master string) (string, error) {
+	lastErr := errJettonUnsupported
+	for _, i := range f.order() {
+		resolver, ok := f.backends[i].(JettonWalletResolver)
+		if !ok {
+			continue
+		}
+		wallet, err := resolver.GetJettonWalletAddress(ctx, owner, master)
+		if err == nil {
+			f.markUp(i)
+			return wallet, nil
+		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		f.markDown(i)
+		lastErr = err
+		logger.Get().Warn("индексатор недоступен, переключаемся на следующий",
+			"indexer", f.names[i],
+			"op", "jetton_wallet",
+			"error", err)
+	}
+	return "", lastErr
+}
+
+var errJettonUnsupported = errors.New("индексатор не поддерживает поиск кошельков жетонов")
@@ -0,0 +1,109 @@
+package ton
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tvm/cell"
+)
+
+const testPlatform = "0:1111111111111111111111111111111111111111111111111111111111111111"
+
+func TestFailoverIndexer(t *testing.T) {
+	primary, secondary := NewFakeIndexer(), NewFakeIndexer()
+	secondary.AddTransactions(testPlatform, Transaction{Hash: "aa", Lt: 7})
+	f := NewFailoverIndexer([]string{"primary", "secondary"}, []ChainIndexer{primary, secondary})
+	now := time.Now()
+	f.now = func() time.Time { return now }
+
+	primary.SetError(errors.New("502 bad gateway"))
+	txs, err := f.GetTransactions(context.Background(), testPlatform, 10, 0)
+	if err != nil || len(txs) != 1 || txs[0].Lt != 7 {
+		t.Fatalf("запрос не переключился на резервный индексатор: %v %v", txs, err)
+	}
+
+	// упавший индексатор пропускается до конца паузы
+	primary.SetError(nil)
+	calls := primary.Calls()
+	if _, err := f.GetTransaction(context.Background(), "aa"); err != nil {
+		t.Fatal(err)
+	}
+	if primary.Calls() != calls {
+		t.Fatal("упавший индексатор опрошен во время паузы")
+	}
+	now = now.Add(indexerCooldown + time.Second)
+	if _, err := f.GetAccountInfo(context.Background(), testPlatform); err != nil || primary.Calls() != calls+1 {
+		t.Fatalf("после паузы основной индексатор не опрошен: %v", err)
+	}
+
+	primary.SetError(errors.New("down"))
+	secondary.SetError(errors.New("down"))
+	if _, err := f.GetTransactions(context.Background(), testPlatform, 10, 0); err == nil {
+		t.Fatal("нет ошибки, когда все индексаторы недоступны")
+	}
+}
+
+func TestTonCenterTransactions(t *testing.T) {
+	hash := make([]byte, 32)
+	hash[0] = 0xab
+	sender := address.MustParseRawAddr("0:2222222222222222222222222222222222222222222222222222222222222222")
+	comment := cell.BeginCell().MustStoreUInt(0, 32).MustStoreStringSnake("deposit_42").EndCell()
+	notify := cell.BeginCell().
+		MustStoreUInt(0x7362d09c, 32).
+		MustStoreUInt(0, 64).
+		MustStoreBigCoins(big.NewInt(12_500_000)).
+		MustStoreAddr(sender).
+		MustStoreBoolBit(true).
+		MustStoreRef(comment).
+		EndCell()
+	body := base64.StdEncoding.EncodeToString(notify.ToBOC())
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/transactions" || r.URL.Query().Get("end_lt") != "99" {
+			http.Error(w, "unexpected request "+r.URL.String(), http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"transactions": [{
+			"account": "0:1111111111111111111111111111111111111111111111111111111111111111",
+			"hash": "` + base64.StdEncoding.EncodeToString(hash) + `",
+			"lt": "98", "now": 1760000000, "total_fees": "1000",
+			"description": {"aborted": false, "compute_ph": {"success": true}, "action": {"success": true}},
+			"in_msg": {
+				"source": "0:4444444444444444444444444444444444444444444444444444444444444444",
+				"destination": "0:1111111111111111111111111111111111111111111111111111111111111111",
+				"value": "50000000", "opcode": "0x7362d09c",
+				"message_content": {"body": "` + body + `", "decoded": null}
+			},
+			"out_msgs": []
+		}]}`))
+	}))
+	defer srv.Close()
+
+	c := NewTonCenterClient(NetworkMainnet, "")
+	c.baseURL = srv.URL
+	txs, err := c.GetTransactions(context.Background(), testPlatform, 10, 100)
+	if err != nil || len(txs) != 1 {
+		t.Fatalf("GetTransactions: %v %v", txs, err)
+	}
+	tx := txs[0]
+	if tx.Lt != 98 || !tx.Success || tx.Hash != hex.EncodeToString(hash) {
+		t.Fatalf("неверно приведена транзакция: %+v", tx)
+	}
+
+	n, ok := ParseJettonNotify(&tx)
+	if !ok {
+		t.Fatal("transfer_notification из toncenter не распознан")
+	}
+	if n.Amount != 12_500_000 || n.Comment != "deposit_42" || n.Sender != sender.StringRaw() ||
+		n.JettonWallet != "0:4444444444444444444444444444444444444444444444444444444444444444" {
+		t.Fatalf("неверно разобран перевод: %+v", n)
+	}
+}
@@ -0,0 +1,393 @@
+package ton
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xssnick/tonutils-go/tvm/cell"
+)
+
+// TonCenterClient индексатор toncenter API v3; ответы приводятся к формату tonapi
+type TonCenterClient struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewTonCenterClient создает клиент toncenter
+func NewTonCenterClient(network Network, apiKey string) *TonCenterClient {
+	baseURL := TonCenterMainnet
+	if network == NetworkTestnet {
+		baseURL = TonCenterTestnet
+	}
+
+	return &TonCenterClient{
+		baseURL: baseURL,
+		apiKey:  apiKey,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// транзакция в формате toncenter v3
+type tcTransaction struct {
+	Account     string       `json:"account"`
+	Hash        string       `json:"hash"`
+	Lt          string       `json:"lt"`
+	Now         int64        `json:"now"`
+	OrigStatus  string       `json:"orig_status"`
+	EndStatus   string       `json:"end_status"`
+	TotalFees   string       `json:"total_fees"`
+	InMsg       *tcMessage   `json:"in_msg"`
+	OutMsgs     []*tcMessage `json:"out_msgs"`
+	Description struct {
+		Aborted bool `json:"aborted"`
+		Compute struct {
+			Success *bool `json:"success"`
+		} `json:"compute_ph"`
+		Action *struct {
+			Success bool `json:"success"`
+		} `json:"action"`
+	} `json:"description"`
+}
+
+type tcMessage struct {
+	Hash           string          `json:"hash"`
+	Source         *string         `json:"source"`
+	Destination    *string         `json:"destination"`
+	Value          *string         `json:"value"`
+	FwdFee         *string         `json:"fwd_fee"`
+	CreatedLt      *string         `json:"created_lt"`
+	CreatedAt      *string         `json:"created_at"`
+	Opcode         json.RawMessage `json:"opcode"`
+	Bounce         *bool           `json:"bounce"`
+	Bounced        *bool           `json:"bounced"`
+	MessageContent *struct {
+		Body    string `json:"body"`
+		Decoded *struct {
+			Type    string `json:"type"`
+			Comment string `json:"comment"`
+		} `json:"decoded"`
+	} `json:"message_content"`
+}
+
+func (c *TonCenterClient) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	reqURL := fmt.Sprintf("%s%s?%s", c.baseURL, path, query.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return err
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("ошибка toncenter: %s - %s", resp.Status, string(body))
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// получает последние транзакции для адреса
+func (c *TonCenterClient) GetTransactions(ctx context.Context, address string, limit int, beforeLt int64) ([]Transaction, error) {
+	query := url.Values{
+		"account": {address},
+		"limit":   {strconv.Itoa(limit)},
+		"sort":    {"desc"},
+	}
+	// end_lt включает границу
+	if beforeLt > 0 {
+		query.Set("end_lt", strconv.FormatInt(beforeLt-1, 10))
+	}
+
+	var result struct {
+		Transactions []tcTransaction `json:"transactions"`
+	}
+	if err := c.get(ctx, "/transactions", query, &result); err != nil {
+		return nil, err
+	}
+
+	txs := make([]Transaction, 0, len(result.Transactions))
+	for i := range result.Transactions {
+		txs = append(txs, result.Transactions[i].toTransaction())
+	}
+	return txs, nil
+}
+
+// получает конкретную транзакцию по хэшу
+func (c *TonCenterClient) GetTransaction(ctx context.Context, hash string) (*Transaction, error) {
+	var result struct {
+		Transactions []tcTransaction `json:"transactions"`
+	}
+	if err := c.get(ctx, "/transactions", url.Values{"hash": {hash}, "limit": {"1"}}, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Transactions) == 0 {
+		return nil, nil
+	}
+
+	tx := result.Transactions[0].toTransaction()
+	return &tx, nil
+}
+
+// получает транзакцию, которую породило входящее сообщение msgHash
+func (c *TonCenterClient) GetTransactionByInMsg(ctx context.Context, msgHash string) (*Transaction, error) {
+	var result struct {
+		Transactions []tcTransaction `json:"transactions"`
+	}
+	query := url.Values{"msg_hash": {msgHash}, "direction": {"in"}, "limit": {"1"}}
+	if err := c.get(ctx, "/transactionsByMessage", query, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Transactions) == 0 {
+		return nil, nil
+	}
+
+	tx := result.Transactions[0].toTransaction()
+	return &tx, nil
+}
+
+// получает информацию об аккаунте
+func (c *TonCenterClient) GetAccountInfo(ctx context.Context, address string) (*AccountInfo, error) {
+	var result struct {
+		Accounts []struct {
+			Address    string  `json:"address"`
+			Balance    string  `json:"balance"`
+			Status     string  `json:"status"`
+			LastTxLt   *string `json:"last_transaction_lt"`
+			LastTxHash *string `json:"last_transaction_hash"`
+		} `json:"accounts"`
+	}
+	query := url.Values{"address": {address}, "include_boc": {"false"}}
+	if err := c.get(ctx, "/accountStates", query, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Accounts) == 0 {
+		return nil, fmt.Errorf("аккаунт %s не найден", address)
+	}
+
+	acc := result.Accounts[0]
+	info := &AccountInfo{
+		Address: rawAddress(acc.Address),
+		Balance: parseInt(acc.Balance),
+		Status:  acc.Status,
+	}
+	if acc.LastTxLt != nil {
+		info.LastTxLt = parseInt(*acc.LastTxLt)
+	}
+	if acc.LastTxHash != nil {
+		info.LastTxHash = hashToHex(*acc.LastTxHash)
+	}
+	return info, nil
+}
+
+// получает адрес кошелька жетона master, принадлежащего owner ("" - кошелька еще нет)
+func (c *TonCenterClient) GetJettonWalletAddress(ctx context.Context, owner, master string) (string, error) {
+	var result struct {
+		JettonWallets []struct {
+			Address string `json:"address"`
+		} `json:"jetton_wallets"`
+	}
+	query := url.Values{"owner_address": {owner}, "jetton_address": {master}, "limit": {"1"}}
+	if err := c.get(ctx, "/jetton/wallets", query, &result); err != nil {
+		return "", err
+	}
+	if len(result.JettonWallets) == 0 {
+		return "", nil
+	}
+	return rawAddress(result.JettonWallets[0].Address), nil
+}
+
+func (t *tcTransaction) toTransaction() Transaction {
+	tx := Transaction{
+		Hash:       hashToHex(t.Hash),
+		Lt:         parseInt(t.Lt),
+		Account:    &AccountAddress{Address: rawAddress(t.Account)},
+		Utime:      t.Now,
+		OrigStatus: t.OrigStatus,
+		EndStatus:  t.EndStatus,
+		TotalFees:  parseInt(t.TotalFees),
+	}
+
+	d := t.Description
+	tx.Success = !d.Aborted &&
+		(d.Compute.Success == nil || *d.Compute.Success) &&
+		(d.Action == nil || d.Action.Success)
+
+	if t.InMsg != nil {
+		msg := t.InMsg.toMessage()
+		tx.InMsg = &msg
+	}
+	for _, m := range t.OutMsgs {
+		if m != nil {
+			tx.OutMsgs = append(tx.OutMsgs, m.toMessage())
+		}
+	}
+	return tx
+}
+
+func (m *tcMessage) toMessage() Message {
+	msg := Message{
+		Hash:   hashToHex(m.Hash),
+		OpCode: parseOpcode(m.Opcode),
+	}
+	if m.Source != nil && *m.Source != "" {
+		msg.Source = &AccountAddress{Address: rawAddress(*m.Source)}
+		msg.MsgType = "int_msg"
+	} else {
+		msg.MsgType = "ext_in_msg"
+	}
+	if m.Destination != nil && *m.Destination != "" {
+		msg.Destination = &AccountAddress{Address: rawAddress(*m.Destination)}
+	}
+	if m.Value != nil {
+		msg.Value = parseInt(*m.Value)
+	}
+	if m.FwdFee != nil {
+		msg.FwdFee = parseInt(*m.FwdFee)
+	}
+	if m.CreatedLt != nil {
+		msg.CreatedLt = parseInt(*m.CreatedLt)
+	}
+	if m.CreatedAt != nil {
+		msg.CreatedAt = parseInt(*m.CreatedAt)
+	}
+	if m.Bounce != nil {
+		msg.Bounce = *m.Bounce
+	}
+	if m.Bounced != nil {
+		msg.Bounced = *m.Bounced
+	}
+
+	if m.MessageContent == nil {
+		return msg
+	}
+	if dec := m.MessageContent.Decoded; dec != nil && dec.Type == "text_comment" {
+		msg.DecodedOpName = "text_comment"
+		msg.DecodedBody = &DecodedBody{Text: dec.Comment}
+		return msg
+	}
+	if msg.OpCode == JettonNotifyOpCode {
+		if body, ok := decodeJettonNotifyBody(m.MessageContent.Body); ok {
+			msg.DecodedOpName = "jetton_notify"
+			msg.DecodedBody = body
+		}
+	}
+	return msg
+}
+
+// decodeJettonNotifyBody разбирает BOC transfer_notification в поля, как их отдает tonapi:
+// op:uint32 query_id:uint64 amount:Coins sender:MsgAddress forward_payload:(Either Cell ^Cell)
+func decodeJettonNotifyBody(boc string) (*DecodedBody, bool) {
+	raw, err := base64.StdEncoding.DecodeString(boc)
+	if err != nil {
+		return nil, false
+	}
+	root, err := cell.FromBOC(raw)
+	if err != nil {
+		return nil, false
+	}
+
+	s := root.BeginParse()
+	if _, err := s.LoadUInt(32); err != nil {
+		return nil, false
+	}
+	if _, err := s.LoadUInt(64); err != nil {
+		return nil, false
+	}
+	amount, err := s.LoadBigCoins()
+	if err != nil {
+		return nil, false
+	}
+	sender, err := s.LoadAddr()
+	if err != nil {
+		return nil, false
+	}
+
+	body := &DecodedBody{
+		Amount: json.RawMessage(strconv.Quote(amount.String())),
+		Sender: json.RawMessage(strconv.Quote(sender.StringRaw())),
+	}
+
+	// комментарий: forward_payload с op 0 и текстом в snake-формате
+	payload := s
+	if isRight, err := s.LoadBoolBit(); err == nil && isRight {
+		if payload, err = s.LoadRef(); err != nil {
+			return body, true
+		}
+	}
+	if op, err := payload.LoadUInt(32); err == nil && op == 0 {
+		if text, err := payload.LoadStringSnake(); err == nil {
+			forward, _ := json.Marshal(map[string]interface{}{
+				"value": map[string]interface{}{
+					"sum_type": "TextComment",
+					"value":    map[string]string{"text": text},
+				},
+			})
+			body.ForwardPayload = forward
+		}
+	}
+	return body, true
+}
+
+// opcode приходит числом или строкой "0x..."; приводим к виду tonapi
+func parseOpcode(raw json.RawMessage) string {
+	s := strings.Trim(string(raw), `"`)
+	if s == "" || s == "null" {
+		return ""
+	}
+	if strings.HasPrefix(s, "0x") {
+		return strings.ToLower(s)
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return s
+	}
+	return fmt.Sprintf("0x%08x", uint32(n))
+}
+
+// хэш toncenter в base64 переводится в hex, как у tonapi
+func hashToHex(hash string) string {
+	if len(hash) == 64 {
+		if _, err := hex.DecodeString(hash); err == nil {
+			return strings.ToLower(hash)
+		}
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding} {
+		if b, err := enc.DecodeString(hash); err == nil && len(b) == 32 {
+			return hex.EncodeToString(b)
+		}
+	}
+	return hash
+}
+
+// toncenter отдает raw адреса в верхнем регистре
+func rawAddress(addr string) string {
+	if normalized, err := NormalizeAddress(addr); err == nil && normalized != "" {
+		return strings.ToLower(normalized)
+	}
+	return addr
+}
+
+func parseInt(s string) int64 {
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
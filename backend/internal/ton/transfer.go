@@ -0,0 +1,33 @@
+package ton
+
+import (
+	"encoding/base64"
+	"net/url"
+	"strconv"
+)
+
+// TransferLink ссылка ton://transfer на перевод с комментарием text.
+// Для жетона добавляется jetton=<мастер>, сумма - в минимальных единицах актива (0 - пользователь вводит сам)
+func TransferLink(toAddress string, asset Asset, master string, amountUnits int64, text string) string {
+	query := url.Values{}
+	if amountUnits > 0 {
+		query.Set("amount", strconv.FormatInt(amountUnits, 10))
+	}
+	if asset.IsJetton() && master != "" {
+		query.Set("jetton", master)
+	}
+	if text != "" {
+		query.Set("text", text)
+	}
+
+	link := "ton://transfer/" + toAddress
+	if encoded := query.Encode(); encoded != "" {
+		link += "?" + encoded
+	}
+	return link
+}
+
+// CommentPayload base64 BOC текстового комментария - поле payload в sendTransaction TON Connect
+func CommentPayload(text string) string {
+	return base64.StdEncoding.EncodeToString(buildCommentCell(text).ToBOC())
+}
@@ -1,14 +1,21 @@
 package ton
 
 import (
+	"bytes"
 	"crypto/ed25519"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/binary"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
+
+	"github.com/xssnick/tonutils-go/address"
+	"github.com/xssnick/tonutils-go/tlb"
+	"github.com/xssnick/tonutils-go/tvm/cell"
 )
 
 // верификация доказательства TON Connect
@@ -33,100 +40,155 @@ type WalletAccount struct {
 	Address   string `json:"address"`
 	Chain     string `json:"chain"`
 	PublicKey string `json:"publicKey"`
+	// base64 BOC StateInit кошелька: его хэш - адрес, в данных - публичный ключ
+	WalletStateInit string `json:"walletStateInit"`
 }
 
-// проверяет доказательство владения кошельком TON Connect
-func VerifyProof(account WalletAccount, proof ConnectProof, allowedDomain string) error {
-	// 1. проверяем временную метку (доказательство должно быть свежим)
+// смещения публичного ключа в данных стандартных кошельков:
+// v3/v4 - seqno(32) subwallet(32), v5 - signature_allowed(1) seqno(32) wallet_id(32), v1/v2 - seqno(32)
+var walletPublicKeyOffsets = []uint{64, 65, 32}
+
+// проверяет доказательство владения кошельком TON Connect: payload должен совпадать с выданным
+// сервером nonce, публичный ключ - с ключом из StateInit, хэш которого равен адресу
+func VerifyProof(account WalletAccount, proof ConnectProof, allowedDomain, expectedPayload string) error {
+	// 1. подписанный payload - выданный сервером nonce
+	if expectedPayload == "" || proof.Payload != expectedPayload {
+		return errors.New("payload не совпадает с выданным")
+	}
+
+	// 2. проверяем временную метку (доказательство должно быть свежим)
 	proofTime := time.Unix(proof.Timestamp, 0)
-	if time.Since(proofTime) > ProofTTL {
+	if time.Since(proofTime) > ProofTTL || time.Until(proofTime) > time.Minute {
 		return errors.New("срок действия доказательства истек")
 	}
 
-	// 2. проверяем домен
+	// 3. проверяем домен
 	if proof.Domain.Value != allowedDomain {
 		return fmt.Errorf("несоответствие домена: ожидался %s, получен %s", allowedDomain, proof.Domain.Value)
 	}
+	if proof.Domain.LengthBytes != len(proof.Domain.Value) {
+		return errors.New("неверная длина домена")
+	}
+
+	// 4. адрес кошелька
+	addr, err := parseAnyAddress(account.Address)
+	if err != nil {
+		return fmt.Errorf("неверный адрес: %w", err)
+	}
 
-	// 3. декодируем публичный ключ
+	// 5. публичный ключ
 	pubKeyBytes, err := hex.DecodeString(account.PublicKey)
 	if err != nil {
 		return fmt.Errorf("неверный формат публичного ключа: %w", err)
 	}
-
 	if len(pubKeyBytes) != ed25519.PublicKeySize {
 		return errors.New("неверный размер публичного ключа")
 	}
 
-	// 4. декодируем подпись
+	// 6. ключ принадлежит адресу: StateInit хэшируется в адрес и содержит этот ключ
+	if err := verifyStateInitKey(addr, account.WalletStateInit, pubKeyBytes); err != nil {
+		return err
+	}
+
+	// 7. декодируем подпись
 	signatureBytes, err := base64.StdEncoding.DecodeString(proof.Signature)
 	if err != nil {
 		return fmt.Errorf("неверный формат подписи: %w", err)
 	}
 
-	// 5. собираем сообщение для проверки
-	message := buildProofMessage(account.Address, proof)
-
-	// 6. проверяем подпись
-	if !ed25519.Verify(pubKeyBytes, message, signatureBytes) {
+	// 8. проверяем подпись
+	if !ed25519.Verify(pubKeyBytes, buildProofMessage(addr, proof), signatureBytes) {
 		return errors.New("неверная подпись")
 	}
 
 	return nil
 }
 
-// собирает сообщение, которое было подписано
-func buildProofMessage(address string, proof ConnectProof) []byte {
-	// формат сообщения:
-	// "ton-proof-item-v2/" + address_workchain (4 байта) + address_hash (32 байта)
-	// + domain_len (4 байта) + domain + timestamp (8 байт) + payload
+// parseAnyAddress разбирает raw (0:hex) или user-friendly адрес
+func parseAnyAddress(s string) (*address.Address, error) {
+	if strings.Contains(s, ":") {
+		return address.ParseRawAddr(s)
+	}
+	return address.ParseAddr(s)
+}
+
+// verifyStateInitKey хэш StateInit равен адресу, а публичный ключ лежит в данных по смещению стандартного кошелька
+func verifyStateInitKey(addr *address.Address, stateInitBOC string, pubKey []byte) error {
+	if stateInitBOC == "" {
+		return errors.New("не передан walletStateInit")
+	}
+	raw, err := base64.StdEncoding.DecodeString(stateInitBOC)
+	if err != nil {
+		return fmt.Errorf("неверный формат walletStateInit: %w", err)
+	}
+	root, err := cell.FromBOC(raw)
+	if err != nil {
+		return fmt.Errorf("неверный walletStateInit: %w", err)
+	}
+	if !bytes.Equal(root.Hash(), addr.Data()) {
+		return errors.New("walletStateInit не соответствует адресу")
+	}
 
-	// парсим адрес для получения workchain и hash
-	// для простоты соберем более простое хэш-сообщение
-	// в продакшене следует правильно парсить TON-адрес
+	var stateInit tlb.StateInit
+	if err := tlb.LoadFromCell(&stateInit, root.BeginParse()); err != nil {
+		return fmt.Errorf("неверный walletStateInit: %w", err)
+	}
+	if stateInit.Data == nil {
+		return errors.New("в walletStateInit нет данных кошелька")
+	}
+	for _, offset := range walletPublicKeyOffsets {
+		data := stateInit.Data.BeginParse()
+		if data.BitsLeft() < offset+256 {
+			continue
+		}
+		if _, err := data.LoadSlice(offset); err != nil {
+			continue
+		}
+		key, err := data.LoadSlice(256)
+		if err == nil && bytes.Equal(key, pubKey) {
+			return nil
+		}
+	}
+	return errors.New("публичный ключ не принадлежит кошельку")
+}
 
-	// фактическое построение сообщения доказательства TON
+// собирает сообщение, которое было подписано (ton-proof-item-v2):
+// sha256(0xffff ++ "ton-connect" ++ sha256(message)), где message =
+// "ton-proof-item-v2/" ++ workchain (4 байта, BE) ++ hash (32 байта) ++ domain_len (4 байта, LE)
+// ++ domain ++ timestamp (8 байт, LE) ++ payload
+func buildProofMessage(addr *address.Address, proof ConnectProof) []byte {
 	var message []byte
-
-	// "ton-proof-item-v2/"
 	message = append(message, []byte("ton-proof-item-v2/")...)
 
-	// адрес (упрощенно - в реальной реализации следует правильно парсить)
-	message = append(message, []byte(address)...)
+	workchain := make([]byte, 4)
+	binary.BigEndian.PutUint32(workchain, uint32(addr.Workchain()))
+	message = append(message, workchain...)
+	message = append(message, addr.Data()...)
 
-	// длина домена (4 байта, little endian)
 	domainLen := make([]byte, 4)
 	binary.LittleEndian.PutUint32(domainLen, uint32(proof.Domain.LengthBytes))
 	message = append(message, domainLen...)
-
-	// значение домена
 	message = append(message, []byte(proof.Domain.Value)...)
 
-	// временная метка (8 байт, little endian)
 	timestamp := make([]byte, 8)
 	binary.LittleEndian.PutUint64(timestamp, uint64(proof.Timestamp))
 	message = append(message, timestamp...)
 
-	// полезная нагрузка
 	message = append(message, []byte(proof.Payload)...)
-
-	// хэшируем сообщение
 	hash := sha256.Sum256(message)
 
-	// добавляем префикс "ton-connect" и снова хэшируем
-	finalMessage := append([]byte("ton-connect"), hash[:]...)
-	finalHash := sha256.Sum256(finalMessage)
-
+	full := append([]byte{0xff, 0xff}, []byte("ton-connect")...)
+	full = append(full, hash[:]...)
+	finalHash := sha256.Sum256(full)
 	return finalHash[:]
 }
 
-// генерирует случайную полезную нагрузку для TON Connect
+// генерирует случайную полезную нагрузку (nonce) для TON Connect proof
+// уникальность и однократность обеспечивает хранилище выданных nonce (ton_proof_payloads)
 func GeneratePayload() string {
-	// генерирует случайную полезную нагрузку, которая будет подписана
-	// должна быть уникальной для каждой сессии для предотвращения replay-атак
-	timestamp := time.Now().Unix()
-	payload := fmt.Sprintf("%d-%x", timestamp, sha256.Sum256([]byte(fmt.Sprintf("%d", timestamp))))
-	return payload[:32] // обрезаем до разумной длины
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf) // crypto/rand не возвращает ошибок
+	return hex.EncodeToString(buf)
 }
 
 // проверяет, является ли формат TON-адреса валидным
@@ -0,0 +1,68 @@
+package ton
+
+import (
+	"crypto/ed25519"
+	"testing"
+)
+
+// testWallet кошелек v4: данные seqno(32) subwallet(32) pubkey(256)
+func testWallet(t *testing.T) (WalletAccount, ed25519.PrivateKey) {
+	t.Helper()
+	account, priv, err := NewFakeConnectWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return account, priv
+}
+
+func signProof(t *testing.T, account WalletAccount, priv ed25519.PrivateKey, domain, payload string) ConnectProof {
+	t.Helper()
+	proof, err := SignFakeProof(account, priv, domain, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return proof
+}
+
+func TestVerifyProof(t *testing.T) {
+	const domain = "app.example.com"
+	account, priv := testWallet(t)
+	payload := GeneratePayload()
+	proof := signProof(t, account, priv, domain, payload)
+
+	if err := VerifyProof(account, proof, domain, payload); err != nil {
+		t.Fatalf("валидный proof отклонен: %v", err)
+	}
+
+	// подписан другой nonce, чем погашается
+	if err := VerifyProof(account, proof, domain, GeneratePayload()); err == nil {
+		t.Fatal("принят proof с чужим payload")
+	}
+
+	// подделка: payload заменен после подписи
+	forged := proof
+	forged.Payload = GeneratePayload()
+	if err := VerifyProof(account, forged, domain, forged.Payload); err == nil {
+		t.Fatal("принят proof с подмененным payload")
+	}
+
+	// свой ключ и подпись для чужого адреса: StateInit не соответствует адресу
+	other, otherPriv := testWallet(t)
+	stolen := account
+	stolen.PublicKey = other.PublicKey
+	stolen.WalletStateInit = other.WalletStateInit
+	if err := VerifyProof(stolen, signProof(t, stolen, otherPriv, domain, payload), domain, payload); err == nil {
+		t.Fatal("принят proof с ключом другого кошелька")
+	}
+
+	// верный StateInit, но ключ не из него
+	wrongKey := account
+	wrongKey.PublicKey = other.PublicKey
+	if err := VerifyProof(wrongKey, signProof(t, wrongKey, otherPriv, domain, payload), domain, payload); err == nil {
+		t.Fatal("принят публичный ключ не из StateInit")
+	}
+
+	if err := VerifyProof(account, signProof(t, account, priv, "evil.example.com", payload), domain, payload); err == nil {
+		t.Fatal("принят proof для чужого домена")
+	}
+}
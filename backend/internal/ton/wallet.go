@@ -3,22 +3,36 @@ package ton
 import (
 	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"math/big"
 	"strings"
 
 	"github.com/xssnick/tonutils-go/address"
 	"github.com/xssnick/tonutils-go/liteclient"
 	"github.com/xssnick/tonutils-go/tlb"
 	"github.com/xssnick/tonutils-go/ton"
+	"github.com/xssnick/tonutils-go/ton/jetton"
 	"github.com/xssnick/tonutils-go/ton/wallet"
 	"github.com/xssnick/tonutils-go/tvm/cell"
 )
 
+// ErrInsufficientBalance на кошельке не хватает средств; перевод не отправлялся
+var ErrInsufficientBalance = errors.New("insufficient balance")
+
+// ErrSendUnconfirmed сообщение могло уйти в сеть, но подтверждение не получено; исход виден только в сети.
+// Остальные ошибки SendTON и SendJetton возникают до отправки
+var ErrSendUnconfirmed = errors.New("send unconfirmed")
+
+// TON на газ перевода жетона (излишек возвращается excesses на кошелек платформы)
+const jettonTransferGas = "0.05"
+
 // Wallet представляет TON кошелек для отправки транзакций
 type Wallet struct {
 	client  *ton.APIClient
 	wallet  *wallet.Wallet
 	network Network
+	jettons map[Asset]string // мастер-контракты жетонов для выводов
 }
 
 // SendResult результат отправки транзакции
@@ -138,7 +152,7 @@ func (w *Wallet) SendTON(ctx context.Context, toAddress string, amountNano uint6
 	// Учитываем комиссию сети (~0.01 TON)
 	networkFee := uint64(10_000_000) // 0.01 TON
 	if balance < amountNano+networkFee {
-		return nil, fmt.Errorf("insufficient balance: have %d, need %d + fee", balance, amountNano)
+		return nil, fmt.Errorf("%w: have %d, need %d + fee", ErrInsufficientBalance, balance, amountNano)
 	}
 
 	// Создаем сообщение с или без комментария
@@ -165,7 +179,7 @@ func (w *Wallet) SendTON(ctx context.Context, toAddress string, amountNano uint6
 	// Отправляем транзакцию
 	tx, _, err := w.wallet.SendWaitTransaction(ctx, msg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send transaction: %w", err)
+		return nil, fmt.Errorf("%w: failed to send transaction: %w", ErrSendUnconfirmed, err)
 	}
 
 	// Получаем хэш транзакции
@@ -177,6 +191,131 @@ func (w *Wallet) SendTON(ctx context.Context, toAddress string, amountNano uint6
 	}, nil
 }
 
+// SetJettonMaster задает мастер-контракт жетона для выводов в asset
+func (w *Wallet) SetJettonMaster(asset Asset, master string) {
+	if w.jettons == nil {
+		w.jettons = make(map[Asset]string)
+	}
+	w.jettons[asset] = master
+}
+
+// SupportsAsset кошелек может отправлять asset
+func (w *Wallet) SupportsAsset(asset Asset) bool {
+	return !asset.IsJetton() || w.jettons[asset] != ""
+}
+
+// SendAsset отправляет amount минимальных единиц asset: TON напрямую, жетоны через кошелек жетона
+func (w *Wallet) SendAsset(ctx context.Context, asset Asset, toAddress string, amount uint64, comment string) (*SendResult, error) {
+	if !asset.IsJetton() {
+		return w.SendTON(ctx, toAddress, amount, comment)
+	}
+	master := w.jettons[asset]
+	if master == "" {
+		return nil, fmt.Errorf("жетон %s не настроен", asset)
+	}
+	return w.SendJetton(ctx, master, asset.Decimals(), toAddress, amount, comment)
+}
+
+// GetAssetBalance баланс asset в минимальных единицах: TON - баланс кошелька, жетон - его кошелек жетона
+func (w *Wallet) GetAssetBalance(ctx context.Context, asset Asset) (uint64, error) {
+	if !asset.IsJetton() {
+		return w.GetBalance(ctx)
+	}
+	master := w.jettons[asset]
+	if master == "" {
+		return 0, fmt.Errorf("жетон %s не настроен", asset)
+	}
+
+	masterAddr, err := address.ParseAddr(master)
+	if err != nil {
+		return 0, fmt.Errorf("invalid jetton master: %w", err)
+	}
+	jw, err := jetton.NewJettonMasterClient(w.client, masterAddr).GetJettonWallet(ctx, w.wallet.WalletAddress())
+	if err != nil {
+		return 0, fmt.Errorf("failed to get jetton wallet: %w", err)
+	}
+	balance, err := jw.GetBalance(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get jetton balance: %w", err)
+	}
+	return balance.Uint64(), nil
+}
+
+// Assets активы, которые кошелек может отправлять
+func (w *Wallet) Assets() []Asset {
+	assets := []Asset{AssetTON}
+	for _, asset := range []Asset{AssetUSDT} {
+		if w.SupportsAsset(asset) {
+			assets = append(assets, asset)
+		}
+	}
+	return assets
+}
+
+// SendJetton переводит amount минимальных единиц жетона master на адрес toAddress.
+// Транзакция кошелька платформы отправляет transfer на свой кошелек жетона с газом jettonTransferGas
+func (w *Wallet) SendJetton(ctx context.Context, master string, decimals int, toAddress string, amount uint64, comment string) (*SendResult, error) {
+	var to *address.Address
+	var err error
+	if strings.HasPrefix(toAddress, "0:") || strings.HasPrefix(toAddress, "-1:") {
+		to, err = parseRawAddress(toAddress)
+	} else {
+		to, err = address.ParseAddr(toAddress)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient address: %w (original: %s)", err, toAddress)
+	}
+
+	masterAddr, err := address.ParseAddr(master)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jetton master: %w", err)
+	}
+
+	jw, err := jetton.NewJettonMasterClient(w.client, masterAddr).GetJettonWallet(ctx, w.wallet.WalletAddress())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get jetton wallet: %w", err)
+	}
+
+	// жетоны и TON на газ проверяем до отправки
+	jettonBalance, err := jw.GetBalance(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check jetton balance: %w", err)
+	}
+	units := new(big.Int).SetUint64(amount)
+	if jettonBalance.Cmp(units) < 0 {
+		return nil, fmt.Errorf("%w: have %s jetton units, need %d", ErrInsufficientBalance, jettonBalance, amount)
+	}
+	gas := tlb.MustFromTON(jettonTransferGas)
+	balance, err := w.GetBalance(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check balance: %w", err)
+	}
+	if balance < gas.Nano().Uint64() {
+		return nil, fmt.Errorf("%w: have %d nanoTON, need %s TON for jetton gas", ErrInsufficientBalance, balance, jettonTransferGas)
+	}
+
+	var forward *cell.Cell
+	if comment != "" {
+		forward = buildCommentCell(comment)
+	}
+	// 1 наноTON форварда - получатель видит transfer_notification с комментарием
+	payload, err := jetton.BuildTransferPayload(to, w.wallet.WalletAddress(),
+		tlb.MustFromNano(units, decimals), tlb.FromNanoTONU(1), forward, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build jetton transfer: %w", err)
+	}
+
+	tx, _, err := w.wallet.SendWaitTransaction(ctx, wallet.SimpleMessage(jw.Address(), gas, payload))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to send transaction: %w", ErrSendUnconfirmed, err)
+	}
+
+	return &SendResult{
+		TxHash:  fmt.Sprintf("%x", tx.Hash),
+		Success: true,
+	}, nil
+}
+
 // buildCommentCell создает cell с текстовым комментарием
 func buildCommentCell(comment string) *cell.Cell {
 	// Комментарий в TON: 32 бита нулей + UTF-8 текст
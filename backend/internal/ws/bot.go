@@ -0,0 +1,193 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math/rand"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/game"
+	"telegram_webapp/internal/repository"
+)
+
+// имя бота-соперника для клиента
+const botDisplayName = "Bot"
+
+// настройки бота-соперника: когда и кому он может занять пустое место
+type BotPolicy struct {
+	Delay      time.Duration   // ожидание человека до подсадки бота
+	Stakes     map[string]bool // разрешенные ставки, ключ валюта_ставка
+	DailyLimit int             // игр с ботом на пользователя в сутки
+	History    *repository.GameHistoryRepository
+}
+
+// создает политику бота; stakes - пары валюта/ставка
+func NewBotPolicy(delay time.Duration, dailyLimit int, history *repository.GameHistoryRepository) *BotPolicy {
+	return &BotPolicy{
+		Delay:      delay,
+		Stakes:     make(map[string]bool),
+		DailyLimit: dailyLimit,
+		History:    history,
+	}
+}
+
+// разрешает бота на ставке
+func (p *BotPolicy) AllowStake(currency string, amount int64) {
+	p.Stakes[stakeKey(currency, amount)] = true
+}
+
+// проверяет ставку и дневной лимит пользователя
+func (p *BotPolicy) allows(ctx context.Context, userID int64, currency string, amount int64) bool {
+	if !p.Stakes[stakeKey(currency, amount)] {
+		return false
+	}
+	if p.DailyLimit <= 0 || p.History == nil {
+		return false
+	}
+
+	now := time.Now().UTC()
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	count, err := p.History.CountBotGames(ctx, userID, dayStart)
+	if err != nil {
+		log.Printf("BotPolicy.allows: не удалось посчитать игры с ботом пользователя=%d: %v", userID, err)
+		return false
+	}
+	return count < p.DailyLimit
+}
+
+func stakeKey(currency string, amount int64) string {
+	return WaitingKey{Currency: currency, BetAmount: amount}.String()
+}
+
+// является ли игрок ботом-соперником
+func isBotPlayer(userID int64) bool {
+	return userID == game.BotPlayerID
+}
+
+// забирает ожидающего игрока из публичной очереди под бота
+// возвращает false, если к нему уже подсел человек
+func (h *Hub) claimSeatForBot(r *Room, userID int64) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if r.game.Players()[1] != 0 {
+		return false
+	}
+	for key, waiting := range h.WaitingByKey {
+		if waiting != nil && waiting.UserID == userID {
+			delete(h.WaitingByKey, key)
+		}
+	}
+	r.game.SetSecondPlayer(game.BotPlayerID)
+	return true
+}
+
+// tryBotFill занимает пустое место ботом, если это разрешено политикой
+func (r *Room) tryBotFill() bool {
+	if r.hub == nil || r.hub.Bot == nil || r.ChallengeCode != "" || r.isSeries() || r.isTournament() {
+		return false
+	}
+
+	r.mu.RLock()
+	players := r.game.Players()
+	waiting := r.Clients[players[0]]
+	r.mu.RUnlock()
+
+	if players[1] != 0 || waiting == nil {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	allowed := r.hub.Bot.allows(ctx, players[0], r.Currency, r.BetAmount)
+	cancel()
+	if !allowed {
+		return false
+	}
+
+	if !r.hub.claimSeatForBot(r, players[0]) {
+		return false
+	}
+
+	strategy := game.NewBotStrategy(r.game.Type())
+	r.mu.Lock()
+	r.botStrategy = strategy
+	r.mu.Unlock()
+
+	if setup := strategy.SetupMove(r.game, game.BotPlayerID); setup != nil {
+		if err := r.game.HandleMove(game.BotPlayerID, setup); err != nil {
+			log.Printf("Room.tryBotFill: bot setup failed in room=%s: %v", r.ID, err)
+		}
+	}
+
+	r.recordEvent(domain.MatchEventJoin, game.BotPlayerID, map[string]interface{}{"bot": true})
+	log.Printf("Room.tryBotFill: bot joined room=%s against user=%d bet=%d %s", r.ID, players[0], r.BetAmount, r.Currency)
+
+	data, _ := json.Marshal(Message{
+		Type: MsgMatched,
+		Payload: MatchedPayload{
+			RoomID: r.ID,
+			Opponent: OpponentInfo{
+				ID:        game.BotPlayerID,
+				FirstName: botDisplayName,
+				IsBot:     true,
+			},
+		},
+	})
+	select {
+	case waiting.Send <- data:
+	case <-time.After(1 * time.Second):
+		log.Printf("Room.tryBotFill: timeout sending matched to user=%d", players[0])
+	}
+
+	return true
+}
+
+// startBotGame начинает игру с ботом, если подготовка уже завершена
+func (r *Room) startBotGame() {
+	if r.game.Type() == game.TypeMines {
+		r.completeSetup()
+		time.Sleep(100 * time.Millisecond)
+	} else {
+		r.mu.Lock()
+		r.setupCompleted = true
+		r.mu.Unlock()
+	}
+
+	r.mu.Lock()
+	r.roundStarted = false
+	r.mu.Unlock()
+	r.startRound()
+}
+
+// botMove делает ход бота в раунде с небольшой паузой "на раздумье"
+func (r *Room) botMove(forRound int) {
+	time.Sleep(time.Duration(1000+rand.Intn(2000)) * time.Millisecond)
+
+	r.mu.RLock()
+	stale := forRound != r.timerRound
+	strategy := r.botStrategy
+	r.mu.RUnlock()
+	if stale || strategy == nil || r.game.IsFinished() {
+		return
+	}
+
+	move := strategy.NextMove(r.game, game.BotPlayerID)
+	if err := r.game.HandleMove(game.BotPlayerID, move); err != nil {
+		log.Printf("Room.botMove: bot move failed in room=%s: %v", r.ID, err)
+		return
+	}
+	r.recordMove(game.BotPlayerID, move)
+	r.notifySpectators()
+
+	if r.game.IsRoundComplete() {
+		r.mu.Lock()
+		if r.timer != nil {
+			r.timer.Stop()
+			r.timer = nil
+		}
+		r.mu.Unlock()
+		r.checkRound()
+	}
+}
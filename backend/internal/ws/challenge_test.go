@@ -0,0 +1,90 @@
+package ws
+
+import (
+	"testing"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/game"
+)
+
+func TestChallengeCanJoin(t *testing.T) {
+	invitee := int64(2)
+	cases := []struct {
+		name    string
+		invitee *int64
+		userID  int64
+		want    bool
+	}{
+		{"создатель", &invitee, 1, true},
+		{"приглашенный", &invitee, 2, true},
+		{"чужой в именном вызове", &invitee, 3, false},
+		{"любой в открытом вызове", nil, 3, true},
+	}
+	for _, c := range cases {
+		ch := &domain.Challenge{CreatorID: 1, InviteeID: c.invitee}
+		if got := ch.CanJoin(c.userID); got != c.want {
+			t.Errorf("%s: CanJoin=%v, ожидалось %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestRoomHoldsStake(t *testing.T) {
+	cases := []struct {
+		name      string
+		challenge string
+		started   bool
+		userID    int64
+		want      bool
+	}{
+		{"публичная комната", "", false, 1, true},
+		{"создатель до старта вызова", "abc", false, 1, false},
+		{"соперник до старта вызова", "abc", false, 2, true},
+		{"создатель после старта вызова", "abc", true, 1, true},
+	}
+	for _, c := range cases {
+		r := &Room{ChallengeCode: c.challenge, ChallengeCreatorID: 1, challengeStarted: c.started}
+		if got := r.holdsStake(c.userID); got != c.want {
+			t.Errorf("%s: holdsStake=%v, ожидалось %v", c.name, got, c.want)
+		}
+	}
+}
+
+// вызов истек без соперника: комната возвращает только ставки, которые держит сама
+func TestChallengeExpiryRefund(t *testing.T) {
+	cases := []struct {
+		name      string
+		challenge string
+		waitingID int64
+		want      int64
+	}{
+		{"ждет создатель: ставку вернет истечение вызова", "abc", 1, 0},
+		{"ждет приглашенный: ставка в комнате", "abc", 2, 50},
+		{"публичная комната", "", 1, 50},
+	}
+	for _, c := range cases {
+		h := NewHub(nil, nil)
+		client := &Client{UserID: c.waitingID, Send: make(chan []byte, 16)}
+
+		h.mu.Lock()
+		room := h.buildRoom(game.TypeRPS, [2]int64{c.waitingID, 0}, 50, "gems", 1)
+		room.ChallengeCode = c.challenge
+		room.ChallengeCreatorID = 1
+		room.Clients[client.UserID] = client
+		h.UserRoom[client.UserID] = room.ID
+		if c.challenge != "" {
+			h.PrivateWaiting[c.challenge] = client
+		}
+		h.mu.Unlock()
+
+		room.cancelGameNoOpponent()
+
+		result := waitMessage(t, client, MsgResult)
+		refunded, _ := result["refunded"].(float64)
+		if result["reason"] != "no_opponent" || refunded != float64(c.want) {
+			t.Fatalf("%s: результат %v, ожидался возврат %d", c.name, result, c.want)
+		}
+		if len(h.PrivateWaiting) != 0 || h.roomCount() != 0 {
+			t.Fatalf("%s: комната или слот ожидания не очищены", c.name)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package ws
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"telegram_webapp/internal/domain"
+)
+
+const (
+	// не больше chatRateLimit сообщений (текст + эмоции) за chatRateWindow
+	chatRateLimit  = 5
+	chatRateWindow = 10 * time.Second
+)
+
+// ссылки, домены и @username - способ увести игрока из приложения
+var chatLinkPattern = regexp.MustCompile(`(?i)(https?://|www\.|t\.me/|telegram\.me/|@[a-z0-9_]{4,}|\b[a-z0-9-]+\.(com|ru|net|org|io|me|xyz|ton|app|gg|info|biz|su|to|cc)\b)`)
+
+// начала нецензурных слов; слово маскируется, если начинается с одного из них
+var chatProfanityRoots = []string{
+	"хуй", "хуе", "хуя", "хуи", "пизд", "ебан", "ебат", "ебал", "еблан", "заеб", "наеб", "уеб", "выеб", "отъеб",
+	"бля", "сука", "суки", "суку", "сучк", "мудак", "мудил", "пидор", "пидар", "залуп", "гандон", "шлюх",
+	"fuck", "shit", "bitch", "cunt", "dick", "asshole", "motherf", "nigg", "fag",
+}
+
+// скользящее окно отправленных сообщений клиента
+// используется только из readPump клиента, поэтому без блокировок
+type chatLimiter struct {
+	sent []time.Time
+}
+
+func (l *chatLimiter) allow(now time.Time) bool {
+	cutoff := now.Add(-chatRateWindow)
+	kept := l.sent[:0]
+	for _, t := range l.sent {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	l.sent = kept
+	if len(l.sent) >= chatRateLimit {
+		return false
+	}
+	l.sent = append(l.sent, now)
+	return true
+}
+
+// filterChatText отклоняет ссылки и маскирует мат
+// возвращает очищенный текст, признак маскировки и false, если текст нельзя отправить
+func filterChatText(text string) (string, bool, bool) {
+	text = strings.TrimSpace(text)
+	if text == "" || chatLinkPattern.MatchString(text) {
+		return "", false, false
+	}
+
+	words := strings.Fields(text)
+	filtered := false
+	for i, word := range words {
+		if isProfane(word) {
+			words[i] = strings.Repeat("*", len([]rune(word)))
+			filtered = true
+		}
+	}
+	return strings.Join(words, " "), filtered, true
+}
+
+func isProfane(word string) bool {
+	normalized := strings.Map(func(r rune) rune {
+		if r == 'ё' {
+			return 'е'
+		}
+		if unicode.IsLetter(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, strings.ToLower(word))
+
+	for _, root := range chatProfanityRoots {
+		if strings.HasPrefix(normalized, root) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleChat обрабатывает emote/chat: лимит, фильтр, доставка сопернику и сохранение
+func (r *Room) handleChat(c *Client, msg *ClientMessage) {
+	if c.chat == nil {
+		c.chat = &chatLimiter{}
+	}
+	if !c.chat.allow(time.Now()) {
+		r.sendError(c.UserID, ErrCodeRateLimited, "too many chat messages")
+		return
+	}
+
+	out := ChatMessagePayload{From: c.UserID, Timestamp: time.Now().UnixMilli()}
+	stored := &domain.ChatMessage{RoomID: r.ID, SenderID: c.UserID}
+
+	if msg.Type == MsgEmote {
+		out.Kind = string(domain.ChatKindEmote)
+		out.Emote = msg.Data.(string)
+		stored.Kind = domain.ChatKindEmote
+		stored.Content = out.Emote
+	} else {
+		text, filtered, ok := filterChatText(msg.Data.(string))
+		if !ok {
+			r.sendError(c.UserID, ErrCodeChatRejected, "links are not allowed")
+			return
+		}
+		out.Kind = string(domain.ChatKindText)
+		out.Text = text
+		stored.Kind = domain.ChatKindText
+		stored.Content = strings.TrimSpace(msg.Data.(string))
+		stored.Filtered = filtered
+	}
+
+	opponentID, ok := r.opponentOf(c.UserID)
+	if !ok {
+		r.sendError(c.UserID, ErrCodeNotAvailable, "no opponent to chat with")
+		return
+	}
+
+	// заглушенный игрок не узнает о mute: свое сообщение он видит как обычно
+	r.mu.RLock()
+	muted := r.mutes[opponentID]
+	r.mu.RUnlock()
+	stored.Delivered = !muted && !isBotPlayer(opponentID)
+	if !isBotPlayer(opponentID) {
+		stored.RecipientID = &opponentID
+	}
+
+	chatMsg := Message{Type: MsgChat, Payload: out}
+	r.send(c.UserID, chatMsg)
+	if stored.Delivered {
+		r.send(opponentID, chatMsg)
+	}
+
+	r.saveChatMessage(stored)
+}
+
+// handleMute включает или выключает сообщения соперника для игрока
+func (r *Room) handleMute(c *Client, muted bool) {
+	r.mu.Lock()
+	if r.mutes == nil {
+		r.mutes = make(map[int64]bool)
+	}
+	r.mutes[c.UserID] = muted
+	r.mu.Unlock()
+
+	log.Printf("Room.handleMute: room=%s user=%d muted=%v", r.ID, c.UserID, muted)
+	r.send(c.UserID, Message{Type: MsgMuted, Payload: MutedPayload{Muted: muted}})
+}
+
+// соперник игрока в комнате (false, если второго игрока еще нет)
+func (r *Room) opponentOf(userID int64) (int64, bool) {
+	players := r.game.Players()
+	switch userID {
+	case players[0]:
+		return players[1], players[1] != 0
+	case players[1]:
+		return players[0], players[0] != 0
+	}
+	return 0, false
+}
+
+// сохраняет сообщение для разбора жалоб, не задерживая игру
+func (r *Room) saveChatMessage(m *domain.ChatMessage) {
+	if r.hub == nil || r.hub.ChatRepo == nil {
+		return
+	}
+	repo := r.hub.ChatRepo
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := repo.CreateMessage(ctx, m); err != nil {
+			log.Printf("Room.saveChatMessage: room=%s user=%d: %v", m.RoomID, m.SenderID, err)
+		}
+	}()
+}
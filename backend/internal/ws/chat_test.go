@@ -0,0 +1,73 @@
+package ws
+
+import (
+	"testing"
+	"time"
+
+	"telegram_webapp/internal/game"
+)
+
+func TestFilterChatText(t *testing.T) {
+	cases := []struct {
+		in       string
+		want     string
+		filtered bool
+		ok       bool
+	}{
+		{"good game", "good game", false, true},
+		{"  ну ты и сука  ", "ну ты и ****", true, true},
+		{"Fucking lucky", "******* lucky", true, true},
+		{"стоит 100 рублей", "стоит 100 рублей", false, true},
+		{"заходи на https://example.com", "", false, false},
+		{"пиши в t.me/somebody", "", false, false},
+		{"мой канал @cool_channel", "", false, false},
+		{"casino.xyz лучше", "", false, false},
+		{"   ", "", false, false},
+	}
+
+	for _, tc := range cases {
+		got, filtered, ok := filterChatText(tc.in)
+		if got != tc.want || filtered != tc.filtered || ok != tc.ok {
+			t.Fatalf("%q: получено (%q, %v, %v), ожидалось (%q, %v, %v)", tc.in, got, filtered, ok, tc.want, tc.filtered, tc.ok)
+		}
+	}
+}
+
+func TestChatLimiter(t *testing.T) {
+	l := &chatLimiter{}
+	now := time.Now()
+	for i := 0; i < chatRateLimit; i++ {
+		if !l.allow(now) {
+			t.Fatalf("сообщение %d отклонено в пределах лимита", i+1)
+		}
+	}
+	if l.allow(now) {
+		t.Fatal("сообщение сверх лимита пропущено")
+	}
+	if !l.allow(now.Add(chatRateWindow + time.Millisecond)) {
+		t.Fatal("после окна лимит не сбросился")
+	}
+}
+
+func TestParseChatMessages(t *testing.T) {
+	msg, perr := ParseClientMessage(2, game.TypeRPS, []byte(`{"type":"emote","payload":{"emote":"gg"}}`))
+	if perr != nil || msg.Type != MsgEmote || msg.Data != "gg" {
+		t.Fatalf("emote: %v %v", msg, perr)
+	}
+	if _, perr := ParseClientMessage(2, game.TypeRPS, []byte(`{"type":"emote","payload":{"emote":"dance"}}`)); perr == nil || perr.Code != ErrCodeInvalidPayload {
+		t.Fatalf("неизвестная эмоция: %v", perr)
+	}
+
+	long := make([]rune, 121)
+	for i := range long {
+		long[i] = 'я'
+	}
+	if _, perr := ParseClientMessage(2, game.TypeMines, []byte(`{"type":"chat","payload":{"text":"`+string(long)+`"}}`)); perr == nil || perr.Code != ErrCodeInvalidPayload {
+		t.Fatalf("длинный текст: %v", perr)
+	}
+
+	msg, perr = ParseClientMessage(0, game.TypeMines, []byte(`{"type":"mute","value":true}`))
+	if perr != nil || msg.Type != MsgMute || msg.Data != true {
+		t.Fatalf("v1 mute: %v %v", msg, perr)
+	}
+}
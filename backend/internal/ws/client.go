@@ -2,10 +2,15 @@ package ws
 
 import (
 	"bytes"
+	"encoding/json"
 	"log"
 	"sync"
 	"time"
 
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/game"
+	"telegram_webapp/internal/logger"
+
 	"github.com/gorilla/websocket"
 )
 
@@ -25,6 +30,24 @@ type Client struct {
 	BetAmount int64
 	Currency  string // gems или coins
 
+	// длина серии best-of-N (1 - одиночная партия)
+	BestOf int
+
+	// параметры поля мин, выбранные при входе в очередь (нулевые - по умолчанию)
+	MinesOptions game.MinesOptions
+
+	// версия протокола, заявленная при подключении (0 - не указана, v1)
+	ProtocolVersion int
+
+	// лимит сообщений чата (создается при первом сообщении)
+	chat *chatLimiter
+
+	// приватный вызов (nil для публичного матчмейкинга)
+	Challenge *domain.Challenge
+
+	// матч турнира (0 для обычной игры)
+	TournamentMatchID int64
+
 	Hub        *Hub
 	Room       *Room
 	Ready      chan struct{}
@@ -52,13 +75,18 @@ func NewClient(userID int64, conn *websocket.Conn, hub *Hub, gameType string, be
 }
 
 func (c *Client) Run() {
+	c.Hub.userConnected(c.UserID)
+
 	// запускаем writer первым, чтобы регистрация комнаты могла наблюдать готовность
 	go c.writePump()
 	// сигнализируем, что writePump запущен
 	close(c.Ready)
 
 	// отправляем явный хендшейк готовности, чтобы тесты/клиенты могли его дождаться
-	readyMsg := []byte(`{"type":"ready"}`)
+	readyMsg, _ := json.Marshal(Message{
+		Type:    MsgReady,
+		Payload: ReadyPayload{ProtocolVersion: c.protocolVersion()},
+	})
 	select {
 	case c.Send <- readyMsg:
 		log.Printf("Client.Run: пользователь=%d сообщение о готовности поставлено в очередь", c.UserID)
@@ -73,7 +101,13 @@ func (c *Client) Run() {
 	}()
 
 	// назначаем комнату (матчмейкинг / реконнект)
-	c.Room = c.Hub.AssignClient(c)
+	if c.Challenge != nil {
+		c.Room = c.Hub.AssignChallenge(c)
+	} else if c.TournamentMatchID != 0 {
+		c.Room = c.Hub.AssignTournament(c)
+	} else {
+		c.Room = c.Hub.AssignClient(c)
+	}
 
 	if c.Room == nil {
 		log.Printf("Client.Run: не удалось назначить комнату для пользователя=%d", c.UserID)
@@ -88,11 +122,20 @@ func (c *Client) Run() {
 	<-c.Done
 }
 
+// версия протокола клиента (без явной версии - v1)
+func (c *Client) protocolVersion() int {
+	if c.ProtocolVersion == 0 {
+		return MinProtocolVersion
+	}
+	return c.ProtocolVersion
+}
+
 // read
 func (c *Client) readPump() {
 	log.Printf("Client.readPump: СТАРТ для пользователя=%d", c.UserID)
 	defer func() {
 		c.disconnect()
+		c.Hub.userDisconnected(c.UserID)
 		close(c.Done)
 	}()
 
@@ -109,7 +152,7 @@ func (c *Client) readPump() {
 			log.Println("ошибка чтения:", err)
 			break
 		}
-		log.Printf("Client.readPump: пользователь=%d получил %d байт: %s", c.UserID, len(msg), string(msg))
+		logger.Debug("Client.readPump: получено сообщение", "user", c.UserID, "bytes", len(msg))
 		if c.Room != nil {
 			c.Room.HandleMessage(c, msg)
 		} else {
@@ -117,7 +160,7 @@ func (c *Client) readPump() {
 			c.pendingMu.Lock()
 			c.pending = append(c.pending, append([]byte(nil), msg...))
 			c.pendingMu.Unlock()
-			log.Printf("Client.readPump: пользователь=%d буферизировал %d байт (еще нет комнаты)", c.UserID, len(msg))
+			logger.Debug("Client.readPump: сообщение буферизовано до назначения комнаты", "user", c.UserID, "bytes", len(msg))
 		}
 	}
 }
@@ -142,7 +185,7 @@ func (c *Client) writePump() {
 				log.Printf("Client.writePump: пользователь=%d ошибка записи: %v", c.UserID, err)
 				return
 			}
-			log.Printf("Client.writePump: пользователь=%d записал %d байт: %s", c.UserID, len(msg), string(msg))
+			logger.Debug("Client.writePump: отправлено сообщение", "user", c.UserID, "bytes", len(msg))
 
 			// если это было сообщение о результате, подтверждаем его,
 			// чтобы сервер мог дождаться доставки
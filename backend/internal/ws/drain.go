@@ -0,0 +1,305 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/game"
+)
+
+const (
+	// через сколько секунд клиенту стоит переподключиться (к другому инстансу)
+	drainRetryAfter = 5
+	// сколько ждать закрытия комнат после принудительной остановки
+	drainAbortWait = 5 * time.Second
+	// сколько ждать записи итогов в БД перед выходом
+	drainWritesWait = 10 * time.Second
+	// причина отмены партии при остановке сервера
+	reasonServerShutdown = "server_shutdown"
+)
+
+var ErrHubDraining = errors.New("hub is draining")
+
+// итог остановки хаба
+type DrainResult struct {
+	Cancelled int // ожидавшие соперника: отменены, ставка возвращена
+	Finished  int // доиграли до дедлайна
+	Aborted   int // не успели: ставки возвращены, итог записан
+}
+
+// Draining сообщает, что хаб останавливается и не принимает новые матчи
+func (h *Hub) Draining() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.draining
+}
+
+// Drain останавливает хаб перед выходом процесса.
+// Матчмейкинг закрывается, ожидающие соперника получают возврат ставки и просьбу переподключиться,
+// идущие матчи доигрываются до дедлайна ctx. Что не успело - отменяется с возвратом ставок и записью итога.
+func (h *Hub) Drain(ctx context.Context) DrainResult {
+	var res DrainResult
+
+	h.mu.Lock()
+	h.draining = true
+	var waiting, live []*Room
+	for _, room := range h.Rooms {
+		if room.awaitingOpponent() {
+			waiting = append(waiting, room)
+		} else {
+			live = append(live, room)
+		}
+	}
+	h.WaitingByKey = make(map[WaitingKey]*Client)
+	h.WaitingByGame = make(map[game.GameType]*Client)
+	h.PrivateWaiting = make(map[string]*Client)
+	h.mu.Unlock()
+
+	deadline, _ := ctx.Deadline()
+	log.Printf("Hub.Drain: старт, ожидающих=%d идущих=%d дедлайн=%s", len(waiting), len(live), deadline.Format(time.RFC3339))
+
+	for _, room := range waiting {
+		room.stop()
+	}
+	res.Cancelled = len(waiting)
+
+	for _, room := range live {
+		room.notifyDraining(deadline)
+	}
+
+	// ждем, пока комнаты доиграют
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+wait:
+	for h.roomCount() > 0 {
+		select {
+		case <-ctx.Done():
+			break wait
+		case <-ticker.C:
+		}
+	}
+
+	remaining := h.rooms()
+	res.Finished = len(live) - len(remaining)
+	if res.Finished < 0 {
+		res.Finished = 0
+	}
+
+	// не успевшие комнаты останавливаются своим циклом Run, зависшие - принудительно
+	for _, room := range remaining {
+		room.stop()
+	}
+	abortDeadline := time.Now().Add(drainAbortWait)
+	for h.roomCount() > 0 && time.Now().Before(abortDeadline) {
+		time.Sleep(100 * time.Millisecond)
+	}
+	for _, room := range h.rooms() {
+		log.Printf("Hub.Drain: комната=%s не остановилась сама, отменяем", room.ID)
+		room.abortOnShutdown()
+	}
+	res.Aborted = len(remaining)
+
+	// дожидаемся асинхронной записи итогов
+	done := make(chan struct{})
+	go func() {
+		h.writes.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(drainWritesWait):
+		log.Printf("Hub.Drain: не дождались записи итогов за %s", drainWritesWait)
+	}
+
+	log.Printf("Hub.Drain: готово, отменено=%d доиграно=%d прервано=%d", res.Cancelled, res.Finished, res.Aborted)
+	return res
+}
+
+func (h *Hub) roomCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.Rooms)
+}
+
+func (h *Hub) rooms() []*Room {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	rooms := make([]*Room, 0, len(h.Rooms))
+	for _, room := range h.Rooms {
+		rooms = append(rooms, room)
+	}
+	return rooms
+}
+
+// отклоняет клиента, пришедшего во время остановки: возвращает ставку и просит переподключиться
+func (h *Hub) rejectDraining(c *Client) {
+	// ставку создателя вызова держит сам вызов
+	if c.Challenge == nil || c.Challenge.CreatorID != c.UserID {
+		h.refundClientBet(c)
+	}
+	data, _ := json.Marshal(Message{
+		Type:    MsgError,
+		Payload: ErrorPayload{Code: ErrCodeServerDraining, Message: "server is draining, reconnect later"},
+	})
+	select {
+	case c.Send <- data:
+	case <-time.After(2 * time.Second):
+	}
+	sendDraining(c, time.Time{})
+	log.Printf("Hub.rejectDraining: пользователь=%d отклонен, хаб останавливается", c.UserID)
+}
+
+// track выполняет запись итога асинхронно, Drain дожидается ее перед выходом
+func (h *Hub) track(fn func()) {
+	if h == nil {
+		go fn()
+		return
+	}
+	h.writes.Add(1)
+	go func() {
+		defer h.writes.Done()
+		fn()
+	}()
+}
+
+func sendDraining(c *Client, deadline time.Time) {
+	p := DrainingPayload{RetryAfter: drainRetryAfter}
+	if !deadline.IsZero() {
+		p.Deadline = deadline.UnixMilli()
+	}
+	data, _ := json.Marshal(Message{Type: MsgDraining, Payload: p})
+	select {
+	case c.Send <- data:
+	case <-time.After(2 * time.Second):
+		log.Printf("sendDraining: таймаут уведомления пользователя=%d", c.UserID)
+	}
+}
+
+// комната ждет второго игрока (публичная очередь или приватный вызов)
+func (r *Room) awaitingOpponent() bool {
+	return !r.isTournament() && r.game.Players()[1] == 0
+}
+
+// stop просит цикл Run комнаты завершиться (один раз)
+func (r *Room) stop() {
+	r.stopOnce.Do(func() {
+		close(r.shutdown)
+	})
+}
+
+// предупреждает игроков, до какого момента нужно доиграть матч
+func (r *Room) notifyDraining(deadline time.Time) {
+	r.mu.RLock()
+	clients := r.getClientsUnlocked()
+	r.mu.RUnlock()
+	for _, c := range clients {
+		sendDraining(c, deadline)
+	}
+}
+
+// abortOnShutdown отменяет незавершенную партию при остановке сервера:
+// возвращает ставки, которые еще не рассчитаны, записывает итог и закрывает комнату.
+// Турнирный матч не сообщает итог: после рестарта движок турниров пересоздаст комнату.
+func (r *Room) abortOnShutdown() {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+	players := r.game.Players()
+	var refunded []int64
+	if r.BetAmount > 0 && !r.betPaid {
+		r.betPaid = true
+		for _, uid := range players {
+			if uid != 0 && !isBotPlayer(uid) && r.holdsStake(uid) {
+				refunded = append(refunded, uid)
+			}
+		}
+	}
+	clients := r.getClientsUnlocked()
+	r.mu.Unlock()
+
+	log.Printf("Room.abortOnShutdown: room=%s players=%v refunded=%v", r.ID, players, refunded)
+	r.recordEvent(domain.MatchEventAborted, 0, map[string]interface{}{"reason": reasonServerShutdown, "refunded": refunded})
+
+	for _, uid := range refunded {
+		r.refundBet(uid)
+	}
+	if players[1] != 0 && !r.isTournament() {
+		r.saveShutdownOutcome(players, refunded)
+	}
+
+	for uid, c := range clients {
+		var amount int64
+		for _, ref := range refunded {
+			if ref == uid {
+				amount = r.BetAmount
+			}
+		}
+		data, _ := json.Marshal(Message{
+			Type: MsgResult,
+			Payload: ResultPayload{
+				You:      "cancelled",
+				Reason:   reasonServerShutdown,
+				Refunded: amount,
+				Currency: r.Currency,
+			},
+		})
+		select {
+		case c.Send <- data:
+		case <-time.After(2 * time.Second):
+			log.Printf("Room.abortOnShutdown: timeout notifying user=%d", uid)
+		}
+		sendDraining(c, time.Time{})
+	}
+
+	r.cleanup()
+}
+
+// записывает отмененную партию в историю: ничья с возвратом ставки
+func (r *Room) saveShutdownOutcome(players [2]int64, refunded []int64) {
+	if r.GameHistoryRepo == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	vsBot := isBotPlayer(players[1])
+	for i, uid := range players {
+		if isBotPlayer(uid) {
+			continue
+		}
+		opponent := players[1-i]
+		var opponentID *int64
+		if !isBotPlayer(opponent) {
+			opponentID = &opponent
+		}
+		var winAmount int64
+		for _, ref := range refunded {
+			if ref == uid {
+				winAmount = r.BetAmount
+			}
+		}
+		gh := &domain.GameHistory{
+			UserID:     uid,
+			GameType:   domain.GameType(r.game.Type()),
+			Mode:       domain.GameModePVP,
+			OpponentID: opponentID,
+			RoomID:     &r.ID,
+			Result:     domain.GameResultDraw,
+			BetAmount:  r.BetAmount,
+			WinAmount:  winAmount,
+			Currency:   domain.Currency(r.Currency),
+			VsBot:      vsBot,
+			Details:    map[string]interface{}{"reason": reasonServerShutdown},
+		}
+		if err := r.GameHistoryRepo.Create(ctx, gh); err != nil {
+			log.Printf("Room.saveShutdownOutcome: game_history user=%d failed: %v", uid, err)
+		}
+	}
+}
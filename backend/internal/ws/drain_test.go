@@ -0,0 +1,82 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"telegram_webapp/internal/game"
+)
+
+// читает сообщения клиента до нужного типа
+func waitMessage(t *testing.T, c *Client, msgType string) map[string]interface{} {
+	t.Helper()
+	timeout := time.After(3 * time.Second)
+	for {
+		select {
+		case data := <-c.Send:
+			var msg struct {
+				Type    string                 `json:"type"`
+				Payload map[string]interface{} `json:"payload"`
+			}
+			if err := json.Unmarshal(data, &msg); err != nil {
+				t.Fatalf("невалидное сообщение: %s", data)
+			}
+			if msg.Type == msgType {
+				return msg.Payload
+			}
+		case <-timeout:
+			t.Fatalf("не дождались сообщения %s", msgType)
+		}
+	}
+}
+
+func TestHubDrainCancelsWaitingRoom(t *testing.T) {
+	h := NewHub(nil, nil)
+	c := &Client{UserID: 1, GameType: string(game.TypeRPS), Send: make(chan []byte, 16)}
+
+	h.mu.Lock()
+	room := h.newRoomWithBet(game.TypeRPS, [2]int64{c.UserID, 0}, 0, "gems", 1)
+	room.Clients[c.UserID] = c
+	h.UserRoom[c.UserID] = room.ID
+	h.WaitingByKey[WaitingKey{GameType: game.TypeRPS, Currency: "gems", BestOf: 1}] = c
+	h.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	res := h.Drain(ctx)
+
+	if res.Cancelled != 1 || res.Aborted != 0 {
+		t.Fatalf("итог остановки: %+v", res)
+	}
+	if !h.Draining() || h.roomCount() != 0 || len(h.WaitingByKey) != 0 {
+		t.Fatalf("хаб не остановлен: draining=%v комнат=%d ожидающих=%d", h.Draining(), h.roomCount(), len(h.WaitingByKey))
+	}
+
+	result := waitMessage(t, c, MsgResult)
+	if result["you"] != "cancelled" || result["reason"] != reasonServerShutdown {
+		t.Fatalf("результат: %v", result)
+	}
+	if p := waitMessage(t, c, MsgDraining); p["retry_after"] != float64(drainRetryAfter) {
+		t.Fatalf("draining: %v", p)
+	}
+}
+
+func TestHubDrainRejectsNewClients(t *testing.T) {
+	h := NewHub(nil, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	h.Drain(ctx)
+
+	c := &Client{UserID: 2, GameType: string(game.TypeRPS), Send: make(chan []byte, 16)}
+	if room := h.AssignClient(c); room != nil {
+		t.Fatal("во время остановки клиент не должен попадать в комнату")
+	}
+	if p := waitMessage(t, c, MsgError); p["code"] != ErrCodeServerDraining {
+		t.Fatalf("ошибка: %v", p)
+	}
+	if h.roomCount() != 0 {
+		t.Fatalf("создана комната во время остановки")
+	}
+}
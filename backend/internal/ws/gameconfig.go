@@ -0,0 +1,113 @@
+package ws
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/game"
+	"telegram_webapp/internal/repository"
+)
+
+// как часто хаб перечитывает pvp_game_configs
+const DefaultGameConfigRefresh = 30 * time.Second
+
+// GameConfigs кэш параметров PvP игр по уровням ставок; комнаты читают его при создании,
+// правки из админ-бота применяются к новым комнатам после очередного обновления
+type GameConfigs struct {
+	repo *repository.PvPConfigRepository
+
+	mu     sync.RWMutex
+	byGame map[game.GameType][]*domain.PvPGameConfig // по убыванию min_stake
+}
+
+func NewGameConfigs(repo *repository.PvPConfigRepository) *GameConfigs {
+	return &GameConfigs{
+		repo:   repo,
+		byGame: make(map[game.GameType][]*domain.PvPGameConfig),
+	}
+}
+
+// Set заменяет содержимое кэша
+func (g *GameConfigs) Set(configs []*domain.PvPGameConfig) {
+	byGame := make(map[game.GameType][]*domain.PvPGameConfig)
+	for _, c := range configs {
+		gt := game.GameType(c.GameType)
+		byGame[gt] = append(byGame[gt], c)
+	}
+	for _, tiers := range byGame {
+		sort.Slice(tiers, func(i, j int) bool { return tiers[i].MinStake > tiers[j].MinStake })
+	}
+	g.mu.Lock()
+	g.byGame = byGame
+	g.mu.Unlock()
+}
+
+// Reload перечитывает таблицу; при ошибке остаются прежние значения
+func (g *GameConfigs) Reload(ctx context.Context) error {
+	configs, err := g.repo.List(ctx)
+	if err != nil {
+		return err
+	}
+	g.Set(configs)
+	return nil
+}
+
+// StartRefresh загружает конфигурацию и перечитывает ее с интервалом
+func (g *GameConfigs) StartRefresh(interval time.Duration) {
+	reload := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := g.Reload(ctx); err != nil {
+			log.Printf("GameConfigs.Reload: не удалось загрузить pvp_game_configs: %v", err)
+		}
+	}
+	reload()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			reload()
+		}
+	}()
+}
+
+// Lookup уровень для ставки: наибольший min_stake <= bet; nil - встроенные значения игры
+func (g *GameConfigs) Lookup(gameType game.GameType, bet int64) *domain.PvPGameConfig {
+	if g == nil {
+		return nil
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	for _, c := range g.byGame[gameType] {
+		if c.MinStake <= bet {
+			return c
+		}
+	}
+	return nil
+}
+
+// Rules таймеры и лимит раундов уровня (нулевые поля - встроенные значения)
+func configRules(c *domain.PvPGameConfig) game.Rules {
+	if c == nil {
+		return game.Rules{}
+	}
+	return game.Rules{
+		SetupTimeout: time.Duration(c.SetupTimeoutSec) * time.Second,
+		TurnTimeout:  time.Duration(c.TurnTimeoutSec) * time.Second,
+		MaxRounds:    c.MaxRounds,
+	}
+}
+
+// GameConfig параметры игры для ставки (nil - конфигурация не загружена или уровня нет)
+func (h *Hub) GameConfig(gameType game.GameType, bet int64) *domain.PvPGameConfig {
+	return h.Configs.Lookup(gameType, bet)
+}
+
+// GameRules таймеры и лимит раундов, с которыми будет создана комната
+func (h *Hub) GameRules(gameType game.GameType, bet int64) game.Rules {
+	return configRules(h.Configs.Lookup(gameType, bet)).WithDefaults(gameType)
+}
@@ -0,0 +1,58 @@
+package ws
+
+import (
+	"testing"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/game"
+)
+
+func TestGameConfigsLookup(t *testing.T) {
+	var empty *GameConfigs
+	if empty.Lookup(game.TypeRPS, 100) != nil {
+		t.Fatal("без кэша - встроенные значения")
+	}
+
+	g := NewGameConfigs(nil)
+	g.Set([]*domain.PvPGameConfig{
+		{GameType: domain.GameTypeRPS, MinStake: 0, SetupTimeoutSec: 10, TurnTimeoutSec: 20, MaxRounds: 5, Enabled: true},
+		{GameType: domain.GameTypeRPS, MinStake: 1000, SetupTimeoutSec: 15, TurnTimeoutSec: 30, RakePercent: 2.5, MaxRounds: 3, Enabled: true},
+		{GameType: domain.GameTypeMines, MinStake: 500, TurnTimeoutSec: 25, Enabled: false},
+	})
+
+	cases := []struct {
+		game  game.GameType
+		bet   int64
+		stake int64 // ожидаемый min_stake, -1 - уровня нет
+	}{
+		{game.TypeRPS, 10, 0},
+		{game.TypeRPS, 999, 0},
+		{game.TypeRPS, 1000, 1000},
+		{game.TypeRPS, 50000, 1000},
+		{game.TypeMines, 100, -1},
+		{game.TypeMines, 500, 500},
+	}
+	for _, tc := range cases {
+		c := g.Lookup(tc.game, tc.bet)
+		if tc.stake < 0 {
+			if c != nil {
+				t.Fatalf("%s %d: ожидались встроенные значения, получен уровень %d", tc.game, tc.bet, c.MinStake)
+			}
+			continue
+		}
+		if c == nil || c.MinStake != tc.stake {
+			t.Fatalf("%s %d: ожидался уровень %d, получен %+v", tc.game, tc.bet, tc.stake, c)
+		}
+	}
+
+	rules := configRules(g.Lookup(game.TypeRPS, 2000)).WithDefaults(game.TypeRPS)
+	if rules.SetupTimeout != 15*time.Second || rules.TurnTimeout != 30*time.Second || rules.MaxRounds != 3 {
+		t.Fatalf("правила уровня: %+v", rules)
+	}
+	// незаданные поля уровня берутся из встроенных значений игры
+	rules = configRules(g.Lookup(game.TypeMines, 500)).WithDefaults(game.TypeMines)
+	if rules.SetupTimeout != game.DefaultSetupTimeout || rules.TurnTimeout != 25*time.Second || rules.MaxRounds != game.DefaultMaxRounds {
+		t.Fatalf("правила mines: %+v", rules)
+	}
+}
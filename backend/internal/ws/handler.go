@@ -43,6 +43,16 @@ func (h *WSHandler) HandleWS() gin.HandlerFunc {
 			return
 		}
 
+		protocolVersion := 0
+		if v := c.Query("protocol_version"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || !SupportedProtocolVersion(n) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "неподдерживаемая версия протокола", "code": ErrCodeUnsupportedVersion})
+				return
+			}
+			protocolVersion = n
+		}
+
 		// получаем тип игры из query (по умолчанию: rps)
 		gameType := c.Query("game")
 		if gameType == "" {
@@ -129,6 +139,7 @@ func (h *WSHandler) HandleWS() gin.HandlerFunc {
 
 		// создаем клиента и запускаем его обработчики и матчмейкинг
 		client := NewClient(userID, conn, h.Hub, gameType, betAmount, currency)
+		client.ProtocolVersion = protocolVersion
 		go client.Run()
 	}
 }
@@ -148,6 +159,16 @@ func HandleWS(hub *Hub) gin.HandlerFunc {
 			return
 		}
 
+		protocolVersion := 0
+		if v := c.Query("protocol_version"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || !SupportedProtocolVersion(n) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "неподдерживаемая версия протокола", "code": ErrCodeUnsupportedVersion})
+				return
+			}
+			protocolVersion = n
+		}
+
 		// получаем тип игры из query (по умолчанию: rps)
 		gameType := c.Query("game")
 		if gameType == "" {
@@ -172,6 +193,7 @@ func HandleWS(hub *Hub) gin.HandlerFunc {
 
 		// создаем клиента и запускаем его обработчики и матчмейкинг (бесплатная игра)
 		client := NewClient(userID, conn, hub, gameType, 0, string(domain.CurrencyGems))
+		client.ProtocolVersion = protocolVersion
 		go client.Run()
 	}
 }
\ No newline at end of file
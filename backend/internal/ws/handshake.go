@@ -0,0 +1,141 @@
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// сколько ждем кадр auth после открытия соединения (и join после auth)
+const DefaultAuthTimeout = 10 * time.Second
+
+// коды закрытия соединения до входа в игру (4000-4999 - коды приложения)
+const (
+	CloseAuthFailed  = 4001 // токен не передан или невалиден
+	CloseAuthTimeout = 4008 // не дождались auth или join
+	// отказ во входе: 4000 + HTTP статус (4400, 4403, 4404, 4410); 5xx - 1011
+	closeJoinBase = 4000
+)
+
+// ошибка хендшейка: код для error и код закрытия соединения
+type HandshakeError struct {
+	Code      string
+	Message   string
+	CloseCode int
+}
+
+func (e *HandshakeError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// JoinRejected - отказ во входе с кодом закрытия по HTTP статусу
+func JoinRejected(status int, message string) *HandshakeError {
+	closeCode := closeJoinBase + status
+	code := ErrCodeJoinRejected
+	switch {
+	case status == 503:
+		closeCode = websocket.CloseTryAgainLater
+		code = ErrCodeServerDraining
+	case status >= 500:
+		closeCode = websocket.CloseInternalServerErr
+	}
+	return &HandshakeError{Code: code, Message: message, CloseCode: closeCode}
+}
+
+// ReadAuth ждет первый кадр auth и возвращает токен
+func ReadAuth(conn *websocket.Conn, timeout time.Duration) (string, *HandshakeError) {
+	var p AuthPayload
+	if herr := readHandshakeFrame(conn, timeout, MsgAuth, &p); herr != nil {
+		if herr.Code != ErrCodeAuthTimeout {
+			herr.Code = ErrCodeAuthRequired
+			herr.CloseCode = CloseAuthFailed
+		}
+		return "", herr
+	}
+	return p.Token, nil
+}
+
+// ReadJoin ждет кадр join после успешного auth
+func ReadJoin(conn *websocket.Conn, timeout time.Duration) (*JoinPayload, *HandshakeError) {
+	var p JoinPayload
+	if herr := readHandshakeFrame(conn, timeout, MsgJoin, &p); herr != nil {
+		return nil, herr
+	}
+	if p.ProtocolVersion != 0 && !SupportedProtocolVersion(p.ProtocolVersion) {
+		return nil, &HandshakeError{Code: ErrCodeUnsupportedVersion, Message: "unsupported protocol version", CloseCode: closeJoinBase + 400}
+	}
+	return &p, nil
+}
+
+// читает один кадр нужного типа в пределах timeout; payload разбирается строго
+func readHandshakeFrame(conn *websocket.Conn, timeout time.Duration, msgType string, dst interface{}) *HandshakeError {
+	if timeout <= 0 {
+		timeout = DefaultAuthTimeout
+	}
+	conn.SetReadLimit(maxHandshakeFrame)
+	_ = conn.SetReadDeadline(time.Now().Add(timeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	_, raw, err := conn.ReadMessage()
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return &HandshakeError{Code: ErrCodeAuthTimeout, Message: "expected " + msgType + " within " + timeout.String(), CloseCode: CloseAuthTimeout}
+		}
+		return &HandshakeError{Code: ErrCodeMalformed, Message: err.Error(), CloseCode: websocket.CloseProtocolError}
+	}
+
+	var env clientEnvelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return &HandshakeError{Code: ErrCodeMalformed, Message: "invalid json", CloseCode: websocket.CloseProtocolError}
+	}
+	if env.Type != msgType {
+		return &HandshakeError{Code: ErrCodeUnknownType, Message: "expected " + msgType + " message", CloseCode: websocket.ClosePolicyViolation}
+	}
+	if perr := decodePayload(env.Payload, dst); perr != nil {
+		return &HandshakeError{Code: perr.Code, Message: perr.Message, CloseCode: websocket.ClosePolicyViolation}
+	}
+	return nil
+}
+
+// предел размера кадров auth/join (игровые кадры ограничивает readPump)
+const maxHandshakeFrame = 8192
+
+// SendAuthOK подтверждает токен; join ожидается до expiresAt
+func SendAuthOK(conn *websocket.Conn, userID int64, expiresAt time.Time) error {
+	data, err := json.Marshal(Message{
+		Type:    MsgAuthOK,
+		Payload: AuthOKPayload{UserID: userID, ExpiresAt: expiresAt.UnixMilli()},
+	})
+	if err != nil {
+		return err
+	}
+	_ = conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// RejectHandshake отправляет error и закрывает соединение с кодом закрытия
+func RejectHandshake(conn *websocket.Conn, herr *HandshakeError) {
+	if data, err := json.Marshal(Message{
+		Type:    MsgError,
+		Payload: ErrorPayload{Code: herr.Code, Message: herr.Message},
+	}); err == nil {
+		_ = conn.SetWriteDeadline(time.Now().Add(writeWait))
+		_ = conn.WriteMessage(websocket.TextMessage, data)
+	}
+	_ = conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(herr.CloseCode, closeReason(herr.Message)),
+		time.Now().Add(time.Second))
+	_ = conn.Close()
+}
+
+// reason кадра закрытия ограничен 123 байтами
+func closeReason(s string) string {
+	if len(s) > 123 {
+		return s[:123]
+	}
+	return s
+}
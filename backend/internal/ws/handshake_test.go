@@ -0,0 +1,114 @@
+package ws
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// поднимает сервер, который проходит хендшейк и отвечает ready с параметрами join
+func handshakeServer(t *testing.T, timeout time.Duration) *httptest.Server {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		token, herr := ReadAuth(conn, timeout)
+		if herr != nil {
+			RejectHandshake(conn, herr)
+			return
+		}
+		if token != "good" {
+			RejectHandshake(conn, &HandshakeError{Code: ErrCodeAuthFailed, Message: "invalid token", CloseCode: CloseAuthFailed})
+			return
+		}
+		_ = SendAuthOK(conn, 7, time.Now().Add(timeout))
+		p, herr := ReadJoin(conn, timeout)
+		if herr != nil {
+			RejectHandshake(conn, herr)
+			return
+		}
+		if p.Bet > 100 {
+			RejectHandshake(conn, JoinRejected(http.StatusBadRequest, "insufficient balance"))
+			return
+		}
+		_ = conn.WriteJSON(Message{Type: MsgReady, Payload: ReadyPayload{ProtocolVersion: ProtocolVersion}})
+		_ = conn.Close()
+	}))
+}
+
+func dialHandshake(t *testing.T, srv *httptest.Server) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// читает кадры до закрытия и возвращает код закрытия
+func closeCode(t *testing.T, conn *websocket.Conn) int {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			if ce, ok := err.(*websocket.CloseError); ok {
+				return ce.Code
+			}
+			t.Fatalf("ожидалось закрытие с кодом, получено %v", err)
+		}
+	}
+}
+
+func TestHandshakeJoin(t *testing.T) {
+	srv := handshakeServer(t, time.Second)
+	defer srv.Close()
+
+	conn := dialHandshake(t, srv)
+	_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"auth","payload":{"token":"good"}}`))
+	var msg struct {
+		Type    string        `json:"type"`
+		Payload AuthOKPayload `json:"payload"`
+	}
+	if err := conn.ReadJSON(&msg); err != nil || msg.Type != MsgAuthOK || msg.Payload.UserID != 7 {
+		t.Fatalf("auth_ok: %+v %v", msg, err)
+	}
+	_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"join","payload":{"game":"mines","bet":50,"currency":"gems","mines":{"cells":16,"mines":5,"turn_seconds":20}}}`))
+	if err := conn.ReadJSON(&msg); err != nil || msg.Type != MsgReady {
+		t.Fatalf("ready: %+v %v", msg, err)
+	}
+}
+
+func TestHandshakeRejects(t *testing.T) {
+	srv := handshakeServer(t, 200*time.Millisecond)
+	defer srv.Close()
+
+	cases := []struct {
+		name   string
+		frames []string
+		want   int
+	}{
+		{"timeout", nil, CloseAuthTimeout},
+		{"move before auth", []string{`{"type":"move","payload":{"move":"rock"}}`}, CloseAuthFailed},
+		{"bad token", []string{`{"type":"auth","payload":{"token":"bad"}}`}, CloseAuthFailed},
+		{"join timeout", []string{`{"type":"auth","payload":{"token":"good"}}`}, CloseAuthTimeout},
+		{"bad join", []string{`{"type":"auth","payload":{"token":"good"}}`, `{"type":"join","payload":{"game":"chess"}}`}, websocket.ClosePolicyViolation},
+		{"join rejected", []string{`{"type":"auth","payload":{"token":"good"}}`, `{"type":"join","payload":{"bet":500}}`}, 4400},
+	}
+	for _, tc := range cases {
+		conn := dialHandshake(t, srv)
+		for _, f := range tc.frames {
+			_ = conn.WriteMessage(websocket.TextMessage, []byte(f))
+		}
+		if got := closeCode(t, conn); got != tc.want {
+			t.Fatalf("%s: код закрытия %d, ожидался %d", tc.name, got, tc.want)
+		}
+	}
+}
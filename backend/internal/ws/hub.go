@@ -1,26 +1,39 @@
 package ws
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"strconv"
 	"sync"
 	"time"
 
+	"telegram_webapp/internal/domain"
 	"telegram_webapp/internal/game"
 	"telegram_webapp/internal/repository"
 )
 
 // уникально идентифицирует очередь матчмейкинга
-// игроки сопоставляются по типу игры, сумме ставки и валюте
+// игроки сопоставляются по типу игры, сумме ставки, валюте, длине серии и параметрам поля мин
 type WaitingKey struct {
 	GameType  game.GameType
 	BetAmount int64
 	Currency  string
+	BestOf    int
+	// параметры мин (нулевые для rps)
+	Mines game.MinesOptions
 }
 
 func (k WaitingKey) String() string {
-	return fmt.Sprintf("%s_%d_%s", k.GameType, k.BetAmount, k.Currency)
+	s := fmt.Sprintf("%s_%d_%s", k.GameType, k.BetAmount, k.Currency)
+	if k.BestOf > 1 {
+		s += fmt.Sprintf("_bo%d", k.BestOf)
+	}
+	if k.Mines.Cells != 0 && k.Mines != game.DefaultMinesOptions() {
+		s += fmt.Sprintf("_c%dm%dt%d", k.Mines.Cells, k.Mines.Mines, int(k.Mines.TurnTimeout/time.Second))
+	}
+	return s
 }
 
 type Hub struct {
@@ -28,6 +41,8 @@ type Hub struct {
 	UserRoom map[int64]string
 	mu       sync.RWMutex
 	roomSeq  int64
+	// префикс id комнат этого процесса
+	roomPrefix string
 	// отдельные очереди ожидания для каждого типа игры + ставки + валюты
 	WaitingByKey map[WaitingKey]*Client
 	// устаревшее: для обратной совместимости
@@ -35,6 +50,32 @@ type Hub struct {
 	GameRepo        *repository.GameRepository
 	GameHistoryRepo *repository.GameHistoryRepository
 	UserRepo        *repository.UserRepository
+	// приватные слоты ожидания по коду вызова (в публичный матчмейкинг не попадают)
+	PrivateWaiting map[string]*Client
+	ChallengeRepo  *repository.ChallengeRepository
+	// бот-соперник для пустых комнат (nil = выключен)
+	Bot *BotPolicy
+	// лимит зрителей на комнату (0 = DefaultMaxSpectators)
+	MaxSpectators int
+	// хранение сообщений чата для жалоб (nil = не сохраняются)
+	ChatRepo *repository.ChatRepository
+	// запись матчей для повторов (nil = не сохраняются)
+	ReplayRepo ReplayStore
+	// комнаты турнирных матчей по id матча
+	TournamentRooms map[int64]*Room
+	// остановка сервера: новые матчи не создаются (см. Drain)
+	draining bool
+	// асинхронные записи итогов партий, которые Drain дожидается
+	writes sync.WaitGroup
+	// онлайн пользователи и подписчики лобби
+	presence *presence
+	// запрет повторных матчей пары и пар под подозрением в сговоре (nil = выключен)
+	Pairs *PairPolicy
+	// таймеры, рейк и лимит раундов по игре и ставке (nil = встроенные значения)
+	Configs *GameConfigs
+	// границы рейка по валютам и запись рейка на счет платформы (nil = рейк не берется)
+	RakeCaps map[string]RakeCap
+	RakeRepo RakePayer
 }
 
 func NewHub(gameRepo *repository.GameRepository, gameHistoryRepo *repository.GameHistoryRepository) *Hub {
@@ -43,6 +84,10 @@ func NewHub(gameRepo *repository.GameRepository, gameHistoryRepo *repository.Gam
 		UserRoom:        make(map[int64]string),
 		WaitingByKey:    make(map[WaitingKey]*Client),
 		WaitingByGame:   make(map[game.GameType]*Client),
+		PrivateWaiting:  make(map[string]*Client),
+		TournamentRooms: make(map[int64]*Room),
+		presence:        newPresence(),
+		roomPrefix:      strconv.FormatInt(time.Now().UnixMilli(), 36) + "-",
 		GameRepo:        gameRepo,
 		GameHistoryRepo: gameHistoryRepo,
 	}
@@ -64,10 +109,34 @@ func (h *Hub) AssignClient(c *Client) *Room {
 	}
 
 	// создаем ключ ожидания для матчмейкинга по типу игры + ставке + валюте
+	bestOf := c.BestOf
+	if !ValidBestOf(bestOf) {
+		bestOf = 1
+	}
+	// параметры поля мин; невалидные (в обход handler) - значения по умолчанию
+	var minesOpts game.MinesOptions
+	if gameType == game.TypeMines {
+		minesOpts = c.MinesOptions
+		if minesOpts.TurnTimeout == 0 {
+			minesOpts.TurnTimeout = h.GameRules(gameType, c.BetAmount).TurnTimeout
+		}
+		minesOpts = minesOpts.WithDefaults()
+		if minesOpts.Validate() != nil {
+			minesOpts = game.DefaultMinesOptions()
+		}
+	}
 	waitingKey := WaitingKey{
 		GameType:  gameType,
 		BetAmount: c.BetAmount,
 		Currency:  c.Currency,
+		BestOf:    bestOf,
+		Mines:     minesOpts,
+	}
+
+	if h.draining {
+		h.mu.Unlock()
+		h.rejectDraining(c)
+		return nil
 	}
 
 	log.Printf("Hub.AssignClient: пользователь=%d игра=%s ставка=%d валюта=%s - назначение через слот ожидания (комнат=%d)",
@@ -99,6 +168,14 @@ func (h *Hub) AssignClient(c *Client) *Room {
 	// если есть ожидающий клиент для этого точного ключа (игра + ставка + валюта), пытаемся соединить
 	waiting := h.WaitingByKey[waitingKey]
 	if waiting != nil {
+		// пара не может встретиться снова (недавний матч или подозрение в сговоре)
+		if waiting.UserID != c.UserID && h.Pairs != nil && !h.Pairs.Allows(c.UserID, waiting.UserID, time.Now()) {
+			h.mu.Unlock()
+			log.Printf("Hub.AssignClient: пользователь=%d не может играть с ожидающим=%d ключ=%s, отклонен", c.UserID, waiting.UserID, waitingKey)
+			h.rejectClient(c, ErrCodePairBlocked, "this opponent is not available right now, try another stake later")
+			return nil
+		}
+
 		// не соединяем с самим собой
 		if waiting.UserID != c.UserID {
 			// проверяем, живое ли еще соединение ожидающего клиента
@@ -139,6 +216,9 @@ func (h *Hub) AssignClient(c *Client) *Room {
 							h.UserRoom[c.UserID] = foundRoom.ID
 							// очищаем слот ожидания для этого ключа
 							delete(h.WaitingByKey, waitingKey)
+							if h.Pairs != nil {
+								h.Pairs.Record(c.UserID, waiting.UserID, time.Now())
+							}
 							h.mu.Unlock()
 
 							log.Printf("Hub.AssignClient: собираюсь зарегистрировать пользователя=%d в комнату=%s", c.UserID, foundRoom.ID)
@@ -177,7 +257,7 @@ func (h *Hub) AssignClient(c *Client) *Room {
 
 	// создаем новую комнату для этого типа игры с информацией о ставке
 	players := [2]int64{c.UserID, 0}
-	room := h.newRoomWithBet(gameType, players, c.BetAmount, c.Currency)
+	room := h.newRoomWithOptions(gameType, players, c.BetAmount, c.Currency, bestOf, minesOpts)
 
 	if room == nil {
 		log.Printf("Hub.AssignClient: не удалось создать комнату для пользователя=%d", c.UserID)
@@ -214,30 +294,243 @@ func (h *Hub) AssignClient(c *Client) *Room {
 	return room
 }
 
+// назначает клиента в приватную комнату вызова
+// первый подключившийся ждет в PrivateWaiting, второй забирает ставку создателя из вызова в комнату
+func (h *Hub) AssignChallenge(c *Client) *Room {
+	ch := c.Challenge
+	gameType := game.GameType(ch.GameType)
+
+	h.mu.Lock()
+
+	if h.draining {
+		h.mu.Unlock()
+		h.rejectDraining(c)
+		return nil
+	}
+
+	log.Printf("Hub.AssignChallenge: пользователь=%d вызов=%s игра=%s ставка=%d валюта=%s",
+		c.UserID, ch.Code, gameType, ch.BetAmount, ch.Currency)
+
+	// очищаем устаревшее отображение комнаты (реконнект)
+	if oldRoomID, exists := h.UserRoom[c.UserID]; exists {
+		log.Printf("Hub.AssignChallenge: пользователь=%d имеет устаревшее отображение комнаты на %s, очищаем", c.UserID, oldRoomID)
+		delete(h.UserRoom, c.UserID)
+	}
+
+	if waiting := h.PrivateWaiting[ch.Code]; waiting != nil && waiting.UserID != c.UserID {
+		waitingAlive := false
+		select {
+		case waiting.Send <- []byte(`{"type":"ping"}`):
+			waitingAlive = true
+		default:
+			log.Printf("Hub.AssignChallenge: Send канал ожидающего клиента=%d заблокирован, возможно мертв", waiting.UserID)
+		}
+
+		var foundRoom *Room
+		if roomID, ok := h.UserRoom[waiting.UserID]; ok && waitingAlive {
+			foundRoom = h.Rooms[roomID]
+		}
+
+		if foundRoom != nil {
+			// вызов не обходит политику пар публичной очереди (недавний матч или подозрение в сговоре)
+			if h.Pairs != nil && !h.Pairs.Allows(c.UserID, waiting.UserID, time.Now()) {
+				h.mu.Unlock()
+				log.Printf("Hub.AssignChallenge: пользователь=%d не может играть с ожидающим=%d вызов=%s, отклонен", c.UserID, waiting.UserID, ch.Code)
+				h.rejectClient(c, ErrCodePairBlocked, "this opponent is not available right now, try again later")
+				return nil
+			}
+
+			// вызов стартует в БД без h.mu: запрос до 3 секунд не держит весь хаб
+			h.mu.Unlock()
+			if !h.startChallenge(c, ch, waiting) {
+				return nil
+			}
+
+			h.mu.Lock()
+			// пока хаб был открыт, ожидающий мог уйти, а комната - закрыться
+			foundRoom.mu.Lock()
+			alive := !foundRoom.closed && h.Rooms[foundRoom.ID] == foundRoom && h.PrivateWaiting[ch.Code] == waiting
+			if alive {
+				foundRoom.game.SetSecondPlayer(c.UserID)
+				foundRoom.Clients[c.UserID] = c
+				foundRoom.challengeStarted = true
+			}
+			foundRoom.mu.Unlock()
+			if !alive {
+				h.mu.Unlock()
+				log.Printf("Hub.AssignChallenge: комната=%s вызова=%s закрылась до входа пользователя=%d", foundRoom.ID, ch.Code, c.UserID)
+				// вызов уже started: ставку создателя не держат ни вызов, ни комната
+				h.refundClientBet(c)
+				if waiting.UserID == ch.CreatorID {
+					h.refundClientBet(waiting)
+				}
+				return nil
+			}
+
+			h.UserRoom[c.UserID] = foundRoom.ID
+			delete(h.PrivateWaiting, ch.Code)
+			h.mu.Unlock()
+			if h.Pairs != nil {
+				h.Pairs.Record(c.UserID, waiting.UserID, time.Now())
+			}
+
+			log.Printf("Hub.AssignChallenge: соединение пользователя=%d с пользователем=%d в комнате=%s вызов=%s",
+				c.UserID, waiting.UserID, foundRoom.ID, ch.Code)
+
+			select {
+			case foundRoom.Register <- c:
+			case <-time.After(5 * time.Second):
+				log.Printf("Hub.AssignChallenge: ТАЙМАУТ регистрации пользователя=%d в комнату=%s", c.UserID, foundRoom.ID)
+				return nil
+			}
+			return foundRoom
+		}
+
+		log.Printf("Hub.AssignChallenge: устаревший слот ожидания вызова=%s (пользователь=%d), очищаем", ch.Code, waiting.UserID)
+		delete(h.PrivateWaiting, ch.Code)
+	}
+
+	room := h.buildRoom(gameType, [2]int64{c.UserID, 0}, ch.BetAmount, ch.Currency, 1)
+	if room == nil {
+		h.mu.Unlock()
+		if c.UserID != ch.CreatorID {
+			h.refundClientBet(c)
+		}
+		return nil
+	}
+	room.ChallengeCode = ch.Code
+	room.ChallengeCreatorID = ch.CreatorID
+	room.opponentDeadline = ch.ExpiresAt
+	room.Clients[c.UserID] = c
+
+	h.UserRoom[c.UserID] = room.ID
+	h.PrivateWaiting[ch.Code] = c
+	h.mu.Unlock()
+
+	log.Printf("Hub.AssignChallenge: пользователь=%d создал приватную комнату=%s вызов=%s до %s",
+		c.UserID, room.ID, ch.Code, ch.ExpiresAt.Format(time.RFC3339))
+	go room.Run()
+
+	select {
+	case room.Register <- c:
+	case <-time.After(5 * time.Second):
+		log.Printf("Hub.AssignChallenge: ТАЙМАУТ регистрации пользователя=%d в комнату=%s", c.UserID, room.ID)
+		return nil
+	}
+	return room
+}
+
+// startChallenge переводит вызов в started (ставка создателя переходит из вызова в комнату).
+// Вызывается без h.mu; из двух одновременно подключившихся стартует только один (status = 'pending' в БД).
+// При отказе возвращает зарезервированную при подключении ставку
+func (h *Hub) startChallenge(c *Client, ch *domain.Challenge, waiting *Client) bool {
+	opponentID := c.UserID
+	if c.UserID == ch.CreatorID {
+		opponentID = waiting.UserID
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	started, err := h.ChallengeRepo.MarkStarted(ctx, ch.Code, opponentID)
+	cancel()
+	if err != nil || !started {
+		log.Printf("Hub.AssignChallenge: вызов=%s не удалось стартовать (started=%v err=%v)", ch.Code, started, err)
+		// ставку создателя держит вызов, возвращаем только зарезервированную при подключении
+		if c.UserID != ch.CreatorID {
+			h.refundClientBet(c)
+		}
+		return false
+	}
+	return true
+}
+
+// отклоняет клиента: возврат ставки (кроме создателя вызова - его ставку держит вызов) и error с кодом
+func (h *Hub) rejectClient(c *Client, code, message string) {
+	if c.Challenge == nil || c.Challenge.CreatorID != c.UserID {
+		h.refundClientBet(c)
+	}
+	data, _ := json.Marshal(Message{
+		Type:    MsgError,
+		Payload: ErrorPayload{Code: code, Message: message},
+	})
+	select {
+	case c.Send <- data:
+	case <-time.After(2 * time.Second):
+	}
+}
+
+// возвращает ставку, зарезервированную при подключении клиента
+func (h *Hub) refundClientBet(c *Client) {
+	if h.UserRepo == nil || c.BetAmount == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var err error
+	if c.Currency == string(domain.CurrencyCoins) {
+		_, err = h.UserRepo.UpdateCoins(ctx, c.UserID, c.BetAmount)
+	} else {
+		_, err = h.UserRepo.UpdateGems(ctx, c.UserID, c.BetAmount)
+	}
+	if err != nil {
+		log.Printf("Hub.refundClientBet: не удалось вернуть %d %s пользователю=%d: %v", c.BetAmount, c.Currency, c.UserID, err)
+	}
+}
+
 func (h *Hub) newRoom(gameType game.GameType, players [2]int64) *Room {
-	return h.newRoomWithBet(gameType, players, 0, "gems")
+	return h.newRoomWithBet(gameType, players, 0, "gems", 1)
+}
+
+func (h *Hub) newRoomWithBet(gameType game.GameType, players [2]int64, betAmount int64, currency string, bestOf int) *Room {
+	return h.newRoomWithOptions(gameType, players, betAmount, currency, bestOf, game.MinesOptions{})
 }
 
-func (h *Hub) newRoomWithBet(gameType game.GameType, players [2]int64, betAmount int64, currency string) *Room {
+func (h *Hub) newRoomWithOptions(gameType game.GameType, players [2]int64, betAmount int64, currency string, bestOf int, mines game.MinesOptions) *Room {
+	room := h.buildRoomWithOptions(gameType, players, betAmount, currency, bestOf, mines)
+	if room == nil {
+		return nil
+	}
+
+	log.Printf("Hub.newRoom: создана комната=%s игра=%s ставка=%d валюта=%s, запуск Run()", room.ID, gameType, betAmount, currency)
+	go room.Run()
+
+	return room
+}
+
+// создает и регистрирует комнату в хабе без запуска Run (вызывается под h.mu)
+func (h *Hub) buildRoom(gameType game.GameType, players [2]int64, betAmount int64, currency string, bestOf int) *Room {
+	return h.buildRoomWithOptions(gameType, players, betAmount, currency, bestOf, game.MinesOptions{})
+}
+
+// buildRoom с параметрами поля мин (нулевые - по умолчанию)
+func (h *Hub) buildRoomWithOptions(gameType game.GameType, players [2]int64, betAmount int64, currency string, bestOf int, mines game.MinesOptions) *Room {
 	h.roomSeq++
-	id := strconv.FormatInt(h.roomSeq, 10)
+	// префикс процесса: id комнаты уникален и после рестарта (по нему хранятся повторы и чат)
+	id := h.roomPrefix + strconv.FormatInt(h.roomSeq, 10)
+
+	// параметры уровня ставки читаются один раз: правки не меняют уже идущие комнаты
+	cfg := h.Configs.Lookup(gameType, betAmount)
 
 	factory := game.NewFactory()
-	g, err := factory.CreateGame(gameType, id, players)
+	g, err := factory.CreateGameWithRules(gameType, id, players, mines, configRules(cfg))
 	if err != nil {
 		log.Printf("Hub.newRoom: не удалось создать игру: %v", err)
 		return nil
 	}
 
 	room := NewRoomWithRepo(id, g, h.GameRepo, h.GameHistoryRepo, h)
+	if cfg != nil {
+		room.RakePercent = cfg.RakePercent
+	}
+	room.rakeCap = h.RakeCaps[currency]
+	room.RakeRepo = h.RakeRepo
 	room.BetAmount = betAmount
 	room.Currency = currency
+	room.series = NewSeries(bestOf)
 	room.UserRepo = h.UserRepo
 	h.Rooms[id] = room
 
-	log.Printf("Hub.newRoom: создана комната=%s игра=%s ставка=%d валюта=%s, запуск Run()", id, gameType, betAmount, currency)
-	go room.Run()
-
 	return room
 }
 
@@ -260,6 +553,14 @@ func (h *Hub) OnDisconnect(c *Client) {
 		delete(h.WaitingByKey, key)
 	}
 
+	// приватные слоты ожидания вызовов
+	for code, waiting := range h.PrivateWaiting {
+		if waiting != nil && waiting.UserID == c.UserID {
+			log.Printf("Hub.OnDisconnect: очистка приватного слота для пользователя=%d вызов=%s", c.UserID, code)
+			delete(h.PrivateWaiting, code)
+		}
+	}
+
 	// устаревшее: также проверяем WaitingByGame
 	var gameTypesToDelete []game.GameType
 	for gt, waiting := range h.WaitingByGame {
@@ -0,0 +1,81 @@
+package ws
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// пара игроков без учета порядка
+type pairKey struct {
+	a, b int64
+}
+
+func newPairKey(x, y int64) pairKey {
+	if x > y {
+		x, y = y, x
+	}
+	return pairKey{a: x, b: y}
+}
+
+// PairPolicy запрещает публичный матч одной и той же пары:
+// повторно в течение Window после их прошлого матча и всегда для пар под подозрением в сговоре
+type PairPolicy struct {
+	Window time.Duration // 0 - повторные матчи не ограничены
+
+	mu      sync.Mutex
+	last    map[pairKey]time.Time
+	blocked map[pairKey]bool
+}
+
+func NewPairPolicy(window time.Duration) *PairPolicy {
+	return &PairPolicy{
+		Window:  window,
+		last:    make(map[pairKey]time.Time),
+		blocked: make(map[pairKey]bool),
+	}
+}
+
+// можно ли свести игроков в публичной очереди
+func (p *PairPolicy) Allows(x, y int64, now time.Time) bool {
+	key := newPairKey(x, y)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.blocked[key] {
+		return false
+	}
+	if p.Window <= 0 {
+		return true
+	}
+	last, ok := p.last[key]
+	return !ok || now.Sub(last) >= p.Window
+}
+
+// запоминает матч пары
+func (p *PairPolicy) Record(x, y int64, now time.Time) {
+	if p.Window <= 0 {
+		return
+	}
+	key := newPairKey(x, y)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.last[key] = now
+	// старые записи больше не влияют на решение
+	for k, t := range p.last {
+		if now.Sub(t) >= p.Window {
+			delete(p.last, k)
+		}
+	}
+}
+
+// SetBlocked заменяет список пар под подозрением (обновляется детектором сговора)
+func (p *PairPolicy) SetBlocked(pairs [][2]int64) {
+	blocked := make(map[pairKey]bool, len(pairs))
+	for _, pair := range pairs {
+		blocked[newPairKey(pair[0], pair[1])] = true
+	}
+	p.mu.Lock()
+	p.blocked = blocked
+	p.mu.Unlock()
+	log.Printf("PairPolicy.SetBlocked: пар под запретом=%d", len(blocked))
+}
@@ -0,0 +1,81 @@
+package ws
+
+import (
+	"testing"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/game"
+)
+
+func TestPairPolicy(t *testing.T) {
+	now := time.Now()
+	p := NewPairPolicy(10 * time.Minute)
+
+	if !p.Allows(1, 2, now) {
+		t.Fatal("новая пара должна матчиться")
+	}
+	p.Record(1, 2, now)
+	if p.Allows(2, 1, now.Add(time.Minute)) {
+		t.Fatal("повторный матч в окне должен быть запрещен")
+	}
+	if !p.Allows(1, 2, now.Add(11*time.Minute)) {
+		t.Fatal("после окна пара снова матчится")
+	}
+
+	p.SetBlocked([][2]int64{{3, 4}})
+	if p.Allows(4, 3, now) {
+		t.Fatal("заблокированная пара не должна матчиться")
+	}
+	p.SetBlocked(nil)
+	if !p.Allows(3, 4, now) {
+		t.Fatal("снятая блокировка должна пропускать пару")
+	}
+}
+
+// реванш и вызов не обходят политику пар публичной очереди
+func TestPairPolicyRematchAndChallenge(t *testing.T) {
+	h := NewHub(nil, nil)
+	h.Pairs = NewPairPolicy(10 * time.Minute)
+	creator := &Client{UserID: 1, Send: make(chan []byte, 16)}
+	opponent := &Client{UserID: 2, Send: make(chan []byte, 16)}
+
+	h.mu.Lock()
+	room := h.buildRoom(game.TypeRPS, [2]int64{1, 2}, 0, "gems", 1)
+	room.Clients[1] = creator
+	room.Clients[2] = opponent
+	h.mu.Unlock()
+
+	if ok, _ := room.canRematch(); !ok {
+		t.Fatal("реванш новой пары должен быть доступен")
+	}
+	h.Pairs.Record(1, 2, time.Now())
+	if ok, reason := room.canRematch(); ok || reason != ErrCodePairBlocked {
+		t.Fatalf("реванш сразу после матча: ok=%v reason=%q", ok, reason)
+	}
+
+	// пара под подозрением не может сыграть и через приватный вызов
+	h.Pairs = NewPairPolicy(0)
+	h.Pairs.SetBlocked([][2]int64{{1, 2}})
+	ch := &domain.Challenge{Code: "pair", CreatorID: 1, GameType: domain.GameType(game.TypeRPS), BetAmount: 50, Currency: "gems"}
+	waiting := &Client{UserID: 1, Challenge: ch, Send: make(chan []byte, 16)}
+	h.mu.Lock()
+	private := h.buildRoom(game.TypeRPS, [2]int64{1, 0}, 50, "gems", 1)
+	private.ChallengeCode = ch.Code
+	private.ChallengeCreatorID = 1
+	private.Clients[1] = waiting
+	h.UserRoom[1] = private.ID
+	h.PrivateWaiting[ch.Code] = waiting
+	h.mu.Unlock()
+
+	joiner := &Client{UserID: 2, Challenge: ch, BetAmount: 50, Currency: "gems", Send: make(chan []byte, 16)}
+	if r := h.AssignChallenge(joiner); r != nil {
+		t.Fatal("заблокированная пара сведена через вызов")
+	}
+	if p := waitMessage(t, joiner, MsgError); p["code"] != ErrCodePairBlocked {
+		t.Fatalf("ошибка: %v", p)
+	}
+	if h.PrivateWaiting[ch.Code] != waiting {
+		t.Fatal("создатель вызова должен остаться в ожидании")
+	}
+}
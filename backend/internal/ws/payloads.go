@@ -1,21 +1,216 @@
 package ws
 
-//Клиент обращается к серверу
+import (
+	"time"
+
+	"telegram_webapp/internal/game"
+)
+
+// Типизированные payload сообщений протокола.
+// Единый источник правды: из этих структур генерируется JSON Schema для фронтенда
+// (go run ./cmd/ws_schema). Теги enum/min/max/minlen/maxlen/items/unique используются
+// и в схеме, и при валидации входящих сообщений.
+
+// ---- клиент к серверу ----
+
+// первый кадр соединения: JWT (вместо ?token= в URL)
+type AuthPayload struct {
+	Token string `json:"token" minlen:"1" maxlen:"4096"`
+}
+
+// вход в игру после auth: параметры очереди, вызова или турнирного матча
+type JoinPayload struct {
+	Game            string               `json:"game,omitempty" enum:"rps,mines"`
+	Bet             int64                `json:"bet,omitempty" min:"0"`
+	Currency        string               `json:"currency,omitempty" enum:"gems,coins"`
+	BestOf          int                  `json:"best_of,omitempty" min:"1" max:"5"`
+	Challenge       string               `json:"challenge,omitempty" maxlen:"64"`
+	TournamentMatch int64                `json:"tournament_match,omitempty" min:"1"`
+	Mines           *MinesOptionsPayload `json:"mines,omitempty"`
+	ProtocolVersion int                  `json:"protocol_version,omitempty" min:"2"`
+}
+
+// ход: move для камень-ножницы-бумага, cell для мин
+// границы cell и число мин зависят от параметров комнаты (matched.mines), их проверяет игра
 type MovePayload struct {
-	Move string `json:"move"` // камень / ножницы / бумага
+	Move string `json:"move,omitempty" enum:"rock,paper,scissors"`
+	Cell int    `json:"cell,omitempty" min:"1" max:"25"`
+}
+
+// расстановка мин (только mines)
+type SetupPayload struct {
+	Mines []int `json:"mines" unique:"true" min:"1" max:"25"`
+}
+
+// эмоция из фиксированного набора
+type EmotePayload struct {
+	Emote string `json:"emote" minlen:"1" enum:"gg,hello,laugh,wow,angry,cry,fire,thinking"`
+}
+
+// короткий текст сопернику (проходит фильтр ссылок и мата)
+type ChatPayload struct {
+	Text string `json:"text" minlen:"1" maxlen:"120"`
+}
+
+// заглушить (muted=true) или снова показывать сообщения соперника
+type MutePayload struct {
+	Muted bool `json:"muted"`
+}
+
+// ---- сервер к клиенту ----
+
+// токен принят, ждем join
+type AuthOKPayload struct {
+	UserID    int64 `json:"user_id"`
+	ExpiresAt int64 `json:"expires_at"` // до какого момента (unix ms) нужно прислать join
+}
+
+type ReadyPayload struct {
+	ProtocolVersion int `json:"protocol_version"`
+}
+
+type OpponentInfo struct {
+	ID        int64  `json:"id"`
+	FirstName string `json:"first_name,omitempty"`
+	Username  string `json:"username,omitempty"`
+	IsBot     bool   `json:"is_bot,omitempty"`
+}
+
+type MatchedPayload struct {
+	RoomID       string       `json:"room_id"`
+	Opponent     OpponentInfo `json:"opponent"`
+	BestOf       int          `json:"best_of,omitempty"`
+	TournamentID int64        `json:"tournament_id,omitempty"`
+	Round        int          `json:"round,omitempty"`
+	// параметры поля (только mines)
+	Mines *MinesOptionsPayload `json:"mines,omitempty"`
+}
+
+// параметры комнаты мин, выбранные при входе в очередь
+type MinesOptionsPayload struct {
+	Cells       int `json:"cells"`
+	Mines       int `json:"mines"`
+	TurnSeconds int `json:"turn_seconds"`
 }
 
-//Сервер отдает ответ клиенту
-type MatchFoundPayload struct {
-	RoomID     string `json:"room_id"`
-	OpponentID int64  `json:"opponent_id"`
+func minesOptionsPayload(o game.MinesOptions) *MinesOptionsPayload {
+	return &MinesOptionsPayload{
+		Cells:       o.Cells,
+		Mines:       o.Mines,
+		TurnSeconds: int(o.TurnTimeout / time.Second),
+	}
+}
+
+type StatePayload struct {
+	RoomID   string `json:"room_id"`
+	Players  int    `json:"players"`
+	GameType string `json:"game_type"`
+}
+
+type StartPayload struct {
+	Type      string `json:"type"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+type RoundDrawPayload struct {
+	Message      string `json:"message"`
+	Round        int    `json:"round,omitempty"`
+	YourMove     string `json:"your_move,omitempty"`
+	OpponentMove string `json:"opponent_move,omitempty"`
+}
+
+type RoundResultPayload struct {
+	Round        int               `json:"round"`
+	NextRound    int               `json:"next_round"`
+	YourMove     int               `json:"your_move"`
+	YourHit      bool              `json:"your_hit"`
+	OpponentMove int               `json:"opponent_move"`
+	OpponentHit  bool              `json:"opponent_hit"`
+	History      []game.MoveResult `json:"history"`
+	Timestamp    int64             `json:"timestamp"`
+}
+
+// счет серии с точки зрения игрока
+type SeriesScore struct {
+	BestOf       int  `json:"best_of"`
+	Game         int  `json:"game"`
+	YourWins     int  `json:"your_wins"`
+	OpponentWins int  `json:"opponent_wins"`
+	Draws        int  `json:"draws"`
+	Finished     bool `json:"finished"`
 }
 
 type ResultPayload struct {
-	Winner int64            `json:"winner"`
-	Moves  map[int64]string `json:"moves"`
+	You       string                 `json:"you" enum:"win,lose,draw,cancelled"`
+	Reason    string                 `json:"reason"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	WinAmount int64                  `json:"win_amount,omitempty"`
+	Refunded  int64                  `json:"refunded,omitempty"`
+	Currency  string                 `json:"currency,omitempty"`
+	Series    *SeriesScore           `json:"series,omitempty"`
+}
+
+type NextGamePayload struct {
+	RoomID    string       `json:"room_id"`
+	Game      int          `json:"game"`
+	Rematch   bool         `json:"rematch"`
+	Setup     bool         `json:"setup"`
+	BetAmount int64        `json:"bet_amount"`
+	Currency  string       `json:"currency"`
+	Series    *SeriesScore `json:"series,omitempty"`
+}
+
+type RematchOpenPayload struct {
+	RoomID    string `json:"room_id"`
+	BetAmount int64  `json:"bet_amount"`
+	Currency  string `json:"currency"`
+	BestOf    int    `json:"best_of"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+type RematchOfferPayload struct {
+	From int64 `json:"from"`
+}
+
+type RematchCancelledPayload struct {
+	Reason string `json:"reason" enum:"declined,opponent_left,timeout,insufficient_balance,server_shutdown,opponent_unavailable"`
+	UserID int64  `json:"user_id,omitempty"`
+}
+
+type SpectatorStatePayload struct {
+	RoomID    string      `json:"room_id"`
+	GameType  string      `json:"game_type"`
+	BetAmount int64       `json:"bet_amount"`
+	Currency  string      `json:"currency"`
+	Players   []int64     `json:"players"`
+	VsBot     bool        `json:"vs_bot"`
+	State     interface{} `json:"state"`
+	Timestamp int64       `json:"timestamp"`
+}
+
+// сообщение чата: emote или text в зависимости от kind
+type ChatMessagePayload struct {
+	From      int64  `json:"from"`
+	Kind      string `json:"kind" enum:"emote,text"`
+	Emote     string `json:"emote,omitempty"`
+	Text      string `json:"text,omitempty"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// подтверждение mute
+type MutedPayload struct {
+	Muted bool `json:"muted"`
+}
+
+// сервер останавливается: retry_after - через сколько секунд переподключиться,
+// deadline (unix ms) - до какого момента можно доиграть текущий матч
+type DrainingPayload struct {
+	RetryAfter int   `json:"retry_after"`
+	Deadline   int64 `json:"deadline,omitempty"`
 }
 
+// ошибка протокола или игры: code - машиночитаемый, message - для логов/отладки
 type ErrorPayload struct {
+	Code    string `json:"code"`
 	Message string `json:"message"`
 }
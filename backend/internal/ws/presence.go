@@ -0,0 +1,326 @@
+package ws
+
+import (
+	"encoding/json"
+	"log"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// как часто пересчитывается статистика лобби и обновляются метрики
+const DefaultLobbyInterval = 2 * time.Second
+
+var (
+	onlineUsersGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "pvp_online_users",
+		Help: "Users connected to PvP or lobby websockets",
+	})
+	waitingPlayersGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "pvp_waiting_players",
+		Help: "Players waiting for an opponent per matchmaking queue",
+	}, []string{"game", "bet", "currency", "best_of"})
+	liveRoomsGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "pvp_live_rooms",
+		Help: "PvP rooms with both seats taken per game",
+	}, []string{"game"})
+)
+
+func init() {
+	prometheus.MustRegister(onlineUsersGauge)
+	prometheus.MustRegister(waitingPlayersGauge)
+	prometheus.MustRegister(liveRoomsGauge)
+}
+
+// очередь матчмейкинга с числом ожидающих
+type WaitingStat struct {
+	GameType  string `json:"game_type"`
+	BetAmount int64  `json:"bet_amount"`
+	Currency  string `json:"currency"`
+	BestOf    int    `json:"best_of"`
+	Players   int    `json:"players"`
+	// параметры поля (только mines)
+	Mines *MinesOptionsPayload `json:"mines,omitempty"`
+}
+
+// статистика лобби: кто онлайн, кто ждет соперника, сколько матчей идет
+type LobbyStats struct {
+	Online         int            `json:"online"`
+	Waiting        []WaitingStat  `json:"waiting"`
+	WaitingTotal   int            `json:"waiting_total"`
+	LiveRooms      map[string]int `json:"live_rooms"`
+	LiveRoomsTotal int            `json:"live_rooms_total"`
+	Timestamp      int64          `json:"timestamp"`
+}
+
+// presence считает подключенных пользователей (у одного может быть несколько соединений)
+type presence struct {
+	mu     sync.Mutex
+	online map[int64]int
+
+	// подписчики канала лобби
+	subscribers map[*LobbySubscriber]struct{}
+	last        *LobbyStats
+}
+
+func newPresence() *presence {
+	return &presence{
+		online:      make(map[int64]int),
+		subscribers: make(map[*LobbySubscriber]struct{}),
+	}
+}
+
+// отмечает соединение пользователя (игровое или лобби)
+func (h *Hub) userConnected(userID int64) {
+	h.presence.mu.Lock()
+	h.presence.online[userID]++
+	h.presence.mu.Unlock()
+}
+
+// снимает отметку соединения пользователя
+func (h *Hub) userDisconnected(userID int64) {
+	h.presence.mu.Lock()
+	if n := h.presence.online[userID]; n > 1 {
+		h.presence.online[userID] = n - 1
+	} else {
+		delete(h.presence.online, userID)
+	}
+	h.presence.mu.Unlock()
+}
+
+// LobbyStats собирает текущую статистику лобби
+func (h *Hub) LobbyStats() LobbyStats {
+	stats := LobbyStats{
+		Waiting:   []WaitingStat{},
+		LiveRooms: make(map[string]int),
+		Timestamp: time.Now().UnixMilli(),
+	}
+
+	h.presence.mu.Lock()
+	stats.Online = len(h.presence.online)
+	h.presence.mu.Unlock()
+
+	h.mu.RLock()
+	for key, c := range h.WaitingByKey {
+		if c == nil {
+			continue
+		}
+		stat := WaitingStat{
+			GameType:  string(key.GameType),
+			BetAmount: key.BetAmount,
+			Currency:  key.Currency,
+			BestOf:    key.BestOf,
+			Players:   1,
+		}
+		if key.Mines.Cells != 0 {
+			stat.Mines = minesOptionsPayload(key.Mines)
+		}
+		stats.Waiting = append(stats.Waiting, stat)
+		stats.WaitingTotal++
+	}
+	rooms := make([]*Room, 0, len(h.Rooms))
+	for _, room := range h.Rooms {
+		rooms = append(rooms, room)
+	}
+	h.mu.RUnlock()
+
+	for _, room := range rooms {
+		room.mu.RLock()
+		closed := room.closed
+		room.mu.RUnlock()
+		if closed || room.game.Players()[1] == 0 {
+			continue
+		}
+		stats.LiveRooms[string(room.game.Type())]++
+		stats.LiveRoomsTotal++
+	}
+
+	sort.Slice(stats.Waiting, func(i, j int) bool {
+		a, b := stats.Waiting[i], stats.Waiting[j]
+		if a.GameType != b.GameType {
+			return a.GameType < b.GameType
+		}
+		if a.Currency != b.Currency {
+			return a.Currency < b.Currency
+		}
+		if a.BetAmount != b.BetAmount {
+			return a.BetAmount < b.BetAmount
+		}
+		if a.BestOf != b.BestOf {
+			return a.BestOf < b.BestOf
+		}
+		return minesSortKey(a.Mines) < minesSortKey(b.Mines)
+	})
+
+	return stats
+}
+
+// порядок очередей мин с одинаковой ставкой: по размеру поля, числу мин, таймеру
+func minesSortKey(m *MinesOptionsPayload) int {
+	if m == nil {
+		return 0
+	}
+	return m.Cells*10000 + m.Mines*100 + m.TurnSeconds
+}
+
+// StartLobby периодически обновляет метрики и рассылает статистику подписчикам лобби при изменении
+func (h *Hub) StartLobby(interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultLobbyInterval
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			h.publishLobby()
+		}
+	}()
+}
+
+func (h *Hub) publishLobby() {
+	stats := h.LobbyStats()
+	exportLobbyMetrics(stats)
+
+	h.presence.mu.Lock()
+	changed := h.presence.last == nil || !sameLobbyStats(*h.presence.last, stats)
+	h.presence.last = &stats
+	subscribers := make([]*LobbySubscriber, 0, len(h.presence.subscribers))
+	for s := range h.presence.subscribers {
+		subscribers = append(subscribers, s)
+	}
+	h.presence.mu.Unlock()
+
+	if !changed || len(subscribers) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(Message{Type: MsgLobbyStats, Payload: stats})
+	if err != nil {
+		log.Printf("Hub.publishLobby: marshal error: %v", err)
+		return
+	}
+	for _, s := range subscribers {
+		s.trySend(data)
+	}
+}
+
+// статистика совпадает без учета времени снимка
+func sameLobbyStats(a, b LobbyStats) bool {
+	a.Timestamp, b.Timestamp = 0, 0
+	return reflect.DeepEqual(a, b)
+}
+
+func exportLobbyMetrics(stats LobbyStats) {
+	onlineUsersGauge.Set(float64(stats.Online))
+
+	waitingPlayersGauge.Reset()
+	for _, w := range stats.Waiting {
+		waitingPlayersGauge.WithLabelValues(w.GameType, strconv.FormatInt(w.BetAmount, 10), w.Currency, strconv.Itoa(w.BestOf)).Add(float64(w.Players))
+	}
+
+	liveRoomsGauge.Reset()
+	for gameType, n := range stats.LiveRooms {
+		liveRoomsGauge.WithLabelValues(gameType).Set(float64(n))
+	}
+}
+
+// подписчик канала лобби: только получает lobby_stats
+type LobbySubscriber struct {
+	UserID int64
+	Conn   *websocket.Conn
+	Send   chan []byte
+	Done   chan struct{}
+}
+
+func NewLobbySubscriber(userID int64, conn *websocket.Conn) *LobbySubscriber {
+	return &LobbySubscriber{
+		UserID: userID,
+		Conn:   conn,
+		Send:   make(chan []byte, 16),
+		Done:   make(chan struct{}),
+	}
+}
+
+// Run подписывает на лобби и держит соединение до закрытия; входящие сообщения игнорируются
+func (s *LobbySubscriber) Run(hub *Hub) {
+	hub.userConnected(s.UserID)
+	hub.presence.mu.Lock()
+	hub.presence.subscribers[s] = struct{}{}
+	hub.presence.mu.Unlock()
+
+	defer func() {
+		hub.presence.mu.Lock()
+		delete(hub.presence.subscribers, s)
+		hub.presence.mu.Unlock()
+		hub.userDisconnected(s.UserID)
+	}()
+
+	go s.writePump()
+
+	// сразу отдаем текущий снимок, дальше - только изменения
+	if data, err := json.Marshal(Message{Type: MsgLobbyStats, Payload: hub.LobbyStats()}); err == nil {
+		s.trySend(data)
+	}
+	s.readPump()
+}
+
+func (s *LobbySubscriber) readPump() {
+	defer func() {
+		close(s.Done)
+		_ = s.Conn.Close()
+	}()
+
+	s.Conn.SetReadLimit(512)
+	s.Conn.SetReadDeadline(time.Now().Add(pongWait))
+	s.Conn.SetPongHandler(func(string) error {
+		s.Conn.SetReadDeadline(time.Now().Add(pongWait))
+		return nil
+	})
+
+	for {
+		if _, _, err := s.Conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+func (s *LobbySubscriber) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		_ = s.Conn.Close()
+	}()
+
+	for {
+		select {
+		case msg := <-s.Send:
+			s.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := s.Conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				log.Printf("LobbySubscriber.writePump: пользователь=%d ошибка записи: %v", s.UserID, err)
+				return
+			}
+		case <-ticker.C:
+			s.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := s.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-s.Done:
+			return
+		}
+	}
+}
+
+// неблокирующая отправка: медленный подписчик пропускает обновление
+func (s *LobbySubscriber) trySend(data []byte) {
+	select {
+	case s.Send <- data:
+	default:
+		log.Printf("LobbySubscriber.trySend: буфер подписчика=%d заполнен, пропускаем обновление", s.UserID)
+	}
+}
@@ -0,0 +1,41 @@
+package ws
+
+import (
+	"testing"
+
+	"telegram_webapp/internal/game"
+)
+
+func TestLobbyStats(t *testing.T) {
+	h := NewHub(nil, nil)
+	h.userConnected(1)
+	h.userConnected(1)
+	h.userConnected(2)
+	h.userConnected(3)
+	h.userDisconnected(1)
+	h.userDisconnected(3)
+
+	waiting := &Client{UserID: 1, Send: make(chan []byte, 1)}
+	h.mu.Lock()
+	h.WaitingByKey[WaitingKey{GameType: game.TypeMines, BetAmount: 50, Currency: "gems", BestOf: 1}] = waiting
+	h.buildRoom(game.TypeMines, [2]int64{1, 0}, 50, "gems", 1)
+	h.buildRoom(game.TypeRPS, [2]int64{2, 4}, 10, "gems", 1)
+	h.buildRoom(game.TypeRPS, [2]int64{5, 6}, 10, "coins", 3)
+	h.mu.Unlock()
+
+	stats := h.LobbyStats()
+	if stats.Online != 2 {
+		t.Fatalf("онлайн: %d, ожидалось 2", stats.Online)
+	}
+	if stats.WaitingTotal != 1 || len(stats.Waiting) != 1 || stats.Waiting[0].GameType != "mines" || stats.Waiting[0].BetAmount != 50 {
+		t.Fatalf("ожидающие: %+v", stats.Waiting)
+	}
+	if stats.LiveRoomsTotal != 2 || stats.LiveRooms["rps"] != 2 || stats.LiveRooms["mines"] != 0 {
+		t.Fatalf("комнаты: %+v", stats.LiveRooms)
+	}
+
+	again := h.LobbyStats()
+	if !sameLobbyStats(stats, again) {
+		t.Fatal("снимки без изменений должны совпадать")
+	}
+}
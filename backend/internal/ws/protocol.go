@@ -0,0 +1,297 @@
+package ws
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"telegram_webapp/internal/game"
+)
+
+// ошибка разбора входящего сообщения с машиночитаемым кодом
+type ProtocolError struct {
+	Code    string
+	Message string
+}
+
+func (e *ProtocolError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+func protocolErrorf(code, format string, args ...any) *ProtocolError {
+	return &ProtocolError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// конверт входящего сообщения: payload для v2, value для v1
+type clientEnvelope struct {
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+	Value   json.RawMessage `json:"value,omitempty"`
+}
+
+// разобранное сообщение клиента
+// Data - значение для game.HandleMove (string, int или []int),
+// для чата - код эмоции или текст, для mute - bool
+type ClientMessage struct {
+	Type string
+	Data interface{}
+}
+
+// поддерживается ли версия протокола (0 - клиент не передал версию, считаем v1)
+func SupportedProtocolVersion(v int) bool {
+	return v == 0 || (v >= MinProtocolVersion && v <= ProtocolVersion)
+}
+
+// ParseClientMessage разбирает и валидирует сообщение клиента для игры gameType
+func ParseClientMessage(version int, gameType game.GameType, raw []byte) (*ClientMessage, *ProtocolError) {
+	var env clientEnvelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return nil, protocolErrorf(ErrCodeMalformed, "invalid json: %v", err)
+	}
+	if env.Type == "" {
+		return nil, protocolErrorf(ErrCodeMalformed, "type is required")
+	}
+
+	if version < 2 {
+		return parseLegacyMessage(env, gameType)
+	}
+
+	switch env.Type {
+	case MsgPing, MsgRematch, MsgRematchDecline:
+		return &ClientMessage{Type: env.Type}, nil
+
+	case MsgMove:
+		var p MovePayload
+		if perr := decodePayload(env.Payload, &p); perr != nil {
+			return nil, perr
+		}
+		return moveMessage(p, gameType)
+
+	case MsgSetup:
+		var p SetupPayload
+		if perr := decodePayload(env.Payload, &p); perr != nil {
+			return nil, perr
+		}
+		if gameType != game.TypeMines {
+			return nil, protocolErrorf(ErrCodeInvalidPayload, "setup is not supported for %s", gameType)
+		}
+		return &ClientMessage{Type: MsgSetup, Data: p.Mines}, nil
+
+	case MsgEmote:
+		var p EmotePayload
+		if perr := decodePayload(env.Payload, &p); perr != nil {
+			return nil, perr
+		}
+		return &ClientMessage{Type: MsgEmote, Data: p.Emote}, nil
+
+	case MsgChat:
+		var p ChatPayload
+		if perr := decodePayload(env.Payload, &p); perr != nil {
+			return nil, perr
+		}
+		return &ClientMessage{Type: MsgChat, Data: p.Text}, nil
+
+	case MsgMute:
+		var p MutePayload
+		if perr := decodePayload(env.Payload, &p); perr != nil {
+			return nil, perr
+		}
+		return &ClientMessage{Type: MsgMute, Data: p.Muted}, nil
+	}
+
+	return nil, protocolErrorf(ErrCodeUnknownType, "unknown message type %q", env.Type)
+}
+
+// v1: {type, value}; массив в value - расстановка мин независимо от type
+func parseLegacyMessage(env clientEnvelope, gameType game.GameType) (*ClientMessage, *ProtocolError) {
+	switch env.Type {
+	case MsgPing, MsgRematch, MsgRematchDecline:
+		return &ClientMessage{Type: env.Type}, nil
+	}
+
+	value := bytes.TrimSpace(env.Value)
+	if len(value) == 0 || bytes.Equal(value, []byte("null")) {
+		return nil, protocolErrorf(ErrCodeInvalidPayload, "value is required")
+	}
+
+	switch env.Type {
+	case MsgEmote:
+		var p EmotePayload
+		if err := json.Unmarshal(value, &p.Emote); err != nil || p.Emote == "" {
+			return nil, protocolErrorf(ErrCodeInvalidPayload, "emote value must be a string")
+		}
+		if perr := validatePayload(p); perr != nil {
+			return nil, perr
+		}
+		return &ClientMessage{Type: MsgEmote, Data: p.Emote}, nil
+
+	case MsgChat:
+		var p ChatPayload
+		if err := json.Unmarshal(value, &p.Text); err != nil {
+			return nil, protocolErrorf(ErrCodeInvalidPayload, "chat value must be a string")
+		}
+		if perr := validatePayload(p); perr != nil {
+			return nil, perr
+		}
+		return &ClientMessage{Type: MsgChat, Data: p.Text}, nil
+
+	case MsgMute:
+		var muted bool
+		if err := json.Unmarshal(value, &muted); err != nil {
+			return nil, protocolErrorf(ErrCodeInvalidPayload, "mute value must be a boolean")
+		}
+		return &ClientMessage{Type: MsgMute, Data: muted}, nil
+	}
+
+	if gameType == game.TypeMines && value[0] == '[' {
+		p := SetupPayload{}
+		if err := json.Unmarshal(value, &p.Mines); err != nil {
+			return nil, protocolErrorf(ErrCodeInvalidPayload, "setup value must be an array of cells")
+		}
+		if perr := validatePayload(p); perr != nil {
+			return nil, perr
+		}
+		return &ClientMessage{Type: MsgSetup, Data: p.Mines}, nil
+	}
+
+	if env.Type != MsgMove && env.Type != MsgSetup {
+		return nil, protocolErrorf(ErrCodeUnknownType, "unknown message type %q", env.Type)
+	}
+
+	var p MovePayload
+	if gameType == game.TypeMines {
+		if err := json.Unmarshal(value, &p.Cell); err != nil {
+			return nil, protocolErrorf(ErrCodeInvalidPayload, "move value must be a cell number")
+		}
+	} else if err := json.Unmarshal(value, &p.Move); err != nil {
+		return nil, protocolErrorf(ErrCodeInvalidPayload, "move value must be a string")
+	}
+	if perr := validatePayload(p); perr != nil {
+		return nil, perr
+	}
+	return moveMessage(p, gameType)
+}
+
+// ход должен соответствовать игре: move для rps, cell для mines
+func moveMessage(p MovePayload, gameType game.GameType) (*ClientMessage, *ProtocolError) {
+	if gameType == game.TypeMines {
+		if p.Cell == 0 {
+			return nil, protocolErrorf(ErrCodeInvalidPayload, "cell is required")
+		}
+		return &ClientMessage{Type: MsgMove, Data: p.Cell}, nil
+	}
+	if p.Move == "" {
+		return nil, protocolErrorf(ErrCodeInvalidPayload, "move is required")
+	}
+	return &ClientMessage{Type: MsgMove, Data: p.Move}, nil
+}
+
+// строгий разбор payload: неизвестные поля - ошибка
+func decodePayload(raw json.RawMessage, dst interface{}) *ProtocolError {
+	if len(raw) == 0 {
+		return protocolErrorf(ErrCodeInvalidPayload, "payload is required")
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		return protocolErrorf(ErrCodeInvalidPayload, "%v", err)
+	}
+	return validatePayload(reflect.ValueOf(dst).Elem().Interface())
+}
+
+// validatePayload проверяет ограничения из тегов enum/min/max/minlen/maxlen/items/unique
+func validatePayload(v interface{}) *ProtocolError {
+	rv := reflect.ValueOf(v)
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		name := jsonFieldName(field)
+		if name == "" {
+			continue
+		}
+		if perr := validateValue(name, field.Tag, rv.Field(i)); perr != nil {
+			return perr
+		}
+	}
+	return nil
+}
+
+func validateValue(name string, tag reflect.StructTag, v reflect.Value) *ProtocolError {
+	switch v.Kind() {
+	case reflect.String:
+		length := int64(utf8.RuneCountInString(v.String()))
+		if min, ok := intTag(tag, "minlen"); ok && length < min {
+			return protocolErrorf(ErrCodeInvalidPayload, "%s must be at least %d characters", name, min)
+		}
+		if max, ok := intTag(tag, "maxlen"); ok && length > max {
+			return protocolErrorf(ErrCodeInvalidPayload, "%s must be at most %d characters", name, max)
+		}
+		if enum := tag.Get("enum"); enum != "" && v.String() != "" {
+			for _, allowed := range strings.Split(enum, ",") {
+				if v.String() == allowed {
+					return nil
+				}
+			}
+			return protocolErrorf(ErrCodeInvalidPayload, "%s must be one of %s", name, enum)
+		}
+
+	case reflect.Int, reflect.Int64:
+		n := v.Int()
+		if n == 0 && isOmitEmpty(tag) {
+			return nil
+		}
+		if min, ok := intTag(tag, "min"); ok && n < min {
+			return protocolErrorf(ErrCodeInvalidPayload, "%s must be >= %d", name, min)
+		}
+		if max, ok := intTag(tag, "max"); ok && n > max {
+			return protocolErrorf(ErrCodeInvalidPayload, "%s must be <= %d", name, max)
+		}
+
+	case reflect.Slice:
+		if items, ok := intTag(tag, "items"); ok && int64(v.Len()) != items {
+			return protocolErrorf(ErrCodeInvalidPayload, "%s must have exactly %d items", name, items)
+		}
+		seen := make(map[interface{}]bool, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			item := v.Index(i)
+			if perr := validateValue(fmt.Sprintf("%s[%d]", name, i), tag, item); perr != nil {
+				return perr
+			}
+			if tag.Get("unique") == "true" {
+				if seen[item.Interface()] {
+					return protocolErrorf(ErrCodeInvalidPayload, "%s items must be unique", name)
+				}
+				seen[item.Interface()] = true
+			}
+		}
+	}
+	return nil
+}
+
+func intTag(tag reflect.StructTag, key string) (int64, bool) {
+	s := tag.Get(key)
+	if s == "" {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	return n, err == nil
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+func isOmitEmpty(tag reflect.StructTag) bool {
+	return strings.Contains(tag.Get("json"), ",omitempty")
+}
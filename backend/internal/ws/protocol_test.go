@@ -0,0 +1,63 @@
+package ws
+
+import (
+	"reflect"
+	"testing"
+
+	"telegram_webapp/internal/game"
+)
+
+func TestParseClientMessageV2(t *testing.T) {
+	cases := []struct {
+		name     string
+		gameType game.GameType
+		raw      string
+		wantData interface{}
+		wantCode string
+	}{
+		{"rps move", game.TypeRPS, `{"type":"move","payload":{"move":"rock"}}`, "rock", ""},
+		{"mines move", game.TypeMines, `{"type":"move","payload":{"cell":7}}`, 7, ""},
+		{"mines setup", game.TypeMines, `{"type":"setup","payload":{"mines":[1,2,3,4]}}`, []int{1, 2, 3, 4}, ""},
+		{"bad rps move", game.TypeRPS, `{"type":"move","payload":{"move":"lizard"}}`, nil, ErrCodeInvalidPayload},
+		{"cell out of range", game.TypeMines, `{"type":"move","payload":{"cell":26}}`, nil, ErrCodeInvalidPayload},
+		{"duplicate mines", game.TypeMines, `{"type":"setup","payload":{"mines":[1,1,2,3]}}`, nil, ErrCodeInvalidPayload},
+		{"setup in rps", game.TypeRPS, `{"type":"setup","payload":{"mines":[1,2,3,4]}}`, nil, ErrCodeInvalidPayload},
+		{"unknown field", game.TypeRPS, `{"type":"move","payload":{"move":"rock","x":1}}`, nil, ErrCodeInvalidPayload},
+		{"unknown type", game.TypeRPS, `{"type":"dance"}`, nil, ErrCodeUnknownType},
+		{"malformed", game.TypeRPS, `{"type":`, nil, ErrCodeMalformed},
+	}
+
+	for _, tc := range cases {
+		msg, perr := ParseClientMessage(2, tc.gameType, []byte(tc.raw))
+		if tc.wantCode != "" {
+			if perr == nil || perr.Code != tc.wantCode {
+				t.Fatalf("%s: ожидался код %s, получено %v", tc.name, tc.wantCode, perr)
+			}
+			continue
+		}
+		if perr != nil {
+			t.Fatalf("%s: неожиданная ошибка %v", tc.name, perr)
+		}
+		if !reflect.DeepEqual(msg.Data, tc.wantData) {
+			t.Fatalf("%s: ожидалось %v, получено %v", tc.name, tc.wantData, msg.Data)
+		}
+	}
+}
+
+func TestParseClientMessageLegacy(t *testing.T) {
+	msg, perr := ParseClientMessage(0, game.TypeMines, []byte(`{"type":"setup","value":[5,6,7,8]}`))
+	if perr != nil || msg.Type != MsgSetup || !reflect.DeepEqual(msg.Data, []int{5, 6, 7, 8}) {
+		t.Fatalf("v1 setup: %v %v", msg, perr)
+	}
+
+	msg, perr = ParseClientMessage(0, game.TypeMines, []byte(`{"type":"move","value":3}`))
+	if perr != nil || msg.Data != 3 {
+		t.Fatalf("v1 mines move: %v %v", msg, perr)
+	}
+
+	// ping больше не превращается в случайный ход
+	msg, perr = ParseClientMessage(0, game.TypeRPS, []byte(`{"type":"ping"}`))
+	if perr != nil || msg.Type != MsgPing || msg.Data != nil {
+		t.Fatalf("v1 ping: %v %v", msg, perr)
+	}
+}
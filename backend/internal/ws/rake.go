@@ -0,0 +1,104 @@
+package ws
+
+import (
+	"context"
+	"log"
+	"math"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/logger"
+)
+
+// RakePayer зачисляет выигрыш и пишет рейк на счет платформы одной транзакцией
+// (repository.RakeRepository)
+type RakePayer interface {
+	PayWinner(ctx context.Context, winnerID int64, currency domain.Currency, payout int64, rake *domain.Transaction) error
+}
+
+// RakeCap границы рейка в одной валюте (Max 0 - без верхней границы)
+type RakeCap struct {
+	Min int64
+	Max int64
+}
+
+// SetRakeCap задает границы рейка для валюты (вызывается при настройке хаба)
+func (h *Hub) SetRakeCap(currency string, min, max int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.RakeCaps == nil {
+		h.RakeCaps = make(map[string]RakeCap)
+	}
+	h.RakeCaps[currency] = RakeCap{Min: min, Max: max}
+}
+
+// rakeAmount доля банка, которую забирает платформа (округление вниз), в границах валюты;
+// рейк не больше ставки соперника: победитель не получает меньше своей ставки
+func rakeAmount(stake int64, percent float64, limits RakeCap) int64 {
+	pot := stake * 2
+	if percent <= 0 || pot <= 0 {
+		return 0
+	}
+	rake := pot * int64(math.Round(percent*100)) / 10000
+	if rake < limits.Min {
+		rake = limits.Min
+	}
+	if limits.Max > 0 && rake > limits.Max {
+		rake = limits.Max
+	}
+	if rake > stake {
+		rake = stake
+	}
+	return rake
+}
+
+// rake рейк платформы с банка комнаты
+func (r *Room) rake() int64 {
+	return rakeAmount(r.BetAmount, r.RakePercent, r.rakeCap)
+}
+
+// winnerPayout выплата победителю: обе ставки за вычетом рейка
+func (r *Room) winnerPayout() int64 {
+	return r.BetAmount*2 - r.rake()
+}
+
+// payWinnerWithRake зачисляет выигрыш и пишет рейк на счет платформы одной транзакцией.
+// Возвращает сумму выигрыша и true, если она уже зачислена вместе с рейком; false - сумму нужно
+// зачислить обычным путем. Если рейк не записан, победитель получает весь банк: не удерживаем то, чего нет в учете
+func (r *Room) payWinnerWithRake(ctx context.Context, winnerID int64) (int64, bool) {
+	rake := r.rake()
+	if rake == 0 || r.RakeRepo == nil {
+		return r.BetAmount * 2, false
+	}
+	payout := r.BetAmount*2 - rake
+	entry := &domain.Transaction{
+		Type:   domain.TransactionTypePvPRake,
+		Amount: rake,
+		Meta: map[string]interface{}{
+			"room_id":   r.ID,
+			"game_type": string(r.game.Type()),
+			"currency":  r.Currency,
+			"winner_id": winnerID,
+			"pot":       r.BetAmount * 2,
+			"percent":   r.RakePercent,
+		},
+	}
+	if err := r.RakeRepo.PayWinner(ctx, winnerID, domain.Currency(r.Currency), payout, entry); err != nil {
+		logger.Get().Error("Room.payWinnerWithRake: рейк не записан, выплачиваем весь банк",
+			"room", r.ID, "winner", winnerID, "rake", rake, "currency", r.Currency, "error", err)
+		return r.BetAmount * 2, false
+	}
+	log.Printf("Room.payWinnerWithRake: room=%s winner=%d payout=%d rake=%d %s tx=%d at %s",
+		r.ID, winnerID, payout, rake, r.Currency, entry.ID, entry.CreatedAt.Format(time.RFC3339))
+	return payout, true
+}
+
+// winAmount выигрыш победителя: после выплаты - фактически зачисленная сумма, до нее - банк за вычетом рейка
+func (r *Room) winAmount() int64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.paidPot > 0 {
+		return r.paidPot
+	}
+	return r.winnerPayout()
+}
@@ -0,0 +1,86 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/game"
+)
+
+func TestWinnerPayoutRake(t *testing.T) {
+	cases := []struct {
+		bet     int64
+		percent float64
+		limits  RakeCap
+		want    int64
+	}{
+		{100, 0, RakeCap{Min: 5}, 200}, // без процента рейка нет, минимум не применяется
+		{100, 5, RakeCap{}, 190},
+		{100, 2.5, RakeCap{}, 195},
+		{33, 2.5, RakeCap{}, 65}, // 66 * 2.5% = 1.65 -> рейк 1
+		{10, 2.5, RakeCap{Min: 3}, 17},
+		{10000, 5, RakeCap{Max: 100}, 19900},
+		{2, 5, RakeCap{Min: 10}, 2}, // рейк не больше ставки соперника
+		{0, 5, RakeCap{}, 0},
+	}
+	for _, tc := range cases {
+		r := &Room{BetAmount: tc.bet, RakePercent: tc.percent, rakeCap: tc.limits}
+		if got := r.winnerPayout(); got != tc.want {
+			t.Fatalf("ставка %d рейк %.2f%% %+v: выплата %d, ожидалась %d", tc.bet, tc.percent, tc.limits, got, tc.want)
+		}
+	}
+}
+
+type fakeRakePayer struct {
+	err    error
+	payout int64
+	rake   *domain.Transaction
+}
+
+func (f *fakeRakePayer) PayWinner(ctx context.Context, winnerID int64, currency domain.Currency, payout int64, rake *domain.Transaction) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.payout = payout
+	f.rake = rake
+	return nil
+}
+
+func TestPayWinnerWithRake(t *testing.T) {
+	newRoom := func(payer RakePayer) *Room {
+		r := NewRoom("r1", game.NewRPSGame("r1", [2]int64{1, 2}), nil)
+		r.BetAmount = 100
+		r.Currency = "coins"
+		r.RakePercent = 5
+		r.RakeRepo = payer
+		return r
+	}
+
+	ok := &fakeRakePayer{}
+	if paid, ledgered := newRoom(ok).payWinnerWithRake(context.Background(), 1); paid != 190 || !ledgered {
+		t.Fatalf("выплата с рейком: %d, записана=%v", paid, ledgered)
+	}
+	if ok.payout != 190 || ok.rake == nil || ok.rake.Amount != 10 || ok.rake.Type != domain.TransactionTypePvPRake {
+		t.Fatalf("выплата %d, рейк %+v", ok.payout, ok.rake)
+	}
+
+	// рейк не записан: победитель получает весь банк, ничего не удерживается
+	failed := newRoom(&fakeRakePayer{err: errors.New("счет платформы не найден")})
+	if paid, ledgered := failed.payWinnerWithRake(context.Background(), 1); paid != 200 || ledgered {
+		t.Fatalf("при ошибке записи рейка: %d, записана=%v, ожидалось 200", paid, ledgered)
+	}
+	if paid, ledgered := newRoom(nil).payWinnerWithRake(context.Background(), 1); paid != 200 || ledgered {
+		t.Fatalf("без репозитория рейка: %d, записана=%v, ожидалось 200", paid, ledgered)
+	}
+
+	// сообщение о победе и история показывают зачисленную сумму
+	failed.paidPot = 200
+	if got := failed.winAmount(); got != 200 {
+		t.Fatalf("выигрыш после выплаты без рейка: %d, ожидалось 200", got)
+	}
+	if got := newRoom(ok).winAmount(); got != 190 {
+		t.Fatalf("выигрыш до выплаты: %d, ожидалось 190", got)
+	}
+}
@@ -0,0 +1,203 @@
+package ws
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/game"
+)
+
+// ReplayStore дописывает журнал матча в БД (repository.ReplayRepository)
+type ReplayStore interface {
+	Append(ctx context.Context, rep *domain.MatchReplay) error
+}
+
+// журнал событий комнаты; дописывается в БД после каждого раунда и при закрытии комнаты
+type replayLog struct {
+	mu        sync.Mutex
+	events    []domain.MatchEvent
+	game      int            // номер текущей партии серии
+	moved     map[int64]bool // кто сходил в текущем раунде (остальным засчитывается timeout)
+	startedAt time.Time
+	flushed   int  // сколько первых событий уже отправлено в БД
+	saved     bool // комната закрыта, журнал записан целиком
+}
+
+func newReplayLog() *replayLog {
+	return &replayLog{game: 1, moved: make(map[int64]bool), startedAt: time.Now()}
+}
+
+// recordEvent добавляет событие в журнал (userID 0 - событие комнаты)
+func (r *Room) recordEvent(t domain.MatchEventType, userID int64, data interface{}) {
+	l := r.replay
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e := domain.MatchEvent{
+		Seq:       len(l.events) + 1,
+		Game:      l.game,
+		Type:      t,
+		Data:      data,
+		CreatedAt: time.Now(),
+	}
+	if userID != 0 {
+		uid := userID
+		e.UserID = &uid
+	}
+	l.events = append(l.events, e)
+}
+
+// ход игрока или бота
+func (r *Room) recordMove(userID int64, move interface{}) {
+	r.replay.mu.Lock()
+	r.replay.moved[userID] = true
+	r.replay.mu.Unlock()
+
+	key := "move"
+	if r.game.Type() == game.TypeMines {
+		key = "cell"
+	}
+	r.recordEvent(domain.MatchEventMove, userID, map[string]interface{}{key: move})
+}
+
+func (r *Room) recordRoundStart(round int) {
+	r.replay.mu.Lock()
+	r.replay.moved = make(map[int64]bool)
+	r.replay.mu.Unlock()
+
+	r.recordEvent(domain.MatchEventRoundStart, 0, map[string]interface{}{"round": round})
+}
+
+// игроки, не успевшие сходить до таймера (их ход делается автоматически)
+func (r *Room) recordTimeouts() {
+	r.replay.mu.Lock()
+	var missed []int64
+	for _, uid := range r.game.Players() {
+		if uid != 0 && !r.replay.moved[uid] {
+			missed = append(missed, uid)
+		}
+	}
+	r.replay.mu.Unlock()
+
+	for _, uid := range missed {
+		r.recordEvent(domain.MatchEventTimeout, uid, nil)
+	}
+}
+
+// расстановка мин обоих игроков (в том числе автоматическая)
+func (r *Room) recordSetup() {
+	mines, ok := r.game.(*game.MinesGame)
+	if !ok {
+		return
+	}
+	for _, uid := range r.game.Players() {
+		if positions := mines.MinePositions(uid); positions != nil {
+			r.recordEvent(domain.MatchEventSetup, uid, map[string]interface{}{"mines": positions})
+		}
+	}
+}
+
+// раскрытые ходы раунда; result != nil, если раунд завершил партию
+func (r *Room) recordRoundResult(result *game.GameResult) {
+	switch g := r.game.(type) {
+	case *game.MinesGame:
+		if rr := g.GetLastRoundResult(); rr != nil {
+			r.recordEvent(domain.MatchEventRoundResult, 0, rr)
+		}
+	case *game.RPSGame:
+		moves := g.GetLastMoves()
+		if result != nil {
+			if m, ok := result.Details["moves"].(map[int64]string); ok {
+				moves = m
+			}
+		}
+		r.recordEvent(domain.MatchEventRoundResult, 0, map[string]interface{}{"round": g.GetRound(), "moves": moves})
+	}
+}
+
+func (r *Room) recordGameResult(result *game.GameResult) {
+	r.recordEvent(domain.MatchEventGameResult, 0, map[string]interface{}{
+		"winner_id": result.WinnerID,
+		"reason":    result.Reason,
+		"details":   result.Details,
+	})
+}
+
+// следующая партия серии или реванш
+func (r *Room) recordNextGame(rematch bool) {
+	r.replay.mu.Lock()
+	r.replay.game++
+	r.replay.moved = make(map[int64]bool)
+	r.replay.mu.Unlock()
+
+	r.recordEvent(domain.MatchEventNextGame, 0, map[string]interface{}{"rematch": rematch})
+}
+
+// flushReplay дописывает в БД события, накопленные с прошлой записи; final закрывает журнал.
+// Матчи без соперника не сохраняются
+func (r *Room) flushReplay(final bool) {
+	if r.hub == nil || r.hub.ReplayRepo == nil {
+		return
+	}
+	players := r.game.Players()
+	if players[1] == 0 {
+		return
+	}
+
+	l := r.replay
+	l.mu.Lock()
+	if l.saved || len(l.events) == 0 || (!final && l.flushed == len(l.events)) {
+		l.mu.Unlock()
+		return
+	}
+	l.saved = final
+	from := l.flushed
+	events := append([]domain.MatchEvent(nil), l.events[from:]...)
+	l.flushed = len(l.events)
+	startedAt := l.startedAt
+	l.mu.Unlock()
+
+	rep := &domain.MatchReplay{
+		RoomID:    r.ID,
+		GameType:  domain.GameType(r.game.Type()),
+		Player1ID: players[0],
+		Player2ID: players[1],
+		BetAmount: r.BetAmount,
+		Currency:  r.Currency,
+		BestOf:    r.series.BestOf,
+		VsBot:     isBotPlayer(players[1]),
+		StartedAt: startedAt,
+		Events:    events,
+	}
+	if final {
+		now := time.Now()
+		rep.FinishedAt = &now
+	}
+	if r.tournament != nil {
+		id := r.tournament.TournamentID
+		rep.TournamentID = &id
+	}
+
+	repo := r.hub.ReplayRepo
+	r.hub.track(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := repo.Append(ctx, rep); err != nil {
+			log.Printf("Room.flushReplay: room=%s failed: %v", rep.RoomID, err)
+			// события не записаны: следующая запись отправит их повторно
+			l.mu.Lock()
+			if l.flushed > from {
+				l.flushed = from
+			}
+			l.mu.Unlock()
+		}
+	})
+}
+
+// saveReplay дописывает остаток журнала и отмечает матч завершенным
+func (r *Room) saveReplay() {
+	r.flushReplay(true)
+}
@@ -0,0 +1,101 @@
+package ws
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/game"
+)
+
+func TestReplayLogRecordsRoundInOrder(t *testing.T) {
+	g, err := game.NewFactory().CreateGame(game.TypeRPS, "r1", [2]int64{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRoom("r1", g, nil)
+
+	r.recordRoundStart(1)
+	if err := g.HandleMove(1, "rock"); err != nil {
+		t.Fatal(err)
+	}
+	r.recordMove(1, "rock")
+	// второй игрок не сходил до таймера
+	r.recordTimeouts()
+	r.recordNextGame(false)
+	r.recordRoundStart(1)
+
+	want := []struct {
+		typ    domain.MatchEventType
+		game   int
+		userID int64
+	}{
+		{domain.MatchEventRoundStart, 1, 0},
+		{domain.MatchEventMove, 1, 1},
+		{domain.MatchEventTimeout, 1, 2},
+		{domain.MatchEventNextGame, 2, 0},
+		{domain.MatchEventRoundStart, 2, 0},
+	}
+	events := r.replay.events
+	if len(events) != len(want) {
+		t.Fatalf("событий %d, ожидалось %d", len(events), len(want))
+	}
+	for i, w := range want {
+		e := events[i]
+		var uid int64
+		if e.UserID != nil {
+			uid = *e.UserID
+		}
+		if e.Seq != i+1 || e.Type != w.typ || e.Game != w.game || uid != w.userID {
+			t.Fatalf("событие %d: %+v, ожидалось %+v", i, e, w)
+		}
+	}
+	if move := events[1].Data.(map[string]interface{}); move["move"] != "rock" {
+		t.Fatalf("ход: %v", move)
+	}
+}
+
+type fakeReplayStore struct {
+	mu      sync.Mutex
+	appends []*domain.MatchReplay
+}
+
+func (f *fakeReplayStore) Append(ctx context.Context, rep *domain.MatchReplay) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.appends = append(f.appends, rep)
+	return nil
+}
+
+// журнал дописывается по раундам, без повторной отправки записанных событий
+func TestReplayFlushedPerRound(t *testing.T) {
+	store := &fakeReplayStore{}
+	hub := NewHub(nil, nil)
+	hub.ReplayRepo = store
+	r := NewRoom("r1", game.NewRPSGame("r1", [2]int64{1, 2}), hub)
+
+	r.recordRoundStart(1)
+	r.recordMove(1, "rock")
+	r.flushReplay(false)
+	r.flushReplay(false) // новых событий нет
+	r.recordRoundStart(2)
+	r.saveReplay()
+	r.saveReplay() // журнал уже закрыт
+	hub.writes.Wait()
+
+	if len(store.appends) != 2 {
+		t.Fatalf("записей %d, ожидалось 2", len(store.appends))
+	}
+	// записи уходят асинхронно и могут прийти в любом порядке
+	first, last := store.appends[0], store.appends[1]
+	if first.FinishedAt != nil {
+		first, last = last, first
+	}
+	if len(first.Events) != 2 || first.Events[0].Seq != 1 || first.FinishedAt != nil {
+		t.Fatalf("первый раунд: %+v", first)
+	}
+	if len(last.Events) != 1 || last.Events[0].Seq != 3 || last.FinishedAt == nil {
+		t.Fatalf("закрытие комнаты: %+v", last)
+	}
+}
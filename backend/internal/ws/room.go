@@ -9,20 +9,16 @@ import (
 
 	"telegram_webapp/internal/domain"
 	"telegram_webapp/internal/game"
+	"telegram_webapp/internal/logger"
 	"telegram_webapp/internal/repository"
 )
 
-const (
-	// ставка обоих игроков отдается победителю
-	winnerPayoutMultiplier = 2
-)
 const (
 	StateWaiting  = "waiting"
 	StatePlaying  = "playing"
 	StateFinished = "finished"
 )
 
-
 type Room struct {
 	ID      string
 	Clients map[int64]*Client
@@ -49,16 +45,65 @@ type Room struct {
 	Currency  string // "gems" or "coins"
 	UserRepo  *repository.UserRepository
 	betPaid   bool // отслеживание выплаты ставки
+	// доля банка, которую забирает платформа с выигрыша (pvp_game_configs), и границы валюты
+	RakePercent float64
+	rakeCap     RakeCap
+	RakeRepo    RakePayer
+	paidPot     int64 // зачислено победителю (0 - выплаты не было)
+
+	// приватная комната по вызову (пусто для публичного матчмейкинга)
+	ChallengeCode      string
+	ChallengeCreatorID int64
+	opponentDeadline   time.Time     // до какого момента ждем соперника (= истечение вызова)
+	challengeStarted   bool          // ставка создателя перешла из вызова в комнату
+	opponentJoined     chan struct{} // закрывается, когда в комнате два игрока
+	opponentJoinedDone bool
+
+	// бот-соперник (nil, если играют двое людей)
+	botStrategy game.BotStrategy
+
+	// зрители (только чтение, безопасное состояние)
+	spectators map[*Spectator]struct{}
+
+	// серия best-of-N: банк выплачивается по окончании серии
+	series       *Series
+	gameRecorded bool // партия учтена в счете серии
+	resultSent   bool // результат партии разослан игрокам
+	closed       bool // комната очищена
+
+	// окно реванша (nil, пока окно закрыто)
+	rematchVotes    map[int64]bool
+	rematchReady    chan struct{}
+	rematchDeclined chan int64
+
+	// чат: игрок -> заглушил соперника
+	mutes map[int64]bool
+
+	// матч турнира (nil для обычной комнаты)
+	tournament *tournamentMatch
+
+	// остановка сервера: закрывается хабом, Run отменяет партию (см. Hub.Drain)
+	shutdown chan struct{}
+	stopOnce sync.Once
+
+	// журнал событий для повтора матча
+	replay *replayLog
 }
+
 func NewRoom(id string, g game.Game, hub *Hub) *Room {
 	return &Room{
-		ID:        id,
-		Clients:   make(map[int64]*Client),
-		Register:  make(chan *Client, 2),
-		Disconnect: make(chan *Client, 2),
-		createdAt: time.Now(),
-		game:      g,
-		hub:       hub,
+		ID:             id,
+		Clients:        make(map[int64]*Client),
+		Register:       make(chan *Client, 2),
+		Disconnect:     make(chan *Client, 2),
+		createdAt:      time.Now(),
+		game:           g,
+		hub:            hub,
+		opponentJoined: make(chan struct{}),
+		spectators:     make(map[*Spectator]struct{}),
+		series:         NewSeries(1),
+		shutdown:       make(chan struct{}),
+		replay:         newReplayLog(),
 	}
 }
 
@@ -69,55 +114,111 @@ func NewRoomWithRepo(id string, g game.Game, gameRepo *repository.GameRepository
 	return r
 }
 
-
-
 func (r *Room) Run() {
 	log.Printf("Room.Run: starting room=%s", r.ID)
 
 	setupDone := make(chan struct{})
 
+	// турнирный матч: оба игрока должны подключиться до дедлайна
+	var noShow <-chan time.Time
+	if r.isTournament() {
+		t := time.NewTimer(time.Until(r.opponentDeadline))
+		defer t.Stop()
+		noShow = t.C
+	}
+
 	// Фаза настройки (если нужна для игры)
 	if r.game.SetupTimeout() > 0 {
 		log.Printf("Room.Run: room=%s has setup phase", r.ID)
 
 		go func() {
+			// приватная комната ждет приглашенного до истечения вызова, setup стартует после
+			if r.ChallengeCode != "" && !r.waitForOpponent() {
+				log.Printf("Room.Run: room=%s challenge=%s expired without opponent", r.ID, r.ChallengeCode)
+				r.cancelGameNoOpponent()
+				close(setupDone)
+				return
+			}
+			// турнирный матч: setup начинается, когда подключились оба (неявка обрабатывается в Run)
+			if r.isTournament() && !r.waitForOpponent() {
+				close(setupDone)
+				return
+			}
+
 			timer := time.NewTimer(r.game.SetupTimeout())
 			defer timer.Stop()
 
-			select {
-			case <-timer.C:
-				log.Printf("Room.Run: room=%s setup timeout", r.ID)
-
-				// Check if second player joined - if not, cancel the game
-				players := r.game.Players()
-				if players[1] == 0 {
-					log.Printf("Room.Run: room=%s no opponent found, cancelling game", r.ID)
-					r.cancelGameNoOpponent()
-					close(setupDone)
-					return
-				}
+			// бот-соперник может занять пустое место через заданную задержку
+			var botTimer <-chan time.Time
+			if r.hub != nil && r.hub.Bot != nil && r.ChallengeCode == "" && !r.isTournament() {
+				bt := time.NewTimer(r.hub.Bot.Delay)
+				defer bt.Stop()
+				botTimer = bt.C
+			}
 
-				// Check if setup was already completed (game already started)
-				r.mu.RLock()
-				alreadyCompleted := r.setupCompleted
-				r.mu.RUnlock()
-				if alreadyCompleted {
-					log.Printf("Room.Run: room=%s setup already completed, skipping", r.ID)
+			for {
+				select {
+				case <-botTimer:
+					botTimer = nil
+					if !r.tryBotFill() {
+						continue
+					}
+					if r.game.IsSetupComplete() {
+						log.Printf("Room.Run: room=%s bot joined, starting game", r.ID)
+						r.startBotGame()
+						return
+					}
+					// у игрока полное время на расстановку после подсадки бота
+					timer.Reset(r.game.SetupTimeout())
+
+				case <-timer.C:
+					log.Printf("Room.Run: room=%s setup timeout", r.ID)
+
+					// Check if second player joined - if not, cancel the game
+					players := r.game.Players()
+					if players[1] == 0 {
+						// задержка бота больше таймаута поиска - подсаживаем бота сейчас
+						if botTimer != nil && r.tryBotFill() {
+							botTimer = nil
+							if r.game.IsSetupComplete() {
+								r.startBotGame()
+								return
+							}
+							timer.Reset(r.game.SetupTimeout())
+							continue
+						}
+						log.Printf("Room.Run: room=%s no opponent found, cancelling game", r.ID)
+						r.cancelGameNoOpponent()
+						close(setupDone)
+						return
+					}
+
+					// Check if setup was already completed (game already started)
+					r.mu.RLock()
+					alreadyCompleted := r.setupCompleted
+					r.mu.RUnlock()
+					if alreadyCompleted {
+						log.Printf("Room.Run: room=%s setup already completed, skipping", r.ID)
+						close(setupDone)
+						return
+					}
+
+					r.completeSetup()
+					// Запускаем первый раунд после таймаута setup
+					time.Sleep(100 * time.Millisecond)
+					r.mu.Lock()
+					r.roundStarted = false
+					r.mu.Unlock()
+					log.Printf("Room.Run: starting round after setup timeout in room=%s", r.ID)
+					r.startRound()
 					close(setupDone)
 					return
+				case <-setupDone:
+					log.Printf("Room.Run: room=%s setup completed manually", r.ID)
+					return
+				case <-r.shutdown:
+					return
 				}
-
-				r.completeSetup()
-				// Запускаем первый раунд после таймаута setup
-				time.Sleep(100 * time.Millisecond)
-				r.mu.Lock()
-				r.roundStarted = false
-				r.mu.Unlock()
-				log.Printf("Room.Run: starting round after setup timeout in room=%s", r.ID)
-				r.startRound()
-				close(setupDone)
-			case <-setupDone:
-				log.Printf("Room.Run: room=%s setup completed manually", r.ID)
 			}
 		}()
 	} else {
@@ -132,9 +233,18 @@ func (r *Room) Run() {
 	// Обработка событий
 	for {
 		// ПРОВЕРКА ЗАВЕРШИЛАСЬ ЛИ ИГРА ПРЕЖДЕ ЧЕМ БЛОКИРОВАТЬ ВЫБОР
-		if r.game.IsFinished() {
-			log.Printf("Room.Run: room=%s game finished, exiting", r.ID)
+		if r.game.IsFinished() && r.resultDelivered() {
+			// серия продолжается - следующая партия в этой же комнате
+			if r.seriesContinues() {
+				r.startNextGame(false)
+				continue
+			}
+			log.Printf("Room.Run: room=%s game finished", r.ID)
 			r.saveResult()
+			if r.awaitRematch() {
+				continue
+			}
+			log.Printf("Room.Run: room=%s exiting", r.ID)
 			r.cleanup()
 			return
 		}
@@ -176,6 +286,18 @@ func (r *Room) Run() {
 				r.startRound()
 			}
 
+		case <-r.shutdown:
+			log.Printf("Room.Run: room=%s stopped on server shutdown", r.ID)
+			r.abortOnShutdown()
+			return
+
+		case <-noShow:
+			noShow = nil
+			if r.tournamentNoShow() {
+				log.Printf("Room.Run: room=%s tournament match closed on no-show", r.ID)
+				return
+			}
+
 		case c := <-r.Disconnect:
 			log.Printf("Room.Run: room=%s received Disconnect for user=%d", r.ID, c.UserID)
 			shouldTerminate := r.handleDisconnect(c)
@@ -209,12 +331,14 @@ func (r *Room) completeSetup() {
 			r.game.HandleMove(playerID, nil)
 		}
 	}
+	r.recordSetup()
 	// Собираем клиентов, удерживая блокировку
 	clients := r.getClientsUnlocked()
 	r.mu.Unlock()
 
 	// Отправка без блокировки
-	r.broadcastToClients(clients, Message{Type: "setup_complete"})
+	r.broadcastToClients(clients, Message{Type: MsgSetupComplete})
+	r.notifySpectators()
 }
 
 func (r *Room) startRound() {
@@ -236,18 +360,25 @@ func (r *Room) startRound() {
 	}
 	r.timerRound++
 	currentRound := r.timerRound
+	r.recordRoundStart(currentRound)
 	r.timer = time.AfterFunc(r.game.TurnTimeout(), func() {
 		r.handleRoundTimeout(currentRound)
 	})
+	withBot := r.botStrategy != nil
 	r.mu.Unlock()
 
+	if withBot {
+		go r.botMove(currentRound)
+	}
+	r.notifySpectators()
+
 	// Отправляем start с меткой времени, чтобы фронтенд обнаружил новый раунд
 	log.Printf("Room.startRound: sending start message to %d clients, timerRound=%d", len(clients), currentRound)
 	r.broadcastToClients(clients, Message{
-		Type: "start",
-		Payload: map[string]any{
-			"type":      "start",
-			"timestamp": time.Now().UnixMilli(),
+		Type: MsgStart,
+		Payload: StartPayload{
+			Type:      MsgStart,
+			Timestamp: time.Now().UnixMilli(),
 		},
 	})
 }
@@ -264,6 +395,7 @@ func (r *Room) handleRoundTimeout(forRound int) {
 	log.Printf("Room.handleRoundTimeout: processing timeout for round=%d in room=%s", forRound, r.ID)
 
 	// Для каждого игрока который не сходил - бот делает ход
+	r.recordTimeouts()
 	for _, playerID := range r.game.Players() {
 		r.game.HandleMove(playerID, nil)
 	}
@@ -298,9 +430,9 @@ func (r *Room) broadcastToClients(clients map[int64]*Client, msg Message) {
 		}
 		select {
 		case c.Send <- data:
-			log.Printf("Room.broadcastToClients: ✅ sent to user=%d type=%s", userID, msg.Type)
+			logger.Debug("Room.broadcastToClients: отправлено", "room", r.ID, "user", userID, "type", msg.Type)
 		case <-time.After(2 * time.Second):
-			log.Printf("Room.broadcastToClients: ❌ timeout sending to user=%d type=%s", userID, msg.Type)
+			logger.Warn("Room.broadcastToClients: таймаут отправки", "room", r.ID, "user", userID, "type", msg.Type)
 		}
 	}
 }
@@ -343,6 +475,13 @@ func (r *Room) checkRound() {
 
 	result := r.game.CheckResult()
 	log.Printf("Room.checkRound: room=%s check result=%v finished=%v", r.ID, result, r.game.IsFinished())
+	r.recordRoundResult(result)
+	if result != nil {
+		r.recordGameResult(result)
+	}
+	r.flushReplay(false)
+	// зрители видят раскрытые ходы после рассылки игрокам
+	defer r.notifySpectators()
 
 	if result == nil {
 		// Round was a draw or both players had same outcome - continue to next round
@@ -383,12 +522,12 @@ func (r *Room) checkRound() {
 							}
 
 							data, _ := json.Marshal(Message{
-								Type: "round_draw",
-								Payload: map[string]any{
-									"message":       "Round ended in draw, starting next round",
-									"round":         rpsGame.GetRound(),
-									"your_move":     yourMove,
-									"opponent_move": opponentMove,
+								Type: MsgRoundDraw,
+								Payload: RoundDrawPayload{
+									Message:      "Round ended in draw, starting next round",
+									Round:        rpsGame.GetRound(),
+									YourMove:     yourMove,
+									OpponentMove: opponentMove,
 								},
 							})
 							select {
@@ -399,9 +538,9 @@ func (r *Room) checkRound() {
 					}
 				} else {
 					r.broadcastToClients(clients, Message{
-						Type: "round_draw",
-						Payload: map[string]any{
-							"message": "Round ended in draw, starting next round",
+						Type: MsgRoundDraw,
+						Payload: RoundDrawPayload{
+							Message: "Round ended in draw, starting next round",
 						},
 					})
 				}
@@ -419,12 +558,20 @@ func (r *Room) checkRound() {
 	// For Mines game, send final round result before game result
 	r.sendMinesRoundResult()
 
+	// счет серии обновляется до рассылки, чтобы попасть в result
+	if r.game.IsFinished() {
+		r.recordSeriesGame(result.WinnerID)
+	}
+
 	// Отправляем результат (this function handles its own locking)
 	r.broadcastResult(result)
 
 	if r.game.IsFinished() {
 		// Игра полностью закончена
 		log.Printf("Room.checkRound: game finished in room %s", r.ID)
+		r.mu.Lock()
+		r.resultSent = true
+		r.mu.Unlock()
 		return
 	}
 
@@ -444,6 +591,7 @@ func (r *Room) cleanup() {
 		r.timer.Stop()
 		r.timer = nil
 	}
+	r.closed = true
 	players := r.game.Players()
 	gameType := r.game.Type()
 	clientIDs := make([]int64, 0, len(r.Clients))
@@ -471,6 +619,23 @@ func (r *Room) cleanup() {
 			delete(hub.UserRoom, uid)
 		}
 
+		// Clear private waiting slot of this challenge
+		if r.ChallengeCode != "" {
+			if waiting := hub.PrivateWaiting[r.ChallengeCode]; waiting != nil {
+				for _, uid := range players {
+					if waiting.UserID == uid {
+						delete(hub.PrivateWaiting, r.ChallengeCode)
+						break
+					}
+				}
+			}
+		}
+
+		// Clear tournament match slot
+		if r.tournament != nil && hub.TournamentRooms[r.tournament.MatchID] == r {
+			delete(hub.TournamentRooms, r.tournament.MatchID)
+		}
+
 		// Clear WaitingByGame if any player from this room was waiting
 		if waiting := hub.WaitingByGame[gameType]; waiting != nil {
 			for _, uid := range players {
@@ -485,6 +650,9 @@ func (r *Room) cleanup() {
 		hub.mu.Unlock()
 	}
 
+	r.closeSpectators()
+	r.saveReplay()
+
 	log.Printf("Room.cleanup: room=%s cleaned up", roomID)
 }
 
@@ -492,6 +660,7 @@ func (r *Room) handleRegister(c *Client) {
 	r.mu.Lock()
 
 	r.Clients[c.UserID] = c
+	r.recordEvent(domain.MatchEventJoin, c.UserID, nil)
 
 	log.Printf("Room.handleRegister: room=%s user=%d players=%d game_type=%s", r.ID, c.UserID, len(r.Clients), r.game.Type())
 
@@ -512,11 +681,14 @@ func (r *Room) handleRegister(c *Client) {
 		log.Printf("Room.handleRegister: closed Registered for user=%d room=%s", c.UserID, r.ID)
 	}
 
-
-
 	if len(r.Clients) == 2 {
 		log.Printf("Room.handleRegister: room=%s BOTH PLAYERS REGISTERED; will send matched messages", r.ID)
 
+		if !r.opponentJoinedDone {
+			r.opponentJoinedDone = true
+			close(r.opponentJoined)
+		}
+
 		// Collect data while holding lock
 		players := r.game.Players()
 		p1, p2 := players[0], players[1]
@@ -528,41 +700,27 @@ func (r *Room) handleRegister(c *Client) {
 		r.mu.Unlock()
 
 		// Load user info for opponents
-		var user1Info, user2Info map[string]any
+		// Fallback if user info not loaded: only ids
+		user1Info := OpponentInfo{ID: p1}
+		user2Info := OpponentInfo{ID: p2}
 		if userRepo != nil {
 			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 			if u1, err := userRepo.GetByID(ctx, p1); err == nil {
-				user1Info = map[string]any{
-					"id":         p1,
-					"first_name": u1.FirstName,
-					"username":   u1.Username,
-				}
+				user1Info.FirstName = u1.FirstName
+				user1Info.Username = u1.Username
 			}
 			if u2, err := userRepo.GetByID(ctx, p2); err == nil {
-				user2Info = map[string]any{
-					"id":         p2,
-					"first_name": u2.FirstName,
-					"username":   u2.Username,
-				}
+				user2Info.FirstName = u2.FirstName
+				user2Info.Username = u2.Username
 			}
 			cancel()
 		}
-		// Fallback if user info not loaded
-		if user1Info == nil {
-			user1Info = map[string]any{"id": p1}
-		}
-		if user2Info == nil {
-			user2Info = map[string]any{"id": p2}
-		}
 
 		// Send matched to both players
 		if c1 != nil {
 			data1, _ := json.Marshal(Message{
-				Type: "matched",
-				Payload: map[string]any{
-					"room_id":  r.ID,
-					"opponent": user2Info,
-				},
+				Type: MsgMatched,
+				Payload: r.matchedPayload(user2Info),
 			})
 			select {
 			case c1.Send <- data1:
@@ -574,11 +732,8 @@ func (r *Room) handleRegister(c *Client) {
 
 		if c2 != nil {
 			data2, _ := json.Marshal(Message{
-				Type: "matched",
-				Payload: map[string]any{
-					"room_id":  r.ID,
-					"opponent": user1Info,
-				},
+				Type: MsgMatched,
+				Payload: r.matchedPayload(user1Info),
 			})
 			select {
 			case c2.Send <- data2:
@@ -607,16 +762,16 @@ func (r *Room) handleRegister(c *Client) {
 
 	// send state now that lock is released
 	r.send(c.UserID, Message{
-		Type: "state",
-		Payload: map[string]any{
-			"room_id":   r.ID,
-			"players":   len(r.Clients),
-			"game_type": string(r.game.Type()),
+		Type: MsgState,
+		Payload: StatePayload{
+			RoomID:   r.ID,
+			Players:  len(r.Clients),
+			GameType: string(r.game.Type()),
 		},
 	})
 
 	for i, m := range pending {
-		log.Printf("Room.handleRegister: replaying pending[%d] for user=%d: %s", i, c.UserID, string(m))
+		logger.Debug("Room.handleRegister: повтор буферизованного сообщения", "room", r.ID, "user", c.UserID, "index", i)
 		r.HandleMessage(c, m)
 	}
 
@@ -636,9 +791,16 @@ func (r *Room) handleDisconnect(c *Client) bool {
 	r.mu.Lock()
 
 	delete(r.Clients, c.UserID)
+	r.recordEvent(domain.MatchEventDisconnect, c.UserID, nil)
 
 	log.Printf("Room.handleDisconnect: room=%s user=%d bet=%d currency=%s", r.ID, c.UserID, r.BetAmount, r.Currency)
 
+	// турнирный матч до подключения обоих ждет дедлайна: игрок может вернуться
+	if r.isTournament() && !r.opponentJoinedDone {
+		r.mu.Unlock()
+		return false
+	}
+
 	// Collect remaining client info while holding lock
 	var remainingUID int64
 	var remainingClient *Client
@@ -660,17 +822,22 @@ func (r *Room) handleDisconnect(c *Client) bool {
 
 	// Handle bet payouts
 	shouldPayWinner := r.BetAmount > 0 && !r.betPaid && hadTwoPlayers && shouldNotifyWinner
-	shouldRefundDisconnecting := r.BetAmount > 0 && !r.betPaid && !hadTwoPlayers // Game never started (waiting for opponent)
-	if shouldPayWinner || shouldRefundDisconnecting {
+	shouldRefundDisconnecting := r.BetAmount > 0 && !r.betPaid && !hadTwoPlayers && r.holdsStake(c.UserID) // Game never started (waiting for opponent)
+	// игрок ушел от бота - поражение, ставка остается у платформы
+	botForfeit := isBotPlayer(players[1]) && !r.betPaid && !r.game.IsFinished()
+	if shouldPayWinner || shouldRefundDisconnecting || botForfeit {
 		r.betPaid = true
 	}
 	r.mu.Unlock()
 
+	if botForfeit {
+		r.saveBotForfeit(c.UserID)
+	}
+
 	// Handle bet payouts outside of lock
 	if shouldPayWinner && remainingClient != nil {
 		// Winner gets both bets (opponent forfeited)
-		log.Printf("Room.handleDisconnect: opponent left, paying winner=%d pot=%d %s",
-			remainingUID, r.BetAmount*2, r.Currency)
+		log.Printf("Room.handleDisconnect: opponent left, paying winner=%d", remainingUID)
 		r.payoutWinner(&remainingUID, remainingUID, c.UserID)
 	} else if shouldRefundDisconnecting {
 		// Game never started, refund disconnecting player
@@ -678,16 +845,26 @@ func (r *Room) handleDisconnect(c *Client) bool {
 		r.refundBet(c.UserID)
 	}
 
+	// итог турнирного матча: игра уже сыграна или соперник ушел посреди нее
+	if r.isTournament() && shouldNotifyWinner {
+		if r.game.IsFinished() {
+			r.reportTournament(r.matchWinner(), domain.TournamentReasonWin)
+		} else {
+			r.reportTournament(&remainingUID, domain.TournamentReasonOpponentLeft)
+		}
+	}
+
 	// Send win notification without holding lock (avoids deadlock with r.send)
 	if shouldNotifyWinner && remainingClient != nil {
-		winAmount := r.BetAmount * 2
+		winAmount := r.winAmount()
 		data, _ := json.Marshal(Message{
-			Type: "result",
-			Payload: map[string]any{
-				"you":        "win",
-				"reason":     "opponent_left",
-				"win_amount": winAmount,
-				"currency":   r.Currency,
+			Type: MsgResult,
+			Payload: ResultPayload{
+				You:       "win",
+				Reason:    "opponent_left",
+				WinAmount: winAmount,
+				Currency:  r.Currency,
+				Series:    r.seriesPayload(remainingUID, c.UserID),
 			},
 		})
 		select {
@@ -712,54 +889,54 @@ func (r *Room) handleDisconnect(c *Client) bool {
 }
 
 func (r *Room) HandleMessage(c *Client, raw []byte) {
-	var msg struct {
-		Type  string      `json:"type"`
-		Value interface{} `json:"value"`
+	msg, perr := ParseClientMessage(c.ProtocolVersion, r.game.Type(), raw)
+	if perr != nil {
+		logger.Debug("Room.HandleMessage: сообщение отклонено", "room", r.ID, "user", c.UserID, "code", perr.Code, "error", perr.Message)
+		r.sendError(c.UserID, perr.Code, perr.Message)
+		return
 	}
 
-	if err := json.Unmarshal(raw, &msg); err != nil {
-		log.Printf("Room.HandleMessage: failed to unmarshal: %v", err)
+	switch msg.Type {
+	case MsgPing:
+		return
+	case MsgRematch:
+		r.handleRematch(c, true)
+		return
+	case MsgRematchDecline:
+		r.handleRematch(c, false)
+		return
+	case MsgEmote, MsgChat:
+		r.handleChat(c, msg)
+		return
+	case MsgMute:
+		r.handleMute(c, msg.Data.(bool))
 		return
 	}
 
-	log.Printf("Room.HandleMessage: room=%s user=%d type=%s value=%v valueType=%T raw=%s", r.ID, c.UserID, msg.Type, msg.Value, msg.Value, string(raw))
+	// без данных хода: расстановка мин не должна попадать в логи
+	logger.Debug("Room.HandleMessage: ход", "room", r.ID, "user", c.UserID, "type", msg.Type)
 
-	// Convert value to appropriate type for the game
-	var moveValue interface{} = msg.Value
-
-	// For RPS, value should be a string
-	// For Mines setup, value should be []int
-	// For Mines move, value should be int
-	if r.game.Type() == "mines" {
-		// Handle setup (array of mine positions)
-		if arr, ok := msg.Value.([]interface{}); ok {
-			intArr := make([]int, len(arr))
-			for i, v := range arr {
-				if num, ok := v.(float64); ok {
-					intArr[i] = int(num)
-				}
-			}
-			moveValue = intArr
-			log.Printf("Room.HandleMessage: converted mines setup value to []int: %v", intArr)
-		}
-		// Handle move (single cell number)
-		if num, ok := msg.Value.(float64); ok {
-			moveValue = int(num)
-			log.Printf("Room.HandleMessage: converted mines move value to int: %d", int(num))
-		} else {
-			log.Printf("Room.HandleMessage: mines move value NOT float64, type=%T value=%v", msg.Value, msg.Value)
-		}
+	// расстановка только в фазе подготовки, ходы только после нее
+	setupDone := r.game.IsSetupComplete()
+	if msg.Type == MsgSetup && setupDone {
+		r.sendError(c.UserID, ErrCodeInvalidMove, "setup is already complete")
+		return
+	}
+	if msg.Type == MsgMove && !setupDone {
+		r.sendError(c.UserID, ErrCodeInvalidMove, "setup is not complete")
+		return
 	}
 
 	// Обрабатываем ход через игру
-	if err := r.game.HandleMove(c.UserID, moveValue); err != nil {
-		log.Printf("Room.HandleMessage: invalid move from user=%d: %v", c.UserID, err)
-		r.send(c.UserID, Message{
-			Type: "error",
-			Payload: map[string]string{"message": err.Error()},
-		})
+	if err := r.game.HandleMove(c.UserID, msg.Data); err != nil {
+		logger.Debug("Room.HandleMessage: недопустимый ход", "room", r.ID, "user", c.UserID, "error", err)
+		r.sendError(c.UserID, ErrCodeInvalidMove, err.Error())
 		return
 	}
+	if msg.Type == MsgMove {
+		r.recordMove(c.UserID, msg.Data)
+	}
+	r.notifySpectators()
 
 	// Проверяем завершение setup фазы
 	r.mu.Lock()
@@ -792,10 +969,7 @@ func (r *Room) HandleMessage(c *Client, raw []byte) {
 	}
 
 	// Если раунд завершён - проверяем результат
-	isComplete := r.game.IsRoundComplete()
-	log.Printf("Room.HandleMessage: after move, isComplete=%v room=%s user=%d", isComplete, r.ID, c.UserID)
-
-	if isComplete {
+	if r.game.IsRoundComplete() {
 		log.Printf("Room.HandleMessage: round complete in room=%s, stopping timer and calling checkRound", r.ID)
 
 		r.mu.Lock()
@@ -807,8 +981,6 @@ func (r *Room) HandleMessage(c *Client, raw []byte) {
 
 		// Один вызов checkRound - он сам обработает результат
 		r.checkRound()
-	} else {
-		log.Printf("Room.HandleMessage: waiting for other player in room=%s", r.ID)
 	}
 }
 
@@ -872,11 +1044,12 @@ func (r *Room) broadcastResult(result *game.GameResult) {
 	// Send to player 1
 	if c1 != nil {
 		data1, _ := json.Marshal(Message{
-			Type: "result",
-			Payload: map[string]any{
-				"you":     result1,
-				"reason":  result.Reason,
-				"details": details1,
+			Type: MsgResult,
+			Payload: ResultPayload{
+				You:     result1,
+				Reason:  result.Reason,
+				Details: details1,
+				Series:  r.seriesPayload(p1, p2),
 			},
 		})
 		select {
@@ -892,11 +1065,12 @@ func (r *Room) broadcastResult(result *game.GameResult) {
 	// Send to player 2
 	if c2 != nil {
 		data2, _ := json.Marshal(Message{
-			Type: "result",
-			Payload: map[string]any{
-				"you":     result2,
-				"reason":  result.Reason,
-				"details": details2,
+			Type: MsgResult,
+			Payload: ResultPayload{
+				You:     result2,
+				Reason:  result.Reason,
+				Details: details2,
+				Series:  r.seriesPayload(p2, p1),
 			},
 		})
 		select {
@@ -910,7 +1084,6 @@ func (r *Room) broadcastResult(result *game.GameResult) {
 	}
 }
 
-
 func (r *Room) send(userID int64, msg Message) {
 	data, err := json.Marshal(msg)
 	if err != nil {
@@ -926,12 +1099,12 @@ func (r *Room) send(userID int64, msg Message) {
 		// blocking send with generous timeout to improve reliability in tests
 		select {
 		case c.Send <- data:
-			log.Printf("Room.send: ✅ sent to user=%d type=%s", userID, msg.Type)
+			logger.Debug("Room.send: отправлено", "room", r.ID, "user", userID, "type", msg.Type)
 		case <-time.After(2 * time.Second):
-			log.Printf("Room.send: ❌ timeout sending to user=%d type=%s", userID, msg.Type)
+			logger.Warn("Room.send: таймаут отправки", "room", r.ID, "user", userID, "type", msg.Type)
 		}
 	} else {
-		log.Printf("Room.send: ❌ user=%d not in room", userID)
+		logger.Debug("Room.send: игрока нет в комнате", "room", r.ID, "user", userID, "type", msg.Type)
 	}
 
 	// if this was a result message, wait for client writePump ack
@@ -945,6 +1118,14 @@ func (r *Room) send(userID int64, msg Message) {
 	}
 }
 
+// sendError отправляет игроку ошибку с машиночитаемым кодом
+func (r *Room) sendError(userID int64, code, message string) {
+	r.send(userID, Message{
+		Type:    MsgError,
+		Payload: ErrorPayload{Code: code, Message: message},
+	})
+}
+
 func (r *Room) broadcast(msg Message) {
 	for _, c := range r.Clients {
 		// use blocking send to ensure clients receive broadcast (with timeout)
@@ -983,16 +1164,16 @@ func (r *Room) sendMinesRoundResult() {
 		myMove := roundResult.PlayerMoves[p1]
 		oppMove := roundResult.PlayerMoves[p2]
 		r.send(p1, Message{
-			Type: "round_result",
-			Payload: map[string]any{
-				"round":         roundResult.Round,
-				"next_round":    nextRound,
-				"your_move":     myMove.Cell,
-				"your_hit":      myMove.HitMine,
-				"opponent_move": oppMove.Cell,
-				"opponent_hit":  oppMove.HitMine,
-				"history":       minesGame.GetMoveHistory(p1),
-				"timestamp":     time.Now().UnixMilli(),
+			Type: MsgRoundResult,
+			Payload: RoundResultPayload{
+				Round:        roundResult.Round,
+				NextRound:    nextRound,
+				YourMove:     myMove.Cell,
+				YourHit:      myMove.HitMine,
+				OpponentMove: oppMove.Cell,
+				OpponentHit:  oppMove.HitMine,
+				History:      minesGame.GetMoveHistory(p1),
+				Timestamp:    time.Now().UnixMilli(),
 			},
 		})
 	}
@@ -1002,16 +1183,16 @@ func (r *Room) sendMinesRoundResult() {
 		myMove := roundResult.PlayerMoves[p2]
 		oppMove := roundResult.PlayerMoves[p1]
 		r.send(p2, Message{
-			Type: "round_result",
-			Payload: map[string]any{
-				"round":         roundResult.Round,
-				"next_round":    nextRound,
-				"your_move":     myMove.Cell,
-				"your_hit":      myMove.HitMine,
-				"opponent_move": oppMove.Cell,
-				"opponent_hit":  oppMove.HitMine,
-				"history":       minesGame.GetMoveHistory(p2),
-				"timestamp":     time.Now().UnixMilli(),
+			Type: MsgRoundResult,
+			Payload: RoundResultPayload{
+				Round:        roundResult.Round,
+				NextRound:    nextRound,
+				YourMove:     myMove.Cell,
+				YourHit:      myMove.HitMine,
+				OpponentMove: oppMove.Cell,
+				OpponentHit:  oppMove.HitMine,
+				History:      minesGame.GetMoveHistory(p2),
+				Timestamp:    time.Now().UnixMilli(),
 			},
 		})
 	}
@@ -1028,6 +1209,22 @@ func (r *Room) saveResult() {
 	players := r.game.Players()
 	p1, p2 := players[0], players[1]
 
+	// в серии банк достается победителю серии, а не последней партии
+	winnerID := result.WinnerID
+	details := result.Details
+	if r.isSeries() {
+		r.mu.RLock()
+		winnerID = r.series.Winner(p1, p2)
+		details = make(map[string]interface{}, len(result.Details)+1)
+		for k, v := range result.Details {
+			details[k] = v
+		}
+		details["series"] = r.series.details(p1, p2)
+		r.mu.RUnlock()
+	}
+
+	r.reportTournament(winnerID, domain.TournamentReasonWin)
+
 	log.Printf("Room.saveResult: room=%s storing game bet=%d currency=%s", r.ID, r.BetAmount, r.Currency)
 
 	// Pay out the winner (if there's a bet and it hasn't been paid yet)
@@ -1039,16 +1236,16 @@ func (r *Room) saveResult() {
 	r.mu.Unlock()
 
 	if shouldPay {
-		r.payoutWinner(result.WinnerID, p1, p2)
+		r.payoutWinner(winnerID, p1, p2)
 	}
 
 	// Calculate win amounts for history
 	var winAmount1, winAmount2 int64
-	if result.WinnerID != nil {
-		if *result.WinnerID == p1 {
-			winAmount1 = r.BetAmount * winnerPayoutMultiplier
+	if winnerID != nil {
+		if *winnerID == p1 {
+			winAmount1 = r.winAmount()
 		} else {
-			winAmount2 = r.BetAmount * winnerPayoutMultiplier
+			winAmount2 = r.winAmount()
 		}
 	} else {
 		// Draw - both get refunded (handled in payoutWinner)
@@ -1063,13 +1260,13 @@ func (r *Room) saveResult() {
 			PlayerAID: p1,
 			PlayerBID: p2,
 			Moves:     make(map[int64]string),
-			WinnerID:  result.WinnerID,
+			WinnerID:  winnerID,
 		}
-		go func(game *domain.Game) {
-			if err := r.GameRepo.Create(context.Background(), game); err != nil {
+		r.hub.track(func() {
+			if err := r.GameRepo.Create(context.Background(), g); err != nil {
 				log.Printf("Room.saveResult: game store failed: %v", err)
 			}
-		}(g)
+		})
 	}
 
 	// Save to new game_history table
@@ -1077,15 +1274,14 @@ func (r *Room) saveResult() {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 
 		gameType := string(r.game.Type())
-		details := result.Details
 
 		// Determine results for each player
 		var result1, result2 domain.GameResult
 
-		if result.WinnerID == nil {
+		if winnerID == nil {
 			result1 = domain.GameResultDraw
 			result2 = domain.GameResultDraw
-		} else if *result.WinnerID == p1 {
+		} else if *winnerID == p1 {
 			result1 = domain.GameResultWin
 			result2 = domain.GameResultLose
 		} else {
@@ -1095,25 +1291,37 @@ func (r *Room) saveResult() {
 
 		currency := domain.Currency(r.Currency)
 
+		// бот занимает второе место; в users его нет, поэтому opponent_id пустой
+		vsBot := isBotPlayer(p2)
+		opponent1 := &p2
+		if vsBot {
+			opponent1 = nil
+		}
+
 		// Save for player 1
 		gh1 := &domain.GameHistory{
 			UserID:     p1,
 			GameType:   domain.GameType(gameType),
 			Mode:       domain.GameModePVP,
-			OpponentID: &p2,
+			OpponentID: opponent1,
 			RoomID:     &r.ID,
 			Result:     result1,
 			BetAmount:  r.BetAmount,
 			WinAmount:  winAmount1,
 			Currency:   currency,
+			VsBot:      vsBot,
 			Details:    details,
 		}
-		go func() {
+		r.hub.track(func() {
 			defer cancel()
 			if err := r.GameHistoryRepo.Create(ctx, gh1); err != nil {
 				log.Printf("Room.saveResult: game_history p1 failed: %v", err)
 			}
-		}()
+		})
+
+		if vsBot {
+			return
+		}
 
 		// Save for player 2
 		gh2 := &domain.GameHistory{
@@ -1128,11 +1336,11 @@ func (r *Room) saveResult() {
 			Currency:   currency,
 			Details:    details,
 		}
-		go func() {
+		r.hub.track(func() {
 			if err := r.GameHistoryRepo.Create(ctx, gh2); err != nil {
 				log.Printf("Room.saveResult: game_history p2 failed: %v", err)
 			}
-		}()
+		})
 	}
 }
 
@@ -1145,8 +1353,6 @@ func (r *Room) payoutWinner(winnerID *int64, p1, p2 int64) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	totalPot := r.BetAmount * 2 // Both players bet the same amount
-
 	if winnerID == nil {
 		// Draw - refund both players
 		log.Printf("Room.payoutWinner: draw in room=%s, refunding both players %d %s", r.ID, r.BetAmount, r.Currency)
@@ -1154,20 +1360,35 @@ func (r *Room) payoutWinner(winnerID *int64, p1, p2 int64) {
 			if _, err := r.UserRepo.UpdateCoins(ctx, p1, r.BetAmount); err != nil {
 				log.Printf("Room.payoutWinner: failed to refund p1: %v", err)
 			}
-			if _, err := r.UserRepo.UpdateCoins(ctx, p2, r.BetAmount); err != nil {
-				log.Printf("Room.payoutWinner: failed to refund p2: %v", err)
+			if !isBotPlayer(p2) {
+				if _, err := r.UserRepo.UpdateCoins(ctx, p2, r.BetAmount); err != nil {
+					log.Printf("Room.payoutWinner: failed to refund p2: %v", err)
+				}
 			}
 		} else {
 			if _, err := r.UserRepo.UpdateGems(ctx, p1, r.BetAmount); err != nil {
 				log.Printf("Room.payoutWinner: failed to refund p1: %v", err)
 			}
-			if _, err := r.UserRepo.UpdateGems(ctx, p2, r.BetAmount); err != nil {
-				log.Printf("Room.payoutWinner: failed to refund p2: %v", err)
+			if !isBotPlayer(p2) {
+				if _, err := r.UserRepo.UpdateGems(ctx, p2, r.BetAmount); err != nil {
+					log.Printf("Room.payoutWinner: failed to refund p2: %v", err)
+				}
 			}
 		}
+	} else if isBotPlayer(*winnerID) {
+		// ставка игрока остается у платформы
+		log.Printf("Room.payoutWinner: bot won in room=%s, stake %d %s kept by house", r.ID, r.BetAmount, r.Currency)
 	} else {
-		// Winner gets the entire pot (2x bet)
+		// Winner gets the pot (2x bet) minus rake
+		// рейк пишется вместе с выплатой; без записи рейка банк зачисляется целиком обычным путем
+		totalPot, paid := r.payWinnerWithRake(ctx, *winnerID)
+		r.mu.Lock()
+		r.paidPot = totalPot
+		r.mu.Unlock()
 		log.Printf("Room.payoutWinner: winner=%d in room=%s gets %d %s", *winnerID, r.ID, totalPot, r.Currency)
+		if paid {
+			return
+		}
 		if r.Currency == string(domain.CurrencyCoins) {
 			if _, err := r.UserRepo.UpdateCoins(ctx, *winnerID, totalPot); err != nil {
 				log.Printf("Room.payoutWinner: failed to pay winner: %v", err)
@@ -1180,6 +1401,31 @@ func (r *Room) payoutWinner(winnerID *int64, p1, p2 int64) {
 	}
 }
 
+// saveBotForfeit records a loss for a player who left a game against the bot
+func (r *Room) saveBotForfeit(userID int64) {
+	if r.GameHistoryRepo == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	gh := &domain.GameHistory{
+		UserID:    userID,
+		GameType:  domain.GameType(r.game.Type()),
+		Mode:      domain.GameModePVP,
+		RoomID:    &r.ID,
+		Result:    domain.GameResultLose,
+		BetAmount: r.BetAmount,
+		Currency:  domain.Currency(r.Currency),
+		VsBot:     true,
+		Details:   map[string]interface{}{"reason": "player_left"},
+	}
+	if err := r.GameHistoryRepo.Create(ctx, gh); err != nil {
+		log.Printf("Room.saveBotForfeit: game_history failed: %v", err)
+	}
+}
+
 // cancelGameNoOpponent cancels the game when no opponent is found, refunds bet and notifies player
 func (r *Room) cancelGameNoOpponent() {
 	r.mu.Lock()
@@ -1189,13 +1435,27 @@ func (r *Room) cancelGameNoOpponent() {
 	// Get the client to notify
 	client := r.Clients[playerID]
 
+	// комнату уже закрыла остановка сервера
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+
 	// Mark bet as refunded
-	shouldRefund := r.BetAmount > 0 && !r.betPaid
+	// (ставку создателя приватной комнаты вернет истечение вызова)
+	shouldRefund := r.BetAmount > 0 && !r.betPaid && r.holdsStake(playerID)
 	if shouldRefund {
 		r.betPaid = true
 	}
 	r.mu.Unlock()
 
+	var refunded int64
+	if shouldRefund {
+		refunded = r.BetAmount
+	}
+
+	r.recordEvent(domain.MatchEventCancelled, 0, map[string]interface{}{"reason": "no_opponent"})
+
 	// Refund the bet
 	if shouldRefund {
 		log.Printf("Room.cancelGameNoOpponent: refunding %d %s to user=%d", r.BetAmount, r.Currency, playerID)
@@ -1205,12 +1465,12 @@ func (r *Room) cancelGameNoOpponent() {
 	// Notify player that no opponent was found
 	if client != nil {
 		data, _ := json.Marshal(Message{
-			Type: "result",
-			Payload: map[string]any{
-				"you":      "cancelled",
-				"reason":   "no_opponent",
-				"refunded": r.BetAmount,
-				"currency": r.Currency,
+			Type: MsgResult,
+			Payload: ResultPayload{
+				You:      "cancelled",
+				Reason:   "no_opponent",
+				Refunded: refunded,
+				Currency: r.Currency,
 			},
 		})
 		select {
@@ -1225,6 +1485,37 @@ func (r *Room) cancelGameNoOpponent() {
 	r.cleanup()
 }
 
+// resultDelivered reports whether the finished game's result was recorded and broadcast
+func (r *Room) resultDelivered() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.resultSent
+}
+
+// waitForOpponent blocks until the second player joins a private room or the challenge expires
+func (r *Room) waitForOpponent() bool {
+	timer := time.NewTimer(time.Until(r.opponentDeadline))
+	defer timer.Stop()
+
+	select {
+	case <-r.opponentJoined:
+		return true
+	case <-timer.C:
+		// в турнирном матче оба места заняты заранее, важно, подключились ли оба
+		if r.isTournament() {
+			return r.playersJoined()
+		}
+		return r.game.Players()[1] != 0
+	}
+}
+
+// holdsStake reports whether the room is responsible for the player's stake.
+// В приватной комнате ставка создателя остается в вызове, пока соперник не подключился.
+// Caller must hold r.mu.
+func (r *Room) holdsStake(userID int64) bool {
+	return r.ChallengeCode == "" || r.challengeStarted || userID != r.ChallengeCreatorID
+}
+
 // refundBet refunds a player's bet (used when game is cancelled or player disconnects early)
 func (r *Room) refundBet(userID int64) {
 	if r.UserRepo == nil || r.BetAmount == 0 {
@@ -1246,3 +1537,20 @@ func (r *Room) refundBet(userID int64) {
 		}
 	}
 }
+
+// matchedPayload описывает матч для игрока: соперник, длина серии, турнир
+func (r *Room) matchedPayload(opponent OpponentInfo) MatchedPayload {
+	p := MatchedPayload{
+		RoomID:   r.ID,
+		Opponent: opponent,
+		BestOf:   r.series.BestOf,
+	}
+	if r.tournament != nil {
+		p.TournamentID = r.tournament.TournamentID
+		p.Round = r.tournament.Round
+	}
+	if mines, ok := r.game.(*game.MinesGame); ok {
+		p.Mines = minesOptionsPayload(mines.Options())
+	}
+	return p
+}
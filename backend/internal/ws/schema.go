@@ -0,0 +1,207 @@
+package ws
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// описание сообщения протокола: тип, направление и payload (nil - без payload)
+type messageSpec struct {
+	Type      string
+	Direction string // client или server
+	Payload   interface{}
+}
+
+// все сообщения протокола; ProtocolSchema строит по ним JSON Schema
+var protocolMessages = []messageSpec{
+	{MsgAuth, "client", AuthPayload{}},
+	{MsgJoin, "client", JoinPayload{}},
+	{MsgMove, "client", MovePayload{}},
+	{MsgSetup, "client", SetupPayload{}},
+	{MsgPing, "client", nil},
+	{MsgRematch, "client", nil},
+	{MsgRematchDecline, "client", nil},
+	{MsgEmote, "client", EmotePayload{}},
+	{MsgChat, "client", ChatPayload{}},
+	{MsgMute, "client", MutePayload{}},
+
+	{MsgAuthOK, "server", AuthOKPayload{}},
+	{MsgReady, "server", ReadyPayload{}},
+	{MsgPing, "server", nil},
+	{MsgMatched, "server", MatchedPayload{}},
+	{MsgState, "server", StatePayload{}},
+	{MsgSetupComplete, "server", nil},
+	{MsgStart, "server", StartPayload{}},
+	{MsgRoundDraw, "server", RoundDrawPayload{}},
+	{MsgRoundResult, "server", RoundResultPayload{}},
+	{MsgResult, "server", ResultPayload{}},
+	{MsgError, "server", ErrorPayload{}},
+	{MsgNextGame, "server", NextGamePayload{}},
+	{MsgRematchOpen, "server", RematchOpenPayload{}},
+	{MsgRematchOffer, "server", RematchOfferPayload{}},
+	{MsgRematchCancelled, "server", RematchCancelledPayload{}},
+	{MsgChat, "server", ChatMessagePayload{}},
+	{MsgMuted, "server", MutedPayload{}},
+	{MsgDraining, "server", DrainingPayload{}},
+	{MsgSpectatorState, "server", SpectatorStatePayload{}},
+	{MsgLobbyStats, "server", LobbyStats{}},
+}
+
+var protocolErrorCodes = []string{
+	ErrCodeMalformed,
+	ErrCodeUnknownType,
+	ErrCodeInvalidPayload,
+	ErrCodeInvalidMove,
+	ErrCodeNotAvailable,
+	ErrCodeUnsupportedVersion,
+	ErrCodeRateLimited,
+	ErrCodeChatRejected,
+	ErrCodeServerDraining,
+	ErrCodeAuthRequired,
+	ErrCodeAuthFailed,
+	ErrCodeAuthTimeout,
+	ErrCodeJoinRejected,
+	ErrCodePairBlocked,
+}
+
+// ProtocolSchema возвращает JSON Schema (draft 2020-12) WebSocket протокола
+func ProtocolSchema() map[string]any {
+	defs := make(map[string]any)
+	var clientRefs, serverRefs []any
+	clientTypes := []string{}
+	serverTypes := []string{}
+
+	for _, spec := range protocolMessages {
+		envelope := map[string]any{
+			"type":                 "object",
+			"required":             []string{"type"},
+			"additionalProperties": false,
+			"properties": map[string]any{
+				"type": map[string]any{"const": spec.Type},
+			},
+		}
+		if spec.Payload != nil {
+			t := reflect.TypeOf(spec.Payload)
+			schemaDef(t, defs)
+			envelope["properties"].(map[string]any)["payload"] = map[string]any{"$ref": "#/$defs/" + t.Name()}
+			envelope["required"] = []string{"type", "payload"}
+		}
+
+		name := spec.Direction + "." + spec.Type
+		defs[name] = envelope
+		ref := map[string]any{"$ref": "#/$defs/" + name}
+		if spec.Direction == "client" {
+			clientRefs = append(clientRefs, ref)
+			clientTypes = append(clientTypes, spec.Type)
+		} else {
+			serverRefs = append(serverRefs, ref)
+			serverTypes = append(serverTypes, spec.Type)
+		}
+	}
+
+	// коды ошибок берутся из констант ErrCode*
+	errorDef := defs["ErrorPayload"].(map[string]any)
+	errorDef["properties"].(map[string]any)["code"] = map[string]any{"type": "string", "enum": protocolErrorCodes}
+
+	defs["ClientMessage"] = map[string]any{"oneOf": clientRefs}
+	defs["ServerMessage"] = map[string]any{"oneOf": serverRefs}
+
+	return map[string]any{
+		"$schema":                "https://json-schema.org/draft/2020-12/schema",
+		"$id":                    "pvp-ws-protocol",
+		"title":                  "PvP WebSocket protocol",
+		"x-protocol-version":     ProtocolVersion,
+		"x-min-protocol-version": MinProtocolVersion,
+		"x-client-message-types": clientTypes,
+		"x-server-message-types": serverTypes,
+		"x-error-codes":          protocolErrorCodes,
+		"$defs":                  defs,
+		"oneOf":                  []any{map[string]any{"$ref": "#/$defs/ClientMessage"}, map[string]any{"$ref": "#/$defs/ServerMessage"}},
+	}
+}
+
+// schemaDef добавляет определение структуры в defs и возвращает ссылку на него
+func schemaDef(t reflect.Type, defs map[string]any) map[string]any {
+	ref := map[string]any{"$ref": "#/$defs/" + t.Name()}
+	if _, ok := defs[t.Name()]; ok {
+		return ref
+	}
+	// заглушка против рекурсии
+	defs[t.Name()] = map[string]any{}
+
+	properties := make(map[string]any)
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := jsonFieldName(field)
+		if name == "" || !field.IsExported() {
+			continue
+		}
+		properties[name] = schemaType(field.Type, field.Tag, defs)
+		if !isOmitEmpty(field.Tag) {
+			required = append(required, name)
+		}
+	}
+
+	defs[t.Name()] = map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+	return ref
+}
+
+func schemaType(t reflect.Type, tag reflect.StructTag, defs map[string]any) map[string]any {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return schemaType(t.Elem(), tag, defs)
+
+	case reflect.String:
+		s := map[string]any{"type": "string"}
+		if enum := tag.Get("enum"); enum != "" {
+			s["enum"] = strings.Split(enum, ",")
+		}
+		if min, ok := intTag(tag, "minlen"); ok {
+			s["minLength"] = min
+		}
+		if max, ok := intTag(tag, "maxlen"); ok {
+			s["maxLength"] = max
+		}
+		return s
+
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		s := map[string]any{"type": "integer"}
+		if min, ok := intTag(tag, "min"); ok {
+			s["minimum"] = min
+		}
+		if max, ok := intTag(tag, "max"); ok {
+			s["maximum"] = max
+		}
+		return s
+
+	case reflect.Slice:
+		s := map[string]any{"type": "array", "items": schemaType(t.Elem(), tag, defs)}
+		if items, ok := intTag(tag, "items"); ok {
+			s["minItems"] = items
+			s["maxItems"] = items
+		}
+		if unique, _ := strconv.ParseBool(tag.Get("unique")); unique {
+			s["uniqueItems"] = true
+		}
+		return s
+
+	case reflect.Map:
+		return map[string]any{"type": "object"}
+
+	case reflect.Struct:
+		return schemaDef(t, defs)
+	}
+
+	// interface{} - любое значение
+	return map[string]any{}
+}
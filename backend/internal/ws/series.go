@@ -0,0 +1,432 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"telegram_webapp/internal/domain"
+)
+
+const (
+	// пауза между партиями серии, чтобы клиенты успели показать результат
+	seriesGamePause = 3 * time.Second
+	// сколько ждем согласия обоих игроков на реванш
+	rematchWindow = 20 * time.Second
+)
+
+var ErrRematchInsufficientBalance = errors.New("insufficient balance for rematch")
+
+// допустимая длина серии: одиночная партия, до 2 или до 3 побед
+func ValidBestOf(n int) bool {
+	return n == 1 || n == 3 || n == 5
+}
+
+// счет серии best-of-N; банк выплачивается только по окончании серии
+type Series struct {
+	BestOf int
+	Wins   map[int64]int
+	Draws  int
+	Games  int
+}
+
+func NewSeries(bestOf int) *Series {
+	if !ValidBestOf(bestOf) {
+		bestOf = 1
+	}
+	return &Series{
+		BestOf: bestOf,
+		Wins:   make(map[int64]int),
+	}
+}
+
+// учитывает результат партии (nil - ничья)
+func (s *Series) Record(winnerID *int64) {
+	s.Games++
+	if winnerID == nil {
+		s.Draws++
+		return
+	}
+	s.Wins[*winnerID]++
+}
+
+func (s *Series) winsNeeded() int {
+	return s.BestOf/2 + 1
+}
+
+// серия закончена, если кто-то набрал нужное число побед
+// ничьи не решают серию, поэтому общее число партий ограничено 2*N
+func (s *Series) Finished() bool {
+	for _, wins := range s.Wins {
+		if wins >= s.winsNeeded() {
+			return true
+		}
+	}
+	return s.Games >= s.BestOf*2
+}
+
+// победитель серии (nil при равном счете)
+func (s *Series) Winner(p1, p2 int64) *int64 {
+	switch {
+	case s.Wins[p1] > s.Wins[p2]:
+		return &p1
+	case s.Wins[p2] > s.Wins[p1]:
+		return &p2
+	default:
+		return nil
+	}
+}
+
+// счет серии с точки зрения игрока (для payload result)
+func (s *Series) scoreFor(userID, opponentID int64) *SeriesScore {
+	return &SeriesScore{
+		BestOf:       s.BestOf,
+		Game:         s.Games,
+		YourWins:     s.Wins[userID],
+		OpponentWins: s.Wins[opponentID],
+		Draws:        s.Draws,
+		Finished:     s.Finished(),
+	}
+}
+
+// счет серии для game_history.details
+func (s *Series) details(p1, p2 int64) map[string]interface{} {
+	return map[string]interface{}{
+		"best_of": s.BestOf,
+		"games":   s.Games,
+		"wins": map[int64]int{
+			p1: s.Wins[p1],
+			p2: s.Wins[p2],
+		},
+		"draws": s.Draws,
+	}
+}
+
+// isSeries сообщает, играется ли в комнате серия из нескольких партий
+func (r *Room) isSeries() bool {
+	return r.series != nil && r.series.BestOf > 1
+}
+
+// seriesPayload возвращает счет серии для игрока или nil для одиночной партии
+func (r *Room) seriesPayload(userID, opponentID int64) *SeriesScore {
+	if !r.isSeries() {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.series.scoreFor(userID, opponentID)
+}
+
+// recordSeriesGame учитывает завершенную партию в счете серии (один раз на партию)
+func (r *Room) recordSeriesGame(winnerID *int64) {
+	r.mu.Lock()
+	if r.gameRecorded {
+		r.mu.Unlock()
+		return
+	}
+	r.gameRecorded = true
+	r.series.Record(winnerID)
+	log.Printf("Room.recordSeriesGame: room=%s game=%d wins=%v draws=%d", r.ID, r.series.Games, r.series.Wins, r.series.Draws)
+	r.mu.Unlock()
+}
+
+// seriesContinues сообщает, нужна ли еще партия в серии
+func (r *Room) seriesContinues() bool {
+	if !r.isSeries() {
+		return false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed || len(r.Clients) < 2 {
+		return false
+	}
+	return !r.series.Finished()
+}
+
+// startNextGame сбрасывает игру и запускает следующую партию в той же комнате
+func (r *Room) startNextGame(rematch bool) {
+	if !rematch {
+		time.Sleep(seriesGamePause)
+	}
+
+	r.game.Reset()
+	r.recordNextGame(rematch)
+
+	setupDone := make(chan struct{})
+	r.mu.Lock()
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+	}
+	r.roundStarted = false
+	r.setupCompleted = false
+	r.gameRecorded = false
+	r.resultSent = false
+	r.setupDoneChan = setupDone
+	clients := r.getClientsUnlocked()
+	nextGame := r.series.Games + 1
+	r.mu.Unlock()
+
+	needsSetup := !r.game.IsSetupComplete()
+	players := r.game.Players()
+	for uid, c := range clients {
+		opponentID := players[0]
+		if uid == players[0] {
+			opponentID = players[1]
+		}
+		r.broadcastToClients(map[int64]*Client{uid: c}, Message{
+			Type: MsgNextGame,
+			Payload: NextGamePayload{
+				RoomID:    r.ID,
+				Game:      nextGame,
+				Rematch:   rematch,
+				Setup:     needsSetup,
+				BetAmount: r.BetAmount,
+				Currency:  r.Currency,
+				Series:    r.seriesPayload(uid, opponentID),
+			},
+		})
+	}
+	r.notifySpectators()
+
+	log.Printf("Room.startNextGame: room=%s game=%d rematch=%v setup=%v", r.ID, nextGame, rematch, needsSetup)
+
+	if needsSetup {
+		go r.awaitSetup(setupDone)
+		return
+	}
+
+	r.mu.Lock()
+	r.setupCompleted = true
+	r.mu.Unlock()
+	r.startRound()
+}
+
+// awaitSetup завершает подготовку следующей партии по таймауту
+// (если оба игрока расставились раньше, HandleMessage закроет done)
+func (r *Room) awaitSetup(done chan struct{}) {
+	timer := time.NewTimer(r.game.SetupTimeout())
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return
+	case <-timer.C:
+	}
+
+	r.mu.Lock()
+	skip := r.closed || r.setupCompleted
+	r.mu.Unlock()
+	if skip {
+		return
+	}
+
+	r.completeSetup()
+	time.Sleep(100 * time.Millisecond)
+	r.mu.Lock()
+	r.roundStarted = false
+	r.mu.Unlock()
+	r.startRound()
+}
+
+// canRematch: реванш возможен только между двумя подключенными людьми, которых политика пар
+// допускает до нового матча. reason - причина rematch_cancelled при отказе политики пар
+func (r *Room) canRematch() (bool, string) {
+	// сервер останавливается: новый матч не начинаем (h.mu берется до r.mu)
+	if r.hub != nil && r.hub.Draining() {
+		return false, ""
+	}
+	r.mu.RLock()
+	players := r.game.Players()
+	ok := !r.closed && len(r.Clients) == 2 && !isBotPlayer(players[1]) && r.botStrategy == nil && !r.isTournament()
+	r.mu.RUnlock()
+	if !ok {
+		return false, ""
+	}
+
+	// недавний матч или подозрение в сговоре: реванш не должен обходить запрет публичной очереди
+	if r.hub != nil && r.hub.Pairs != nil && !r.hub.Pairs.Allows(players[0], players[1], time.Now()) {
+		return false, ErrCodePairBlocked
+	}
+	return true, ""
+}
+
+// awaitRematch открывает окно реванша после окончания матча.
+// Возвращает true, если оба согласились и новый матч запущен в этой же комнате.
+func (r *Room) awaitRematch() bool {
+	if ok, reason := r.canRematch(); !ok {
+		if reason != "" {
+			log.Printf("Room.awaitRematch: room=%s rematch blocked: %s", r.ID, reason)
+			r.cancelRematch(reason, 0)
+		}
+		return false
+	}
+
+	ready := make(chan struct{})
+	declined := make(chan int64, 2)
+	r.mu.Lock()
+	r.rematchVotes = make(map[int64]bool)
+	r.rematchReady = ready
+	r.rematchDeclined = declined
+	clients := r.getClientsUnlocked()
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		r.rematchVotes = nil
+		r.rematchReady = nil
+		r.rematchDeclined = nil
+		r.mu.Unlock()
+	}()
+
+	r.broadcastToClients(clients, Message{
+		Type: MsgRematchOpen,
+		Payload: RematchOpenPayload{
+			RoomID:    r.ID,
+			BetAmount: r.BetAmount,
+			Currency:  r.Currency,
+			BestOf:    r.series.BestOf,
+			ExpiresAt: time.Now().Add(rematchWindow).UnixMilli(),
+		},
+	})
+
+	timer := time.NewTimer(rematchWindow)
+	defer timer.Stop()
+
+	select {
+	case <-ready:
+	case userID := <-declined:
+		r.cancelRematch("declined", userID)
+		return false
+	case c := <-r.Disconnect:
+		r.mu.Lock()
+		delete(r.Clients, c.UserID)
+		r.mu.Unlock()
+		r.cancelRematch("opponent_left", c.UserID)
+		return false
+	case <-timer.C:
+		r.cancelRematch("timeout", 0)
+		return false
+	case <-r.shutdown:
+		r.cancelRematch(reasonServerShutdown, 0)
+		return false
+	}
+
+	players := r.game.Players()
+	if err := r.escrowRematch(players[0], players[1]); err != nil {
+		log.Printf("Room.awaitRematch: room=%s escrow failed: %v", r.ID, err)
+		r.cancelRematch("insufficient_balance", 0)
+		return false
+	}
+
+	r.mu.Lock()
+	r.series = NewSeries(r.series.BestOf)
+	r.betPaid = false
+	r.paidPot = 0
+	r.mu.Unlock()
+	if r.hub != nil && r.hub.Pairs != nil {
+		r.hub.Pairs.Record(players[0], players[1], time.Now())
+	}
+
+	log.Printf("Room.awaitRematch: room=%s rematch accepted, stakes re-escrowed", r.ID)
+	r.startNextGame(true)
+	return true
+}
+
+// handleRematch обрабатывает предложение/согласие или отказ от реванша
+func (r *Room) handleRematch(c *Client, accept bool) {
+	r.mu.Lock()
+	if r.rematchVotes == nil {
+		r.mu.Unlock()
+		r.sendError(c.UserID, ErrCodeNotAvailable, "rematch is not available")
+		return
+	}
+
+	if !accept {
+		declined := r.rematchDeclined
+		r.mu.Unlock()
+		select {
+		case declined <- c.UserID:
+		default:
+		}
+		return
+	}
+
+	if r.rematchVotes[c.UserID] {
+		r.mu.Unlock()
+		return
+	}
+	r.rematchVotes[c.UserID] = true
+	votes := len(r.rematchVotes)
+	ready := r.rematchReady
+	var opponent *Client
+	for uid, cl := range r.Clients {
+		if uid != c.UserID {
+			opponent = cl
+		}
+	}
+	r.mu.Unlock()
+
+	if votes >= 2 {
+		close(ready)
+		return
+	}
+
+	// первый голос - это предложение сопернику
+	if opponent != nil {
+		r.broadcastToClients(map[int64]*Client{opponent.UserID: opponent}, Message{
+			Type:    MsgRematchOffer,
+			Payload: RematchOfferPayload{From: c.UserID},
+		})
+	}
+}
+
+// cancelRematch сообщает оставшимся игрокам, что реванша не будет
+func (r *Room) cancelRematch(reason string, userID int64) {
+	r.mu.RLock()
+	clients := r.getClientsUnlocked()
+	r.mu.RUnlock()
+
+	log.Printf("Room.cancelRematch: room=%s reason=%s user=%d", r.ID, reason, userID)
+	r.broadcastToClients(clients, Message{
+		Type: MsgRematchCancelled,
+		Payload: RematchCancelledPayload{
+			Reason: reason,
+			UserID: userID,
+		},
+	})
+}
+
+// escrowRematch заново списывает ставки обоих игроков; при нехватке у второго возвращает первому
+func (r *Room) escrowRematch(p1, p2 int64) error {
+	if r.BetAmount == 0 || r.UserRepo == nil {
+		return nil
+	}
+	if err := r.debitBet(p1); err != nil {
+		return err
+	}
+	if err := r.debitBet(p2); err != nil {
+		r.refundBet(p1)
+		return err
+	}
+	return nil
+}
+
+// debitBet списывает ставку с игрока (баланс не уходит в минус)
+func (r *Room) debitBet(userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var err error
+	if r.Currency == string(domain.CurrencyCoins) {
+		_, err = r.UserRepo.UpdateCoins(ctx, userID, -r.BetAmount)
+	} else {
+		_, err = r.UserRepo.UpdateGems(ctx, userID, -r.BetAmount)
+	}
+	if err != nil {
+		log.Printf("Room.debitBet: user=%d bet=%d %s failed: %v", userID, r.BetAmount, r.Currency, err)
+		return ErrRematchInsufficientBalance
+	}
+	return nil
+}
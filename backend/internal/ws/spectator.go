@@ -0,0 +1,272 @@
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// максимум зрителей в одной комнате по умолчанию
+const DefaultMaxSpectators = 50
+
+var (
+	ErrRoomNotFound     = errors.New("room not found")
+	ErrRoomNotWatchable = errors.New("room is not available for spectators")
+	ErrSpectatorsFull   = errors.New("spectator limit reached")
+)
+
+// зритель: соединение только на чтение, получает безопасное состояние комнаты
+type Spectator struct {
+	UserID int64
+	Conn   *websocket.Conn
+	Send   chan []byte
+	Room   *Room
+	Done   chan struct{}
+}
+
+func NewSpectator(userID int64, conn *websocket.Conn) *Spectator {
+	return &Spectator{
+		UserID: userID,
+		Conn:   conn,
+		Send:   make(chan []byte, 64),
+		Done:   make(chan struct{}),
+	}
+}
+
+// Run запускает запись и чтение; входящие сообщения зрителя игнорируются
+func (s *Spectator) Run(hub *Hub, roomID string) error {
+	room, err := hub.AddSpectator(roomID, s)
+	if err != nil {
+		return err
+	}
+	s.Room = room
+
+	go s.writePump()
+	room.sendSpectatorState(s)
+	s.readPump()
+	return nil
+}
+
+// читает только для обработки pong и закрытия соединения
+func (s *Spectator) readPump() {
+	defer func() {
+		if s.Room != nil {
+			s.Room.removeSpectator(s)
+		}
+		close(s.Done)
+		_ = s.Conn.Close()
+	}()
+
+	s.Conn.SetReadLimit(512)
+	s.Conn.SetReadDeadline(time.Now().Add(pongWait))
+	s.Conn.SetPongHandler(func(string) error {
+		s.Conn.SetReadDeadline(time.Now().Add(pongWait))
+		return nil
+	})
+
+	for {
+		if _, _, err := s.Conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Spectator) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		_ = s.Conn.Close()
+	}()
+
+	for {
+		select {
+		case msg := <-s.Send:
+			s.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := s.Conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				log.Printf("Spectator.writePump: пользователь=%d ошибка записи: %v", s.UserID, err)
+				return
+			}
+		case <-ticker.C:
+			s.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := s.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-s.Done:
+			return
+		}
+	}
+}
+
+// неблокирующая отправка: медленный зритель пропускает обновления, а не тормозит игру
+func (s *Spectator) trySend(data []byte) {
+	select {
+	case s.Send <- data:
+	default:
+		log.Printf("Spectator.trySend: буфер зрителя=%d заполнен, пропускаем обновление", s.UserID)
+	}
+}
+
+// информация о живой комнате для списка трансляций
+type LiveRoomInfo struct {
+	RoomID     string  `json:"room_id"`
+	GameType   string  `json:"game_type"`
+	BetAmount  int64   `json:"bet_amount"`
+	Currency   string  `json:"currency"`
+	BestOf     int     `json:"best_of"`
+	Players    []int64 `json:"players"`
+	VsBot      bool    `json:"vs_bot"`
+	Spectators int     `json:"spectators"`
+	CreatedAt  int64   `json:"created_at"`
+
+	TournamentID int64 `json:"tournament_id,omitempty"`
+}
+
+// возвращает идущие публичные матчи (оба места заняты)
+func (h *Hub) LiveRooms() []LiveRoomInfo {
+	h.mu.RLock()
+	rooms := make([]*Room, 0, len(h.Rooms))
+	for _, room := range h.Rooms {
+		rooms = append(rooms, room)
+	}
+	h.mu.RUnlock()
+
+	result := make([]LiveRoomInfo, 0, len(rooms))
+	for _, room := range rooms {
+		if !room.watchable() {
+			continue
+		}
+		players := room.game.Players()
+		room.mu.RLock()
+		info := LiveRoomInfo{
+			RoomID:     room.ID,
+			GameType:   string(room.game.Type()),
+			BetAmount:  room.BetAmount,
+			Currency:   room.Currency,
+			BestOf:     room.series.BestOf,
+			Players:    []int64{players[0], players[1]},
+			VsBot:      isBotPlayer(players[1]),
+			Spectators: len(room.spectators),
+			CreatedAt:  room.createdAt.UnixMilli(),
+		}
+		if room.tournament != nil {
+			info.TournamentID = room.tournament.TournamentID
+		}
+		room.mu.RUnlock()
+		result = append(result, info)
+	}
+	return result
+}
+
+// подключает зрителя к комнате с учетом лимита
+func (h *Hub) AddSpectator(roomID string, s *Spectator) (*Room, error) {
+	h.mu.RLock()
+	room, ok := h.Rooms[roomID]
+	max := h.MaxSpectators
+	h.mu.RUnlock()
+
+	if !ok {
+		return nil, ErrRoomNotFound
+	}
+	if !room.watchable() {
+		return nil, ErrRoomNotWatchable
+	}
+	if max <= 0 {
+		max = DefaultMaxSpectators
+	}
+
+	room.mu.Lock()
+	defer room.mu.Unlock()
+	if len(room.spectators) >= max {
+		return nil, ErrSpectatorsFull
+	}
+	room.spectators[s] = struct{}{}
+	log.Printf("Room.AddSpectator: room=%s spectator=%d (total=%d)", room.ID, s.UserID, len(room.spectators))
+	return room, nil
+}
+
+// за публичным матчем можно наблюдать, когда оба места заняты и игра не закончена
+func (r *Room) watchable() bool {
+	if r.ChallengeCode != "" {
+		return false
+	}
+	return r.game.Players()[1] != 0 && !r.game.IsFinished()
+}
+
+func (r *Room) removeSpectator(s *Spectator) {
+	r.mu.Lock()
+	delete(r.spectators, s)
+	r.mu.Unlock()
+}
+
+// состояние комнаты для зрителей
+func (r *Room) spectatorMessage() Message {
+	players := r.game.Players()
+	return Message{
+		Type: MsgSpectatorState,
+		Payload: SpectatorStatePayload{
+			RoomID:    r.ID,
+			GameType:  string(r.game.Type()),
+			BetAmount: r.BetAmount,
+			Currency:  r.Currency,
+			Players:   []int64{players[0], players[1]},
+			VsBot:     isBotPlayer(players[1]),
+			State:     r.game.SerializeSpectatorState(),
+			Timestamp: time.Now().UnixMilli(),
+		},
+	}
+}
+
+func (r *Room) sendSpectatorState(s *Spectator) {
+	data, err := json.Marshal(r.spectatorMessage())
+	if err != nil {
+		return
+	}
+	s.trySend(data)
+}
+
+// рассылает актуальное состояние всем зрителям комнаты
+func (r *Room) notifySpectators() {
+	r.mu.RLock()
+	if len(r.spectators) == 0 {
+		r.mu.RUnlock()
+		return
+	}
+	spectators := make([]*Spectator, 0, len(r.spectators))
+	for s := range r.spectators {
+		spectators = append(spectators, s)
+	}
+	r.mu.RUnlock()
+
+	data, err := json.Marshal(r.spectatorMessage())
+	if err != nil {
+		return
+	}
+	for _, s := range spectators {
+		s.trySend(data)
+	}
+}
+
+// закрывает соединения зрителей при завершении комнаты
+func (r *Room) closeSpectators() {
+	r.mu.Lock()
+	spectators := r.spectators
+	r.spectators = make(map[*Spectator]struct{})
+	r.mu.Unlock()
+
+	if len(spectators) == 0 {
+		return
+	}
+
+	data, _ := json.Marshal(r.spectatorMessage())
+	for s := range spectators {
+		s.trySend(data)
+		// даем writePump отправить финальное состояние
+		go func(s *Spectator) {
+			time.Sleep(time.Second)
+			_ = s.Conn.Close()
+		}(s)
+	}
+}
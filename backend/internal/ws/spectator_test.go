@@ -0,0 +1,63 @@
+package ws
+
+import (
+	"encoding/json"
+	"testing"
+
+	"telegram_webapp/internal/game"
+)
+
+func TestHubSpectators(t *testing.T) {
+	h := NewHub(nil, nil)
+	h.MaxSpectators = 2
+
+	h.mu.Lock()
+	waiting := h.buildRoom(game.TypeRPS, [2]int64{1, 0}, 10, "gems", 1)
+	live := h.buildRoom(game.TypeRPS, [2]int64{2, 3}, 10, "gems", 1)
+	h.mu.Unlock()
+
+	if _, err := h.AddSpectator("missing", NewSpectator(10, nil)); err != ErrRoomNotFound {
+		t.Fatalf("несуществующая комната: %v", err)
+	}
+	if _, err := h.AddSpectator(waiting.ID, NewSpectator(10, nil)); err != ErrRoomNotWatchable {
+		t.Fatalf("комната без соперника: %v", err)
+	}
+
+	first, second := NewSpectator(10, nil), NewSpectator(11, nil)
+	for _, s := range []*Spectator{first, second} {
+		room, err := h.AddSpectator(live.ID, s)
+		if err != nil || room != live {
+			t.Fatalf("зритель %d: комната %v, ошибка %v", s.UserID, room, err)
+		}
+	}
+	if _, err := h.AddSpectator(live.ID, NewSpectator(12, nil)); err != ErrSpectatorsFull {
+		t.Fatalf("сверх лимита: %v", err)
+	}
+	if rooms := h.LiveRooms(); len(rooms) != 1 || rooms[0].RoomID != live.ID || rooms[0].Spectators != 2 {
+		t.Fatalf("живые комнаты: %+v", rooms)
+	}
+
+	// обновления получают только подключенные зрители
+	live.removeSpectator(first)
+	live.notifySpectators()
+	select {
+	case data := <-first.Send:
+		t.Fatalf("ушедший зритель получил обновление: %s", data)
+	default:
+	}
+	var msg struct {
+		Type    string                `json:"type"`
+		Payload SpectatorStatePayload `json:"payload"`
+	}
+	if err := json.Unmarshal(<-second.Send, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Type != MsgSpectatorState || msg.Payload.RoomID != live.ID {
+		t.Fatalf("состояние для зрителя: %+v", msg)
+	}
+
+	// место освободилось после ухода
+	if _, err := h.AddSpectator(live.ID, NewSpectator(12, nil)); err != nil {
+		t.Fatalf("после ухода зрителя: %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"telegram_webapp/internal/domain"
+	"telegram_webapp/internal/game"
+)
+
+var (
+	ErrTournamentRoomExists = errors.New("tournament match room already exists")
+	ErrTournamentRoomFailed = errors.New("failed to create tournament match room")
+)
+
+// матч турнира, который играется в комнате
+type tournamentMatch struct {
+	TournamentID int64
+	MatchID      int64
+	Round        int
+
+	// сообщает итог движку турниров, вызывается один раз
+	report   func(winnerID *int64, reason string)
+	reported sync.Once
+}
+
+// StartTournamentMatch создает комнату матча сетки с двумя заранее известными игроками.
+// Ставки в комнате нет: взносы уже в призовом фонде турнира.
+// Если к дедлайну подключились не оба, пришедший побеждает, а если никто - матч пуст.
+func (h *Hub) StartTournamentMatch(t *domain.Tournament, m *domain.TournamentMatch, report func(winnerID *int64, reason string)) (string, error) {
+	if m.Player1ID == nil || m.Player2ID == nil || m.Deadline == nil {
+		return "", ErrTournamentRoomFailed
+	}
+
+	h.mu.Lock()
+	if h.draining {
+		h.mu.Unlock()
+		return "", ErrHubDraining
+	}
+	if _, exists := h.TournamentRooms[m.ID]; exists {
+		h.mu.Unlock()
+		return "", ErrTournamentRoomExists
+	}
+	room := h.buildRoom(game.GameType(t.GameType), [2]int64{*m.Player1ID, *m.Player2ID}, 0, t.Currency, t.BestOf)
+	if room == nil {
+		h.mu.Unlock()
+		return "", ErrTournamentRoomFailed
+	}
+	room.tournament = &tournamentMatch{
+		TournamentID: t.ID,
+		MatchID:      m.ID,
+		Round:        m.Round,
+		report:       report,
+	}
+	room.opponentDeadline = *m.Deadline
+	h.TournamentRooms[m.ID] = room
+	h.mu.Unlock()
+
+	log.Printf("Hub.StartTournamentMatch: комната=%s турнир=%d матч=%d раунд=%d игроки=%d,%d до %s",
+		room.ID, t.ID, m.ID, m.Round, *m.Player1ID, *m.Player2ID, m.Deadline.Format(time.RFC3339))
+	go room.Run()
+	return room.ID, nil
+}
+
+// назначает клиента в комнату его турнирного матча
+func (h *Hub) AssignTournament(c *Client) *Room {
+	h.mu.Lock()
+	if h.draining {
+		h.mu.Unlock()
+		h.rejectDraining(c)
+		return nil
+	}
+	room := h.TournamentRooms[c.TournamentMatchID]
+	if room == nil {
+		h.mu.Unlock()
+		log.Printf("Hub.AssignTournament: нет комнаты для матча=%d (пользователь=%d)", c.TournamentMatchID, c.UserID)
+		return nil
+	}
+	players := room.game.Players()
+	if c.UserID != players[0] && c.UserID != players[1] {
+		h.mu.Unlock()
+		log.Printf("Hub.AssignTournament: пользователь=%d не участник матча=%d", c.UserID, c.TournamentMatchID)
+		return nil
+	}
+	h.UserRoom[c.UserID] = room.ID
+	h.mu.Unlock()
+
+	log.Printf("Hub.AssignTournament: пользователь=%d подключается к комнате=%s матч=%d", c.UserID, room.ID, c.TournamentMatchID)
+
+	select {
+	case room.Register <- c:
+	case <-time.After(5 * time.Second):
+		log.Printf("Hub.AssignTournament: ТАЙМАУТ регистрации пользователя=%d в комнату=%s", c.UserID, room.ID)
+		return nil
+	}
+	return room
+}
+
+// isTournament: комната играет матч турнира
+func (r *Room) isTournament() bool {
+	return r.tournament != nil
+}
+
+// reportTournament передает итог матча турниру (только первый вызов)
+func (r *Room) reportTournament(winnerID *int64, reason string) {
+	if r.tournament == nil || r.tournament.report == nil {
+		return
+	}
+	r.tournament.reported.Do(func() {
+		log.Printf("Room.reportTournament: room=%s match=%d winner=%v reason=%s", r.ID, r.tournament.MatchID, winnerID, reason)
+		go r.tournament.report(winnerID, reason)
+	})
+}
+
+// оба игрока турнирного матча подключались
+func (r *Room) playersJoined() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.opponentJoinedDone
+}
+
+// tournamentNoShow завершает матч, если к дедлайну подключились не оба игрока.
+// Пришедший побеждает, если не пришел никто - матч пуст. Возвращает true, если комната закрыта.
+func (r *Room) tournamentNoShow() bool {
+	r.mu.Lock()
+	if r.opponentJoinedDone {
+		r.mu.Unlock()
+		return false
+	}
+	var winnerID *int64
+	var winner *Client
+	for uid, c := range r.Clients {
+		id := uid
+		winnerID = &id
+		winner = c
+	}
+	r.mu.Unlock()
+
+	log.Printf("Room.tournamentNoShow: room=%s match=%d winner=%v", r.ID, r.tournament.MatchID, winnerID)
+	r.recordEvent(domain.MatchEventCancelled, 0, map[string]interface{}{"reason": "no_show", "winner_id": winnerID})
+	r.reportTournament(winnerID, domain.TournamentReasonNoShow)
+
+	if winner != nil {
+		data, _ := json.Marshal(Message{
+			Type:    MsgResult,
+			Payload: ResultPayload{You: "win", Reason: "opponent_no_show", Currency: r.Currency},
+		})
+		select {
+		case winner.Send <- data:
+		case <-time.After(2 * time.Second):
+			log.Printf("Room.tournamentNoShow: timeout notifying user=%d", winner.UserID)
+		}
+	}
+
+	r.cleanup()
+	return true
+}
+
+// победитель завершенного матча (с учетом серии)
+func (r *Room) matchWinner() *int64 {
+	players := r.game.Players()
+	if r.isSeries() {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		return r.series.Winner(players[0], players[1])
+	}
+	if result := r.game.CheckResult(); result != nil {
+		return result.WinnerID
+	}
+	return nil
+}
@@ -1,12 +1,73 @@
 package ws
 
+// версии протокола: клиент передает protocol_version при подключении
+// v1 - устаревший формат {type, value}, v2 - типизированный {type, payload}
+const (
+	ProtocolVersion    = 2
+	MinProtocolVersion = 1
+)
+
 const (
 	// клиент к серверу
-	MsgMove = "move"
-	MsgPing = "ping"
+	MsgMove           = "move"
+	MsgSetup          = "setup"
+	MsgPing           = "ping"
+	MsgRematch        = "rematch"         // предложить или принять реванш
+	MsgRematchDecline = "rematch_decline" // отказаться от реванша
+	MsgEmote          = "emote"           // эмоция из фиксированного набора
+	MsgChat           = "chat"            // короткий текст сопернику
+	MsgMute           = "mute"            // заглушить/включить чат соперника
+
+	// хендшейк подключения без токена в URL: auth первым кадром, затем join
+	MsgAuth = "auth"
+	MsgJoin = "join"
 
 	// сервер к клиенту
-	MsgMatchFound = "match_found"
-	MsgResult     = "result"
-	MsgError      = "error"
+	MsgAuthOK        = "auth_ok"
+	MsgReady         = "ready"
+	MsgMatchFound    = "match_found"
+	MsgMatched       = "matched"
+	MsgState         = "state"
+	MsgSetupComplete = "setup_complete"
+	MsgStart         = "start"
+	MsgRoundDraw     = "round_draw"
+	MsgRoundResult   = "round_result"
+	MsgResult        = "result"
+	MsgError         = "error"
+
+	// серия и реванш
+	MsgNextGame         = "next_game"
+	MsgRematchOpen      = "rematch_open"
+	MsgRematchOffer     = "rematch_offer"
+	MsgRematchCancelled = "rematch_cancelled"
+
+	// чат комнаты (MsgChat отправляется и сервером)
+	MsgMuted = "muted"
+
+	// сервер останавливается (переподключиться позже)
+	MsgDraining = "draining"
+
+	// сервер к подписчику лобби
+	MsgLobbyStats = "lobby_stats"
+
+	// сервер к зрителю
+	MsgSpectatorState = "spectator_state"
+)
+
+// коды ошибок протокола (payload.code сообщения error)
+const (
+	ErrCodeMalformed          = "malformed_message"
+	ErrCodeUnknownType        = "unknown_type"
+	ErrCodeInvalidPayload     = "invalid_payload"
+	ErrCodeInvalidMove        = "invalid_move"
+	ErrCodeNotAvailable       = "not_available"
+	ErrCodeUnsupportedVersion = "unsupported_protocol_version"
+	ErrCodeRateLimited        = "rate_limited"
+	ErrCodeChatRejected       = "chat_rejected"
+	ErrCodeServerDraining     = "server_draining"
+	ErrCodeAuthRequired       = "auth_required"
+	ErrCodeAuthFailed         = "auth_failed"
+	ErrCodeAuthTimeout        = "auth_timeout"
+	ErrCodeJoinRejected       = "join_rejected"
+	ErrCodePairBlocked        = "opponent_unavailable"
 )
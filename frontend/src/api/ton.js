@@ -10,6 +10,11 @@ export async function getWallet() {
   return api.get('/ton/wallet')
 }
 
+// nonce для TON Connect proof (одноразовый, привязан к пользователю)
+export async function getProofPayload() {
+  return api.post('/ton/proof/payload')
+}
+
 export async function connectWallet(account, proof) {
   return api.post('/ton/wallet/connect', { account, proof })
 }
@@ -166,7 +166,7 @@ export function PvPMinesGame({
   const submitSetup = () => {
     if (selectedMines.length !== MINES_COUNT) return
 
-    send({ type: 'setup', value: selectedMines })
+    send({ type: 'setup', payload: { mines: selectedMines } })
     setSetupSubmitted(true)
     // НЕ ставим phase='playing' здесь - ждём setup_complete от сервера
   }
@@ -191,7 +191,7 @@ export function PvPMinesGame({
     console.log('PvPMinesGame: sending move for cell', cellNum, 'to room', roomId)
     setSelectedCell(cellNum)
     stopTimer()
-    const moveData = { type: 'move', value: cellNum }
+    const moveData = { type: 'move', payload: { cell: cellNum } }
     console.log('PvPMinesGame: calling send() with', JSON.stringify(moveData))
     send(moveData)
     console.log('PvPMinesGame: send() completed')
@@ -134,7 +134,7 @@ export function PvPRPSGame({
     setSelectedMove(move)
     setWaiting(true)
     stopTimer()
-    send({ type: 'move', value: move })
+    send({ type: 'move', payload: { move } })
   }
 
   const handlePlayAgain = () => {
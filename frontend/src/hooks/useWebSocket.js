@@ -1,5 +1,9 @@
 import { useState, useCallback, useRef, useEffect } from 'react'
 import { getToken } from '../api/client'
+import wsProtocol from '../api/wsProtocol.schema.json'
+
+// версия протокола генерируется на бэкенде (go run ./cmd/ws_schema)
+const PROTOCOL_VERSION = wsProtocol['x-protocol-version']
 
 export function useWebSocket(gameType = 'rps') {
   const [status, setStatus] = useState('disconnected') // disconnected, connecting, waiting, matched, playing
@@ -12,6 +16,7 @@ export function useWebSocket(gameType = 'rps') {
 
   const wsRef = useRef(null)
   const handlersRef = useRef({})
+  const joinRef = useRef(null) // отправка join после auth_ok
 
   const connect = useCallback((betAmount, currency = 'gems') => {
     const token = getToken()
@@ -26,13 +31,14 @@ export function useWebSocket(gameType = 'rps') {
       wsRef.current = null
     }
 
+    // токен передается первым кадром auth, а не в URL (не попадает в логи прокси)
     let wsUrl
     const wsBase = import.meta.env.VITE_WS_URL
     if (wsBase) {
-      wsUrl = `${wsBase}/ws?token=${token}&bet=${betAmount}&game=${gameType}&currency=${currency}`
+      wsUrl = `${wsBase}/ws`
     } else {
       const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:'
-      wsUrl = `${protocol}//${window.location.host}/ws?token=${token}&bet=${betAmount}&game=${gameType}&currency=${currency}`
+      wsUrl = `${protocol}//${window.location.host}/ws`
     }
 
     setStatus('connecting')
@@ -40,6 +46,15 @@ export function useWebSocket(gameType = 'rps') {
     wsRef.current = ws
 
     ws.onopen = () => {
+      ws.send(JSON.stringify({ type: 'auth', payload: { token } }))
+    }
+
+    // после auth_ok отправляем параметры игры; ставка списывается только после входа
+    joinRef.current = () => {
+      ws.send(JSON.stringify({
+        type: 'join',
+        payload: { game: gameType, bet: Number(betAmount) || 0, currency, protocol_version: PROTOCOL_VERSION },
+      }))
       setStatus('waiting')
     }
 
@@ -69,6 +84,10 @@ export function useWebSocket(gameType = 'rps') {
     const payload = msg.payload || {}
 
     switch (msg.type) {
+      case 'auth_ok':
+        joinRef.current?.()
+        break
+
       case 'matched':
         setStatus('matched')
         setOpponent(payload.opponent)
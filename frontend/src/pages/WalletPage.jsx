@@ -68,6 +68,24 @@ export function WalletPage({ user, refreshBalance }) {
     fetchData()
   }, [fetchData])
 
+  // nonce для TON Connect proof: запрашиваем, пока кошелек не подключен, чтобы кошелек его подписал
+  useEffect(() => {
+    if (tonWallet || wallet || loading) return
+    tonConnectUI.setConnectRequestParameters({ state: 'loading' })
+    tonApi
+      .getProofPayload()
+      .then((res) => {
+        tonConnectUI.setConnectRequestParameters({
+          state: 'ready',
+          value: { tonProof: res.payload },
+        })
+      })
+      .catch((err) => {
+        console.error('Failed to get proof payload:', err)
+        tonConnectUI.setConnectRequestParameters(null)
+      })
+  }, [tonWallet, wallet, loading, tonConnectUI])
+
   // Sync TON Connect wallet with backend (только один раз при подключении)
   const [syncAttempted, setSyncAttempted] = useState(false)
 
@@ -79,13 +97,15 @@ export function WalletPage({ user, refreshBalance }) {
         setSyncAttempted(true) // помечаем что попытка была
         try {
           // Get proof from TON Connect
-          const proof = tonConnectUI.wallet?.connectItems?.tonProof
+          const tonProof = tonConnectUI.wallet?.connectItems?.tonProof
+          const proof = tonProof && 'proof' in tonProof ? tonProof.proof : null
 
           await tonApi.connectWallet(
             {
               address: tonWallet.account.address,
               chain: tonWallet.account.chain,
               publicKey: tonWallet.account.publicKey,
+              walletStateInit: tonWallet.account.walletStateInit,
             },
             proof || {
               timestamp: Date.now(),